@@ -0,0 +1,337 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/k8snetworkplumbingwg/sriovnet"
+	"github.com/k8snetworkplumbingwg/sriovnet/pkg/daemon"
+	"github.com/k8snetworkplumbingwg/sriovnet/pkg/inventory"
+	"github.com/k8snetworkplumbingwg/sriovnet/pkg/sysfssnapshot"
+)
+
+func runInspect(args []string, out, errOut io.Writer) error {
+	fs := newFlagSet("inspect", "[-o text|json|yaml]", errOut)
+	output := fs.String("o", "text", "output format: text, json or yaml")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *output != "text" && *output != string(inventory.FormatJSON) && *output != string(inventory.FormatYAML) {
+		fmt.Fprintf(errOut, "invalid output format %q\n", *output)
+		fs.Usage()
+		return errUsage
+	}
+	inv, err := sriovnet.GetNodeSriovInventory()
+	if err != nil {
+		return err
+	}
+	if *output != "text" {
+		data, err := inventory.Marshal(inv, inventory.Format(*output))
+		if err != nil {
+			return err
+		}
+		if _, err = out.Write(data); err != nil {
+			return err
+		}
+		if *output == string(inventory.FormatJSON) {
+			_, err = fmt.Fprintln(out)
+		}
+		return err
+	}
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	for _, pf := range inv.Pfs {
+		mode := "legacy"
+		if pf.Switchdev {
+			mode = "switchdev"
+		}
+		fmt.Fprintf(w, "PF %s\tdriver=%s\tnetdevs=%s\tnuma=%d\tvfs=%d/%d\tmode=%s\tuplink=%s\n", pf.PciAddress,
+			valueOrNone(pf.Driver), valueOrNone(strings.Join(pf.NetDevs, ",")), pf.NumaNode, pf.NumVfs,
+			pf.TotalVfs, mode, valueOrNone(pf.Uplink))
+		for _, vf := range pf.Vfs {
+			fmt.Fprintf(w, "  VF %d\t%s\tdriver=%s\tnetdev=%s\tmac=%s\trep=%s\n", vf.Index, vf.PciAddress,
+				valueOrNone(vf.Driver), valueOrNone(vf.NetDev), valueOrNone(vf.MacAddress),
+				valueOrNone(vf.Representor))
+		}
+		for _, sf := range pf.Sfs {
+			fmt.Fprintf(w, "  SF %d\tport=%d\tdriver=%s\tnetdev=%s\tmac=%s\trep=%s\tstate=%s/%s\n", sf.SfNum,
+				sf.PortIndex, valueOrNone(sf.Driver), valueOrNone(sf.NetDev), valueOrNone(sf.MacAddress),
+				valueOrNone(sf.Representor), valueOrNone(string(sf.State)), valueOrNone(string(sf.OpState)))
+		}
+	}
+	return w.Flush()
+}
+
+func valueOrNone(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+func runEnableSriov(args []string, _, errOut io.Writer) error {
+	fs := newFlagSet("enable-sriov", "-pf <netdev> [-disable]", errOut)
+	pf := fs.String("pf", "", "PF netdev name")
+	disable := fs.Bool("disable", false, "disable the VFs instead")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := requireFlag(fs, "pf", *pf); err != nil {
+		return err
+	}
+	if *disable {
+		return sriovnet.DisableSriov(*pf)
+	}
+	if !sriovnet.IsSriovSupported(*pf) {
+		return fmt.Errorf("SR-IOV is not supported by %s", *pf)
+	}
+	return sriovnet.EnableSriov(*pf)
+}
+
+func runListReps(args []string, out, errOut io.Writer) error {
+	fs := newFlagSet("list-reps", "(-uplink <netdev> | -pci <PF PCI address>) [-flavour <flavour>]", errOut)
+	uplink := fs.String("uplink", "", "uplink representor netdev name")
+	pci := fs.String("pci", "", "PF PCI address, resolved to its uplink representor")
+	flavour := fs.String("flavour", "", "only list representors of the given port flavour (e.g pcivf)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *uplink == "" && *pci != "" {
+		var err error
+		if *uplink, err = sriovnet.GetUplinkRepresentor(*pci); err != nil {
+			return err
+		}
+	}
+	if err := requireFlag(fs, "uplink", *uplink); err != nil {
+		return err
+	}
+	var filter *sriovnet.RepresentorFilter
+	if *flavour != "" {
+		f, err := sriovnet.ParsePortFlavour(*flavour)
+		if err != nil {
+			return err
+		}
+		filter = &sriovnet.RepresentorFilter{Flavour: &f}
+	}
+	reps, err := sriovnet.ListRepresentors(*uplink, filter)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tFLAVOUR\tCONTROLLER\tPF\tINDEX")
+	for _, rep := range reps {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\n", rep.Name, rep.Flavour, rep.ControllerNum, rep.PfID, rep.FuncIndex)
+	}
+	return w.Flush()
+}
+
+func runCreateSf(args []string, out, errOut io.Writer) error {
+	fs := newFlagSet("create-sf", "-pci <PF PCI address> [-pfnum <n>] [-sfnum <n>] [-mac <address>]", errOut)
+	pci := fs.String("pci", "", "PF PCI address")
+	pfNum := fs.Int("pfnum", -1, "PF number of the SF, the PCI function of the PF if negative")
+	sfNum := fs.Int("sfnum", -1, "sfnum of the SF, the lowest free sfnum if negative")
+	mac := fs.String("mac", "", "hardware address of the SF")
+	timeout := fs.Duration("timeout", 0, "time to wait for the SF to be probed (default of the library if 0)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := requireFlag(fs, "pci", *pci); err != nil {
+		return err
+	}
+	if *pfNum < 0 {
+		pfAddr, err := sriovnet.ParsePciAddress(*pci)
+		if err != nil {
+			return err
+		}
+		*pfNum = int(pfAddr.Function)
+	}
+	cfg := &sriovnet.SfConfig{PfNum: *pfNum, Timeout: *timeout}
+	if *sfNum >= 0 {
+		cfg.SfNum = sfNum
+	}
+	if *mac != "" {
+		hwAddr, err := net.ParseMAC(*mac)
+		if err != nil {
+			return err
+		}
+		cfg.HwAddr = hwAddr
+	}
+	sf, err := sriovnet.DeploySF(*pci, cfg)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "sfnum=%d port=%s/%d representor=%s auxdev=%s netdev=%s rdma=%s\n", sf.SfNum,
+		sf.PfPciAddress, sf.PortIndex, valueOrNone(sf.Representor), valueOrNone(sf.AuxDev),
+		valueOrNone(sf.NetDev), valueOrNone(sf.RdmaDev))
+	return nil
+}
+
+// optionalBool is a boolean flag which tracks whether it was set
+type optionalBool struct {
+	set   bool
+	value bool
+}
+
+func (b *optionalBool) String() string {
+	if !b.set {
+		return ""
+	}
+	return fmt.Sprint(b.value)
+}
+
+func (b *optionalBool) Set(value string) error {
+	switch value {
+	case "true", "on":
+		b.value = true
+	case "false", "off":
+		b.value = false
+	default:
+		return fmt.Errorf("invalid boolean %q, expected on or off", value)
+	}
+	b.set = true
+	return nil
+}
+
+func runSetVf(args []string, _, errOut io.Writer) error {
+	fs := newFlagSet("set-vf", "-pf <netdev> -vf <index> [-vlan <id>] [-trust on|off] [-mac <address>] [-mtu <mtu>]",
+		errOut)
+	pf := fs.String("pf", "", "PF netdev name")
+	vfIndex := fs.Int("vf", -1, "VF index")
+	vlan := fs.Int("vlan", -1, "VLAN ID, 0 to clear")
+	var trust optionalBool
+	fs.Var(&trust, "trust", "trust the VF and disable its spoof check (on|off)")
+	mac := fs.String("mac", "", "hardware address of the VF, set through its representor (switchdev only)")
+	mtu := fs.Int("mtu", 0, "MTU of the VF and of its representor")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := requireFlag(fs, "pf", *pf); err != nil {
+		return err
+	}
+	if *vfIndex < 0 {
+		return requireFlag(fs, "vf", "")
+	}
+
+	handle, err := sriovnet.GetPfNetdevHandle(*pf)
+	if err != nil {
+		return err
+	}
+	var vf *sriovnet.VfObj
+	for _, obj := range handle.List {
+		if obj.Index == *vfIndex {
+			vf = obj
+		}
+	}
+	if vf == nil {
+		return fmt.Errorf("VF %d of %s not found", *vfIndex, *pf)
+	}
+
+	if *vlan >= 0 {
+		if err = sriovnet.SetVfVlan(handle, vf, *vlan); err != nil {
+			return fmt.Errorf("failed to set VLAN: %v", err)
+		}
+	}
+	if trust.set {
+		if err = sriovnet.SetVfPrivileged(handle, vf, trust.value); err != nil {
+			return fmt.Errorf("failed to set trust: %v", err)
+		}
+	}
+	if *mac != "" {
+		var hwAddr net.HardwareAddr
+		if hwAddr, err = net.ParseMAC(*mac); err != nil {
+			return err
+		}
+		var rep string
+		if rep, err = sriovnet.GetVfRepresentorByVfPciAddress(vf.PciAddress); err != nil {
+			return err
+		}
+		if err = sriovnet.SetPortFnHwAddr(rep, hwAddr); err != nil {
+			return fmt.Errorf("failed to set MAC address: %v", err)
+		}
+	}
+	if *mtu > 0 {
+		if err = sriovnet.SetVfAndRepresentorMtu(vf.PciAddress, *mtu); err != nil {
+			return fmt.Errorf("failed to set MTU: %v", err)
+		}
+	}
+	return nil
+}
+
+func runDaemon(args []string, _, errOut io.Writer) error {
+	fs := newFlagSet("daemon", "[-socket <path>] [-sf-timeout <duration>]", errOut)
+	socketPath := fs.String("socket", daemon.DefaultSocketPath, "path of the Unix socket to listen on")
+	sfTimeout := fs.Duration("sf-timeout", 0, "time to wait for a SF to be probed or removed (default 30s if 0)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	server := daemon.NewServer(&daemon.ServerOptions{SfTimeout: *sfTimeout})
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		<-signals
+		server.Close()
+	}()
+	fmt.Fprintf(errOut, "listening on %s\n", *socketPath)
+	return server.ListenAndServe(*socketPath)
+}
+
+func runSnapshot(args []string, _, errOut io.Writer) error {
+	fs := newFlagSet("snapshot", "-o <file> [-pci <PCI address>[,<PCI address>...]] [-anonymize]", errOut)
+	output := fs.String("o", "", "path of the snapshot archive to write")
+	pcis := fs.String("pci", "", "comma separated PCI addresses of the PFs to capture (default all the SR-IOV PFs)")
+	anonymize := fs.Bool("anonymize", false,
+		"replace the MAC addresses, switch IDs and InfiniBand GUIDs, recorded as is otherwise, with fake ones")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := requireFlag(fs, "o", *output); err != nil {
+		return err
+	}
+	var pciAddresses []string
+	if *pcis != "" {
+		pciAddresses = strings.Split(*pcis, ",")
+	} else {
+		pfs, err := sriovnet.ListSriovCapablePfs()
+		if err != nil {
+			return err
+		}
+		for _, pf := range pfs {
+			pciAddresses = append(pciAddresses, pf.PciAddress)
+		}
+	}
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	if err = sysfssnapshot.Capture(f, pciAddresses, &sysfssnapshot.CaptureOptions{Anonymize: *anonymize}); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(errOut, "captured %d PFs to %s\n", len(pciAddresses), *output)
+	return nil
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// sriovnet is a command line interface to the sriovnet library, for field debugging and scripting
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/k8snetworkplumbingwg/sriovnet"
+)
+
+// command is a sriovnet subcommand
+type command struct {
+	description string
+	run         func(args []string, out, errOut io.Writer) error
+}
+
+var commands = map[string]*command{
+	"inspect":      {"print the SR-IOV PFs of the node with their VFs and representors", runInspect},
+	"enable-sriov": {"enable (or disable) the VFs of a PF", runEnableSriov},
+	"list-reps":    {"list the representors of an uplink", runListReps},
+	"create-sf":    {"create, configure and activate a SF", runCreateSf},
+	"set-vf":       {"configure a VF", runSetVf},
+	"snapshot":     {"capture the sysfs of SR-IOV PFs into an archive for regression tests", runSnapshot},
+	"daemon":       {"serve VF allocation, representor lookup and SF lifecycle over a Unix socket", runDaemon},
+}
+
+// errUsage is returned for invalid command lines, the usage has already been printed
+var errUsage = errors.New("invalid usage")
+
+func usage(out io.Writer) {
+	fmt.Fprintf(out, "Usage: sriovnet [-v] <command> [flags]\n\nCommands:\n")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(out, "  %-14s %s\n", name, commands[name].description)
+	}
+	fmt.Fprintf(out, "\nRun 'sriovnet <command> -h' for the flags of a command.\n")
+}
+
+// newFlagSet returns a flag set of the given command printing its errors and usage to out
+func newFlagSet(name, args string, out io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(out)
+	fs.Usage = func() {
+		fmt.Fprintf(out, "Usage: sriovnet %s %s\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags parses args into fs, failing on positional arguments
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return flag.ErrHelp
+		}
+		return errUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(fs.Output(), "unexpected arguments: %s\n", strings.Join(fs.Args(), " "))
+		fs.Usage()
+		return errUsage
+	}
+	return nil
+}
+
+// requireFlag fails if the value of the given flag is empty
+func requireFlag(fs *flag.FlagSet, name, value string) error {
+	if value == "" {
+		fmt.Fprintf(fs.Output(), "flag -%s is required\n", name)
+		fs.Usage()
+		return errUsage
+	}
+	return nil
+}
+
+func run(args []string, out, errOut io.Writer) error {
+	fs := flag.NewFlagSet("sriovnet", flag.ContinueOnError)
+	fs.SetOutput(errOut)
+	fs.Usage = func() { usage(errOut) }
+	verbose := fs.Bool("v", false, "log the representor and VF resolution steps")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return errUsage
+	}
+	if fs.NArg() == 0 {
+		usage(errOut)
+		return errUsage
+	}
+	cmd, ok := commands[fs.Arg(0)]
+	if !ok {
+		fmt.Fprintf(errOut, "unknown command %q\n\n", fs.Arg(0))
+		usage(errOut)
+		return errUsage
+	}
+	if *verbose {
+		sriovnet.SetLogVerbosity(sriovnet.LogLevelDebug)
+	}
+	err := cmd.run(fs.Args()[1:], out, errOut)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	return err
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		if !errors.Is(err, errUsage) {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		}
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vishvananda/netlink"
+
+	"github.com/k8snetworkplumbingwg/sriovnet"
+	utilfs "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/filesystem"
+	"github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/netlinkops"
+	netlinkopsMocks "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/netlinkops/mocks"
+)
+
+func TestRunUsage(t *testing.T) {
+	for _, args := range [][]string{
+		{},
+		{"unknown"},
+		{"enable-sriov"},
+		{"list-reps"},
+		{"create-sf", "-pfnum", "1"},
+		{"set-vf", "-pf", "p0"},
+		{"set-vf", "-pf", "p0", "-vf", "1", "-trust", "maybe"},
+		{"inspect", "extra"},
+	} {
+		var out, errOut bytes.Buffer
+		assert.ErrorIs(t, run(args, &out, &errOut), errUsage, "args %v", args)
+		assert.Empty(t, out.String())
+		assert.Contains(t, errOut.String(), "Usage: sriovnet", "args %v", args)
+	}
+
+	var out, errOut bytes.Buffer
+	assert.NoError(t, run([]string{"set-vf", "-h"}, &out, &errOut))
+	assert.Contains(t, errOut.String(), "-trust")
+}
+
+func TestRunInspect(t *testing.T) {
+	var teardown func()
+	var err error
+	utilfs.Fs, teardown, err = utilfs.NewFakeFs(t.TempDir())
+	assert.NoError(t, err)
+	defer func() {
+		teardown()
+		utilfs.Fs = utilfs.DefaultFs{}
+	}()
+	pfPath := filepath.Join(sriovnet.PciSysDir, "0000:03:00.0")
+	assert.NoError(t, utilfs.Fs.MkdirAll(pfPath, os.FileMode(0755)))
+	assert.NoError(t, utilfs.Fs.WriteFile(filepath.Join(pfPath, "sriov_totalvfs"), []byte("8\n"), 0644))
+	assert.NoError(t, utilfs.Fs.WriteFile(filepath.Join(pfPath, "sriov_numvfs"), []byte("0\n"), 0644))
+
+	var out, errOut bytes.Buffer
+	assert.NoError(t, run([]string{"inspect"}, &out, &errOut))
+	assert.Contains(t, out.String(), "PF 0000:03:00.0")
+	assert.Contains(t, out.String(), "vfs=0/8")
+	assert.Contains(t, out.String(), "mode=legacy")
+
+	out.Reset()
+	assert.NoError(t, run([]string{"inspect", "-o", "json"}, &out, &errOut))
+	assert.Contains(t, out.String(), `"pciAddress": "0000:03:00.0"`)
+	out.Reset()
+	assert.NoError(t, run([]string{"inspect", "-o", "yaml"}, &out, &errOut))
+	assert.Contains(t, out.String(), "totalVfs: 8")
+	assert.ErrorIs(t, run([]string{"inspect", "-o", "xml"}, &out, &errOut), errUsage)
+}
+
+func TestRunCreateSfPfNum(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+	addErr := fmt.Errorf("no devlink support")
+	nlOpsMock.On("DevLinkPortAdd", "pci", "0000:03:00.1", uint16(sriovnet.PORT_FLAVOUR_PCI_SF),
+		netlink.DevLinkPortAddAttrs{PfNumber: 1, SfNumber: 5, SfNumberValid: true}).Return(nil, addErr).Once()
+	nlOpsMock.On("DevLinkPortAdd", "pci", "0000:03:00.1", uint16(sriovnet.PORT_FLAVOUR_PCI_SF),
+		netlink.DevLinkPortAddAttrs{PfNumber: 0, SfNumber: 5, SfNumberValid: true}).Return(nil, addErr).Once()
+
+	var out, errOut bytes.Buffer
+	// the PF number defaults to the PCI function of the PF
+	err := run([]string{"create-sf", "-pci", "0000:03:00.1", "-sfnum", "5"}, &out, &errOut)
+	assert.ErrorContains(t, err, "pfnum 1 sfnum 5")
+	err = run([]string{"create-sf", "-pci", "0000:03:00.1", "-pfnum", "0", "-sfnum", "5"}, &out, &errOut)
+	assert.ErrorContains(t, err, "pfnum 0 sfnum 5")
+	nlOpsMock.AssertExpectations(t)
+
+	assert.Error(t, run([]string{"create-sf", "-pci", "invalid"}, &out, &errOut))
+}
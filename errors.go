@@ -21,5 +21,12 @@ import (
 )
 
 var (
-	ErrDeviceNotFound = errors.New("device not found")
+	ErrDeviceNotFound    = errors.New("device not found")
+	ErrNoDriver          = errors.New("device is not bound to a driver")
+	ErrInvalidPciAddress = errors.New("invalid PCI address")
+	ErrNoNetDevice       = errors.New("device has no netdevice")
+	ErrNotSupported      = errors.New("operation not supported")
+	ErrNetDeviceExists   = errors.New("netdevice already exists")
+	ErrNoPhysPortName    = errors.New("netdevice has no phys_port_name")
+	ErrNoPhysSwitchID    = errors.New("netdevice has no phys_switch_id")
 )
@@ -80,6 +80,10 @@ func (attrib *fileObject) Read() (str string, err error) {
 }
 
 func (attrib *fileObject) Write(value string) (err error) {
+	return auditSysfsWrite(attrib.Path, value, func() error { return attrib.write(value) })
+}
+
+func (attrib *fileObject) write(value string) (err error) {
 	if attrib.File == nil {
 		err = attrib.OpenWO()
 		if err != nil {
@@ -17,6 +17,7 @@ limitations under the License.
 package sriovnet
 
 import (
+	"fmt"
 	"net"
 	"path/filepath"
 	"strconv"
@@ -28,6 +29,15 @@ const (
 	ibSriovPortFile             = "port"
 	ibSriovPortAdminFile        = "policy"
 	ibSriovPortAdminStateFollow = "Follow"
+	ibGUIDLen                   = 8
+)
+
+// InfiniBand VF port state policies
+const (
+	// IbVfPortPolicyFollow makes the VF port state follow the PF port state
+	IbVfPortPolicyFollow = ibSriovPortAdminStateFollow
+	IbVfPortPolicyUp     = "Up"
+	IbVfPortPolicyDown   = "Down"
 )
 
 func ibGetPortAdminState(pfNetdevName string, vfIndex int) (string, error) {
@@ -71,3 +81,27 @@ func ibSetPortGUID(pfNetdevName string, vfIndex int, guid net.HardwareAddr) erro
 	kernelGUIDFormat := guid.String()
 	return portGUIDFile.Write(kernelGUIDFormat)
 }
+
+func ibRdmaSetVfGUID(pfRdmaDevice string, vfIndex int, guidFile string, guid net.HardwareAddr) error {
+	if len(guid) != ibGUIDLen {
+		return fmt.Errorf("invalid GUID %s, expected %d bytes", guid.String(), ibGUIDLen)
+	}
+	path := filepath.Join(InfinibandSysDir, pfRdmaDevice, pcidevPrefix, ibSriovCfgDir, strconv.Itoa(vfIndex), guidFile)
+	if err := writePciSysfsFile(path, guid.String()); err != nil {
+		return fmt.Errorf("failed to set %s GUID of VF %d of %s: %v", guidFile, vfIndex, pfRdmaDevice, err)
+	}
+	return nil
+}
+
+// SetVfNodeGUIDByRdmaDevice sets the node GUID of the VF with the given index of the PF with the given
+// RDMA device (e.g 'mlx5_0') via sysfs. Unlike netlink, it does not require the VF to be bound to its
+// driver, e.g for VFs destined for vfio passthrough. The GUID is applied on the next VF driver bind.
+func SetVfNodeGUIDByRdmaDevice(pfRdmaDevice string, vfIndex int, guid net.HardwareAddr) error {
+	return ibRdmaSetVfGUID(pfRdmaDevice, vfIndex, ibSriovNodeFile, guid)
+}
+
+// SetVfPortGUIDByRdmaDevice sets the port GUID of the VF with the given index of the PF with the given
+// RDMA device (e.g 'mlx5_0') via sysfs. See SetVfNodeGUIDByRdmaDevice.
+func SetVfPortGUIDByRdmaDevice(pfRdmaDevice string, vfIndex int, guid net.HardwareAddr) error {
+	return ibRdmaSetVfGUID(pfRdmaDevice, vfIndex, ibSriovPortFile, guid)
+}
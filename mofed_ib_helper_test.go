@@ -0,0 +1,92 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sriovnet
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vishvananda/netlink"
+
+	utilfs "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/filesystem"
+)
+
+func TestSetVfGUIDByRdmaDevice(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+	setupRdmaDeviceEnv(t, "mlx5_0", "/sys/devices/pci0000:00/0000:03:00.0", []string{"ib0"})
+	vfDir := filepath.Join("/sys/devices/pci0000:00/0000:03:00.0", ibSriovCfgDir, "1")
+	assert.NoError(t, utilfs.Fs.MkdirAll(vfDir, os.FileMode(0755)))
+	for _, f := range []string{ibSriovNodeFile, ibSriovPortFile} {
+		assert.NoError(t, utilfs.Fs.WriteFile(filepath.Join(vfDir, f), []byte(""), os.FileMode(0644)))
+	}
+
+	guid, _ := net.ParseMAC("00:11:22:33:44:55:66:77")
+	assert.NoError(t, SetVfNodeGUIDByRdmaDevice("mlx5_0", 1, guid))
+	assert.Equal(t, "00:11:22:33:44:55:66:77", readFakeFile(t, filepath.Join(vfDir, ibSriovNodeFile)))
+	assert.NoError(t, SetVfPortGUIDByRdmaDevice("mlx5_0", 1, guid))
+	assert.Equal(t, "00:11:22:33:44:55:66:77", readFakeFile(t, filepath.Join(vfDir, ibSriovPortFile)))
+
+	assert.Error(t, SetVfNodeGUIDByRdmaDevice("mlx5_0", 2, guid))
+	mac, _ := net.ParseMAC("00:11:22:33:44:55")
+	assert.Error(t, SetVfPortGUIDByRdmaDevice("mlx5_0", 1, mac))
+}
+
+func TestConfigIbVfsNotInfiniband(t *testing.T) {
+	handle := &PfNetdevHandle{
+		PfNetdevName: "p0",
+		pfLinkHandle: &netlink.Device{LinkAttrs: netlink.LinkAttrs{Name: "p0", EncapType: etherEncapType}},
+	}
+	err := ConfigIbVfs(handle, &IbVfConfig{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "is not an InfiniBand device")
+}
+
+func TestConfigIbVfsNilConfig(t *testing.T) {
+	handle := &PfNetdevHandle{
+		PfNetdevName: "ib0",
+		pfLinkHandle: &netlink.Device{LinkAttrs: netlink.LinkAttrs{Name: "ib0", EncapType: ibEncapType}},
+	}
+	err := ConfigIbVfs(handle, nil)
+	assert.NoError(t, err)
+}
+
+func TestConfigIbVfsInvalidPortPolicy(t *testing.T) {
+	handle := &PfNetdevHandle{
+		PfNetdevName: "ib0",
+		pfLinkHandle: &netlink.Device{LinkAttrs: netlink.LinkAttrs{Name: "ib0", EncapType: ibEncapType}},
+		List:         []*VfObj{{Index: 0}},
+	}
+	err := ConfigIbVfs(handle, &IbVfConfig{PortPolicy: "up"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid VF port policy")
+}
+
+func TestConfigIbVfsInvalidGUID(t *testing.T) {
+	handle := &PfNetdevHandle{
+		PfNetdevName: "ib0",
+		pfLinkHandle: &netlink.Device{LinkAttrs: netlink.LinkAttrs{Name: "ib0", EncapType: ibEncapType}},
+		List:         []*VfObj{{Index: 0}},
+	}
+	mac, _ := net.ParseMAC("00:11:22:33:44:55")
+	err := ConfigIbVfs(handle, &IbVfConfig{GUIDs: map[int]net.HardwareAddr{0: mac}})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid GUID 00:11:22:33:44:55 for VF 0")
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+//nolint:gomnd
+package sriovnet
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	pciMaxDevice   = 0x1f
+	pciMaxFunction = 0x7
+)
+
+// PciAddress is a PCI address in Domain:Bus:Device.Function format, e.g 0000:03:00.4
+type PciAddress struct {
+	Domain   uint32
+	Bus      uint8
+	Device   uint8
+	Function uint8
+}
+
+// ParsePciAddress parses a PCI address in the DDDD:BB:DD.F format (e.g '0000:03:00.4'), or in the BB:DD.F
+// format (e.g '03:00.4') in which case the domain is assumed to be 0000. Hex digits are case-insensitive.
+func ParsePciAddress(pciAddress string) (PciAddress, error) {
+	addr := PciAddress{}
+	invalid := func() (PciAddress, error) {
+		return PciAddress{}, fmt.Errorf("%w %q", ErrInvalidPciAddress, pciAddress)
+	}
+
+	devFn := strings.Split(pciAddress, ".")
+	if len(devFn) != 2 {
+		return invalid()
+	}
+	parts := strings.Split(devFn[0], ":")
+	switch len(parts) {
+	case 3:
+		if len(parts[0]) < 4 || len(parts[0]) > 8 {
+			return invalid()
+		}
+		domain, err := strconv.ParseUint(parts[0], 16, 32)
+		if err != nil {
+			return invalid()
+		}
+		addr.Domain = uint32(domain)
+		parts = parts[1:]
+	case 2:
+	default:
+		return invalid()
+	}
+
+	if len(parts[0]) != 2 || len(parts[1]) != 2 || len(devFn[1]) != 1 {
+		return invalid()
+	}
+	bus, err := strconv.ParseUint(parts[0], 16, 8)
+	if err != nil {
+		return invalid()
+	}
+	device, err := strconv.ParseUint(parts[1], 16, 8)
+	if err != nil || device > pciMaxDevice {
+		return invalid()
+	}
+	function, err := strconv.ParseUint(devFn[1], 16, 8)
+	if err != nil || function > pciMaxFunction {
+		return invalid()
+	}
+	addr.Bus = uint8(bus)
+	addr.Device = uint8(device)
+	addr.Function = uint8(function)
+	return addr, nil
+}
+
+// String returns the PCI address in the canonical DDDD:BB:DD.F format used by sysfs
+func (a PciAddress) String() string {
+	return fmt.Sprintf("%04x:%02x:%02x.%x", a.Domain, a.Bus, a.Device, a.Function)
+}
+
+// NetDevices returns the netdevices of the PCI device, see GetNetDevicesFromPci
+func (a PciAddress) NetDevices() ([]string, error) {
+	return GetNetDevicesFromPci(a.String())
+}
+
+// Driver returns the name of the driver the PCI device is bound to, see GetDriverNameByPciAddress
+func (a PciAddress) Driver() (string, error) {
+	return GetDriverNameByPciAddress(a.String())
+}
+
+// PhysFn returns the PCI address of the parent PF of the VF, see GetPfPciFromVfPci
+func (a PciAddress) PhysFn() (PciAddress, error) {
+	pf, err := GetPfPciFromVfPci(a.String())
+	if err != nil {
+		return PciAddress{}, err
+	}
+	return ParsePciAddress(pf)
+}
+
+// VfIndex returns the index of the VF, see GetVfIndexByPciAddress
+func (a PciAddress) VfIndex() (int, error) {
+	return GetVfIndexByPciAddress(a.String())
+}
+
+// VfRepresentor returns the representor netdev name of the VF, see GetVfRepresentorByVfPciAddress
+func (a PciAddress) VfRepresentor() (string, error) {
+	return GetVfRepresentorByVfPciAddress(a.String())
+}
+
+// normalizePciAddress validates the given PCI address and returns it in the canonical format used by sysfs.
+// Public functions accepting a PCI address string should call it to fail early on malformed input,
+// callers holding a PciAddress can use its methods or pass its String() representation.
+func normalizePciAddress(pciAddress string) (string, error) {
+	addr, err := ParsePciAddress(pciAddress)
+	if err != nil {
+		return "", err
+	}
+	return addr.String(), nil
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sriovnet
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	utilfs "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/filesystem"
+)
+
+func TestParsePciAddress(t *testing.T) {
+	tcases := []struct {
+		input    string
+		expected PciAddress
+	}{
+		{input: "0000:03:00.4", expected: PciAddress{Domain: 0, Bus: 0x03, Device: 0x00, Function: 4}},
+		{input: "00d5:af:1f.7", expected: PciAddress{Domain: 0xd5, Bus: 0xaf, Device: 0x1f, Function: 7}},
+		{input: "10000:00:02.1", expected: PciAddress{Domain: 0x10000, Bus: 0x00, Device: 0x02, Function: 1}},
+		{input: "0000:AF:0A.2", expected: PciAddress{Domain: 0, Bus: 0xaf, Device: 0x0a, Function: 2}},
+		{input: "82:00.1", expected: PciAddress{Domain: 0, Bus: 0x82, Device: 0x00, Function: 1}},
+	}
+	for _, tc := range tcases {
+		addr, err := ParsePciAddress(tc.input)
+		assert.NoError(t, err, tc.input)
+		assert.Equal(t, tc.expected, addr, tc.input)
+	}
+}
+
+func TestParsePciAddressInvalid(t *testing.T) {
+	inputs := []string{
+		"",
+		"0000:03:00",
+		"0000:03:00.8",
+		"0000:03:20.0",
+		"0000:3:00.0",
+		"000:03:00.0",
+		"0000:03:00.0.1",
+		"0000:0g:00.0",
+		"123456789:03:00.0",
+		"0000:00:03:00.0",
+		"eth0",
+	}
+	for _, input := range inputs {
+		_, err := ParsePciAddress(input)
+		assert.ErrorIs(t, err, ErrInvalidPciAddress, input)
+	}
+}
+
+func TestPciAddressString(t *testing.T) {
+	for _, input := range []string{"0000:03:00.4", "00d5:af:1f.7", "10000:00:02.1"} {
+		addr, err := ParsePciAddress(input)
+		assert.NoError(t, err)
+		assert.Equal(t, input, addr.String())
+	}
+}
+
+func TestNormalizePciAddress(t *testing.T) {
+	addr, err := normalizePciAddress("0000:AF:00.1")
+	assert.NoError(t, err)
+	assert.Equal(t, "0000:af:00.1", addr)
+
+	addr, err = normalizePciAddress("af:00.1")
+	assert.NoError(t, err)
+	assert.Equal(t, "0000:af:00.1", addr)
+
+	_, err = normalizePciAddress("af:00")
+	assert.ErrorIs(t, err, ErrInvalidPciAddress)
+}
+
+func TestGetPfPciFromVfPciInvalidAddress(t *testing.T) {
+	_, err := GetPfPciFromVfPci("not-a-pci-address")
+	assert.ErrorIs(t, err, ErrInvalidPciAddress)
+}
+
+func TestPciAddressMethods(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+	setUpPfVfsPciEnv(t, "0000:03:00.0", []string{"0000:03:00.2", "0000:03:00.3"})
+	setUpPciDriverEnv(t, "mlx5_core", "0000:03:00.3")
+	assert.NoError(t, utilfs.Fs.MkdirAll(filepath.Join(PciSysDir, "0000:03:00.3", "net", "eth1"), os.FileMode(0755)))
+
+	vf := PciAddress{Domain: 0, Bus: 0x03, Device: 0x00, Function: 3}
+	netdevs, err := vf.NetDevices()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"eth1"}, netdevs)
+	driver, err := vf.Driver()
+	assert.NoError(t, err)
+	assert.Equal(t, "mlx5_core", driver)
+	pf, err := vf.PhysFn()
+	assert.NoError(t, err)
+	assert.Equal(t, PciAddress{Domain: 0, Bus: 0x03, Device: 0x00, Function: 0}, pf)
+	vfIndex, err := vf.VfIndex()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, vfIndex)
+
+	_, err = pf.PhysFn()
+	assert.Error(t, err)
+	_, err = PciAddress{Bus: 0x03, Function: 2}.Driver()
+	assert.ErrorIs(t, err, ErrNoDriver)
+}
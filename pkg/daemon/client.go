@@ -0,0 +1,136 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package daemon
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/k8snetworkplumbingwg/sriovnet"
+)
+
+// Client is a client of the daemon API, safe for concurrent use. Requests are serialized over a single
+// connection.
+type Client struct {
+	mu      sync.Mutex
+	conn    net.Conn
+	reader  *bufio.Reader
+	encoder *json.Encoder
+}
+
+// Dial connects to the daemon listening on the Unix socket at the given path
+func Dial(socketPath string) (*Client, error) {
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{conn: conn, reader: bufio.NewReader(conn), encoder: json.NewEncoder(conn)}, nil
+}
+
+// Close closes the connection to the daemon
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// call sends a request with the given method and params and decodes its result into result, if not nil
+func (c *Client) call(method string, params, result interface{}) error {
+	rawParams, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err = c.encoder.Encode(&Request{Method: method, Params: rawParams}); err != nil {
+		return fmt.Errorf("failed to send %s request: %v", method, err)
+	}
+	line, err := c.reader.ReadBytes('\n')
+	if err != nil {
+		return fmt.Errorf("failed to read %s response: %v", method, err)
+	}
+	var resp Response
+	if err = json.Unmarshal(line, &resp); err != nil {
+		return fmt.Errorf("invalid %s response: %v", method, err)
+	}
+	if resp.Error != "" {
+		return errors.New(resp.Error)
+	}
+	if result == nil || len(resp.Result) == 0 {
+		return nil
+	}
+	return json.Unmarshal(resp.Result, result)
+}
+
+// AllocateVf allocates a free VF of the given PF. If macAddress is not empty, only the VF with the given default
+// MAC address is allocated.
+func (c *Client) AllocateVf(pfNetdev, macAddress string) (*sriovnet.VfObj, error) {
+	vf := &sriovnet.VfObj{}
+	if err := c.call(MethodAllocateVf, &AllocateVfParams{PfNetdev: pfNetdev, MacAddress: macAddress}, vf); err != nil {
+		return nil, err
+	}
+	return vf, nil
+}
+
+// FreeVf frees the VF with the given index of the given PF
+func (c *Client) FreeVf(pfNetdev string, vfIndex int) error {
+	return c.call(MethodFreeVf, &FreeVfParams{PfNetdev: pfNetdev, VfIndex: vfIndex}, nil)
+}
+
+// GetUplinkRepresentor returns the uplink representor of the PF with the given PCI address
+func (c *Client) GetUplinkRepresentor(pciAddress string) (string, error) {
+	var uplink string
+	err := c.call(MethodGetUplinkRepresentor, &UplinkRepresentorParams{PciAddress: pciAddress}, &uplink)
+	return uplink, err
+}
+
+// GetVfRepresentor returns the representor of the VF with the given index of the given uplink
+func (c *Client) GetVfRepresentor(uplink string, vfIndex int) (string, error) {
+	var rep string
+	err := c.call(MethodGetVfRepresentor, &VfRepresentorParams{Uplink: uplink, VfIndex: vfIndex}, &rep)
+	return rep, err
+}
+
+// GetVfRepresentorByVfPciAddress returns the representor of the VF with the given PCI address
+func (c *Client) GetVfRepresentorByVfPciAddress(vfPciAddress string) (string, error) {
+	var rep string
+	err := c.call(MethodGetVfRepresentorByVfPciAddress, &VfRepresentorByPciParams{VfPciAddress: vfPciAddress}, &rep)
+	return rep, err
+}
+
+// CreateSf creates, configures and activates a SF, see sriovnet.DeploySF
+func (c *Client) CreateSf(params *CreateSfParams) (*sriovnet.DeployedSF, error) {
+	sf := &sriovnet.DeployedSF{}
+	if err := c.call(MethodCreateSf, params, sf); err != nil {
+		return nil, err
+	}
+	return sf, nil
+}
+
+// DeleteSf deletes a SF, see sriovnet.DeleteSF
+func (c *Client) DeleteSf(pfPciAddress string, pfNum, sfNum int) error {
+	return c.call(MethodDeleteSf, &DeleteSfParams{PfPciAddress: pfPciAddress, PfNum: pfNum, SfNum: sfNum}, nil)
+}
+
+// ListSfs lists the SFs of the PF with the given PCI address, see sriovnet.ListSFs
+func (c *Client) ListSfs(pfPciAddress string) ([]*sriovnet.SfInfo, error) {
+	var sfs []*sriovnet.SfInfo
+	err := c.call(MethodListSfs, &ListSfsParams{PfPciAddress: pfPciAddress}, &sfs)
+	return sfs, err
+}
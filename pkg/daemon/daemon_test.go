@@ -0,0 +1,191 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package daemon
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k8snetworkplumbingwg/sriovnet"
+	utilfs "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/filesystem"
+	"github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/netlinkops"
+	netlinkopsMocks "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/netlinkops/mocks"
+)
+
+// setUpDaemon starts a server on a Unix socket serving PF p0 with the given number of VFs over an empty fake
+// sysfs, devlink notifications are not available. Returns the socket path.
+func setUpDaemon(t *testing.T, numVfs int) string {
+	count := &atomic.Int32{}
+	count.Store(int32(numVfs))
+	return setUpDaemonWithVfCount(t, count)
+}
+
+// setUpDaemonWithVfCount is setUpDaemon with a number of VFs which can be changed while the server runs
+func setUpDaemonWithVfCount(t *testing.T, numVfs *atomic.Int32) string {
+	var err error
+	var teardownFs func()
+	utilfs.Fs, teardownFs, err = utilfs.NewFakeFs(filepath.Join(t.TempDir(), "fakefs"))
+	require.NoError(t, err)
+	t.Cleanup(teardownFs)
+
+	nlOpsMock := &netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(nlOpsMock)
+	t.Cleanup(netlinkops.ResetNetlinkOps)
+	nlOpsMock.On("DevlinkPortSubscribe", mock.Anything, mock.Anything).Return(fmt.Errorf("not supported"))
+
+	socketPath := filepath.Join(t.TempDir(), "sriovnet.sock")
+	server := NewServer(nil)
+	server.numVfs = func(pfNetdev string) (int, error) {
+		if pfNetdev != "p0" {
+			return 0, fmt.Errorf("device %s not found", pfNetdev)
+		}
+		return int(numVfs.Load()), nil
+	}
+	server.newHandle = func(pfNetdev string) (*sriovnet.PfNetdevHandle, error) {
+		handle := &sriovnet.PfNetdevHandle{PfNetdevName: pfNetdev}
+		for i := 0; i < int(numVfs.Load()); i++ {
+			handle.List = append(handle.List, &sriovnet.VfObj{Index: i, PciAddress: fmt.Sprintf("0000:03:00.%d", i+2)})
+		}
+		return handle, nil
+	}
+	served := make(chan error, 1)
+	l, err := net.Listen("unix", socketPath)
+	require.NoError(t, err)
+	go func() { served <- server.Serve(l) }()
+	t.Cleanup(func() {
+		assert.NoError(t, server.Close())
+		assert.NoError(t, <-served)
+	})
+	return socketPath
+}
+
+func TestAllocateVfShared(t *testing.T) {
+	socketPath := setUpDaemon(t, 2)
+	client1, err := Dial(socketPath)
+	require.NoError(t, err)
+	defer client1.Close()
+	client2, err := Dial(socketPath)
+	require.NoError(t, err)
+	defer client2.Close()
+
+	vf, err := client1.AllocateVf("p0", "")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, vf.Index)
+	assert.Equal(t, "0000:03:00.2", vf.PciAddress)
+	assert.True(t, vf.Allocated)
+
+	// allocations are shared by all the clients
+	vf, err = client2.AllocateVf("p0", "")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, vf.Index)
+	_, err = client1.AllocateVf("p0", "")
+	assert.Error(t, err)
+
+	assert.NoError(t, client2.FreeVf("p0", 0))
+	assert.Error(t, client2.FreeVf("p0", 0))
+	assert.Error(t, client2.FreeVf("p1", 0))
+	vf, err = client1.AllocateVf("p0", "")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, vf.Index)
+}
+
+func TestAllocateVfNumVfsChanged(t *testing.T) {
+	numVfs := &atomic.Int32{}
+	numVfs.Store(1)
+	socketPath := setUpDaemonWithVfCount(t, numVfs)
+	client, err := Dial(socketPath)
+	require.NoError(t, err)
+	defer client.Close()
+
+	vf, err := client.AllocateVf("p0", "")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, vf.Index)
+	_, err = client.AllocateVf("p0", "")
+	assert.Error(t, err)
+
+	// the handle is re-created with the new VFs, VF 0 is still allocated
+	numVfs.Store(2)
+	vf, err = client.AllocateVf("p0", "")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, vf.Index)
+	_, err = client.AllocateVf("p0", "")
+	assert.Error(t, err)
+}
+
+func TestLockPfSfs(t *testing.T) {
+	server := &Server{sfLocks: make(map[string]*sync.Mutex)}
+
+	unlock, err := server.lockPfSfs("0000:03:00.0")
+	require.NoError(t, err)
+	locked := make(chan struct{})
+	go func() {
+		// the same PF with its short PCI address
+		unlockShort, err := server.lockPfSfs("03:00.0")
+		assert.NoError(t, err)
+		close(locked)
+		unlockShort()
+	}()
+	select {
+	case <-locked:
+		t.Fatal("SFs of the PF locked twice")
+	case <-time.After(50 * time.Millisecond):
+	}
+	unlock()
+	<-locked
+
+	// other PFs are not serialized
+	unlock, err = server.lockPfSfs("0000:03:00.1")
+	require.NoError(t, err)
+	unlock()
+	_, err = server.lockPfSfs("invalid")
+	assert.Error(t, err)
+}
+
+func TestDaemonErrors(t *testing.T) {
+	socketPath := setUpDaemon(t, 0)
+	client, err := Dial(socketPath)
+	require.NoError(t, err)
+	defer client.Close()
+
+	_, err = client.GetUplinkRepresentor("0000:03:00.0")
+	assert.Error(t, err)
+	// the connection is still usable after an error
+	_, err = client.AllocateVf("p0", "")
+	assert.Error(t, err)
+
+	conn, err := net.Dial("unix", socketPath)
+	require.NoError(t, err)
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for _, req := range []string{"not json", `{"method":"Bogus"}`, `{"method":"FreeVf"}`} {
+		_, err = fmt.Fprintln(conn, req)
+		require.NoError(t, err)
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		assert.Contains(t, line, `"error"`)
+	}
+}
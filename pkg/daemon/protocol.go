@@ -0,0 +1,108 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package daemon provides a local daemon exposing VF allocation, representor lookup and SF lifecycle
+// operations of the sriovnet library over a Unix socket, along with its client.
+// Short-lived processes (e.g CNI plugin invocations) going through the daemon share a single VF allocation
+// state instead of each racing through sysfs.
+//
+// The protocol is newline delimited JSON: each request is a JSON encoded Request followed by a newline,
+// answered by a JSON encoded Response followed by a newline. A connection may carry any number of requests.
+package daemon
+
+import (
+	"encoding/json"
+	"net"
+)
+
+// DefaultSocketPath is the default path of the daemon Unix socket
+const DefaultSocketPath = "/run/sriovnet/sriovnet.sock"
+
+// Methods of the daemon API
+const (
+	MethodAllocateVf                     = "AllocateVf"
+	MethodFreeVf                         = "FreeVf"
+	MethodGetUplinkRepresentor           = "GetUplinkRepresentor"
+	MethodGetVfRepresentor               = "GetVfRepresentor"
+	MethodGetVfRepresentorByVfPciAddress = "GetVfRepresentorByVfPciAddress"
+	MethodCreateSf                       = "CreateSf"
+	MethodDeleteSf                       = "DeleteSf"
+	MethodListSfs                        = "ListSfs"
+)
+
+// Request is a daemon API request
+type Request struct {
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// Response is a daemon API response, Error is set if the request failed
+type Response struct {
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// AllocateVfParams are the parameters of MethodAllocateVf
+type AllocateVfParams struct {
+	// PfNetdev is the PF netdev name
+	PfNetdev string `json:"pfNetdev"`
+	// MacAddress restricts the allocation to the VF with the given default MAC address if set
+	MacAddress string `json:"macAddress,omitempty"`
+}
+
+// FreeVfParams are the parameters of MethodFreeVf
+type FreeVfParams struct {
+	PfNetdev string `json:"pfNetdev"`
+	VfIndex  int    `json:"vfIndex"`
+}
+
+// UplinkRepresentorParams are the parameters of MethodGetUplinkRepresentor
+type UplinkRepresentorParams struct {
+	PciAddress string `json:"pciAddress"`
+}
+
+// VfRepresentorParams are the parameters of MethodGetVfRepresentor
+type VfRepresentorParams struct {
+	Uplink  string `json:"uplink"`
+	VfIndex int    `json:"vfIndex"`
+}
+
+// VfRepresentorByPciParams are the parameters of MethodGetVfRepresentorByVfPciAddress
+type VfRepresentorByPciParams struct {
+	VfPciAddress string `json:"vfPciAddress"`
+}
+
+// CreateSfParams are the parameters of MethodCreateSf
+type CreateSfParams struct {
+	PfPciAddress string `json:"pfPciAddress"`
+	PfNum        int    `json:"pfNum"`
+	// SfNum is the sfnum of the SF, the lowest free sfnum is used if nil
+	SfNum *int `json:"sfNum,omitempty"`
+	// HwAddr is the hardware address of the SF, left to the driver default if nil
+	HwAddr net.HardwareAddr `json:"hwAddr,omitempty"`
+}
+
+// DeleteSfParams are the parameters of MethodDeleteSf
+type DeleteSfParams struct {
+	PfPciAddress string `json:"pfPciAddress"`
+	PfNum        int    `json:"pfNum"`
+	SfNum        int    `json:"sfNum"`
+}
+
+// ListSfsParams are the parameters of MethodListSfs
+type ListSfsParams struct {
+	PfPciAddress string `json:"pfPciAddress"`
+}
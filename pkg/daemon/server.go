@@ -0,0 +1,421 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package daemon
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/k8snetworkplumbingwg/sriovnet"
+)
+
+const (
+	// defaultSfTimeout is the default timeout to wait for a SF to be probed or removed
+	defaultSfTimeout = 30 * time.Second
+	// maxRequestSize bounds the size of a request
+	maxRequestSize = 1 << 20
+)
+
+// ServerOptions are the options of a Server
+type ServerOptions struct {
+	// SfTimeout is the timeout to wait for a SF to be probed or removed, defaults to 30s
+	SfTimeout time.Duration
+}
+
+// handlerFunc handles the JSON encoded params of a request and returns its result
+type handlerFunc func(params json.RawMessage) (interface{}, error)
+
+// Server serves the daemon API. VFs are allocated from PF handles shared by all the clients. The server
+// invalidates the representor cache of the sriovnet library, if enabled by the application, upon every devlink
+// port notification (e.g representor added, removed or renamed).
+type Server struct {
+	sfTimeout time.Duration
+	handlers  map[string]handlerFunc
+	// newHandle returns the handle of a PF, overridden in tests
+	newHandle func(pfNetdev string) (*sriovnet.PfNetdevHandle, error)
+	// numVfs returns the current number of VFs of a PF, overridden in tests
+	numVfs func(pfNetdev string) (int, error)
+
+	// allocMu protects handles
+	allocMu sync.Mutex
+	// handles maps a PF netdev name to the handle VFs are allocated from
+	handles map[string]*pfHandle
+
+	// sfMu protects sfLocks
+	sfMu sync.Mutex
+	// sfLocks maps a PF PCI address to the lock serializing the creation and deletion of its SFs
+	sfLocks map[string]*sync.Mutex
+
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]struct{}
+	done      chan struct{}
+	closed    bool
+	wg        sync.WaitGroup
+}
+
+// NewServer returns a new daemon server
+func NewServer(opts *ServerOptions) *Server {
+	s := &Server{
+		sfTimeout: defaultSfTimeout,
+		newHandle: sriovnet.GetPfNetdevHandle,
+		numVfs:    getNumVfs,
+		handles:   make(map[string]*pfHandle),
+		sfLocks:   make(map[string]*sync.Mutex),
+		listeners: make(map[net.Listener]struct{}),
+		conns:     make(map[net.Conn]struct{}),
+		done:      make(chan struct{}),
+	}
+	if opts != nil && opts.SfTimeout > 0 {
+		s.sfTimeout = opts.SfTimeout
+	}
+	s.handlers = map[string]handlerFunc{
+		MethodAllocateVf:                     s.allocateVf,
+		MethodFreeVf:                         s.freeVf,
+		MethodGetUplinkRepresentor:           getUplinkRepresentor,
+		MethodGetVfRepresentor:               getVfRepresentor,
+		MethodGetVfRepresentorByVfPciAddress: getVfRepresentorByVfPciAddress,
+		MethodCreateSf:                       s.createSf,
+		MethodDeleteSf:                       s.deleteSf,
+		MethodListSfs:                        listSfs,
+	}
+	s.watchDevlinkPorts()
+	return s
+}
+
+// watchDevlinkPorts invalidates the representor cache upon every devlink port notification, including the
+// ones of ports whose netdev is attached or renamed. Nothing is done if devlink notifications are not available.
+func (s *Server) watchDevlinkPorts() {
+	events, err := sriovnet.WatchDevlinkPorts(s.done)
+	if err != nil {
+		return
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for range events {
+			sriovnet.InvalidateRepresentorCache()
+		}
+	}()
+}
+
+// ListenAndServe listens on the Unix socket at the given path and serves the daemon API until the server is
+// closed. A stale socket file at path is removed, and the socket is only accessible by its owner.
+func (s *Server) ListenAndServe(socketPath string) error {
+	if err := os.MkdirAll(filepath.Dir(socketPath), 0o755); err != nil {
+		return err
+	}
+	if err := os.Remove(socketPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove stale socket %s: %v", socketPath, err)
+	}
+	l, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return err
+	}
+	if err = os.Chmod(socketPath, 0o600); err != nil {
+		l.Close()
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts connections on l and serves the daemon API until the server is closed. l is closed on return.
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		l.Close()
+		return net.ErrClosed
+	}
+	s.listeners[l] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.listeners, l)
+		s.mu.Unlock()
+		l.Close()
+	}()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			select {
+			case <-s.done:
+				return nil
+			default:
+				return err
+			}
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return nil
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+		go s.serveConn(conn)
+	}
+}
+
+// Close stops the server, closing its listeners and connections
+func (s *Server) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.done)
+	for l := range s.listeners {
+		l.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return nil
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 4096), maxRequestSize)
+	encoder := json.NewEncoder(conn)
+	for scanner.Scan() {
+		if err := encoder.Encode(s.handle(scanner.Bytes())); err != nil {
+			return
+		}
+	}
+}
+
+// handle decodes and runs a request and returns its response
+func (s *Server) handle(data []byte) *Response {
+	var req Request
+	if err := json.Unmarshal(data, &req); err != nil {
+		return &Response{Error: fmt.Sprintf("invalid request: %v", err)}
+	}
+	handler, ok := s.handlers[req.Method]
+	if !ok {
+		return &Response{Error: fmt.Sprintf("unknown method %q", req.Method)}
+	}
+	result, err := handler(req.Params)
+	if err != nil {
+		return &Response{Error: err.Error()}
+	}
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return &Response{Error: fmt.Sprintf("failed to encode result: %v", err)}
+	}
+	return &Response{Result: raw}
+}
+
+// decodeParams decodes the JSON encoded params of a request into v
+func decodeParams(params json.RawMessage, v interface{}) error {
+	if len(params) == 0 {
+		return fmt.Errorf("missing params")
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return fmt.Errorf("invalid params: %v", err)
+	}
+	return nil
+}
+
+// pfHandle is the handle of a PF along with its number of VFs when the handle was created
+type pfHandle struct {
+	handle *sriovnet.PfNetdevHandle
+	numVfs int
+}
+
+// getNumVfs returns the current number of VFs of the given PF
+func getNumVfs(pfNetdev string) (int, error) {
+	vfs, err := sriovnet.GetVfPciDevList(pfNetdev)
+	if err != nil {
+		return 0, err
+	}
+	return len(vfs), nil
+}
+
+// getHandle returns the handle of the given PF, creating it on first use and re-creating it whenever the
+// number of VFs of the PF changed. VFs allocated from the previous handle are still allocated in the new one.
+// Must be called with allocMu held.
+func (s *Server) getHandle(pfNetdev string) (*sriovnet.PfNetdevHandle, error) {
+	numVfs, err := s.numVfs(pfNetdev)
+	if err != nil {
+		return nil, err
+	}
+	prev, ok := s.handles[pfNetdev]
+	if ok && prev.numVfs == numVfs {
+		return prev.handle, nil
+	}
+	handle, err := s.newHandle(pfNetdev)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		allocated := make(map[string]bool)
+		for _, vf := range prev.handle.List {
+			allocated[vf.PciAddress] = vf.Allocated
+		}
+		for _, vf := range handle.List {
+			vf.Allocated = allocated[vf.PciAddress]
+		}
+	}
+	s.handles[pfNetdev] = &pfHandle{handle: handle, numVfs: numVfs}
+	return handle, nil
+}
+
+func (s *Server) allocateVf(params json.RawMessage) (interface{}, error) {
+	var p AllocateVfParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	s.allocMu.Lock()
+	defer s.allocMu.Unlock()
+	handle, err := s.getHandle(p.PfNetdev)
+	if err != nil {
+		return nil, err
+	}
+	if p.MacAddress != "" {
+		return sriovnet.AllocateVfByMacAddress(handle, p.MacAddress)
+	}
+	return sriovnet.AllocateVf(handle)
+}
+
+func (s *Server) freeVf(params json.RawMessage) (interface{}, error) {
+	var p FreeVfParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	s.allocMu.Lock()
+	defer s.allocMu.Unlock()
+	pf, ok := s.handles[p.PfNetdev]
+	if !ok {
+		return nil, fmt.Errorf("no VF of %s is allocated", p.PfNetdev)
+	}
+	handle := pf.handle
+	for _, vf := range handle.List {
+		if vf.Index != p.VfIndex {
+			continue
+		}
+		if !vf.Allocated {
+			return nil, fmt.Errorf("VF %d of %s is not allocated", p.VfIndex, p.PfNetdev)
+		}
+		sriovnet.FreeVf(handle, vf)
+		return nil, nil
+	}
+	return nil, fmt.Errorf("VF %d of %s not found", p.VfIndex, p.PfNetdev)
+}
+
+func getUplinkRepresentor(params json.RawMessage) (interface{}, error) {
+	var p UplinkRepresentorParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	return sriovnet.GetUplinkRepresentor(p.PciAddress)
+}
+
+func getVfRepresentor(params json.RawMessage) (interface{}, error) {
+	var p VfRepresentorParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	return sriovnet.GetVfRepresentor(p.Uplink, p.VfIndex)
+}
+
+func getVfRepresentorByVfPciAddress(params json.RawMessage) (interface{}, error) {
+	var p VfRepresentorByPciParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	return sriovnet.GetVfRepresentorByVfPciAddress(p.VfPciAddress)
+}
+
+// lockPfSfs locks the SFs of the PF with the given PCI address, so that concurrent requests do not pick the
+// same free sfnum, and returns the function unlocking them
+func (s *Server) lockPfSfs(pfPciAddress string) (func(), error) {
+	addr, err := sriovnet.ParsePciAddress(pfPciAddress)
+	if err != nil {
+		return nil, err
+	}
+	s.sfMu.Lock()
+	lock, ok := s.sfLocks[addr.String()]
+	if !ok {
+		lock = &sync.Mutex{}
+		s.sfLocks[addr.String()] = lock
+	}
+	s.sfMu.Unlock()
+	lock.Lock()
+	return lock.Unlock, nil
+}
+
+func (s *Server) createSf(params json.RawMessage) (interface{}, error) {
+	var p CreateSfParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	unlock, err := s.lockPfSfs(p.PfPciAddress)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	defer sriovnet.InvalidateRepresentorCache()
+	return sriovnet.DeploySF(p.PfPciAddress, &sriovnet.SfConfig{
+		PfNum:   p.PfNum,
+		SfNum:   p.SfNum,
+		HwAddr:  p.HwAddr,
+		Timeout: s.sfTimeout,
+	})
+}
+
+func (s *Server) deleteSf(params json.RawMessage) (interface{}, error) {
+	var p DeleteSfParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	unlock, err := s.lockPfSfs(p.PfPciAddress)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	defer sriovnet.InvalidateRepresentorCache()
+	return nil, sriovnet.DeleteSF(p.PfPciAddress, p.PfNum, p.SfNum, s.sfTimeout)
+}
+
+func listSfs(params json.RawMessage) (interface{}, error) {
+	var p ListSfsParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	return sriovnet.ListSFs(p.PfPciAddress)
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package inventory encodes the SR-IOV inventory of the node returned by sriovnet.GetNodeSriovInventory in
+// machine-readable formats, e.g for ingestion by inventory systems. It is kept apart from the sriovnet package
+// so that the library does not depend on the YAML encoder.
+package inventory
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/k8snetworkplumbingwg/sriovnet"
+)
+
+// Format is a machine-readable format of the SR-IOV inventory
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatYAML Format = "yaml"
+)
+
+// Marshal encodes the given inventory (see sriovnet.GetNodeSriovInventory) in the given format.
+// JSON output is indented.
+func Marshal(inv *sriovnet.SriovInventory, format Format) ([]byte, error) {
+	switch format {
+	case FormatJSON:
+		return json.MarshalIndent(inv, "", "  ")
+	case FormatYAML:
+		var buf bytes.Buffer
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(inv); err != nil {
+			return nil, err
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("unsupported inventory format %q", format)
+	}
+}
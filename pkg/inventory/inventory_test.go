@@ -0,0 +1,59 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+
+	"github.com/k8snetworkplumbingwg/sriovnet"
+)
+
+func TestMarshal(t *testing.T) {
+	inv := &sriovnet.SriovInventory{Pfs: []*sriovnet.PfInventory{{
+		PciAddress: "0000:03:00.0",
+		NumaNode:   sriovnet.NoNumaNode,
+		Switchdev:  true,
+		Uplink:     "p0",
+		Vfs:        []*sriovnet.VfInventory{{Index: 0, PciAddress: "0000:03:00.2"}},
+		Representors: []*sriovnet.RepresentorInventory{
+			{Name: "rep0", Flavour: sriovnet.PORT_FLAVOUR_PCI_VF, FuncIndex: 0},
+		},
+	}}}
+
+	out, err := Marshal(inv, FormatJSON)
+	assert.NoError(t, err)
+	assert.Contains(t, string(out), `"flavour": "pcivf"`)
+	decoded := &sriovnet.SriovInventory{}
+	assert.NoError(t, json.Unmarshal(out, decoded))
+	assert.Equal(t, inv, decoded)
+
+	out, err = Marshal(inv, FormatYAML)
+	assert.NoError(t, err)
+	assert.Contains(t, string(out), "pfs:\n  - pciAddress: \"0000:03:00.0\"\n")
+	assert.Contains(t, string(out), "flavour: pcivf")
+	assert.NotContains(t, string(out), "macAddress")
+	decoded = &sriovnet.SriovInventory{}
+	assert.NoError(t, yaml.Unmarshal(out, decoded))
+	assert.Equal(t, inv, decoded)
+
+	_, err = Marshal(inv, "xml")
+	assert.Error(t, err)
+}
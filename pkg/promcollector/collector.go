@@ -0,0 +1,90 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package promcollector exposes the SR-IOV metrics of sriovnet.CollectMetrics as a Prometheus collector.
+// It is kept apart from the sriovnet package so that the library does not depend on the Prometheus client.
+package promcollector
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/k8snetworkplumbingwg/sriovnet"
+)
+
+// metricDesc is the Prometheus description of a sriovnet metric
+type metricDesc struct {
+	desc      *prometheus.Desc
+	labels    []string
+	valueType prometheus.ValueType
+}
+
+// Collector is a prometheus.Collector of the SR-IOV metrics of the node, collected with
+// sriovnet.CollectMetrics upon every scrape
+type Collector struct {
+	descs map[string]*metricDesc
+	// collect returns the metrics samples, overridden in tests
+	collect func() ([]*sriovnet.Metric, error)
+}
+
+// NewCollector returns a Collector of all the metrics described by sriovnet.MetricDescs
+func NewCollector() *Collector {
+	c := &Collector{descs: make(map[string]*metricDesc), collect: sriovnet.CollectMetrics}
+	for _, d := range sriovnet.MetricDescs() {
+		valueType := prometheus.GaugeValue
+		if d.Type == sriovnet.MetricTypeCounter {
+			valueType = prometheus.CounterValue
+		}
+		c.descs[d.Name] = &metricDesc{
+			desc:      prometheus.NewDesc(d.Name, d.Help, d.Labels, nil),
+			labels:    d.Labels,
+			valueType: valueType,
+		}
+	}
+	return c
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range c.descs {
+		ch <- d.desc
+	}
+}
+
+// Collect implements prometheus.Collector. A failure to collect the metrics is reported as an invalid metric,
+// which fails the scrape.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	metrics, err := c.collect()
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(prometheus.NewInvalidDesc(err), err)
+		return
+	}
+	for _, m := range metrics {
+		d, ok := c.descs[m.Name]
+		if !ok {
+			continue
+		}
+		labelValues := make([]string, len(d.labels))
+		for i, label := range d.labels {
+			labelValues[i] = m.Labels[label]
+		}
+		metric, err := prometheus.NewConstMetric(d.desc, d.valueType, m.Value, labelValues...)
+		if err != nil {
+			ch <- prometheus.NewInvalidMetric(d.desc, err)
+			continue
+		}
+		ch <- metric
+	}
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package promcollector
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/k8snetworkplumbingwg/sriovnet"
+)
+
+func TestCollector(t *testing.T) {
+	c := NewCollector()
+	c.collect = func() ([]*sriovnet.Metric, error) {
+		return []*sriovnet.Metric{
+			{Name: "sriovnet_pf_num_vfs", Labels: map[string]string{"pf": "0000:03:00.0"}, Value: 2},
+			{Name: "sriovnet_vf_rx_bytes_total", Value: 100,
+				Labels: map[string]string{"pf": "0000:03:00.0", "vf": "0", "representor": "rep0"}},
+			{Name: "sriovnet_unknown", Value: 1},
+		}, nil
+	}
+
+	registry := prometheus.NewPedanticRegistry()
+	assert.NoError(t, registry.Register(c))
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP sriovnet_pf_num_vfs Number of VFs enabled on the PF.
+# TYPE sriovnet_pf_num_vfs gauge
+sriovnet_pf_num_vfs{pf="0000:03:00.0"} 2
+# HELP sriovnet_vf_rx_bytes_total Bytes received by the VF, as counted by its representor.
+# TYPE sriovnet_vf_rx_bytes_total counter
+sriovnet_vf_rx_bytes_total{pf="0000:03:00.0",representor="rep0",vf="0"} 100
+`)))
+
+	c.collect = func() ([]*sriovnet.Metric, error) {
+		return nil, fmt.Errorf("failed to list PFs")
+	}
+	_, err := registry.Gather()
+	assert.ErrorContains(t, err, "failed to list PFs")
+}
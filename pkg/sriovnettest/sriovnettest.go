@@ -0,0 +1,331 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package sriovnettest provides builders of fake SR-IOV sysfs layouts for unit tests of sriovnet consumers.
+// An Env installs a fake filesystem as the filesystem of the sriovnet library, PFs with their VFs and
+// representors, auxiliary devices and PCI drivers are then added to it with the layout of the Linux kernel.
+//
+// The package does not depend on the sriovnet package so it is used by the sriovnet tests too.
+package sriovnettest
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/k8snetworkplumbingwg/sriovnet/pkg/sysfssnapshot"
+	utilfs "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/filesystem"
+)
+
+// Sysfs directories, the same as the ones of the sriovnet package
+const (
+	NetSysDir        = "/sys/class/net"
+	PciSysDir        = "/sys/bus/pci/devices"
+	PciDriversSysDir = "/sys/bus/pci/drivers"
+	AuxSysDir        = "/sys/bus/auxiliary/devices"
+)
+
+const (
+	pciDevicesRoot = "/sys/devices/pci0000:00"
+	dirMode        = os.FileMode(0755)
+	fileMode       = os.FileMode(0644)
+)
+
+// Env is a fake sysfs installed as the filesystem of the sriovnet library
+type Env struct {
+	t testing.TB
+	// Root is the directory of the host filesystem the fake sysfs is stored in
+	Root string
+}
+
+// NewEnv creates an empty fake sysfs and installs it as the filesystem of the sriovnet library. The previous
+// filesystem is restored and the fake sysfs removed when the test completes.
+// Note: tests using an Env must not run in parallel as the filesystem of the library is global.
+func NewEnv(t testing.TB) *Env {
+	t.Helper()
+	root := filepath.Join(t.TempDir(), "sysfs")
+	fs, teardown, err := utilfs.NewFakeFs(root)
+	if err != nil {
+		t.Fatalf("failed to create fake sysfs: %v", err)
+	}
+	prevFs := utilfs.Fs
+	utilfs.Fs = fs
+	t.Cleanup(func() {
+		utilfs.Fs = prevFs
+		teardown()
+	})
+	return &Env{t: t, Root: root}
+}
+
+// PF is a SR-IOV physical function
+type PF struct {
+	PciAddress string
+	// Driver the PF is bound to (e.g mlx5_core), the PF is left unbound if empty
+	Driver string
+	// Netdev is the PF netdev name, the uplink representor in switchdev mode (e.g p0)
+	Netdev string
+	// MacAddress of the PF netdev
+	MacAddress string
+	// NumaNode of the PF, -1 for no NUMA affinity
+	NumaNode int
+	// TotalVfs is the maximum number of VFs, len(VFs) if lower
+	TotalVfs int
+	// SwitchID is the eswitch ID (phys_switch_id) of the PF and its representors, set for a switchdev PF
+	SwitchID string
+	// PhysPortName of the PF netdev, defaults to p0 for a switchdev PF
+	PhysPortName string
+	// VFs of the PF, by index
+	VFs []*VF
+}
+
+// VF is a SR-IOV virtual function
+type VF struct {
+	PciAddress string
+	// Driver the VF is bound to (e.g mlx5_core or vfio-pci), the VF is left unbound if empty
+	Driver string
+	// Netdev is the VF netdev name, the VF has no netdev if empty
+	Netdev string
+	// MacAddress of the VF netdev
+	MacAddress string
+	// Representor is the VF representor netdev name, requires a switchdev PF
+	Representor string
+	// RepresentorPortName is the phys_port_name of the representor, defaults to pf0vf<index>
+	RepresentorPortName string
+}
+
+// Representor is a representor netdev of an eswitch which is not the representor of a VF of a PF added
+// with AddPF (e.g a SF representor, or a host PF representor on a DPU)
+type Representor struct {
+	Name         string
+	PhysPortName string
+	// PhysSwitchID of the representor, defaults to the switch ID of the PF it is added to
+	PhysSwitchID string
+}
+
+// AuxDevice is an auxiliary device of a PCI device, e.g a SF (mlx5_core.sf.2) or the representors
+// device of a PF (mlx5_core.eth-rep.0)
+type AuxDevice struct {
+	// Parent is the PCI address of the parent device
+	Parent string
+	Name   string
+	// SfNum is the sfnum of a SF auxiliary device, nil if the device is not a SF
+	SfNum *int
+	// Netdev is the netdev of the auxiliary device, the device has no netdev if empty
+	Netdev string
+}
+
+// AddPF adds a PF along with its VFs and their representors. The PF and VF PCI devices are created under
+// /sys/devices with their /sys/bus/pci/devices links, their netdevs are linked from /sys/class/net.
+func (e *Env) AddPF(pf *PF) {
+	e.t.Helper()
+	pfPath := filepath.Join(PciSysDir, pf.PciAddress)
+	totalVfs := pf.TotalVfs
+	if totalVfs < len(pf.VFs) {
+		totalVfs = len(pf.VFs)
+	}
+	e.addPciDevice(pf.PciAddress, pf.Driver, pf.NumaNode)
+	e.WriteFile(filepath.Join(pfPath, "sriov_totalvfs"), strconv.Itoa(totalVfs)+"\n")
+	e.WriteFile(filepath.Join(pfPath, "sriov_numvfs"), strconv.Itoa(len(pf.VFs))+"\n")
+
+	if pf.Netdev != "" {
+		e.addNetdev(pf.PciAddress, pf.Netdev, pf.MacAddress)
+		if pf.SwitchID != "" {
+			portName := pf.PhysPortName
+			if portName == "" {
+				portName = "p0"
+			}
+			e.writeSwitchAttrs(pf.Netdev, portName, pf.SwitchID)
+		} else if pf.PhysPortName != "" {
+			e.WriteFile(filepath.Join(NetSysDir, pf.Netdev, "phys_port_name"), pf.PhysPortName+"\n")
+		}
+	}
+
+	for i, vf := range pf.VFs {
+		vfPath := filepath.Join(PciSysDir, vf.PciAddress)
+		e.addPciDevice(vf.PciAddress, vf.Driver, pf.NumaNode)
+		e.symlink(pfPath, filepath.Join(vfPath, "physfn"))
+		e.symlink(vfPath, filepath.Join(pfPath, fmt.Sprintf("virtfn%d", i)))
+		if vf.Netdev != "" {
+			e.addNetdev(vf.PciAddress, vf.Netdev, vf.MacAddress)
+		}
+		if vf.Representor == "" {
+			continue
+		}
+		if pf.SwitchID == "" {
+			e.t.Fatalf("VF %s has a representor but PF %s is not in switchdev mode", vf.PciAddress, pf.PciAddress)
+		}
+		portName := vf.RepresentorPortName
+		if portName == "" {
+			portName = fmt.Sprintf("pf0vf%d", i)
+		}
+		e.addRepresentor(pf.PciAddress, vf.Representor, portName, pf.SwitchID)
+	}
+}
+
+// AddRepresentor adds a representor netdev to the eswitch of the PF with the given PCI address, added
+// beforehand with AddPF
+func (e *Env) AddRepresentor(pfPciAddress string, rep *Representor) {
+	e.t.Helper()
+	switchID := rep.PhysSwitchID
+	if switchID == "" {
+		for _, netdev := range e.readDirNames(filepath.Join(PciSysDir, pfPciAddress, "net")) {
+			content, err := utilfs.Fs.ReadFile(filepath.Join(NetSysDir, netdev, "phys_switch_id"))
+			if err != nil {
+				continue
+			}
+			if switchID = strings.TrimSpace(string(content)); switchID != "" {
+				break
+			}
+		}
+	}
+	e.addRepresentor(pfPciAddress, rep.Name, rep.PhysPortName, switchID)
+}
+
+// AddAuxDevice adds an auxiliary device under its parent PCI device along with its /sys/bus/auxiliary link
+func (e *Env) AddAuxDevice(dev *AuxDevice) {
+	e.t.Helper()
+	devPath := filepath.Join(pciDevicesRoot, dev.Parent, dev.Name)
+	e.mkdirAll(devPath)
+	e.mkdirAll(AuxSysDir)
+	e.symlink(devPath, filepath.Join(AuxSysDir, dev.Name))
+	if dev.SfNum != nil {
+		e.WriteFile(filepath.Join(devPath, "sfnum"), strconv.Itoa(*dev.SfNum)+"\n")
+	}
+	if dev.Netdev != "" {
+		e.mkdirAll(filepath.Join(devPath, "net", dev.Netdev))
+		e.symlink(filepath.Join(devPath, "net", dev.Netdev), filepath.Join(NetSysDir, dev.Netdev))
+		e.symlink(devPath, filepath.Join(devPath, "net", dev.Netdev, "device"))
+	}
+}
+
+// AddPciDriver adds a PCI driver with its bind and unbind files. Devices are bound to the driver with the
+// Driver field of a PF or VF.
+func (e *Env) AddPciDriver(driver string) {
+	e.t.Helper()
+	driverPath := filepath.Join(PciDriversSysDir, driver)
+	e.mkdirAll(driverPath)
+	for _, f := range []string{"bind", "unbind"} {
+		if _, err := utilfs.Fs.Stat(filepath.Join(driverPath, f)); err != nil {
+			e.WriteFile(filepath.Join(driverPath, f), "")
+		}
+	}
+}
+
+// LoadSnapshot restores a sysfs snapshot written by sysfssnapshot.Capture (e.g with `sriovnet snapshot`)
+// into the fake sysfs
+func (e *Env) LoadSnapshot(r io.Reader) {
+	e.t.Helper()
+	if err := sysfssnapshot.Restore(utilfs.Fs, r); err != nil {
+		e.t.Fatalf("failed to load snapshot: %v", err)
+	}
+}
+
+// LoadSnapshotFile restores the sysfs snapshot stored in the given file of the host filesystem, e.g a
+// testdata file, into the fake sysfs
+func (e *Env) LoadSnapshotFile(path string) {
+	e.t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		e.t.Fatalf("failed to open snapshot: %v", err)
+	}
+	defer f.Close()
+	e.LoadSnapshot(f)
+}
+
+// WriteFile writes a file of the fake sysfs, creating its directory if needed
+func (e *Env) WriteFile(path, content string) {
+	e.t.Helper()
+	e.mkdirAll(filepath.Dir(path))
+	if err := utilfs.Fs.WriteFile(path, []byte(content), fileMode); err != nil {
+		e.t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+// ReadFile returns the content of a file of the fake sysfs, e.g to check what the code under test wrote
+func (e *Env) ReadFile(path string) string {
+	e.t.Helper()
+	content, err := utilfs.Fs.ReadFile(path)
+	if err != nil {
+		e.t.Fatalf("failed to read %s: %v", path, err)
+	}
+	return string(content)
+}
+
+// addPciDevice creates a PCI device under /sys/devices with its /sys/bus/pci/devices link
+func (e *Env) addPciDevice(pciAddress, driver string, numaNode int) {
+	devPath := filepath.Join(pciDevicesRoot, pciAddress)
+	e.mkdirAll(devPath)
+	e.mkdirAll(PciSysDir)
+	e.symlink(devPath, filepath.Join(PciSysDir, pciAddress))
+	e.WriteFile(filepath.Join(devPath, "numa_node"), strconv.Itoa(numaNode)+"\n")
+	if driver != "" {
+		e.AddPciDriver(driver)
+		e.symlink(filepath.Join(PciDriversSysDir, driver), filepath.Join(devPath, "driver"))
+	}
+}
+
+// addNetdev creates a netdev of the PCI device with the given address
+func (e *Env) addNetdev(pciAddress, netdev, macAddress string) {
+	devPath := filepath.Join(pciDevicesRoot, pciAddress)
+	netdevPath := filepath.Join(devPath, "net", netdev)
+	e.mkdirAll(netdevPath)
+	e.mkdirAll(NetSysDir)
+	e.symlink(netdevPath, filepath.Join(NetSysDir, netdev))
+	e.symlink(devPath, filepath.Join(netdevPath, "device"))
+	e.symlink(NetSysDir, filepath.Join(netdevPath, "subsystem"))
+	if macAddress != "" {
+		e.WriteFile(filepath.Join(netdevPath, "address"), macAddress+"\n")
+	}
+}
+
+// addRepresentor creates a representor netdev of the PF with the given PCI address
+func (e *Env) addRepresentor(pfPciAddress, name, portName, switchID string) {
+	e.addNetdev(pfPciAddress, name, "")
+	e.writeSwitchAttrs(name, portName, switchID)
+}
+
+func (e *Env) writeSwitchAttrs(netdev, portName, switchID string) {
+	if portName != "" {
+		e.WriteFile(filepath.Join(NetSysDir, netdev, "phys_port_name"), portName+"\n")
+	}
+	if switchID != "" {
+		e.WriteFile(filepath.Join(NetSysDir, netdev, "phys_switch_id"), switchID+"\n")
+	}
+}
+
+func (e *Env) mkdirAll(path string) {
+	if err := utilfs.Fs.MkdirAll(path, dirMode); err != nil {
+		e.t.Fatalf("failed to create %s: %v", path, err)
+	}
+}
+
+func (e *Env) symlink(target, link string) {
+	if err := utilfs.Fs.Symlink(target, link); err != nil {
+		e.t.Fatalf("failed to link %s to %s: %v", link, target, err)
+	}
+}
+
+func (e *Env) readDirNames(dir string) []string {
+	names, err := utilfs.ReadDirNames(utilfs.Fs, dir)
+	if err != nil {
+		return nil
+	}
+	return names
+}
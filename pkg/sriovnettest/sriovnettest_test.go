@@ -0,0 +1,124 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sriovnettest_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/k8snetworkplumbingwg/sriovnet"
+	"github.com/k8snetworkplumbingwg/sriovnet/pkg/sriovnettest"
+)
+
+func setUpSwitchdevPF(t *testing.T) *sriovnettest.Env {
+	env := sriovnettest.NewEnv(t)
+	env.AddPF(&sriovnettest.PF{
+		PciAddress: "0000:03:00.0",
+		Driver:     "mlx5_core",
+		Netdev:     "p0",
+		MacAddress: "0c:42:a1:de:cf:7a",
+		NumaNode:   1,
+		TotalVfs:   4,
+		SwitchID:   "c2cfc60003a1420c",
+		VFs: []*sriovnettest.VF{
+			{PciAddress: "0000:03:00.2", Driver: "mlx5_core", Netdev: "eth2", Representor: "rep0"},
+			{PciAddress: "0000:03:00.3", Driver: "vfio-pci", Representor: "rep1"},
+		},
+	})
+	return env
+}
+
+func TestSwitchdevPF(t *testing.T) {
+	setUpSwitchdevPF(t)
+
+	uplink, err := sriovnet.GetUplinkRepresentor("0000:03:00.0")
+	assert.NoError(t, err)
+	assert.Equal(t, "p0", uplink)
+
+	reps, err := sriovnet.GetVfRepresentors("p0")
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]string{0: "rep0", 1: "rep1"}, reps)
+
+	rep, err := sriovnet.GetVfRepresentorByVfPciAddress("0000:03:00.3")
+	assert.NoError(t, err)
+	assert.Equal(t, "rep1", rep)
+
+	vfPci, err := sriovnet.GetVfPciFromRepresentor("rep0")
+	assert.NoError(t, err)
+	assert.Equal(t, "0000:03:00.2", vfPci)
+
+	pfPci, err := sriovnet.GetPfPciFromVfPci("0000:03:00.3")
+	assert.NoError(t, err)
+	assert.Equal(t, "0000:03:00.0", pfPci)
+
+	pci, err := sriovnet.GetPciFromNetDevice("eth2")
+	assert.NoError(t, err)
+	assert.Equal(t, "0000:03:00.2", pci)
+
+	driver, err := sriovnet.GetDriverNameByPciAddress("0000:03:00.3")
+	assert.NoError(t, err)
+	assert.Equal(t, "vfio-pci", driver)
+
+	numaNode, err := sriovnet.GetPciNumaNode("0000:03:00.2")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, numaNode)
+}
+
+func TestPciDriver(t *testing.T) {
+	env := setUpSwitchdevPF(t)
+
+	assert.NoError(t, sriovnet.UnbindDriverByPciAddress("0000:03:00.2"))
+	assert.Equal(t, "0000:03:00.2",
+		env.ReadFile(filepath.Join(sriovnettest.PciDriversSysDir, "mlx5_core", "unbind")))
+}
+
+func TestSfAuxDevice(t *testing.T) {
+	env := setUpSwitchdevPF(t)
+	sfNum := 88
+	env.AddRepresentor("0000:03:00.0", &sriovnettest.Representor{Name: "en3f0pf0sf88", PhysPortName: "pf0sf88"})
+	env.AddAuxDevice(&sriovnettest.AuxDevice{Parent: "0000:03:00.0", Name: "mlx5_core.sf.2", SfNum: &sfNum,
+		Netdev: "enp3s0f0s88"})
+
+	rep, err := sriovnet.GetSfRepresentor("p0", 88)
+	assert.NoError(t, err)
+	assert.Equal(t, "en3f0pf0sf88", rep)
+
+	num, err := sriovnet.GetSfIndexByAuxDev("mlx5_core.sf.2")
+	assert.NoError(t, err)
+	assert.Equal(t, 88, num)
+
+	netdevs, err := sriovnet.GetNetDevicesFromAux("mlx5_core.sf.2")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"enp3s0f0s88"}, netdevs)
+
+	pci, err := sriovnet.GetPfPciFromAux("mlx5_core.sf.2")
+	assert.NoError(t, err)
+	assert.Equal(t, "0000:03:00.0", pci)
+}
+
+func TestAddRepresentorEmptySwitchID(t *testing.T) {
+	env := sriovnettest.NewEnv(t)
+	env.AddPF(&sriovnettest.PF{PciAddress: "0000:03:00.0", Netdev: "p0"})
+	env.WriteFile(filepath.Join(sriovnettest.NetSysDir, "p0", "phys_switch_id"), "")
+
+	env.AddRepresentor("0000:03:00.0", &sriovnettest.Representor{Name: "eth0", PhysPortName: "pf0vf0"})
+	assert.Equal(t, "pf0vf0\n", env.ReadFile(filepath.Join(sriovnettest.NetSysDir, "eth0", "phys_port_name")))
+	_, err := sriovnet.GetPhysSwitchID("eth0")
+	assert.Error(t, err)
+}
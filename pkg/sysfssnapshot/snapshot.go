@@ -0,0 +1,505 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package sysfssnapshot captures the slice of a node's sysfs relevant to SR-IOV (PFs, VFs, representors,
+// auxiliary devices and their drivers) into a gzipped tar archive, and restores such an archive into a
+// filesystem, e.g the fake filesystem of the sriovnet tests. This allows turning the sysfs of a node running
+// an exotic NIC into a reproducible regression test.
+//
+// Only readable sysfs attributes are captured, attributes failing to read (e.g phys_port_name of a netdev
+// not backed by an eswitch port) are left out as if the kernel did not expose them. Symbolic links are
+// captured with absolute targets.
+//
+// A snapshot records the MAC addresses, switch IDs and InfiniBand GUIDs of the captured devices, set
+// CaptureOptions.Anonymize before sharing it.
+package sysfssnapshot
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	utilfs "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/filesystem"
+)
+
+const (
+	sysDir           = "/sys"
+	pciSysDir        = "/sys/bus/pci/devices"
+	pciDriversSysDir = "/sys/bus/pci/drivers"
+	auxSysDir        = "/sys/bus/auxiliary/devices"
+	auxDriversSysDir = "/sys/bus/auxiliary/drivers"
+	netSysDir        = "/sys/class/net"
+	infinibandSysDir = "/sys/class/infiniband"
+	iommuGroupsDir   = "/sys/kernel/iommu_groups"
+
+	// maxAttrSize bounds the size of a captured attribute, sysfs attributes are at most a page long
+	maxAttrSize = 4096
+	// maxNetdevDepth bounds the depth of the netdev subdirectories captured (e.g compat/devlink)
+	maxNetdevDepth = 3
+)
+
+// skippedAttrs are device attributes which are not captured as reading them is slow, has side effects on
+// some devices or yields binary data irrelevant to SR-IOV
+var skippedAttrs = map[string]bool{
+	"config": true, "rom": true, "vpd": true, "remove": true, "rescan": true, "reset": true, "uevent": true,
+	"modalias": true, "resource": true,
+}
+
+// capturedNetdevDirs are the netdev subdirectories captured
+var capturedNetdevDirs = map[string]bool{"compat": true, "smart_nic": true}
+
+// anonymizedAttrs are the attributes holding hardware identifiers, replaced when anonymizing a snapshot
+var anonymizedAttrs = map[string]bool{
+	"address": true, "perm_addr": true, "phys_switch_id": true, "phys_port_id": true, "node_guid": true,
+	"sys_image_guid": true,
+}
+
+// anonymizedSriovAttrs are the per VF InfiniBand attributes of a PF holding GUIDs, under its sriov directory
+var anonymizedSriovAttrs = map[string]bool{"node": true, "port": true}
+
+// auxDeviceRe matches auxiliary device names, e.g mlx5_core.sf.2
+var auxDeviceRe = regexp.MustCompile(`^[^.]+\.[^.]+\.\d+$`)
+
+// CaptureOptions are the options of Capture
+type CaptureOptions struct {
+	// Root is the directory sysfs is read from, defaults to / i.e the sysfs of the node
+	Root string
+	// Anonymize replaces the MAC addresses, switch IDs and InfiniBand GUIDs of the captured devices with
+	// fake ones. Equal identifiers are replaced with equal fake ones, and all-zero ones are kept, so the
+	// relations between devices (e.g a PF and its representors sharing a switch ID) are preserved.
+	Anonymize bool
+}
+
+// entry is a captured sysfs entry
+type entry struct {
+	typeflag byte
+	content  []byte
+	target   string
+}
+
+// capturer captures sysfs entries by their path under sysDir
+type capturer struct {
+	root    string
+	entries map[string]*entry
+	drivers map[string]bool
+	// anonymized maps the identifiers replaced so far to their replacement, nil if not anonymizing
+	anonymized map[string]string
+}
+
+// Capture writes a snapshot of the sysfs of the PCI devices with the given addresses to w, along with their
+// VFs, netdevs, representors, auxiliary devices, RDMA devices, drivers and IOMMU groups.
+func Capture(w io.Writer, pciAddresses []string, opts *CaptureOptions) error {
+	c := &capturer{root: "/", entries: make(map[string]*entry), drivers: make(map[string]bool)}
+	if opts != nil && opts.Root != "" {
+		c.root = opts.Root
+	}
+	if opts != nil && opts.Anonymize {
+		c.anonymized = make(map[string]string)
+	}
+	for _, pciAddress := range pciAddresses {
+		if err := c.capturePciDevice(pciAddress, true); err != nil {
+			return err
+		}
+	}
+	for driver := range c.drivers {
+		c.addFile(filepath.Join(driver, "bind"), nil)
+		c.addFile(filepath.Join(driver, "unbind"), nil)
+	}
+	return c.write(w)
+}
+
+// hostPath returns the path of a sysfs path on the host
+func (c *capturer) hostPath(path string) string {
+	return filepath.Join(c.root, path)
+}
+
+func (c *capturer) addDir(path string) {
+	for dir := path; dir != sysDir && dir != "/"; dir = filepath.Dir(dir) {
+		if _, ok := c.entries[dir]; ok {
+			return
+		}
+		c.entries[dir] = &entry{typeflag: tar.TypeDir}
+	}
+}
+
+func (c *capturer) addFile(path string, content []byte) {
+	c.addDir(filepath.Dir(path))
+	c.entries[path] = &entry{typeflag: tar.TypeReg, content: content}
+}
+
+func (c *capturer) addLink(path, target string) {
+	c.addDir(filepath.Dir(path))
+	c.entries[path] = &entry{typeflag: tar.TypeSymlink, target: target}
+}
+
+// readLink returns the absolute sysfs target of the symbolic link at the given sysfs path, whose parent
+// directory must be a canonical path
+func (c *capturer) readLink(path string) (string, error) {
+	target, err := os.Readlink(c.hostPath(path))
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(target) {
+		return filepath.Join(filepath.Dir(path), target), nil
+	}
+	if c.root != "/" {
+		rel, err := filepath.Rel(c.root, target)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			return "", fmt.Errorf("link %s points outside of %s", path, c.root)
+		}
+		target = "/" + rel
+	}
+	return filepath.Clean(target), nil
+}
+
+// captureLink captures the symbolic link at the given sysfs path and returns its target
+func (c *capturer) captureLink(path string) (string, error) {
+	target, err := c.readLink(path)
+	if err != nil {
+		return "", err
+	}
+	c.addLink(path, target)
+	return target, nil
+}
+
+// captureAttrs captures the readable attributes of the given sysfs directory and returns the names of its
+// subdirectories and symbolic links
+func (c *capturer) captureAttrs(dir string) (dirs, links []string, err error) {
+	infos, err := os.ReadDir(c.hostPath(dir))
+	if err != nil {
+		return nil, nil, err
+	}
+	c.addDir(dir)
+	for _, info := range infos {
+		name := info.Name()
+		switch {
+		case info.Type()&fs.ModeSymlink != 0:
+			links = append(links, name)
+		case info.IsDir():
+			dirs = append(dirs, name)
+		case info.Type().IsRegular():
+			if skippedAttrs[name] || strings.HasPrefix(name, "resource") {
+				continue
+			}
+			if content, ok := c.readAttr(filepath.Join(dir, name)); ok {
+				c.addFile(filepath.Join(dir, name), c.anonymize(filepath.Join(dir, name), content))
+			}
+		}
+	}
+	return dirs, links, nil
+}
+
+// readAttr reads a sysfs attribute, returns false if it is not readable
+func (c *capturer) readAttr(path string) ([]byte, bool) {
+	f, err := os.Open(c.hostPath(path))
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+	content, err := io.ReadAll(io.LimitReader(f, maxAttrSize))
+	if err != nil {
+		return nil, false
+	}
+	return content, true
+}
+
+// anonymize returns the content of the attribute at the given sysfs path, with its identifier replaced if
+// anonymizing
+func (c *capturer) anonymize(path string, content []byte) []byte {
+	if c.anonymized == nil {
+		return content
+	}
+	name := filepath.Base(path)
+	if !anonymizedAttrs[name] &&
+		!(anonymizedSriovAttrs[name] && filepath.Base(filepath.Dir(filepath.Dir(path))) == "sriov") {
+		return content
+	}
+	value := strings.TrimSpace(string(content))
+	if strings.Trim(value, "0:.") == "" {
+		return content
+	}
+	replacement, ok := c.anonymized[value]
+	if !ok {
+		replacement = fakeIdentifier(value, len(c.anonymized)+1)
+		c.anonymized[value] = replacement
+	}
+	return []byte(strings.Replace(string(content), value, replacement, 1))
+}
+
+// fakeIdentifier returns the given identifier with its hexadecimal digits replaced by those of n, keeping its
+// length and separators, e.g 00:00:00:00:00:02 for 0c:42:a1:d1:d0:71 and 2
+func fakeIdentifier(value string, n int) string {
+	digits := 0
+	for _, r := range value {
+		if isHexDigit(r) {
+			digits++
+		}
+	}
+	hex := fmt.Sprintf("%0*x", digits, n)
+	hex = hex[len(hex)-digits:]
+	fake := []byte(value)
+	for i := len(fake) - 1; i >= 0; i-- {
+		if isHexDigit(rune(fake[i])) {
+			digits--
+			fake[i] = hex[digits]
+		}
+	}
+	return string(fake)
+}
+
+func isHexDigit(r rune) bool {
+	return (r >= '0' && r <= '9') || (r >= 'a' && r <= 'f') || (r >= 'A' && r <= 'F')
+}
+
+// capturePciDevice captures the PCI device with the given address, and its VFs if withVfs is set
+func (c *capturer) capturePciDevice(pciAddress string, withVfs bool) error {
+	busLink := filepath.Join(pciSysDir, pciAddress)
+	if _, ok := c.entries[busLink]; ok {
+		return nil
+	}
+	devDir, err := c.captureLink(busLink)
+	if err != nil {
+		return fmt.Errorf("PCI device %s not found: %v", pciAddress, err)
+	}
+	return c.captureDevice(devDir, pciDriversSysDir, withVfs)
+}
+
+// captureDevice captures the PCI or auxiliary device at the given canonical sysfs path
+func (c *capturer) captureDevice(devDir, driversDir string, withVfs bool) error {
+	dirs, links, err := c.captureAttrs(devDir)
+	if err != nil {
+		return err
+	}
+	var vfs []string
+	for _, link := range links {
+		switch {
+		case link == "driver":
+			driver, err := c.captureLink(filepath.Join(devDir, link))
+			if err == nil && filepath.Dir(driver) == driversDir {
+				c.drivers[driver] = true
+			}
+		case link == "physfn":
+			_, _ = c.captureLink(filepath.Join(devDir, link))
+		case strings.HasPrefix(link, "virtfn"):
+			vf, err := c.captureLink(filepath.Join(devDir, link))
+			if err == nil {
+				vfs = append(vfs, filepath.Base(vf))
+			}
+		case link == "iommu_group":
+			if group, err := c.captureLink(filepath.Join(devDir, link)); err == nil {
+				c.addLink(filepath.Join(group, "devices", filepath.Base(devDir)), devDir)
+			}
+		}
+	}
+
+	for _, dir := range dirs {
+		switch {
+		case dir == "net":
+			err = c.captureClassDevices(filepath.Join(devDir, dir), netSysDir, c.captureNetdev)
+		case dir == "sriov":
+			// per VF InfiniBand configuration of a PF
+			err = c.captureTree(filepath.Join(devDir, dir), 2)
+		case dir == "infiniband":
+			err = c.captureClassDevices(filepath.Join(devDir, dir), infinibandSysDir, c.captureRdmaDevice)
+		case auxDeviceRe.MatchString(dir):
+			auxDir := filepath.Join(devDir, dir)
+			c.addLink(filepath.Join(auxSysDir, dir), auxDir)
+			err = c.captureDevice(auxDir, auxDriversSysDir, false)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if withVfs {
+		for _, vf := range vfs {
+			if err = c.capturePciDevice(vf, false); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// captureClassDevices captures the devices of a class (e.g net) under a device along with their class links
+func (c *capturer) captureClassDevices(dir, classDir string, capture func(dir string) error) error {
+	infos, err := os.ReadDir(c.hostPath(dir))
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		if !info.IsDir() {
+			continue
+		}
+		devDir := filepath.Join(dir, info.Name())
+		c.addLink(filepath.Join(classDir, info.Name()), devDir)
+		if err = capture(devDir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *capturer) captureNetdev(dir string) error {
+	dirs, links, err := c.captureAttrs(dir)
+	if err != nil {
+		return err
+	}
+	for _, link := range links {
+		if link == "device" || link == "subsystem" {
+			_, _ = c.captureLink(filepath.Join(dir, link))
+		}
+	}
+	for _, sub := range dirs {
+		if capturedNetdevDirs[sub] {
+			if err = c.captureTree(filepath.Join(dir, sub), maxNetdevDepth); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c *capturer) captureRdmaDevice(dir string) error {
+	_, links, err := c.captureAttrs(dir)
+	if err != nil {
+		return err
+	}
+	for _, link := range links {
+		if link == "device" {
+			_, _ = c.captureLink(filepath.Join(dir, link))
+		}
+	}
+	return nil
+}
+
+// captureTree captures the attributes of a directory and of its subdirectories up to the given depth
+func (c *capturer) captureTree(dir string, depth int) error {
+	dirs, _, err := c.captureAttrs(dir)
+	if err != nil || depth <= 1 {
+		return err
+	}
+	for _, sub := range dirs {
+		if err = c.captureTree(filepath.Join(dir, sub), depth-1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// write writes the captured entries as a gzipped tar archive, ordered by path
+func (c *capturer) write(w io.Writer) error {
+	paths := make([]string, 0, len(c.entries))
+	for path := range c.entries {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	gzw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gzw)
+	modTime := time.Unix(0, 0)
+	for _, path := range paths {
+		e := c.entries[path]
+		hdr := &tar.Header{Typeflag: e.typeflag, Name: strings.TrimPrefix(path, "/"), ModTime: modTime}
+		switch e.typeflag {
+		case tar.TypeDir:
+			hdr.Name += "/"
+			hdr.Mode = 0o755
+		case tar.TypeReg:
+			hdr.Mode = 0o644
+			hdr.Size = int64(len(e.content))
+		case tar.TypeSymlink:
+			hdr.Mode = 0o777
+			hdr.Linkname = e.target
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(e.content); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gzw.Close()
+}
+
+// sysfsPath validates that the given archive path is under /sys and returns its absolute path
+func sysfsPath(name string) (string, error) {
+	path := filepath.Clean("/" + name)
+	if path != sysDir && !strings.HasPrefix(path, sysDir+"/") {
+		return "", fmt.Errorf("invalid snapshot entry %q, not under %s", name, sysDir)
+	}
+	return path, nil
+}
+
+// Restore restores a snapshot written by Capture into the given filesystem
+func Restore(fsys utilfs.Filesystem, r io.Reader) error {
+	gzr, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("invalid snapshot: %v", err)
+	}
+	defer gzr.Close()
+	tr := tar.NewReader(gzr)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid snapshot: %v", err)
+		}
+		path, err := sysfsPath(hdr.Name)
+		if err != nil {
+			return err
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = fsys.MkdirAll(path, os.FileMode(0755))
+		case tar.TypeReg:
+			var content []byte
+			if content, err = io.ReadAll(io.LimitReader(tr, maxAttrSize)); err != nil {
+				return err
+			}
+			if err = fsys.MkdirAll(filepath.Dir(path), os.FileMode(0755)); err == nil {
+				err = fsys.WriteFile(path, content, os.FileMode(0644))
+			}
+		case tar.TypeSymlink:
+			var target string
+			if target, err = sysfsPath(hdr.Linkname); err != nil {
+				return err
+			}
+			if err = fsys.MkdirAll(filepath.Dir(path), os.FileMode(0755)); err == nil {
+				err = fsys.Symlink(target, path)
+			}
+		default:
+			return fmt.Errorf("invalid snapshot entry %q, unsupported type %c", hdr.Name, hdr.Typeflag)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to restore %s: %v", path, err)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sysfssnapshot_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k8snetworkplumbingwg/sriovnet"
+	"github.com/k8snetworkplumbingwg/sriovnet/pkg/sriovnettest"
+	"github.com/k8snetworkplumbingwg/sriovnet/pkg/sysfssnapshot"
+	utilfs "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/filesystem"
+)
+
+func TestCaptureRestore(t *testing.T) {
+	sfNum := 88
+	src := sriovnettest.NewEnv(t)
+	src.AddPF(&sriovnettest.PF{
+		PciAddress: "0000:03:00.0",
+		Driver:     "mlx5_core",
+		Netdev:     "p0",
+		TotalVfs:   4,
+		SwitchID:   "c2cfc60003a1420c",
+		VFs: []*sriovnettest.VF{
+			{PciAddress: "0000:03:00.2", Driver: "mlx5_core", Netdev: "eth2", Representor: "rep0"},
+			{PciAddress: "0000:03:00.3", Driver: "vfio-pci", Representor: "rep1"},
+		},
+	})
+	src.AddRepresentor("0000:03:00.0", &sriovnettest.Representor{Name: "en3f0pf0sf88", PhysPortName: "pf0sf88"})
+	src.AddAuxDevice(&sriovnettest.AuxDevice{Parent: "0000:03:00.0", Name: "mlx5_core.sf.2", SfNum: &sfNum,
+		Netdev: "enp3s0f0s88"})
+	// not captured
+	src.AddPF(&sriovnettest.PF{PciAddress: "0000:04:00.0", Netdev: "p1"})
+
+	var snapshot bytes.Buffer
+	require.NoError(t, sysfssnapshot.Capture(&snapshot, []string{"0000:03:00.0"},
+		&sysfssnapshot.CaptureOptions{Root: src.Root}))
+	assert.Error(t, sysfssnapshot.Capture(&bytes.Buffer{}, []string{"0000:05:00.0"},
+		&sysfssnapshot.CaptureOptions{Root: src.Root}))
+
+	dst := sriovnettest.NewEnv(t)
+	dst.LoadSnapshot(&snapshot)
+
+	reps, err := sriovnet.GetVfRepresentors("p0")
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]string{0: "rep0", 1: "rep1"}, reps)
+	rep, err := sriovnet.GetVfRepresentorByVfPciAddress("0000:03:00.3")
+	assert.NoError(t, err)
+	assert.Equal(t, "rep1", rep)
+	driver, err := sriovnet.GetDriverNameByPciAddress("0000:03:00.3")
+	assert.NoError(t, err)
+	assert.Equal(t, "vfio-pci", driver)
+	rep, err = sriovnet.GetSfRepresentor("p0", 88)
+	assert.NoError(t, err)
+	assert.Equal(t, "en3f0pf0sf88", rep)
+	num, err := sriovnet.GetSfIndexByAuxDev("mlx5_core.sf.2")
+	assert.NoError(t, err)
+	assert.Equal(t, 88, num)
+	assert.Equal(t, "", dst.ReadFile(filepath.Join(sriovnettest.PciDriversSysDir, "vfio-pci", "unbind")))
+
+	_, err = sriovnet.GetUplinkRepresentor("0000:04:00.0")
+	assert.Error(t, err)
+}
+
+// TestCaptureRelativeLinks captures a sysfs with relative links, as exposed by the kernel
+func TestCaptureRelativeLinks(t *testing.T) {
+	root := t.TempDir()
+	pfDir := filepath.Join(root, "sys/devices/pci0000:00/0000:00:01.0/0000:03:00.0")
+	vfDir := filepath.Join(root, "sys/devices/pci0000:00/0000:00:01.0/0000:03:00.2")
+	for _, dir := range []string{pfDir, vfDir, filepath.Join(root, sriovnettest.PciSysDir)} {
+		require.NoError(t, os.MkdirAll(dir, os.FileMode(0755)))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(pfDir, "sriov_totalvfs"), []byte("8\n"), os.FileMode(0644)))
+	// write-only attribute
+	require.NoError(t, os.WriteFile(filepath.Join(pfDir, "remove"), nil, os.FileMode(0200)))
+	require.NoError(t, os.Symlink("../../../devices/pci0000:00/0000:00:01.0/0000:03:00.0",
+		filepath.Join(root, sriovnettest.PciSysDir, "0000:03:00.0")))
+	require.NoError(t, os.Symlink("../../../devices/pci0000:00/0000:00:01.0/0000:03:00.2",
+		filepath.Join(root, sriovnettest.PciSysDir, "0000:03:00.2")))
+	require.NoError(t, os.Symlink("../0000:03:00.2", filepath.Join(pfDir, "virtfn0")))
+	require.NoError(t, os.Symlink("../0000:03:00.0", filepath.Join(vfDir, "physfn")))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "sys/kernel/iommu_groups/12/devices"), os.FileMode(0755)))
+	require.NoError(t, os.Symlink("../../../../kernel/iommu_groups/12", filepath.Join(pfDir, "iommu_group")))
+
+	var snapshot bytes.Buffer
+	require.NoError(t, sysfssnapshot.Capture(&snapshot, []string{"0000:03:00.0"},
+		&sysfssnapshot.CaptureOptions{Root: root}))
+
+	sriovnettest.NewEnv(t).LoadSnapshot(&snapshot)
+	pf, err := sriovnet.GetPfPciFromVfPci("0000:03:00.2")
+	assert.NoError(t, err)
+	assert.Equal(t, "0000:03:00.0", pf)
+	vfIndex, err := sriovnet.GetVfIndexByPciAddress("0000:03:00.2")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, vfIndex)
+	group, err := sriovnet.GetPciIommuGroup("0000:03:00.0")
+	assert.NoError(t, err)
+	assert.Equal(t, 12, group)
+	devices, err := sriovnet.ListDevicesInIommuGroup(12)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"0000:03:00.0"}, devices)
+	_, err = utilfs.Fs.Stat("/sys/devices/pci0000:00/0000:00:01.0/0000:03:00.0/remove")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestCaptureAnonymize(t *testing.T) {
+	src := sriovnettest.NewEnv(t)
+	src.AddPF(&sriovnettest.PF{
+		PciAddress: "0000:03:00.0",
+		Netdev:     "p0",
+		MacAddress: "0c:42:a1:d1:d0:70",
+		TotalVfs:   2,
+		SwitchID:   "c2cfc60003a1420c",
+		VFs: []*sriovnettest.VF{
+			{PciAddress: "0000:03:00.2", Netdev: "eth2", MacAddress: "00:00:00:00:00:00", Representor: "rep0"},
+		},
+	})
+	pfDir := filepath.Join(sriovnettest.PciSysDir, "0000:03:00.0")
+	src.WriteFile(filepath.Join(pfDir, "sriov", "0", "node"), "0c42:a103:00d1:d071\n")
+	src.WriteFile(filepath.Join(pfDir, "sriov", "0", "policy"), "Follow\n")
+
+	var snapshot bytes.Buffer
+	require.NoError(t, sysfssnapshot.Capture(&snapshot, []string{"0000:03:00.0"},
+		&sysfssnapshot.CaptureOptions{Root: src.Root, Anonymize: true}))
+	for _, identifier := range []string{"0c:42:a1:d1:d0:70", "c2cfc60003a1420c", "0c42:a103:00d1:d071"} {
+		assert.NotContains(t, readArchive(t, snapshot.Bytes()), identifier)
+	}
+
+	dst := sriovnettest.NewEnv(t)
+	dst.LoadSnapshot(&snapshot)
+	// representors are still matched to their uplink by switch ID
+	reps, err := sriovnet.GetVfRepresentors("p0")
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]string{0: "rep0"}, reps)
+	assert.Equal(t, "00:00:00:00:00:01\n", dst.ReadFile(filepath.Join(sriovnettest.NetSysDir, "p0", "address")))
+	assert.Equal(t, "00:00:00:00:00:00\n", dst.ReadFile(filepath.Join(sriovnettest.NetSysDir, "eth2", "address")))
+	assert.Equal(t, "0000:0000:0000:0003\n", dst.ReadFile(filepath.Join(pfDir, "sriov", "0", "node")))
+	assert.Equal(t, "Follow\n", dst.ReadFile(filepath.Join(pfDir, "sriov", "0", "policy")))
+}
+
+// readArchive returns the uncompressed tar stream of a snapshot
+func readArchive(t *testing.T, snapshot []byte) string {
+	gzr, err := gzip.NewReader(bytes.NewReader(snapshot))
+	require.NoError(t, err)
+	content, err := io.ReadAll(gzr)
+	require.NoError(t, err)
+	return string(content)
+}
+
+func TestRestoreInvalidEntry(t *testing.T) {
+	for _, hdr := range []*tar.Header{
+		{Typeflag: tar.TypeReg, Name: "etc/passwd"},
+		{Typeflag: tar.TypeReg, Name: "sys/../etc/passwd"},
+		{Typeflag: tar.TypeSymlink, Name: "sys/class/net/p0", Linkname: "/etc"},
+	} {
+		var snapshot bytes.Buffer
+		gzw := gzip.NewWriter(&snapshot)
+		tw := tar.NewWriter(gzw)
+		require.NoError(t, tw.WriteHeader(hdr))
+		require.NoError(t, tw.Close())
+		require.NoError(t, gzw.Close())
+
+		sriovnettest.NewEnv(t)
+		assert.Error(t, sysfssnapshot.Restore(utilfs.Fs, &snapshot), hdr.Name)
+	}
+}
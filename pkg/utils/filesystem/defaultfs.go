@@ -4,6 +4,7 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
 	"time"
 )
 
@@ -96,6 +97,23 @@ func (DefaultFs) ReadDir(dirname string) ([]os.FileInfo, error) {
 	return infos, nil
 }
 
+var _ DirNamesReader = DefaultFs{}
+
+// ReadDirNames via os.File.Readdirnames, sorted by filename
+func (DefaultFs) ReadDirNames(dirname string) ([]string, error) {
+	dir, err := os.Open(dirname)
+	if err != nil {
+		return nil, err
+	}
+	defer dir.Close()
+	names, err := dir.Readdirnames(-1)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
 // Walk via filepath.Walk
 func (DefaultFs) Walk(root string, walkFn filepath.WalkFunc) error {
 	return filepath.Walk(root, walkFn)
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"time"
 
 	"github.com/spf13/afero"
@@ -100,6 +101,30 @@ func (fs *FakeFs) ReadDir(dirname string) ([]os.FileInfo, error) {
 	return fs.a.ReadDir(dirname)
 }
 
+var _ DirNamesReader = &FakeFs{}
+
+// ReadDirNames via afero.Fs.Open and afero.File.Readdirnames, sorted by filename
+func (fs *FakeFs) ReadDirNames(dirname string) ([]string, error) {
+	dir, err := fs.a.Fs.Open(dirname)
+	if err != nil {
+		return nil, err
+	}
+	defer dir.Close()
+	names, err := dir.Readdirnames(-1)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+var _ RealPather = &FakeFs{}
+
+// RealPath via afero.BasePathFs.RealPath
+func (fs *FakeFs) RealPath(name string) (string, error) {
+	return fs.a.Fs.(*afero.BasePathFs).RealPath(name)
+}
+
 // Walk via afero.Walk
 func (fs *FakeFs) Walk(root string, walkFn filepath.WalkFunc) error {
 	return fs.a.Walk(root, walkFn)
@@ -30,6 +30,44 @@ type Filesystem interface {
 	Walk(root string, walkFn filepath.WalkFunc) error
 }
 
+// DirNamesReader is an optional interface of a Filesystem able to list the names of the directory entries
+// without stat-ing them
+type DirNamesReader interface {
+	ReadDirNames(dirname string) ([]string, error)
+}
+
+// ReadDirNames returns the names of the entries of dirname sorted by filename, via fs.ReadDirNames if fs
+// implements DirNamesReader and fs.ReadDir otherwise
+func ReadDirNames(fs Filesystem, dirname string) ([]string, error) {
+	if r, ok := fs.(DirNamesReader); ok {
+		return r.ReadDirNames(dirname)
+	}
+	infos, err := fs.ReadDir(dirname)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(infos))
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	return names, nil
+}
+
+// RealPather is an optional interface of a Filesystem whose paths are not those of the host, e.g a fake
+// filesystem rooted in a temporary directory
+type RealPather interface {
+	RealPath(name string) (string, error)
+}
+
+// RealPath returns the host path of name, e.g to watch it with inotify, via fs.RealPath if fs implements
+// RealPather and name otherwise
+func RealPath(fs Filesystem, name string) (string, error) {
+	if r, ok := fs.(RealPather); ok {
+		return r.RealPath(name)
+	}
+	return name, nil
+}
+
 // File is an interface that we can use to mock various filesystem operations typically
 // accessed through the File object from the "os" package
 type File interface {
@@ -0,0 +1,393 @@
+package netlinkops
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/vishvananda/netlink"
+)
+
+// MutationRecord describes a mutating netlink operation
+type MutationRecord struct {
+	// Op is the NetlinkOps method name, e.g 'LinkSetMTU'
+	Op string
+	// Target identifies the mutated object, e.g a netdev name or a devlink device/port ('pci/0000:03:00.0/1')
+	Target string
+	// OldValue is the value before the operation, empty if unknown
+	OldValue string
+	// NewValue is the requested value, empty for operations with no value (e.g port deletion)
+	NewValue string
+	// Err is the result of the operation
+	Err error
+}
+
+// MutationHook is called after every mutating netlink operation
+type MutationHook func(record *MutationRecord)
+
+var mutationHook MutationHook
+
+// SetMutationHook sets the hook called after every mutating operation of the NetlinkOps returned by
+// GetNetlinkOps, nil disables it. Old values are looked up only while a hook is set.
+func SetMutationHook(hook MutationHook) {
+	mutationHook = hook
+}
+
+// auditedNetlinkOps wraps a NetlinkOps, reporting its mutating operations to a MutationHook
+type auditedNetlinkOps struct {
+	NetlinkOps
+	hook MutationHook
+}
+
+func (a *auditedNetlinkOps) report(op, target, oldValue, newValue string, err error) {
+	a.hook(&MutationRecord{Op: op, Target: target, OldValue: oldValue, NewValue: newValue, Err: err})
+}
+
+func linkTarget(link netlink.Link) string {
+	if link == nil || link.Attrs() == nil {
+		return ""
+	}
+	return link.Attrs().Name
+}
+
+func devlinkTarget(bus, device string) string {
+	return bus + "/" + device
+}
+
+func devlinkPortTarget(bus, device string, portIndex uint32) string {
+	return fmt.Sprintf("%s/%s/%d", bus, device, portIndex)
+}
+
+func devlinkDeviceTarget(dev *netlink.DevlinkDevice) string {
+	if dev == nil {
+		return ""
+	}
+	return devlinkTarget(dev.BusName, dev.DeviceName)
+}
+
+// linkVfInfo returns the VF info of link as reported when the link was queried, nil if not reported
+func linkVfInfo(link netlink.Link, vf int) *netlink.VfInfo {
+	if link == nil || link.Attrs() == nil {
+		return nil
+	}
+	for i := range link.Attrs().Vfs {
+		if link.Attrs().Vfs[i].ID == vf {
+			return &link.Attrs().Vfs[i]
+		}
+	}
+	return nil
+}
+
+func onOff(state bool) string {
+	if state {
+		return "on"
+	}
+	return "off"
+}
+
+func formatFeatures(features map[string]bool) string {
+	names := make([]string, 0, len(features))
+	for name := range features {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	values := make([]string, 0, len(names))
+	for _, name := range names {
+		values = append(values, name+"="+onOff(features[name]))
+	}
+	return strings.Join(values, ",")
+}
+
+func formatRateAttrs(attrs DevlinkRateSetAttrs) string {
+	values := make([]string, 0, 3)
+	if attrs.TxShare != nil {
+		values = append(values, fmt.Sprintf("tx_share=%d", *attrs.TxShare))
+	}
+	if attrs.TxMax != nil {
+		values = append(values, fmt.Sprintf("tx_max=%d", *attrs.TxMax))
+	}
+	if attrs.ParentNodeName != nil {
+		values = append(values, "parent="+*attrs.ParentNodeName)
+	}
+	return strings.Join(values, ",")
+}
+
+func formatPortFnAttrs(attrs netlink.DevlinkPortFnSetAttrs) string {
+	values := make([]string, 0, 2)
+	if attrs.HwAddrValid {
+		values = append(values, "hw_addr="+attrs.FnAttrs.HwAddr.String())
+	}
+	if attrs.StateValid {
+		values = append(values, fmt.Sprintf("state=%d", attrs.FnAttrs.State))
+	}
+	return strings.Join(values, ",")
+}
+
+func (a *auditedNetlinkOps) LinkSetFeatures(name string, features map[string]bool) error {
+	oldValue := ""
+	if current, err := a.NetlinkOps.LinkGetFeatures(name); err == nil {
+		old := make(map[string]bool, len(features))
+		for feature := range features {
+			if value, ok := current[feature]; ok {
+				old[feature] = value
+			}
+		}
+		oldValue = formatFeatures(old)
+	}
+	err := a.NetlinkOps.LinkSetFeatures(name, features)
+	a.report("LinkSetFeatures", name, oldValue, formatFeatures(features), err)
+	return err
+}
+
+func (a *auditedNetlinkOps) linkSetState(op string, link netlink.Link, up bool, set func(netlink.Link) error) error {
+	oldValue := ""
+	if link != nil && link.Attrs() != nil {
+		oldValue = "down"
+		if link.Attrs().Flags&net.FlagUp != 0 {
+			oldValue = "up"
+		}
+	}
+	newValue := "down"
+	if up {
+		newValue = "up"
+	}
+	err := set(link)
+	a.report(op, linkTarget(link), oldValue, newValue, err)
+	return err
+}
+
+func (a *auditedNetlinkOps) LinkSetUp(link netlink.Link) error {
+	return a.linkSetState("LinkSetUp", link, true, a.NetlinkOps.LinkSetUp)
+}
+
+func (a *auditedNetlinkOps) LinkSetDown(link netlink.Link) error {
+	return a.linkSetState("LinkSetDown", link, false, a.NetlinkOps.LinkSetDown)
+}
+
+func (a *auditedNetlinkOps) LinkSetMTU(link netlink.Link, mtu int) error {
+	oldValue := ""
+	if link != nil && link.Attrs() != nil {
+		oldValue = fmt.Sprint(link.Attrs().MTU)
+	}
+	err := a.NetlinkOps.LinkSetMTU(link, mtu)
+	a.report("LinkSetMTU", linkTarget(link), oldValue, fmt.Sprint(mtu), err)
+	return err
+}
+
+func (a *auditedNetlinkOps) LinkSetMTUByName(name string, mtu int) error {
+	oldValue := ""
+	if current, err := a.NetlinkOps.LinkGetMTUByName(name); err == nil {
+		oldValue = fmt.Sprint(current)
+	}
+	err := a.NetlinkOps.LinkSetMTUByName(name, mtu)
+	a.report("LinkSetMTUByName", name, oldValue, fmt.Sprint(mtu), err)
+	return err
+}
+
+func (a *auditedNetlinkOps) LinkSetName(link netlink.Link, name string) error {
+	target := linkTarget(link)
+	err := a.NetlinkOps.LinkSetName(link, name)
+	a.report("LinkSetName", target, target, name, err)
+	return err
+}
+
+func (a *auditedNetlinkOps) LinkSetNs(link netlink.Link, netnsPath string) error {
+	err := a.NetlinkOps.LinkSetNs(link, netnsPath)
+	a.report("LinkSetNs", linkTarget(link), "", netnsPath, err)
+	return err
+}
+
+func (a *auditedNetlinkOps) LinkSetVfHardwareAddr(link netlink.Link, vf int, hwaddr net.HardwareAddr) error {
+	oldValue := ""
+	if info := linkVfInfo(link, vf); info != nil {
+		oldValue = info.Mac.String()
+	}
+	err := a.NetlinkOps.LinkSetVfHardwareAddr(link, vf, hwaddr)
+	a.report("LinkSetVfHardwareAddr", fmt.Sprintf("%s/vf%d", linkTarget(link), vf), oldValue, hwaddr.String(), err)
+	return err
+}
+
+func (a *auditedNetlinkOps) LinkSetVfVlan(link netlink.Link, vf, vlan int) error {
+	oldValue := ""
+	if info := linkVfInfo(link, vf); info != nil {
+		oldValue = fmt.Sprint(info.Vlan)
+	}
+	err := a.NetlinkOps.LinkSetVfVlan(link, vf, vlan)
+	a.report("LinkSetVfVlan", fmt.Sprintf("%s/vf%d", linkTarget(link), vf), oldValue, fmt.Sprint(vlan), err)
+	return err
+}
+
+func (a *auditedNetlinkOps) LinkSetVfNodeGUID(link netlink.Link, vf int, nodeguid net.HardwareAddr) error {
+	err := a.NetlinkOps.LinkSetVfNodeGUID(link, vf, nodeguid)
+	a.report("LinkSetVfNodeGUID", fmt.Sprintf("%s/vf%d", linkTarget(link), vf), "", nodeguid.String(), err)
+	return err
+}
+
+func (a *auditedNetlinkOps) LinkSetVfPortGUID(link netlink.Link, vf int, portguid net.HardwareAddr) error {
+	err := a.NetlinkOps.LinkSetVfPortGUID(link, vf, portguid)
+	a.report("LinkSetVfPortGUID", fmt.Sprintf("%s/vf%d", linkTarget(link), vf), "", portguid.String(), err)
+	return err
+}
+
+func (a *auditedNetlinkOps) LinkSetVfTrust(link netlink.Link, vf int, state bool) error {
+	oldValue := ""
+	if info := linkVfInfo(link, vf); info != nil {
+		oldValue = onOff(info.Trust != 0)
+	}
+	err := a.NetlinkOps.LinkSetVfTrust(link, vf, state)
+	a.report("LinkSetVfTrust", fmt.Sprintf("%s/vf%d", linkTarget(link), vf), oldValue, onOff(state), err)
+	return err
+}
+
+func (a *auditedNetlinkOps) LinkSetVfSpoofchk(link netlink.Link, vf int, check bool) error {
+	oldValue := ""
+	if info := linkVfInfo(link, vf); info != nil {
+		oldValue = onOff(info.Spoofchk)
+	}
+	err := a.NetlinkOps.LinkSetVfSpoofchk(link, vf, check)
+	a.report("LinkSetVfSpoofchk", fmt.Sprintf("%s/vf%d", linkTarget(link), vf), oldValue, onOff(check), err)
+	return err
+}
+
+func (a *auditedNetlinkOps) DevLinkSetEswitchMode(dev *netlink.DevlinkDevice, newMode string) error {
+	oldValue := ""
+	if dev != nil {
+		oldValue = dev.Attrs.Eswitch.Mode
+	}
+	err := a.NetlinkOps.DevLinkSetEswitchMode(dev, newMode)
+	a.report("DevLinkSetEswitchMode", devlinkDeviceTarget(dev), oldValue, newMode, err)
+	return err
+}
+
+func (a *auditedNetlinkOps) DevLinkSetEswitchEncapMode(dev *netlink.DevlinkDevice, encapMode string) error {
+	oldValue := ""
+	if dev != nil {
+		oldValue = dev.Attrs.Eswitch.EncapMode
+	}
+	err := a.NetlinkOps.DevLinkSetEswitchEncapMode(dev, encapMode)
+	a.report("DevLinkSetEswitchEncapMode", devlinkDeviceTarget(dev), oldValue, encapMode, err)
+	return err
+}
+
+func (a *auditedNetlinkOps) DevLinkSetEswitchInlineMode(dev *netlink.DevlinkDevice, inlineMode string) error {
+	oldValue := ""
+	if dev != nil {
+		oldValue = dev.Attrs.Eswitch.InlineMode
+	}
+	err := a.NetlinkOps.DevLinkSetEswitchInlineMode(dev, inlineMode)
+	a.report("DevLinkSetEswitchInlineMode", devlinkDeviceTarget(dev), oldValue, inlineMode, err)
+	return err
+}
+
+func (a *auditedNetlinkOps) DevlinkSetDeviceParam(bus, device, param string, cmode uint8, value interface{}) error {
+	oldValue := ""
+	if current, err := a.NetlinkOps.DevlinkGetDeviceParamByName(bus, device, param); err == nil && current != nil {
+		for _, v := range current.Values {
+			if v.CMODE == cmode {
+				oldValue = fmt.Sprint(v.Data)
+			}
+		}
+	}
+	err := a.NetlinkOps.DevlinkSetDeviceParam(bus, device, param, cmode, value)
+	a.report("DevlinkSetDeviceParam", devlinkTarget(bus, device)+"/"+param, oldValue, fmt.Sprint(value), err)
+	return err
+}
+
+func (a *auditedNetlinkOps) DevlinkReload(bus, device string, attrs DevlinkReloadAttrs) (uint32, error) {
+	performed, err := a.NetlinkOps.DevlinkReload(bus, device, attrs)
+	newValue := fmt.Sprintf("action=%d", attrs.Action)
+	if attrs.NetnsPath != "" {
+		newValue += ",netns=" + attrs.NetnsPath
+	}
+	a.report("DevlinkReload", devlinkTarget(bus, device), "", newValue, err)
+	return performed, err
+}
+
+func (a *auditedNetlinkOps) DevlinkPortFnSet(bus, device string, portIndex uint32,
+	fnAttrs netlink.DevlinkPortFnSetAttrs) error {
+	err := a.NetlinkOps.DevlinkPortFnSet(bus, device, portIndex, fnAttrs)
+	a.report("DevlinkPortFnSet", devlinkPortTarget(bus, device, portIndex), "", formatPortFnAttrs(fnAttrs), err)
+	return err
+}
+
+func (a *auditedNetlinkOps) DevLinkPortAdd(bus, device string, flavour uint16,
+	attrs netlink.DevLinkPortAddAttrs) (*netlink.DevlinkPort, error) {
+	port, err := a.NetlinkOps.DevLinkPortAdd(bus, device, flavour, attrs)
+	newValue := fmt.Sprintf("flavour=%d,pfnum=%d", flavour, attrs.PfNumber)
+	if attrs.SfNumberValid {
+		newValue += fmt.Sprintf(",sfnum=%d", attrs.SfNumber)
+	}
+	target := devlinkTarget(bus, device)
+	if port != nil {
+		target = devlinkPortTarget(bus, device, port.PortIndex)
+	}
+	a.report("DevLinkPortAdd", target, "", newValue, err)
+	return port, err
+}
+
+func (a *auditedNetlinkOps) DevLinkPortDel(bus, device string, portIndex uint32) error {
+	err := a.NetlinkOps.DevLinkPortDel(bus, device, portIndex)
+	a.report("DevLinkPortDel", devlinkPortTarget(bus, device, portIndex), "", "", err)
+	return err
+}
+
+func (a *auditedNetlinkOps) DevLinkPortSplit(bus, device string, portIndex, count uint32) error {
+	err := a.NetlinkOps.DevLinkPortSplit(bus, device, portIndex, count)
+	a.report("DevLinkPortSplit", devlinkPortTarget(bus, device, portIndex), "", fmt.Sprint(count), err)
+	return err
+}
+
+func (a *auditedNetlinkOps) DevLinkPortUnsplit(bus, device string, portIndex uint32) error {
+	err := a.NetlinkOps.DevLinkPortUnsplit(bus, device, portIndex)
+	a.report("DevLinkPortUnsplit", devlinkPortTarget(bus, device, portIndex), "", "", err)
+	return err
+}
+
+func (a *auditedNetlinkOps) DevlinkPortFnSetCaps(bus, device string, portIndex, caps, mask uint32) error {
+	oldValue := ""
+	if current, err := a.NetlinkOps.DevlinkPortFnGetCaps(bus, device, portIndex); err == nil {
+		oldValue = fmt.Sprintf("%#x", current&mask)
+	}
+	err := a.NetlinkOps.DevlinkPortFnSetCaps(bus, device, portIndex, caps, mask)
+	a.report("DevlinkPortFnSetCaps", devlinkPortTarget(bus, device, portIndex), oldValue,
+		fmt.Sprintf("%#x", caps&mask), err)
+	return err
+}
+
+func (a *auditedNetlinkOps) LinkSetNameAt(netnsPath string, link netlink.Link, name string) error {
+	target := linkTarget(link)
+	err := a.NetlinkOps.LinkSetNameAt(netnsPath, link, name)
+	a.report("LinkSetNameAt", netnsPath+"/"+target, target, name, err)
+	return err
+}
+
+func (a *auditedNetlinkOps) LinkSetNsCurrentFrom(netnsPath string, link netlink.Link) error {
+	err := a.NetlinkOps.LinkSetNsCurrentFrom(netnsPath, link)
+	a.report("LinkSetNsCurrentFrom", linkTarget(link), netnsPath, "", err)
+	return err
+}
+
+func (a *auditedNetlinkOps) DevlinkRateNodeNew(bus, device, name string) error {
+	err := a.NetlinkOps.DevlinkRateNodeNew(bus, device, name)
+	a.report("DevlinkRateNodeNew", devlinkTarget(bus, device)+"/"+name, "", "", err)
+	return err
+}
+
+func (a *auditedNetlinkOps) DevlinkRateNodeDel(bus, device, name string) error {
+	err := a.NetlinkOps.DevlinkRateNodeDel(bus, device, name)
+	a.report("DevlinkRateNodeDel", devlinkTarget(bus, device)+"/"+name, "", "", err)
+	return err
+}
+
+func (a *auditedNetlinkOps) DevlinkRateNodeSet(bus, device, name string, attrs DevlinkRateSetAttrs) error {
+	err := a.NetlinkOps.DevlinkRateNodeSet(bus, device, name, attrs)
+	a.report("DevlinkRateNodeSet", devlinkTarget(bus, device)+"/"+name, "", formatRateAttrs(attrs), err)
+	return err
+}
+
+func (a *auditedNetlinkOps) DevlinkPortRateSet(bus, device string, portIndex uint32, attrs DevlinkRateSetAttrs) error {
+	err := a.NetlinkOps.DevlinkPortRateSet(bus, device, portIndex, attrs)
+	a.report("DevlinkPortRateSet", devlinkPortTarget(bus, device, portIndex), "", formatRateAttrs(attrs), err)
+	return err
+}
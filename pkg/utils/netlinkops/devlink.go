@@ -0,0 +1,659 @@
+package netlinkops
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+	"github.com/vishvananda/netns"
+	"golang.org/x/sys/unix"
+)
+
+// devlink commands and attributes missing from the netlink library, see include/uapi/linux/devlink.h
+const (
+	devlinkCmdPortSplit         = 9
+	devlinkCmdPortUnsplit       = 10
+	devlinkCmdReload            = 37
+	devlinkCmdHealthReporterGet = 52
+	devlinkCmdRateGet           = 74
+	devlinkCmdRateSet           = 75
+	devlinkCmdRateNew           = 76
+	devlinkCmdRateDel           = 77
+
+	devlinkAttrPortSplitCount = 9
+
+	devlinkAttrHealthReporter               = 114
+	devlinkAttrHealthReporterName           = 115
+	devlinkAttrHealthReporterState          = 116
+	devlinkAttrHealthReporterErrCount       = 117
+	devlinkAttrHealthReporterRecoverCount   = 118
+	devlinkAttrHealthReporterGracefulPeriod = 120
+	devlinkAttrHealthReporterAutoRecover    = 121
+	devlinkAttrHealthReporterDumpTsNs       = 137
+
+	devlinkAttrNetnsFd       = 138
+	devlinkAttrReloadAction  = 153
+	devlinkAttrReloadActions = 154
+
+	devlinkAttrRateType           = 165
+	devlinkAttrRateTxShare        = 166
+	devlinkAttrRateTxMax          = 167
+	devlinkAttrRateNodeName       = 168
+	devlinkAttrRateParentNodeName = 169
+
+	devlinkPortFnAttrCaps = 4
+
+	devlinkGenlMcgrpConfigName = "config"
+)
+
+// Devlink port function capabilities, as bits of the port function caps bitfield
+const (
+	DevlinkPortFnCapRoce       uint32 = 1 << 0
+	DevlinkPortFnCapMigratable uint32 = 1 << 1
+)
+
+// Devlink health reporter states
+const (
+	DevlinkHealthReporterStateHealthy = 0
+	DevlinkHealthReporterStateError   = 1
+)
+
+// DevlinkHealthReporter represents a devlink health reporter and its state
+type DevlinkHealthReporter struct {
+	BusName    string
+	DeviceName string
+	// PortIndex is the index of the devlink port the reporter belongs to, valid if IsPortReporter is set
+	PortIndex      uint32
+	IsPortReporter bool
+	Name           string
+	State          uint8
+	ErrorCount     uint64
+	RecoverCount   uint64
+	// GracefulPeriod is the minimal time in msec between recoveries
+	GracefulPeriod uint64
+	AutoRecover    bool
+	// DumpTimestampNs is the time in nsec of the last dump, 0 if no dump is available
+	DumpTimestampNs uint64
+}
+
+// DevlinkPortUpdate is a devlink port notification received from the kernel
+type DevlinkPortUpdate struct {
+	// Cmd is nl.DEVLINK_CMD_PORT_NEW for registered or changed ports and nl.DEVLINK_CMD_PORT_DEL for removed ports
+	Cmd  uint8
+	Port *netlink.DevlinkPort
+}
+
+// Devlink reload actions
+const (
+	DevlinkReloadActionDriverReinit uint8 = 1
+	DevlinkReloadActionFwActivate   uint8 = 2
+)
+
+// DevlinkReloadAttrs holds the options of a devlink reload
+type DevlinkReloadAttrs struct {
+	// Action is the reload action to perform, 0 lets the kernel default to DevlinkReloadActionDriverReinit
+	Action uint8
+	// NetnsPath is the network namespace to reload the devlink instance into, empty to stay in the current one
+	NetnsPath string
+}
+
+// Devlink rate object types
+const (
+	DevlinkRateTypeLeaf uint16 = 0
+	DevlinkRateTypeNode uint16 = 1
+)
+
+// DevlinkRate represents a devlink rate object, either a leaf (a port) or a node (a group of leaves and nodes)
+type DevlinkRate struct {
+	BusName    string
+	DeviceName string
+	Type       uint16
+	// PortIndex is the index of the devlink port of leaf rate objects
+	PortIndex uint32
+	// NodeName is the name of node rate objects
+	NodeName string
+	// TxShare is the guaranteed tx rate in bytes per second
+	TxShare uint64
+	// TxMax is the maximal tx rate in bytes per second, 0 if unlimited
+	TxMax uint64
+	// ParentNodeName is the name of the node the rate object belongs to, empty if none
+	ParentNodeName string
+}
+
+// DevlinkRateSetAttrs holds the devlink rate attributes to set, nil attributes are left unchanged
+type DevlinkRateSetAttrs struct {
+	TxShare *uint64
+	TxMax   *uint64
+	// ParentNodeName is the node to assign the rate object to, an empty name unassigns it
+	ParentNodeName *string
+}
+
+// Devlink encapsulation modes, as accepted by `devlink dev eswitch set $dev encap-mode`
+const (
+	DevlinkEswitchEncapModeNone  = "none"
+	DevlinkEswitchEncapModeBasic = "basic"
+)
+
+// Devlink eswitch inline modes, as accepted by `devlink dev eswitch set $dev inline-mode`
+const (
+	DevlinkEswitchInlineModeNone      = "none"
+	DevlinkEswitchInlineModeLink      = "link"
+	DevlinkEswitchInlineModeNetwork   = "network"
+	DevlinkEswitchInlineModeTransport = "transport"
+)
+
+var devlinkEswitchInlineModes = map[string]uint8{
+	DevlinkEswitchInlineModeNone:      nl.DEVLINK_ESWITCH_INLINE_MODE_NONE,
+	DevlinkEswitchInlineModeLink:      nl.DEVLINK_ESWITCH_INLINE_MODE_LINK,
+	DevlinkEswitchInlineModeNetwork:   nl.DEVLINK_ESWITCH_INLINE_MODE_NETWORK,
+	DevlinkEswitchInlineModeTransport: nl.DEVLINK_ESWITCH_INLINE_MODE_TRANSPORT,
+}
+
+// newDevlinkRequest creates a devlink generic netlink request for the given command and device.
+// The netlink library does not expose every devlink command, this allows sending the missing ones.
+func newDevlinkRequest(cmd uint8, bus, device string) (*nl.NetlinkRequest, error) {
+	family, err := netlink.GenlFamilyGet(nl.GENL_DEVLINK_NAME)
+	if err != nil {
+		return nil, err
+	}
+	req := nl.NewNetlinkRequest(int(family.ID), unix.NLM_F_REQUEST|unix.NLM_F_ACK)
+	req.AddData(&nl.Genlmsg{Command: cmd, Version: nl.GENL_DEVLINK_VERSION})
+	req.AddData(nl.NewRtAttr(nl.DEVLINK_ATTR_BUS_NAME, nl.ZeroTerminated(bus)))
+	req.AddData(nl.NewRtAttr(nl.DEVLINK_ATTR_DEV_NAME, nl.ZeroTerminated(device)))
+	return req, nil
+}
+
+// devlinkEswitchEncapModeToAttr converts an encapsulation mode name to its devlink attribute value
+func devlinkEswitchEncapModeToAttr(encapMode string) (uint8, error) {
+	switch encapMode {
+	case DevlinkEswitchEncapModeNone:
+		return nl.DEVLINK_ESWITCH_ENCAP_MODE_NONE, nil
+	case DevlinkEswitchEncapModeBasic:
+		return nl.DEVLINK_ESWITCH_ENCAP_MODE_BASIC, nil
+	default:
+		return 0, fmt.Errorf("invalid eswitch encap mode %q", encapMode)
+	}
+}
+
+// devLinkSetEswitchEncapMode sets the eswitch encapsulation mode of the given devlink device.
+// Equivalent to: `devlink dev eswitch set $dev encap-mode basic`
+func devLinkSetEswitchEncapMode(dev *netlink.DevlinkDevice, encapMode string) error {
+	mode, err := devlinkEswitchEncapModeToAttr(encapMode)
+	if err != nil {
+		return err
+	}
+	req, err := newDevlinkRequest(nl.DEVLINK_CMD_ESWITCH_SET, dev.BusName, dev.DeviceName)
+	if err != nil {
+		return err
+	}
+	req.AddData(nl.NewRtAttr(nl.DEVLINK_ATTR_ESWITCH_ENCAP_MODE, nl.Uint8Attr(mode)))
+	_, err = req.Execute(unix.NETLINK_GENERIC, 0)
+	return err
+}
+
+// devLinkSetEswitchInlineMode sets the eswitch inline mode of the given devlink device.
+// Equivalent to: `devlink dev eswitch set $dev inline-mode transport`
+func devLinkSetEswitchInlineMode(dev *netlink.DevlinkDevice, inlineMode string) error {
+	mode, ok := devlinkEswitchInlineModes[inlineMode]
+	if !ok {
+		return fmt.Errorf("invalid eswitch inline mode %q", inlineMode)
+	}
+	req, err := newDevlinkRequest(nl.DEVLINK_CMD_ESWITCH_SET, dev.BusName, dev.DeviceName)
+	if err != nil {
+		return err
+	}
+	req.AddData(nl.NewRtAttr(nl.DEVLINK_ATTR_ESWITCH_INLINE_MODE, nl.Uint8Attr(mode)))
+	_, err = req.Execute(unix.NETLINK_GENERIC, 0)
+	return err
+}
+
+// devlinkAttrString converts a NUL terminated string attribute value to a string
+func devlinkAttrString(value []byte) string {
+	return strings.TrimRight(string(value), "\x00")
+}
+
+// parseDevlinkHealthReporterMsg parses a health reporter get message payload (without the genetlink header)
+func parseDevlinkHealthReporterMsg(msg []byte) (*DevlinkHealthReporter, error) {
+	attrs, err := nl.ParseRouteAttr(msg)
+	if err != nil {
+		return nil, err
+	}
+	reporter := &DevlinkHealthReporter{}
+	var reporterAttrs []syscall.NetlinkRouteAttr
+	for _, attr := range attrs {
+		switch attr.Attr.Type {
+		case nl.DEVLINK_ATTR_BUS_NAME:
+			reporter.BusName = devlinkAttrString(attr.Value)
+		case nl.DEVLINK_ATTR_DEV_NAME:
+			reporter.DeviceName = devlinkAttrString(attr.Value)
+		case nl.DEVLINK_ATTR_PORT_INDEX:
+			reporter.PortIndex = nl.NativeEndian().Uint32(attr.Value)
+			reporter.IsPortReporter = true
+		case devlinkAttrHealthReporter | unix.NLA_F_NESTED, devlinkAttrHealthReporter:
+			if reporterAttrs, err = nl.ParseRouteAttr(attr.Value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if reporterAttrs == nil {
+		return nil, fmt.Errorf("health reporter attribute not found")
+	}
+
+	native := nl.NativeEndian()
+	for _, attr := range reporterAttrs {
+		switch attr.Attr.Type {
+		case devlinkAttrHealthReporterName:
+			reporter.Name = devlinkAttrString(attr.Value)
+		case devlinkAttrHealthReporterState:
+			reporter.State = attr.Value[0]
+		case devlinkAttrHealthReporterErrCount:
+			reporter.ErrorCount = native.Uint64(attr.Value)
+		case devlinkAttrHealthReporterRecoverCount:
+			reporter.RecoverCount = native.Uint64(attr.Value)
+		case devlinkAttrHealthReporterGracefulPeriod:
+			reporter.GracefulPeriod = native.Uint64(attr.Value)
+		case devlinkAttrHealthReporterAutoRecover:
+			reporter.AutoRecover = attr.Value[0] != 0
+		case devlinkAttrHealthReporterDumpTsNs:
+			reporter.DumpTimestampNs = native.Uint64(attr.Value)
+		}
+	}
+	return reporter, nil
+}
+
+// devlinkGetHealthReporters returns the health reporters of the given devlink device and of its ports.
+// Equivalent to: `devlink health show $dev`
+func devlinkGetHealthReporters(bus, device string) ([]*DevlinkHealthReporter, error) {
+	req, err := newDevlinkRequest(devlinkCmdHealthReporterGet, bus, device)
+	if err != nil {
+		return nil, err
+	}
+	req.Flags |= unix.NLM_F_DUMP
+	msgs, err := req.Execute(unix.NETLINK_GENERIC, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	reporters := make([]*DevlinkHealthReporter, 0, len(msgs))
+	for _, msg := range msgs {
+		reporter, err := parseDevlinkHealthReporterMsg(msg[nl.SizeofGenlmsg:])
+		if err != nil {
+			return nil, err
+		}
+		// older kernels ignore the device attributes on dump requests
+		if reporter.BusName == bus && reporter.DeviceName == device {
+			reporters = append(reporters, reporter)
+		}
+	}
+	return reporters, nil
+}
+
+// devlinkPortFnSetCaps sets the port function capabilities selected by mask to the values in caps.
+// Equivalent to: `devlink port function set $dev/$port roce enable`
+func devlinkPortFnSetCaps(bus, device string, portIndex, caps, mask uint32) error {
+	req, err := newDevlinkRequest(nl.DEVLINK_CMD_PORT_SET, bus, device)
+	if err != nil {
+		return err
+	}
+	req.AddData(nl.NewRtAttr(nl.DEVLINK_ATTR_PORT_INDEX, nl.Uint32Attr(portIndex)))
+	// struct nla_bitfield32 { value, selector }
+	bitfield := make([]byte, 8)
+	nl.NativeEndian().PutUint32(bitfield[0:4], caps)
+	nl.NativeEndian().PutUint32(bitfield[4:8], mask)
+	fnAttr := nl.NewRtAttr(nl.DEVLINK_ATTR_PORT_FUNCTION|unix.NLA_F_NESTED, nil)
+	fnAttr.AddRtAttr(devlinkPortFnAttrCaps, bitfield)
+	req.AddData(fnAttr)
+	_, err = req.Execute(unix.NETLINK_GENERIC, 0)
+	return err
+}
+
+// parseDevlinkPortFnCaps parses the port function capabilities from a port get message payload
+// (without the genetlink header)
+func parseDevlinkPortFnCaps(msg []byte) (uint32, error) {
+	attrs, err := nl.ParseRouteAttr(msg)
+	if err != nil {
+		return 0, err
+	}
+	for _, attr := range attrs {
+		if attr.Attr.Type&^unix.NLA_F_NESTED != nl.DEVLINK_ATTR_PORT_FUNCTION {
+			continue
+		}
+		fnAttrs, err := nl.ParseRouteAttr(attr.Value)
+		if err != nil {
+			return 0, err
+		}
+		for _, fnAttr := range fnAttrs {
+			if fnAttr.Attr.Type == devlinkPortFnAttrCaps && len(fnAttr.Value) >= 4 {
+				return nl.NativeEndian().Uint32(fnAttr.Value[0:4]), nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("port function capabilities not reported")
+}
+
+// devlinkPortFnGetCaps returns the port function capabilities of the given devlink port.
+// Equivalent to: `devlink port function show $dev/$port`
+func devlinkPortFnGetCaps(bus, device string, portIndex uint32) (uint32, error) {
+	req, err := newDevlinkRequest(nl.DEVLINK_CMD_PORT_GET, bus, device)
+	if err != nil {
+		return 0, err
+	}
+	req.AddData(nl.NewRtAttr(nl.DEVLINK_ATTR_PORT_INDEX, nl.Uint32Attr(portIndex)))
+	msgs, err := req.Execute(unix.NETLINK_GENERIC, 0)
+	if err != nil {
+		return 0, err
+	}
+	if len(msgs) == 0 {
+		return 0, fmt.Errorf("no response for devlink port %s/%s/%d", bus, device, portIndex)
+	}
+	return parseDevlinkPortFnCaps(msgs[0][nl.SizeofGenlmsg:])
+}
+
+// parseDevlinkPortMsg parses a port message payload (without the genetlink header)
+func parseDevlinkPortMsg(msg []byte) (*netlink.DevlinkPort, error) {
+	attrs, err := nl.ParseRouteAttr(msg)
+	if err != nil {
+		return nil, err
+	}
+	native := nl.NativeEndian()
+	port := &netlink.DevlinkPort{}
+	for _, attr := range attrs {
+		switch attr.Attr.Type &^ unix.NLA_F_NESTED {
+		case nl.DEVLINK_ATTR_BUS_NAME:
+			port.BusName = devlinkAttrString(attr.Value)
+		case nl.DEVLINK_ATTR_DEV_NAME:
+			port.DeviceName = devlinkAttrString(attr.Value)
+		case nl.DEVLINK_ATTR_PORT_INDEX:
+			port.PortIndex = native.Uint32(attr.Value)
+		case nl.DEVLINK_ATTR_PORT_TYPE:
+			port.PortType = native.Uint16(attr.Value)
+		case nl.DEVLINK_ATTR_PORT_NETDEV_NAME:
+			port.NetdeviceName = devlinkAttrString(attr.Value)
+		case nl.DEVLINK_ATTR_PORT_NETDEV_IFINDEX:
+			port.NetdevIfIndex = native.Uint32(attr.Value)
+		case nl.DEVLINK_ATTR_PORT_IBDEV_NAME:
+			port.RdmaDeviceName = devlinkAttrString(attr.Value)
+		case nl.DEVLINK_ATTR_PORT_FLAVOUR:
+			port.PortFlavour = native.Uint16(attr.Value)
+		case nl.DEVLINK_ATTR_PORT_FUNCTION:
+			fnAttrs, err := nl.ParseRouteAttr(attr.Value)
+			if err != nil {
+				return nil, err
+			}
+			port.Fn = &netlink.DevlinkPortFn{}
+			for _, fnAttr := range fnAttrs {
+				switch fnAttr.Attr.Type {
+				case nl.DEVLINK_PORT_FUNCTION_ATTR_HW_ADDR:
+					port.Fn.HwAddr = fnAttr.Value
+				case nl.DEVLINK_PORT_FN_ATTR_STATE:
+					port.Fn.State = fnAttr.Value[0]
+				case nl.DEVLINK_PORT_FN_ATTR_OPSTATE:
+					port.Fn.OpState = fnAttr.Value[0]
+				}
+			}
+		}
+	}
+	return port, nil
+}
+
+// devlinkPortSubscribe subscribes to the devlink "config" multicast group and sends the port
+// notifications on ch until done is closed, ch is closed when the subscription terminates.
+// done is required as the subscription socket is only released once done is closed.
+// Equivalent to: `devlink monitor port`
+func devlinkPortSubscribe(ch chan<- DevlinkPortUpdate, done <-chan struct{}) error {
+	if done == nil {
+		return fmt.Errorf("done channel is required to subscribe to devlink port notifications")
+	}
+	family, err := netlink.GenlFamilyGet(nl.GENL_DEVLINK_NAME)
+	if err != nil {
+		return err
+	}
+	var groupID uint32
+	for _, group := range family.Groups {
+		if group.Name == devlinkGenlMcgrpConfigName {
+			groupID = group.ID
+		}
+	}
+	if groupID == 0 {
+		return fmt.Errorf("devlink %q multicast group not found", devlinkGenlMcgrpConfigName)
+	}
+
+	s, err := nl.Subscribe(unix.NETLINK_GENERIC)
+	if err != nil {
+		return err
+	}
+	// genetlink group ids may exceed the bind() groups bitmask, join the group explicitly
+	if err = unix.SetsockoptInt(s.GetFd(), unix.SOL_NETLINK, unix.NETLINK_ADD_MEMBERSHIP, int(groupID)); err != nil {
+		s.Close()
+		return err
+	}
+	go func() {
+		<-done
+		s.Close()
+	}()
+
+	go func() {
+		defer close(ch)
+		for {
+			msgs, from, err := s.Receive()
+			if err != nil {
+				return
+			}
+			if from.Pid != nl.PidKernel {
+				continue
+			}
+			for _, msg := range msgs {
+				if len(msg.Data) < nl.SizeofGenlmsg {
+					continue
+				}
+				cmd := nl.DeserializeGenlmsg(msg.Data).Command
+				if cmd != nl.DEVLINK_CMD_PORT_NEW && cmd != nl.DEVLINK_CMD_PORT_DEL {
+					continue
+				}
+				port, err := parseDevlinkPortMsg(msg.Data[nl.SizeofGenlmsg:])
+				if err != nil {
+					continue
+				}
+				select {
+				case ch <- DevlinkPortUpdate{Cmd: cmd, Port: port}:
+				case <-done:
+					return
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// parseDevlinkRateMsg parses a rate get message payload (without the genetlink header)
+func parseDevlinkRateMsg(msg []byte) (*DevlinkRate, error) {
+	attrs, err := nl.ParseRouteAttr(msg)
+	if err != nil {
+		return nil, err
+	}
+	native := nl.NativeEndian()
+	rate := &DevlinkRate{}
+	for _, attr := range attrs {
+		switch attr.Attr.Type {
+		case nl.DEVLINK_ATTR_BUS_NAME:
+			rate.BusName = devlinkAttrString(attr.Value)
+		case nl.DEVLINK_ATTR_DEV_NAME:
+			rate.DeviceName = devlinkAttrString(attr.Value)
+		case nl.DEVLINK_ATTR_PORT_INDEX:
+			rate.PortIndex = native.Uint32(attr.Value)
+		case devlinkAttrRateType:
+			rate.Type = native.Uint16(attr.Value)
+		case devlinkAttrRateTxShare:
+			rate.TxShare = native.Uint64(attr.Value)
+		case devlinkAttrRateTxMax:
+			rate.TxMax = native.Uint64(attr.Value)
+		case devlinkAttrRateNodeName:
+			rate.NodeName = devlinkAttrString(attr.Value)
+		case devlinkAttrRateParentNodeName:
+			rate.ParentNodeName = devlinkAttrString(attr.Value)
+		}
+	}
+	return rate, nil
+}
+
+// devlinkGetRates returns the rate objects (leaves and nodes) of the given devlink device.
+// Equivalent to: `devlink port function rate show $dev`
+func devlinkGetRates(bus, device string) ([]*DevlinkRate, error) {
+	req, err := newDevlinkRequest(devlinkCmdRateGet, bus, device)
+	if err != nil {
+		return nil, err
+	}
+	req.Flags |= unix.NLM_F_DUMP
+	msgs, err := req.Execute(unix.NETLINK_GENERIC, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	rates := make([]*DevlinkRate, 0, len(msgs))
+	for _, msg := range msgs {
+		rate, err := parseDevlinkRateMsg(msg[nl.SizeofGenlmsg:])
+		if err != nil {
+			return nil, err
+		}
+		// older kernels ignore the device attributes on dump requests
+		if rate.BusName == bus && rate.DeviceName == device {
+			rates = append(rates, rate)
+		}
+	}
+	return rates, nil
+}
+
+// devlinkRateNodeNew creates a rate node of the given devlink device.
+// Equivalent to: `devlink port function rate add $dev/$name`
+func devlinkRateNodeNew(bus, device, name string) error {
+	req, err := newDevlinkRequest(devlinkCmdRateNew, bus, device)
+	if err != nil {
+		return err
+	}
+	req.AddData(nl.NewRtAttr(devlinkAttrRateNodeName, nl.ZeroTerminated(name)))
+	_, err = req.Execute(unix.NETLINK_GENERIC, 0)
+	return err
+}
+
+// devlinkRateNodeDel deletes a rate node of the given devlink device.
+// Equivalent to: `devlink port function rate del $dev/$name`
+func devlinkRateNodeDel(bus, device, name string) error {
+	req, err := newDevlinkRequest(devlinkCmdRateDel, bus, device)
+	if err != nil {
+		return err
+	}
+	req.AddData(nl.NewRtAttr(devlinkAttrRateNodeName, nl.ZeroTerminated(name)))
+	_, err = req.Execute(unix.NETLINK_GENERIC, 0)
+	return err
+}
+
+// addDevlinkRateSetAttrs adds the attributes to set to a rate set request
+func addDevlinkRateSetAttrs(req *nl.NetlinkRequest, attrs DevlinkRateSetAttrs) {
+	if attrs.TxShare != nil {
+		req.AddData(nl.NewRtAttr(devlinkAttrRateTxShare, nl.Uint64Attr(*attrs.TxShare)))
+	}
+	if attrs.TxMax != nil {
+		req.AddData(nl.NewRtAttr(devlinkAttrRateTxMax, nl.Uint64Attr(*attrs.TxMax)))
+	}
+	if attrs.ParentNodeName != nil {
+		req.AddData(nl.NewRtAttr(devlinkAttrRateParentNodeName, nl.ZeroTerminated(*attrs.ParentNodeName)))
+	}
+}
+
+// devlinkPortRateSet sets the rate attributes of the leaf rate object of a devlink port.
+// Equivalent to: `devlink port function rate set $dev/$port tx_share 1Gbit tx_max 2Gbit parent $node`
+func devlinkPortRateSet(bus, device string, portIndex uint32, attrs DevlinkRateSetAttrs) error {
+	req, err := newDevlinkRequest(devlinkCmdRateSet, bus, device)
+	if err != nil {
+		return err
+	}
+	req.AddData(nl.NewRtAttr(nl.DEVLINK_ATTR_PORT_INDEX, nl.Uint32Attr(portIndex)))
+	addDevlinkRateSetAttrs(req, attrs)
+	_, err = req.Execute(unix.NETLINK_GENERIC, 0)
+	return err
+}
+
+// devlinkRateNodeSet sets the rate attributes of a rate node.
+// Equivalent to: `devlink port function rate set $dev/$name tx_share 1Gbit tx_max 2Gbit`
+func devlinkRateNodeSet(bus, device, name string, attrs DevlinkRateSetAttrs) error {
+	req, err := newDevlinkRequest(devlinkCmdRateSet, bus, device)
+	if err != nil {
+		return err
+	}
+	req.AddData(nl.NewRtAttr(devlinkAttrRateNodeName, nl.ZeroTerminated(name)))
+	addDevlinkRateSetAttrs(req, attrs)
+	_, err = req.Execute(unix.NETLINK_GENERIC, 0)
+	return err
+}
+
+// devLinkPortSplit splits the given physical devlink port into count ports.
+// Equivalent to: `devlink port split $dev/$port count 4`
+func devLinkPortSplit(bus, device string, portIndex, count uint32) error {
+	req, err := newDevlinkRequest(devlinkCmdPortSplit, bus, device)
+	if err != nil {
+		return err
+	}
+	req.AddData(nl.NewRtAttr(nl.DEVLINK_ATTR_PORT_INDEX, nl.Uint32Attr(portIndex)))
+	req.AddData(nl.NewRtAttr(devlinkAttrPortSplitCount, nl.Uint32Attr(count)))
+	_, err = req.Execute(unix.NETLINK_GENERIC, 0)
+	return err
+}
+
+// devLinkPortUnsplit unsplits the devlink port group the given split port belongs to.
+// Equivalent to: `devlink port unsplit $dev/$port`
+func devLinkPortUnsplit(bus, device string, portIndex uint32) error {
+	req, err := newDevlinkRequest(devlinkCmdPortUnsplit, bus, device)
+	if err != nil {
+		return err
+	}
+	req.AddData(nl.NewRtAttr(nl.DEVLINK_ATTR_PORT_INDEX, nl.Uint32Attr(portIndex)))
+	_, err = req.Execute(unix.NETLINK_GENERIC, 0)
+	return err
+}
+
+// parseDevlinkReloadActionsPerformed returns the reload actions performed bitmask of a reload reply
+// payload (without the genetlink header), 0 if the kernel does not report it
+func parseDevlinkReloadActionsPerformed(msg []byte) (uint32, error) {
+	attrs, err := nl.ParseRouteAttr(msg)
+	if err != nil {
+		return 0, err
+	}
+	for _, attr := range attrs {
+		// bitfield32 attribute: 32 bit value followed by a 32 bit selector
+		if attr.Attr.Type == devlinkAttrReloadActions && len(attr.Value) >= 4 {
+			return nl.NativeEndian().Uint32(attr.Value[0:4]), nil
+		}
+	}
+	return 0, nil
+}
+
+// devlinkReload reloads the given devlink device and returns the bitmask of the reload actions performed
+// (1 << DevlinkReloadActionDriverReinit ...), 0 if not reported by the kernel.
+// Equivalent to: `devlink dev reload $dev netns $ns action driver_reinit`
+func devlinkReload(bus, device string, attrs DevlinkReloadAttrs) (uint32, error) {
+	req, err := newDevlinkRequest(devlinkCmdReload, bus, device)
+	if err != nil {
+		return 0, err
+	}
+	if attrs.Action != 0 {
+		req.AddData(nl.NewRtAttr(devlinkAttrReloadAction, nl.Uint8Attr(attrs.Action)))
+	}
+	if attrs.NetnsPath != "" {
+		ns, err := netns.GetFromPath(attrs.NetnsPath)
+		if err != nil {
+			return 0, fmt.Errorf("failed to open netns %s: %v", attrs.NetnsPath, err)
+		}
+		defer ns.Close()
+		req.AddData(nl.NewRtAttr(devlinkAttrNetnsFd, nl.Uint32Attr(uint32(ns))))
+	}
+	msgs, err := req.Execute(unix.NETLINK_GENERIC, 0)
+	if err != nil {
+		return 0, err
+	}
+	if len(msgs) == 0 {
+		return 0, nil
+	}
+	return parseDevlinkReloadActionsPerformed(msgs[0][nl.SizeofGenlmsg:])
+}
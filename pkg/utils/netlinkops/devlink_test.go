@@ -0,0 +1,136 @@
+package netlinkops
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+)
+
+func TestParseDevlinkHealthReporterMsg(t *testing.T) {
+	reporterAttr := nl.NewRtAttr(devlinkAttrHealthReporter|unix.NLA_F_NESTED, nil)
+	reporterAttr.AddRtAttr(devlinkAttrHealthReporterName, nl.ZeroTerminated("fw_fatal"))
+	reporterAttr.AddRtAttr(devlinkAttrHealthReporterState, nl.Uint8Attr(DevlinkHealthReporterStateError))
+	reporterAttr.AddRtAttr(devlinkAttrHealthReporterErrCount, nl.Uint64Attr(3))
+	reporterAttr.AddRtAttr(devlinkAttrHealthReporterRecoverCount, nl.Uint64Attr(2))
+	reporterAttr.AddRtAttr(devlinkAttrHealthReporterGracefulPeriod, nl.Uint64Attr(500))
+	reporterAttr.AddRtAttr(devlinkAttrHealthReporterAutoRecover, nl.Uint8Attr(1))
+
+	var msg []byte
+	msg = append(msg, nl.NewRtAttr(nl.DEVLINK_ATTR_BUS_NAME, nl.ZeroTerminated("pci")).Serialize()...)
+	msg = append(msg, nl.NewRtAttr(nl.DEVLINK_ATTR_DEV_NAME, nl.ZeroTerminated("0000:03:00.0")).Serialize()...)
+	msg = append(msg, reporterAttr.Serialize()...)
+
+	reporter, err := parseDevlinkHealthReporterMsg(msg)
+	assert.NoError(t, err)
+	assert.Equal(t, &DevlinkHealthReporter{
+		BusName:        "pci",
+		DeviceName:     "0000:03:00.0",
+		Name:           "fw_fatal",
+		State:          DevlinkHealthReporterStateError,
+		ErrorCount:     3,
+		RecoverCount:   2,
+		GracefulPeriod: 500,
+		AutoRecover:    true,
+	}, reporter)
+}
+
+func TestParseDevlinkHealthReporterMsgNoReporter(t *testing.T) {
+	msg := nl.NewRtAttr(nl.DEVLINK_ATTR_BUS_NAME, nl.ZeroTerminated("pci")).Serialize()
+	_, err := parseDevlinkHealthReporterMsg(msg)
+	assert.Error(t, err)
+}
+
+func TestParseDevlinkPortFnCaps(t *testing.T) {
+	bitfield := make([]byte, 8)
+	nl.NativeEndian().PutUint32(bitfield[0:4], DevlinkPortFnCapRoce)
+	nl.NativeEndian().PutUint32(bitfield[4:8], DevlinkPortFnCapRoce|DevlinkPortFnCapMigratable)
+	fnAttr := nl.NewRtAttr(nl.DEVLINK_ATTR_PORT_FUNCTION|unix.NLA_F_NESTED, nil)
+	fnAttr.AddRtAttr(nl.DEVLINK_PORT_FUNCTION_ATTR_HW_ADDR, []byte{0, 0, 0, 0, 0x88, 0x88})
+	fnAttr.AddRtAttr(devlinkPortFnAttrCaps, bitfield)
+
+	var msg []byte
+	msg = append(msg, nl.NewRtAttr(nl.DEVLINK_ATTR_BUS_NAME, nl.ZeroTerminated("pci")).Serialize()...)
+	msg = append(msg, nl.NewRtAttr(nl.DEVLINK_ATTR_PORT_INDEX, nl.Uint32Attr(32768)).Serialize()...)
+	msg = append(msg, fnAttr.Serialize()...)
+
+	caps, err := parseDevlinkPortFnCaps(msg)
+	assert.NoError(t, err)
+	assert.Equal(t, DevlinkPortFnCapRoce, caps)
+
+	_, err = parseDevlinkPortFnCaps(nl.NewRtAttr(nl.DEVLINK_ATTR_BUS_NAME, nl.ZeroTerminated("pci")).Serialize())
+	assert.Error(t, err)
+}
+
+func TestParseDevlinkPortMsg(t *testing.T) {
+	fnAttr := nl.NewRtAttr(nl.DEVLINK_ATTR_PORT_FUNCTION|unix.NLA_F_NESTED, nil)
+	fnAttr.AddRtAttr(nl.DEVLINK_PORT_FUNCTION_ATTR_HW_ADDR, []byte{0x02, 0x00, 0x00, 0x00, 0x00, 0x01})
+	fnAttr.AddRtAttr(nl.DEVLINK_PORT_FN_ATTR_STATE, nl.Uint8Attr(nl.DEVLINK_PORT_FN_STATE_ACTIVE))
+	fnAttr.AddRtAttr(nl.DEVLINK_PORT_FN_ATTR_OPSTATE, nl.Uint8Attr(nl.DEVLINK_PORT_FN_OPSTATE_ATTACHED))
+
+	var msg []byte
+	msg = append(msg, nl.NewRtAttr(nl.DEVLINK_ATTR_BUS_NAME, nl.ZeroTerminated("pci")).Serialize()...)
+	msg = append(msg, nl.NewRtAttr(nl.DEVLINK_ATTR_DEV_NAME, nl.ZeroTerminated("0000:03:00.0")).Serialize()...)
+	msg = append(msg, nl.NewRtAttr(nl.DEVLINK_ATTR_PORT_INDEX, nl.Uint32Attr(98304)).Serialize()...)
+	msg = append(msg, nl.NewRtAttr(nl.DEVLINK_ATTR_PORT_FLAVOUR, nl.Uint16Attr(nl.DEVLINK_PORT_FLAVOUR_PCI_SF)).Serialize()...)
+	msg = append(msg, nl.NewRtAttr(nl.DEVLINK_ATTR_PORT_NETDEV_NAME, nl.ZeroTerminated("en3f0pf0sf88")).Serialize()...)
+	msg = append(msg, fnAttr.Serialize()...)
+
+	port, err := parseDevlinkPortMsg(msg)
+	assert.NoError(t, err)
+	assert.Equal(t, &netlink.DevlinkPort{
+		BusName:       "pci",
+		DeviceName:    "0000:03:00.0",
+		PortIndex:     98304,
+		PortFlavour:   nl.DEVLINK_PORT_FLAVOUR_PCI_SF,
+		NetdeviceName: "en3f0pf0sf88",
+		Fn: &netlink.DevlinkPortFn{
+			HwAddr:  []byte{0x02, 0x00, 0x00, 0x00, 0x00, 0x01},
+			State:   nl.DEVLINK_PORT_FN_STATE_ACTIVE,
+			OpState: nl.DEVLINK_PORT_FN_OPSTATE_ATTACHED,
+		},
+	}, port)
+}
+
+func TestParseDevlinkRateMsg(t *testing.T) {
+	var msg []byte
+	msg = append(msg, nl.NewRtAttr(nl.DEVLINK_ATTR_BUS_NAME, nl.ZeroTerminated("pci")).Serialize()...)
+	msg = append(msg, nl.NewRtAttr(nl.DEVLINK_ATTR_DEV_NAME, nl.ZeroTerminated("0000:03:00.0")).Serialize()...)
+	msg = append(msg, nl.NewRtAttr(nl.DEVLINK_ATTR_PORT_INDEX, nl.Uint32Attr(2)).Serialize()...)
+	msg = append(msg, nl.NewRtAttr(devlinkAttrRateType, nl.Uint16Attr(DevlinkRateTypeLeaf)).Serialize()...)
+	msg = append(msg, nl.NewRtAttr(devlinkAttrRateTxShare, nl.Uint64Attr(1250000)).Serialize()...)
+	msg = append(msg, nl.NewRtAttr(devlinkAttrRateTxMax, nl.Uint64Attr(12500000)).Serialize()...)
+	msg = append(msg, nl.NewRtAttr(devlinkAttrRateParentNodeName, nl.ZeroTerminated("group1")).Serialize()...)
+
+	rate, err := parseDevlinkRateMsg(msg)
+	assert.NoError(t, err)
+	assert.Equal(t, &DevlinkRate{
+		BusName:        "pci",
+		DeviceName:     "0000:03:00.0",
+		Type:           DevlinkRateTypeLeaf,
+		PortIndex:      2,
+		TxShare:        1250000,
+		TxMax:          12500000,
+		ParentNodeName: "group1",
+	}, rate)
+}
+
+func TestParseDevlinkReloadActionsPerformed(t *testing.T) {
+	bitfield := make([]byte, 8)
+	nl.NativeEndian().PutUint32(bitfield[0:4], 1<<DevlinkReloadActionDriverReinit)
+	nl.NativeEndian().PutUint32(bitfield[4:8], 1<<DevlinkReloadActionDriverReinit)
+
+	var msg []byte
+	msg = append(msg, nl.NewRtAttr(nl.DEVLINK_ATTR_BUS_NAME, nl.ZeroTerminated("pci")).Serialize()...)
+	msg = append(msg, nl.NewRtAttr(devlinkAttrReloadActions, bitfield).Serialize()...)
+	performed, err := parseDevlinkReloadActionsPerformed(msg)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1<<DevlinkReloadActionDriverReinit), performed)
+
+	performed, err = parseDevlinkReloadActionsPerformed(
+		nl.NewRtAttr(nl.DEVLINK_ATTR_BUS_NAME, nl.ZeroTerminated("pci")).Serialize())
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(0), performed)
+}
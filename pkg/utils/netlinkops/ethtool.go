@@ -0,0 +1,304 @@
+package netlinkops
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"unsafe"
+
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// ethtoolMaxAddrLen is MAX_ADDR_LEN from include/linux/netdevice.h
+	ethtoolMaxAddrLen = 32
+	// ethtoolGstringLen is ETH_GSTRING_LEN from include/uapi/linux/ethtool.h
+	ethtoolGstringLen = 32
+	// ethtoolSsFeatures is the ETH_SS_FEATURES string set holding the netdev feature names
+	ethtoolSsFeatures = 4
+)
+
+// ethtoolGetFeaturesBlock is struct ethtool_get_features_block, describing 32 features
+type ethtoolGetFeaturesBlock struct {
+	available    uint32
+	requested    uint32
+	active       uint32
+	neverChanged uint32
+}
+
+// ethtoolSetFeaturesBlock is struct ethtool_set_features_block, describing 32 features
+type ethtoolSetFeaturesBlock struct {
+	valid     uint32
+	requested uint32
+}
+
+// EthtoolDriverInfo is the driver information of a netdev as reported by ethtool
+type EthtoolDriverInfo struct {
+	Driver    string
+	Version   string
+	FwVersion string
+	// BusInfo is the device bus address, e.g the PCI address
+	BusInfo string
+}
+
+// ethtoolIfreq is struct ifreq with ifr_data pointing to an ethtool command
+type ethtoolIfreq struct {
+	name [unix.IFNAMSIZ]byte
+	data unsafe.Pointer
+	_    [24 - unsafe.Sizeof(uintptr(0))]byte
+}
+
+// ethtoolPermAddr is struct ethtool_perm_addr
+type ethtoolPermAddr struct {
+	cmd  uint32
+	size uint32
+	data [ethtoolMaxAddrLen]byte
+}
+
+// ethtoolIoctl runs the ethtool command pointed by data on the given netdev of the current network namespace.
+// data must point to an ethtool command struct starting with the command number.
+func ethtoolIoctl(netdev string, data unsafe.Pointer) error {
+	if len(netdev) >= unix.IFNAMSIZ {
+		return fmt.Errorf("invalid netdev name %s", netdev)
+	}
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+
+	ifr := ethtoolIfreq{data: data}
+	copy(ifr.name[:], netdev)
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(fd), unix.SIOCETHTOOL, uintptr(unsafe.Pointer(&ifr)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// ethtoolString converts a NUL padded ethtool string to a string
+func ethtoolString(str []byte) string {
+	if end := bytes.IndexByte(str, 0); end >= 0 {
+		str = str[:end]
+	}
+	return string(str)
+}
+
+// newEthtoolDriverInfo converts the ethtool driver info returned by ETHTOOL_GDRVINFO
+func newEthtoolDriverInfo(info *unix.EthtoolDrvinfo) *EthtoolDriverInfo {
+	return &EthtoolDriverInfo{
+		Driver:    ethtoolString(info.Driver[:]),
+		Version:   ethtoolString(info.Version[:]),
+		FwVersion: ethtoolString(info.Fw_version[:]),
+		BusInfo:   ethtoolString(info.Bus_info[:]),
+	}
+}
+
+// linkDriverInfo returns the driver information of the given netdev.
+// Equivalent to: `ethtool -i $netdev`
+func linkDriverInfo(netdev string) (*EthtoolDriverInfo, error) {
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer unix.Close(fd)
+
+	info, err := unix.IoctlGetEthtoolDrvinfo(fd, netdev)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get driver info of %s: %v", netdev, err)
+	}
+	return newEthtoolDriverInfo(info), nil
+}
+
+// linkPermHardwareAddr returns the permanent (burned-in) hardware address of the given netdev,
+// empty if the driver does not report one.
+// Equivalent to: `ethtool -P $netdev`
+func linkPermHardwareAddr(netdev string) (net.HardwareAddr, error) {
+	permAddr := ethtoolPermAddr{cmd: unix.ETHTOOL_GPERMADDR, size: ethtoolMaxAddrLen}
+	if err := ethtoolIoctl(netdev, unsafe.Pointer(&permAddr)); err != nil {
+		return nil, fmt.Errorf("failed to get permanent address of %s: %v", netdev, err)
+	}
+	if permAddr.size > ethtoolMaxAddrLen {
+		return nil, fmt.Errorf("invalid permanent address length %d of %s", permAddr.size, netdev)
+	}
+	addr := make(net.HardwareAddr, permAddr.size)
+	copy(addr, permAddr.data[:permAddr.size])
+	return addr, nil
+}
+
+// ethtoolStringSetLen returns the number of strings of the given ethtool string set of the netdev
+func ethtoolStringSetLen(netdev string, stringSet uint32) (int, error) {
+	// struct ethtool_sset_info followed by a single u32 data entry
+	buf := make([]byte, 20)
+	native := nl.NativeEndian()
+	native.PutUint32(buf[0:4], unix.ETHTOOL_GSSET_INFO)
+	native.PutUint64(buf[8:16], 1<<stringSet)
+	if err := ethtoolIoctl(netdev, unsafe.Pointer(&buf[0])); err != nil {
+		return 0, err
+	}
+	if native.Uint64(buf[8:16]) == 0 {
+		return 0, fmt.Errorf("string set %d not supported", stringSet)
+	}
+	return int(native.Uint32(buf[16:20])), nil
+}
+
+// parseEthtoolStrings parses count NUL padded strings of ETH_GSTRING_LEN bytes
+func parseEthtoolStrings(data []byte, count int) []string {
+	strs := make([]string, 0, count)
+	for i := 0; i < count && (i+1)*ethtoolGstringLen <= len(data); i++ {
+		strs = append(strs, ethtoolString(data[i*ethtoolGstringLen:(i+1)*ethtoolGstringLen]))
+	}
+	return strs
+}
+
+// ethtoolStrings returns the strings of the given ethtool string set of the netdev
+func ethtoolStrings(netdev string, stringSet uint32, count int) ([]string, error) {
+	// struct ethtool_gstrings followed by the strings
+	buf := make([]byte, 12+count*ethtoolGstringLen)
+	native := nl.NativeEndian()
+	native.PutUint32(buf[0:4], unix.ETHTOOL_GSTRINGS)
+	native.PutUint32(buf[4:8], stringSet)
+	native.PutUint32(buf[8:12], uint32(count))
+	if err := ethtoolIoctl(netdev, unsafe.Pointer(&buf[0])); err != nil {
+		return nil, err
+	}
+	return parseEthtoolStrings(buf[12:], int(native.Uint32(buf[8:12]))), nil
+}
+
+// ethtoolFeatureBlocksLen returns the number of 32 feature blocks needed to describe count features
+func ethtoolFeatureBlocksLen(count int) int {
+	return (count + 31) / 32
+}
+
+// ethtoolGetFeatureBlocks returns the feature blocks of the netdev describing count features
+func ethtoolGetFeatureBlocks(netdev string, count int) ([]ethtoolGetFeaturesBlock, error) {
+	size := ethtoolFeatureBlocksLen(count)
+	// struct ethtool_gfeatures followed by the feature blocks
+	buf := make([]byte, 8+size*16)
+	native := nl.NativeEndian()
+	native.PutUint32(buf[0:4], unix.ETHTOOL_GFEATURES)
+	native.PutUint32(buf[4:8], uint32(size))
+	if err := ethtoolIoctl(netdev, unsafe.Pointer(&buf[0])); err != nil {
+		return nil, err
+	}
+	blocks := make([]ethtoolGetFeaturesBlock, size)
+	for i := range blocks {
+		block := buf[8+i*16:]
+		blocks[i] = ethtoolGetFeaturesBlock{
+			available:    native.Uint32(block[0:4]),
+			requested:    native.Uint32(block[4:8]),
+			active:       native.Uint32(block[8:12]),
+			neverChanged: native.Uint32(block[12:16]),
+		}
+	}
+	return blocks, nil
+}
+
+// ethtoolFeatureBit returns the block index and the bit of the feature with the given index
+func ethtoolFeatureBit(index int) (int, uint32) {
+	return index / 32, 1 << (index % 32)
+}
+
+// ethtoolActiveFeatures returns the active state of the given features by name
+func ethtoolActiveFeatures(names []string, blocks []ethtoolGetFeaturesBlock) map[string]bool {
+	features := make(map[string]bool, len(names))
+	for i, name := range names {
+		block, bit := ethtoolFeatureBit(i)
+		if name == "" || block >= len(blocks) {
+			continue
+		}
+		features[name] = blocks[block].active&bit != 0
+	}
+	return features
+}
+
+// ethtoolSetFeatureBlocks returns the feature blocks requesting the given features state
+func ethtoolSetFeatureBlocks(names []string, blocks []ethtoolGetFeaturesBlock,
+	features map[string]bool) ([]ethtoolSetFeaturesBlock, error) {
+	indexes := make(map[string]int, len(names))
+	for i, name := range names {
+		indexes[name] = i
+	}
+	setBlocks := make([]ethtoolSetFeaturesBlock, len(blocks))
+	for name, enable := range features {
+		index, ok := indexes[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown feature %s", name)
+		}
+		block, bit := ethtoolFeatureBit(index)
+		if block >= len(blocks) || blocks[block].available&bit == 0 || blocks[block].neverChanged&bit != 0 {
+			return nil, fmt.Errorf("feature %s cannot be changed", name)
+		}
+		setBlocks[block].valid |= bit
+		if enable {
+			setBlocks[block].requested |= bit
+		}
+	}
+	return setBlocks, nil
+}
+
+// linkFeatures returns the feature names and blocks of the given netdev
+func linkFeatures(netdev string) ([]string, []ethtoolGetFeaturesBlock, error) {
+	count, err := ethtoolStringSetLen(netdev, ethtoolSsFeatures)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get number of features of %s: %v", netdev, err)
+	}
+	names, err := ethtoolStrings(netdev, ethtoolSsFeatures, count)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get feature names of %s: %v", netdev, err)
+	}
+	blocks, err := ethtoolGetFeatureBlocks(netdev, count)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get features of %s: %v", netdev, err)
+	}
+	return names, blocks, nil
+}
+
+// linkGetFeatures returns the active state of the features of the given netdev by name (e.g hw-tc-offload).
+// Equivalent to: `ethtool -k $netdev`
+func linkGetFeatures(netdev string) (map[string]bool, error) {
+	names, blocks, err := linkFeatures(netdev)
+	if err != nil {
+		return nil, err
+	}
+	return ethtoolActiveFeatures(names, blocks), nil
+}
+
+// linkSetFeatures enables or disables the given features of the netdev and verifies they took effect.
+// Equivalent to: `ethtool -K $netdev hw-tc-offload on`
+func linkSetFeatures(netdev string, features map[string]bool) error {
+	names, blocks, err := linkFeatures(netdev)
+	if err != nil {
+		return err
+	}
+	setBlocks, err := ethtoolSetFeatureBlocks(names, blocks, features)
+	if err != nil {
+		return fmt.Errorf("failed to set features of %s: %v", netdev, err)
+	}
+
+	// struct ethtool_sfeatures followed by the feature blocks
+	buf := make([]byte, 8+len(setBlocks)*8)
+	native := nl.NativeEndian()
+	native.PutUint32(buf[0:4], unix.ETHTOOL_SFEATURES)
+	native.PutUint32(buf[4:8], uint32(len(setBlocks)))
+	for i, block := range setBlocks {
+		native.PutUint32(buf[8+i*8:], block.valid)
+		native.PutUint32(buf[12+i*8:], block.requested)
+	}
+	if err = ethtoolIoctl(netdev, unsafe.Pointer(&buf[0])); err != nil {
+		return fmt.Errorf("failed to set features of %s: %v", netdev, err)
+	}
+
+	// the driver may refuse some of the requested changes without failing the request
+	active, err := linkGetFeatures(netdev)
+	if err != nil {
+		return err
+	}
+	for name, enable := range features {
+		if active[name] != enable {
+			return fmt.Errorf("feature %s of %s could not be changed", name, netdev)
+		}
+	}
+	return nil
+}
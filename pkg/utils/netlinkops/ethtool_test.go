@@ -0,0 +1,49 @@
+package netlinkops
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+func TestParseEthtoolStrings(t *testing.T) {
+	data := make([]byte, 3*ethtoolGstringLen)
+	copy(data, "rx-checksum")
+	copy(data[ethtoolGstringLen:], "hw-tc-offload")
+	assert.Equal(t, []string{"rx-checksum", "hw-tc-offload", ""}, parseEthtoolStrings(data, 3))
+	// count larger than the data is truncated
+	assert.Equal(t, []string{"rx-checksum"}, parseEthtoolStrings(data[:ethtoolGstringLen], 2))
+}
+
+func TestEthtoolFeatures(t *testing.T) {
+	names := make([]string, 34)
+	names[0] = "rx-checksum"
+	names[1] = "rx-fcs"
+	names[33] = "hw-tc-offload"
+	blocks := []ethtoolGetFeaturesBlock{
+		{available: 0x1, active: 0x3, neverChanged: 0x2},
+		{available: 0x2, active: 0x0},
+	}
+	assert.Equal(t, map[string]bool{"rx-checksum": true, "rx-fcs": true, "hw-tc-offload": false},
+		ethtoolActiveFeatures(names, blocks))
+
+	setBlocks, err := ethtoolSetFeatureBlocks(names, blocks, map[string]bool{"hw-tc-offload": true, "rx-checksum": false})
+	assert.NoError(t, err)
+	assert.Equal(t, []ethtoolSetFeaturesBlock{{valid: 0x1}, {valid: 0x2, requested: 0x2}}, setBlocks)
+
+	_, err = ethtoolSetFeatureBlocks(names, blocks, map[string]bool{"rx-fcs": false})
+	assert.Error(t, err)
+	_, err = ethtoolSetFeatureBlocks(names, blocks, map[string]bool{"bogus": true})
+	assert.Error(t, err)
+}
+
+func TestNewEthtoolDriverInfo(t *testing.T) {
+	drvinfo := &unix.EthtoolDrvinfo{}
+	copy(drvinfo.Driver[:], "mlx5_core")
+	copy(drvinfo.Version[:], "6.5.0")
+	copy(drvinfo.Fw_version[:], "22.36.1010 (MT_0000000359)")
+	copy(drvinfo.Bus_info[:], "0000:03:00.0")
+	assert.Equal(t, &EthtoolDriverInfo{Driver: "mlx5_core", Version: "6.5.0", FwVersion: "22.36.1010 (MT_0000000359)",
+		BusInfo: "0000:03:00.0"}, newEthtoolDriverInfo(drvinfo))
+}
@@ -8,6 +8,8 @@ import (
 	mock "github.com/stretchr/testify/mock"
 
 	netlink "github.com/vishvananda/netlink"
+
+	netlinkops "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/netlinkops"
 )
 
 // NetlinkOps is an autogenerated mock type for the NetlinkOps type
@@ -38,6 +40,29 @@ func (_m *NetlinkOps) DevLinkGetAllPortList() ([]*netlink.DevlinkPort, error) {
 	return r0, r1
 }
 
+// DevLinkGetDeviceByName provides a mock function with given fields: bus, device
+func (_m *NetlinkOps) DevLinkGetDeviceByName(bus string, device string) (*netlink.DevlinkDevice, error) {
+	ret := _m.Called(bus, device)
+
+	var r0 *netlink.DevlinkDevice
+	if rf, ok := ret.Get(0).(func(string, string) *netlink.DevlinkDevice); ok {
+		r0 = rf(bus, device)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*netlink.DevlinkDevice)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(bus, device)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // DevLinkGetPortByNetdevName provides a mock function with given fields: netdev
 func (_m *NetlinkOps) DevLinkGetPortByNetdevName(netdev string) (*netlink.DevlinkPort, error) {
 	ret := _m.Called(netdev)
@@ -61,6 +86,403 @@ func (_m *NetlinkOps) DevLinkGetPortByNetdevName(netdev string) (*netlink.Devlin
 	return r0, r1
 }
 
+// DevLinkPortAdd provides a mock function with given fields: bus, device, flavour, attrs
+func (_m *NetlinkOps) DevLinkPortAdd(bus string, device string, flavour uint16, attrs netlink.DevLinkPortAddAttrs) (*netlink.DevlinkPort, error) {
+	ret := _m.Called(bus, device, flavour, attrs)
+
+	var r0 *netlink.DevlinkPort
+	if rf, ok := ret.Get(0).(func(string, string, uint16, netlink.DevLinkPortAddAttrs) *netlink.DevlinkPort); ok {
+		r0 = rf(bus, device, flavour, attrs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*netlink.DevlinkPort)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string, uint16, netlink.DevLinkPortAddAttrs) error); ok {
+		r1 = rf(bus, device, flavour, attrs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DevLinkPortDel provides a mock function with given fields: bus, device, portIndex
+func (_m *NetlinkOps) DevLinkPortDel(bus string, device string, portIndex uint32) error {
+	ret := _m.Called(bus, device, portIndex)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, uint32) error); ok {
+		r0 = rf(bus, device, portIndex)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DevLinkPortSplit provides a mock function with given fields: bus, device, portIndex, count
+func (_m *NetlinkOps) DevLinkPortSplit(bus string, device string, portIndex uint32, count uint32) error {
+	ret := _m.Called(bus, device, portIndex, count)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, uint32, uint32) error); ok {
+		r0 = rf(bus, device, portIndex, count)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DevLinkPortUnsplit provides a mock function with given fields: bus, device, portIndex
+func (_m *NetlinkOps) DevLinkPortUnsplit(bus string, device string, portIndex uint32) error {
+	ret := _m.Called(bus, device, portIndex)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, uint32) error); ok {
+		r0 = rf(bus, device, portIndex)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DevLinkSetEswitchEncapMode provides a mock function with given fields: dev, encapMode
+func (_m *NetlinkOps) DevLinkSetEswitchEncapMode(dev *netlink.DevlinkDevice, encapMode string) error {
+	ret := _m.Called(dev, encapMode)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*netlink.DevlinkDevice, string) error); ok {
+		r0 = rf(dev, encapMode)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DevLinkSetEswitchInlineMode provides a mock function with given fields: dev, inlineMode
+func (_m *NetlinkOps) DevLinkSetEswitchInlineMode(dev *netlink.DevlinkDevice, inlineMode string) error {
+	ret := _m.Called(dev, inlineMode)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*netlink.DevlinkDevice, string) error); ok {
+		r0 = rf(dev, inlineMode)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DevLinkSetEswitchMode provides a mock function with given fields: dev, newMode
+func (_m *NetlinkOps) DevLinkSetEswitchMode(dev *netlink.DevlinkDevice, newMode string) error {
+	ret := _m.Called(dev, newMode)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*netlink.DevlinkDevice, string) error); ok {
+		r0 = rf(dev, newMode)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DevlinkGetDeviceInfoByNameAsMap provides a mock function with given fields: bus, device
+func (_m *NetlinkOps) DevlinkGetDeviceInfoByNameAsMap(bus string, device string) (map[string]string, error) {
+	ret := _m.Called(bus, device)
+
+	var r0 map[string]string
+	if rf, ok := ret.Get(0).(func(string, string) map[string]string); ok {
+		r0 = rf(bus, device)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(bus, device)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DevlinkGetDeviceParamByName provides a mock function with given fields: bus, device, param
+func (_m *NetlinkOps) DevlinkGetDeviceParamByName(bus string, device string, param string) (*netlink.DevlinkParam, error) {
+	ret := _m.Called(bus, device, param)
+
+	var r0 *netlink.DevlinkParam
+	if rf, ok := ret.Get(0).(func(string, string, string) *netlink.DevlinkParam); ok {
+		r0 = rf(bus, device, param)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*netlink.DevlinkParam)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string, string) error); ok {
+		r1 = rf(bus, device, param)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DevlinkGetDeviceResources provides a mock function with given fields: bus, device
+func (_m *NetlinkOps) DevlinkGetDeviceResources(bus string, device string) (*netlink.DevlinkResources, error) {
+	ret := _m.Called(bus, device)
+
+	var r0 *netlink.DevlinkResources
+	if rf, ok := ret.Get(0).(func(string, string) *netlink.DevlinkResources); ok {
+		r0 = rf(bus, device)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*netlink.DevlinkResources)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(bus, device)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DevlinkGetHealthReporters provides a mock function with given fields: bus, device
+func (_m *NetlinkOps) DevlinkGetHealthReporters(bus string, device string) ([]*netlinkops.DevlinkHealthReporter, error) {
+	ret := _m.Called(bus, device)
+
+	var r0 []*netlinkops.DevlinkHealthReporter
+	if rf, ok := ret.Get(0).(func(string, string) []*netlinkops.DevlinkHealthReporter); ok {
+		r0 = rf(bus, device)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*netlinkops.DevlinkHealthReporter)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(bus, device)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DevlinkGetRates provides a mock function with given fields: bus, device
+func (_m *NetlinkOps) DevlinkGetRates(bus string, device string) ([]*netlinkops.DevlinkRate, error) {
+	ret := _m.Called(bus, device)
+
+	var r0 []*netlinkops.DevlinkRate
+	if rf, ok := ret.Get(0).(func(string, string) []*netlinkops.DevlinkRate); ok {
+		r0 = rf(bus, device)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*netlinkops.DevlinkRate)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(bus, device)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DevlinkPortFnGetCaps provides a mock function with given fields: bus, device, portIndex
+func (_m *NetlinkOps) DevlinkPortFnGetCaps(bus string, device string, portIndex uint32) (uint32, error) {
+	ret := _m.Called(bus, device, portIndex)
+
+	var r0 uint32
+	if rf, ok := ret.Get(0).(func(string, string, uint32) uint32); ok {
+		r0 = rf(bus, device, portIndex)
+	} else {
+		r0 = ret.Get(0).(uint32)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string, uint32) error); ok {
+		r1 = rf(bus, device, portIndex)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DevlinkPortFnSet provides a mock function with given fields: bus, device, portIndex, fnAttrs
+func (_m *NetlinkOps) DevlinkPortFnSet(bus string, device string, portIndex uint32, fnAttrs netlink.DevlinkPortFnSetAttrs) error {
+	ret := _m.Called(bus, device, portIndex, fnAttrs)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, uint32, netlink.DevlinkPortFnSetAttrs) error); ok {
+		r0 = rf(bus, device, portIndex, fnAttrs)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DevlinkPortFnSetCaps provides a mock function with given fields: bus, device, portIndex, caps, mask
+func (_m *NetlinkOps) DevlinkPortFnSetCaps(bus string, device string, portIndex uint32, caps uint32, mask uint32) error {
+	ret := _m.Called(bus, device, portIndex, caps, mask)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, uint32, uint32, uint32) error); ok {
+		r0 = rf(bus, device, portIndex, caps, mask)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DevlinkPortRateSet provides a mock function with given fields: bus, device, portIndex, attrs
+func (_m *NetlinkOps) DevlinkPortRateSet(bus string, device string, portIndex uint32, attrs netlinkops.DevlinkRateSetAttrs) error {
+	ret := _m.Called(bus, device, portIndex, attrs)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, uint32, netlinkops.DevlinkRateSetAttrs) error); ok {
+		r0 = rf(bus, device, portIndex, attrs)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DevlinkPortSubscribe provides a mock function with given fields: ch, done
+func (_m *NetlinkOps) DevlinkPortSubscribe(ch chan<- netlinkops.DevlinkPortUpdate, done <-chan struct{}) error {
+	ret := _m.Called(ch, done)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(chan<- netlinkops.DevlinkPortUpdate, <-chan struct{}) error); ok {
+		r0 = rf(ch, done)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DevlinkRateNodeDel provides a mock function with given fields: bus, device, name
+func (_m *NetlinkOps) DevlinkRateNodeDel(bus string, device string, name string) error {
+	ret := _m.Called(bus, device, name)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, string) error); ok {
+		r0 = rf(bus, device, name)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DevlinkRateNodeNew provides a mock function with given fields: bus, device, name
+func (_m *NetlinkOps) DevlinkRateNodeNew(bus string, device string, name string) error {
+	ret := _m.Called(bus, device, name)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, string) error); ok {
+		r0 = rf(bus, device, name)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DevlinkRateNodeSet provides a mock function with given fields: bus, device, name, attrs
+func (_m *NetlinkOps) DevlinkRateNodeSet(bus string, device string, name string, attrs netlinkops.DevlinkRateSetAttrs) error {
+	ret := _m.Called(bus, device, name, attrs)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, string, netlinkops.DevlinkRateSetAttrs) error); ok {
+		r0 = rf(bus, device, name, attrs)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DevlinkReload provides a mock function with given fields: bus, device, attrs
+func (_m *NetlinkOps) DevlinkReload(bus string, device string, attrs netlinkops.DevlinkReloadAttrs) (uint32, error) {
+	ret := _m.Called(bus, device, attrs)
+
+	var r0 uint32
+	if rf, ok := ret.Get(0).(func(string, string, netlinkops.DevlinkReloadAttrs) uint32); ok {
+		r0 = rf(bus, device, attrs)
+	} else {
+		r0 = ret.Get(0).(uint32)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string, netlinkops.DevlinkReloadAttrs) error); ok {
+		r1 = rf(bus, device, attrs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DevlinkSetDeviceParam provides a mock function with given fields: bus, device, param, cmode, value
+func (_m *NetlinkOps) DevlinkSetDeviceParam(bus string, device string, param string, cmode uint8, value interface{}) error {
+	ret := _m.Called(bus, device, param, cmode, value)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, string, uint8, interface{}) error); ok {
+		r0 = rf(bus, device, param, cmode, value)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// LinkBusInfoAt provides a mock function with given fields: netnsPath, netdev
+func (_m *NetlinkOps) LinkBusInfoAt(netnsPath string, netdev string) (string, error) {
+	ret := _m.Called(netnsPath, netdev)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(string, string) string); ok {
+		r0 = rf(netnsPath, netdev)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(netnsPath, netdev)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // LinkByName provides a mock function with given fields: name
 func (_m *NetlinkOps) LinkByName(name string) (netlink.Link, error) {
 	ret := _m.Called(name)
@@ -84,6 +506,254 @@ func (_m *NetlinkOps) LinkByName(name string) (netlink.Link, error) {
 	return r0, r1
 }
 
+// LinkByNameAt provides a mock function with given fields: netnsPath, name
+func (_m *NetlinkOps) LinkByNameAt(netnsPath string, name string) (netlink.Link, error) {
+	ret := _m.Called(netnsPath, name)
+
+	var r0 netlink.Link
+	if rf, ok := ret.Get(0).(func(string, string) netlink.Link); ok {
+		r0 = rf(netnsPath, name)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(netlink.Link)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(netnsPath, name)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// LinkDriverInfo provides a mock function with given fields: name
+func (_m *NetlinkOps) LinkDriverInfo(name string) (*netlinkops.EthtoolDriverInfo, error) {
+	ret := _m.Called(name)
+
+	var r0 *netlinkops.EthtoolDriverInfo
+	if rf, ok := ret.Get(0).(func(string) *netlinkops.EthtoolDriverInfo); ok {
+		r0 = rf(name)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*netlinkops.EthtoolDriverInfo)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(name)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// LinkGetFeatures provides a mock function with given fields: name
+func (_m *NetlinkOps) LinkGetFeatures(name string) (map[string]bool, error) {
+	ret := _m.Called(name)
+
+	var r0 map[string]bool
+	if rf, ok := ret.Get(0).(func(string) map[string]bool); ok {
+		r0 = rf(name)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]bool)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(name)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// LinkGetMTUByName provides a mock function with given fields: name
+func (_m *NetlinkOps) LinkGetMTUByName(name string) (int, error) {
+	ret := _m.Called(name)
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func(string) int); ok {
+		r0 = rf(name)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(name)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// LinkListAt provides a mock function with given fields: netnsPath
+func (_m *NetlinkOps) LinkListAt(netnsPath string) ([]netlink.Link, error) {
+	ret := _m.Called(netnsPath)
+
+	var r0 []netlink.Link
+	if rf, ok := ret.Get(0).(func(string) []netlink.Link); ok {
+		r0 = rf(netnsPath)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]netlink.Link)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(netnsPath)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// LinkPermHardwareAddr provides a mock function with given fields: name
+func (_m *NetlinkOps) LinkPermHardwareAddr(name string) (net.HardwareAddr, error) {
+	ret := _m.Called(name)
+
+	var r0 net.HardwareAddr
+	if rf, ok := ret.Get(0).(func(string) net.HardwareAddr); ok {
+		r0 = rf(name)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(net.HardwareAddr)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(name)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// LinkSetDown provides a mock function with given fields: link
+func (_m *NetlinkOps) LinkSetDown(link netlink.Link) error {
+	ret := _m.Called(link)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link) error); ok {
+		r0 = rf(link)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// LinkSetFeatures provides a mock function with given fields: name, features
+func (_m *NetlinkOps) LinkSetFeatures(name string, features map[string]bool) error {
+	ret := _m.Called(name, features)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, map[string]bool) error); ok {
+		r0 = rf(name, features)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// LinkSetMTU provides a mock function with given fields: link, mtu
+func (_m *NetlinkOps) LinkSetMTU(link netlink.Link, mtu int) error {
+	ret := _m.Called(link, mtu)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link, int) error); ok {
+		r0 = rf(link, mtu)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// LinkSetMTUByName provides a mock function with given fields: name, mtu
+func (_m *NetlinkOps) LinkSetMTUByName(name string, mtu int) error {
+	ret := _m.Called(name, mtu)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, int) error); ok {
+		r0 = rf(name, mtu)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// LinkSetName provides a mock function with given fields: link, name
+func (_m *NetlinkOps) LinkSetName(link netlink.Link, name string) error {
+	ret := _m.Called(link, name)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link, string) error); ok {
+		r0 = rf(link, name)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// LinkSetNameAt provides a mock function with given fields: netnsPath, link, name
+func (_m *NetlinkOps) LinkSetNameAt(netnsPath string, link netlink.Link, name string) error {
+	ret := _m.Called(netnsPath, link, name)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, netlink.Link, string) error); ok {
+		r0 = rf(netnsPath, link, name)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// LinkSetNs provides a mock function with given fields: link, netnsPath
+func (_m *NetlinkOps) LinkSetNs(link netlink.Link, netnsPath string) error {
+	ret := _m.Called(link, netnsPath)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link, string) error); ok {
+		r0 = rf(link, netnsPath)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// LinkSetNsCurrentFrom provides a mock function with given fields: netnsPath, link
+func (_m *NetlinkOps) LinkSetNsCurrentFrom(netnsPath string, link netlink.Link) error {
+	ret := _m.Called(netnsPath, link)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, netlink.Link) error); ok {
+		r0 = rf(netnsPath, link)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // LinkSetUp provides a mock function with given fields: link
 func (_m *NetlinkOps) LinkSetUp(link netlink.Link) error {
 	ret := _m.Called(link)
@@ -181,3 +851,17 @@ func (_m *NetlinkOps) LinkSetVfVlan(link netlink.Link, vf int, vlan int) error {
 
 	return r0
 }
+
+// LinkSubscribeWithOptions provides a mock function with given fields: ch, done, options
+func (_m *NetlinkOps) LinkSubscribeWithOptions(ch chan<- netlink.LinkUpdate, done <-chan struct{}, options netlink.LinkSubscribeOptions) error {
+	ret := _m.Called(ch, done, options)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(chan<- netlink.LinkUpdate, <-chan struct{}, netlink.LinkSubscribeOptions) error); ok {
+		r0 = rf(ch, done, options)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
@@ -13,8 +13,31 @@ var nlOpsImpl NetlinkOps
 type NetlinkOps interface {
 	// LinkByName gets link by netdev name
 	LinkByName(name string) (netlink.Link, error)
+	// LinkPermHardwareAddr gets the permanent (burned-in) hardware address of a netdev, empty if not reported
+	LinkPermHardwareAddr(name string) (net.HardwareAddr, error)
+	// LinkDriverInfo gets the ethtool driver information (driver, versions, bus info) of a netdev
+	LinkDriverInfo(name string) (*EthtoolDriverInfo, error)
+	// LinkGetFeatures gets the active state of the ethtool features of a netdev by name
+	LinkGetFeatures(name string) (map[string]bool, error)
+	// LinkSetFeatures enables or disables ethtool features of a netdev by name
+	LinkSetFeatures(name string, features map[string]bool) error
 	// LinkSetUp sets Link state to up
 	LinkSetUp(link netlink.Link) error
+	// LinkSetDown sets Link state to down
+	LinkSetDown(link netlink.Link) error
+	// LinkSetMTU sets Link MTU
+	LinkSetMTU(link netlink.Link, mtu int) error
+	// LinkGetMTUByName gets the MTU of a netdev
+	LinkGetMTUByName(name string) (int, error)
+	// LinkSetMTUByName sets the MTU of a netdev
+	LinkSetMTUByName(name string, mtu int) error
+	// LinkSubscribeWithOptions subscribes to link notifications, updates are sent on ch until done is closed
+	LinkSubscribeWithOptions(ch chan<- netlink.LinkUpdate, done <-chan struct{},
+		options netlink.LinkSubscribeOptions) error
+	// LinkSetName renames Link
+	LinkSetName(link netlink.Link, name string) error
+	// LinkSetNs moves Link to the network namespace at the given path
+	LinkSetNs(link netlink.Link, netnsPath string) error
 	// LinkSetVfHardwareAddr sets VF hardware address
 	LinkSetVfHardwareAddr(link netlink.Link, vf int, hwaddr net.HardwareAddr) error
 	// LinkSetVfVlan sets VF vlan
@@ -31,13 +54,74 @@ type NetlinkOps interface {
 	DevLinkGetAllPortList() ([]*netlink.DevlinkPort, error)
 	// DevLinkGetPortByNetdevName gets devlink port by netdev name
 	DevLinkGetPortByNetdevName(netdev string) (*netlink.DevlinkPort, error)
+	// DevLinkGetDeviceByName gets devlink device by bus and device name, including its eswitch attributes
+	DevLinkGetDeviceByName(bus, device string) (*netlink.DevlinkDevice, error)
+	// DevLinkSetEswitchMode sets the eswitch mode (legacy or switchdev) of the given devlink device
+	DevLinkSetEswitchMode(dev *netlink.DevlinkDevice, newMode string) error
+	// DevLinkSetEswitchEncapMode sets the eswitch encapsulation mode (none or basic) of the given devlink device
+	DevLinkSetEswitchEncapMode(dev *netlink.DevlinkDevice, encapMode string) error
+	// DevLinkSetEswitchInlineMode sets the eswitch inline mode (none, link, network or transport)
+	// of the given devlink device
+	DevLinkSetEswitchInlineMode(dev *netlink.DevlinkDevice, inlineMode string) error
+	// DevlinkGetDeviceParamByName gets devlink device parameter by name
+	DevlinkGetDeviceParamByName(bus, device, param string) (*netlink.DevlinkParam, error)
+	// DevlinkSetDeviceParam sets devlink device parameter value for the given configuration mode
+	DevlinkSetDeviceParam(bus, device, param string, cmode uint8, value interface{}) error
+	// DevlinkGetDeviceInfoByNameAsMap gets devlink device info (driver, serial number and versions) as a map
+	DevlinkGetDeviceInfoByNameAsMap(bus, device string) (map[string]string, error)
+	// DevlinkReload reloads a devlink device and returns the bitmask of the reload actions performed
+	DevlinkReload(bus, device string, attrs DevlinkReloadAttrs) (uint32, error)
+	// DevlinkGetHealthReporters gets the health reporters of a devlink device and of its ports
+	DevlinkGetHealthReporters(bus, device string) ([]*DevlinkHealthReporter, error)
+	// DevlinkGetDeviceResources gets the resources of a devlink device
+	DevlinkGetDeviceResources(bus, device string) (*netlink.DevlinkResources, error)
+	// DevlinkPortFnSet sets devlink port function attributes (hardware address, state)
+	DevlinkPortFnSet(bus, device string, portIndex uint32, fnAttrs netlink.DevlinkPortFnSetAttrs) error
+	// DevLinkPortAdd adds a devlink port of the given flavour and returns it
+	DevLinkPortAdd(bus, device string, flavour uint16, attrs netlink.DevLinkPortAddAttrs) (*netlink.DevlinkPort, error)
+	// DevLinkPortDel deletes the devlink port with the given index
+	DevLinkPortDel(bus, device string, portIndex uint32) error
+	// DevLinkPortSplit splits the physical devlink port with the given index into count ports
+	DevLinkPortSplit(bus, device string, portIndex, count uint32) error
+	// DevLinkPortUnsplit unsplits the devlink port group the split port with the given index belongs to
+	DevLinkPortUnsplit(bus, device string, portIndex uint32) error
+	// DevlinkPortFnSetCaps sets the port function capabilities selected by mask (e.g DevlinkPortFnCapRoce)
+	DevlinkPortFnSetCaps(bus, device string, portIndex, caps, mask uint32) error
+	// DevlinkPortFnGetCaps gets the port function capabilities
+	DevlinkPortFnGetCaps(bus, device string, portIndex uint32) (uint32, error)
+	// LinkListAt gets the links of the network namespace at the given path
+	LinkListAt(netnsPath string) ([]netlink.Link, error)
+	// LinkByNameAt gets link by netdev name in the network namespace at the given path
+	LinkByNameAt(netnsPath, name string) (netlink.Link, error)
+	// LinkSetNameAt renames Link in the network namespace at the given path
+	LinkSetNameAt(netnsPath string, link netlink.Link, name string) error
+	// LinkSetNsCurrentFrom moves Link from the network namespace at the given path to the current one
+	LinkSetNsCurrentFrom(netnsPath string, link netlink.Link) error
+	// LinkBusInfoAt gets the bus info (e.g PCI address) of a netdev in the network namespace at the given path
+	LinkBusInfoAt(netnsPath, netdev string) (string, error)
+	// DevlinkGetRates gets the rate objects (port leaves and nodes) of a devlink device
+	DevlinkGetRates(bus, device string) ([]*DevlinkRate, error)
+	// DevlinkRateNodeNew creates a devlink rate node
+	DevlinkRateNodeNew(bus, device, name string) error
+	// DevlinkRateNodeDel deletes a devlink rate node
+	DevlinkRateNodeDel(bus, device, name string) error
+	// DevlinkRateNodeSet sets the rate attributes of a devlink rate node
+	DevlinkRateNodeSet(bus, device, name string, attrs DevlinkRateSetAttrs) error
+	// DevlinkPortRateSet sets the rate attributes of a devlink port
+	DevlinkPortRateSet(bus, device string, portIndex uint32, attrs DevlinkRateSetAttrs) error
+	// DevlinkPortSubscribe subscribes to devlink port notifications, updates are sent on ch until done is closed.
+	// done is required.
+	DevlinkPortSubscribe(ch chan<- DevlinkPortUpdate, done <-chan struct{}) error
 }
 
-// GetNetlinkOps returns NetlinkOps interface
+// GetNetlinkOps returns NetlinkOps interface, reporting mutating operations to the hook set by SetMutationHook
 func GetNetlinkOps() NetlinkOps {
 	if nlOpsImpl == nil {
 		nlOpsImpl = &netlinkOps{}
 	}
+	if mutationHook != nil {
+		return &auditedNetlinkOps{NetlinkOps: nlOpsImpl, hook: mutationHook}
+	}
 	return nlOpsImpl
 }
 
@@ -58,11 +142,75 @@ func (nlo *netlinkOps) LinkByName(name string) (netlink.Link, error) {
 	return netlink.LinkByName(name)
 }
 
+// LinkPermHardwareAddr gets the permanent (burned-in) hardware address of a netdev, empty if not reported
+func (nlo *netlinkOps) LinkPermHardwareAddr(name string) (net.HardwareAddr, error) {
+	return linkPermHardwareAddr(name)
+}
+
+// LinkDriverInfo gets the ethtool driver information (driver, versions, bus info) of a netdev
+func (nlo *netlinkOps) LinkDriverInfo(name string) (*EthtoolDriverInfo, error) {
+	return linkDriverInfo(name)
+}
+
+// LinkGetFeatures gets the active state of the ethtool features of a netdev by name
+func (nlo *netlinkOps) LinkGetFeatures(name string) (map[string]bool, error) {
+	return linkGetFeatures(name)
+}
+
+// LinkSetFeatures enables or disables ethtool features of a netdev by name
+func (nlo *netlinkOps) LinkSetFeatures(name string, features map[string]bool) error {
+	return linkSetFeatures(name, features)
+}
+
 // LinkSetUp sets Link state to up
 func (nlo *netlinkOps) LinkSetUp(link netlink.Link) error {
 	return netlink.LinkSetUp(link)
 }
 
+// LinkSetDown sets Link state to down
+func (nlo *netlinkOps) LinkSetDown(link netlink.Link) error {
+	return netlink.LinkSetDown(link)
+}
+
+// LinkSetMTU sets Link MTU
+func (nlo *netlinkOps) LinkSetMTU(link netlink.Link, mtu int) error {
+	return netlink.LinkSetMTU(link, mtu)
+}
+
+// LinkGetMTUByName gets the MTU of a netdev
+func (nlo *netlinkOps) LinkGetMTUByName(name string) (int, error) {
+	link, err := netlink.LinkByName(name)
+	if err != nil {
+		return 0, err
+	}
+	return link.Attrs().MTU, nil
+}
+
+// LinkSetMTUByName sets the MTU of a netdev
+func (nlo *netlinkOps) LinkSetMTUByName(name string, mtu int) error {
+	link, err := netlink.LinkByName(name)
+	if err != nil {
+		return err
+	}
+	return netlink.LinkSetMTU(link, mtu)
+}
+
+// LinkSubscribeWithOptions subscribes to link notifications, updates are sent on ch until done is closed
+func (nlo *netlinkOps) LinkSubscribeWithOptions(ch chan<- netlink.LinkUpdate, done <-chan struct{},
+	options netlink.LinkSubscribeOptions) error {
+	return netlink.LinkSubscribeWithOptions(ch, done, options)
+}
+
+// LinkSetName renames Link
+func (nlo *netlinkOps) LinkSetName(link netlink.Link, name string) error {
+	return netlink.LinkSetName(link, name)
+}
+
+// LinkSetNs moves Link to the network namespace at the given path
+func (nlo *netlinkOps) LinkSetNs(link netlink.Link, netnsPath string) error {
+	return linkSetNs(link, netnsPath)
+}
+
 // LinkSetVfHardwareAddr sets VF hardware address
 func (nlo *netlinkOps) LinkSetVfHardwareAddr(link netlink.Link, vf int, hwaddr net.HardwareAddr) error {
 	return netlink.LinkSetVfHardwareAddr(link, vf, hwaddr)
@@ -112,3 +260,147 @@ func (nlo *netlinkOps) DevLinkGetPortByNetdevName(netdev string) (*netlink.Devli
 	}
 	return nil, fmt.Errorf("failed to get devlink port for netdev %s", netdev)
 }
+
+// DevLinkGetDeviceByName gets devlink device by bus and device name, including its eswitch attributes
+func (nlo *netlinkOps) DevLinkGetDeviceByName(bus, device string) (*netlink.DevlinkDevice, error) {
+	return netlink.DevLinkGetDeviceByName(bus, device)
+}
+
+// DevLinkSetEswitchMode sets the eswitch mode (legacy or switchdev) of the given devlink device
+func (nlo *netlinkOps) DevLinkSetEswitchMode(dev *netlink.DevlinkDevice, newMode string) error {
+	return netlink.DevLinkSetEswitchMode(dev, newMode)
+}
+
+// DevLinkSetEswitchEncapMode sets the eswitch encapsulation mode (none or basic) of the given devlink device
+func (nlo *netlinkOps) DevLinkSetEswitchEncapMode(dev *netlink.DevlinkDevice, encapMode string) error {
+	return devLinkSetEswitchEncapMode(dev, encapMode)
+}
+
+// DevLinkSetEswitchInlineMode sets the eswitch inline mode (none, link, network or transport)
+// of the given devlink device
+func (nlo *netlinkOps) DevLinkSetEswitchInlineMode(dev *netlink.DevlinkDevice, inlineMode string) error {
+	return devLinkSetEswitchInlineMode(dev, inlineMode)
+}
+
+// DevlinkGetDeviceParamByName gets devlink device parameter by name
+func (nlo *netlinkOps) DevlinkGetDeviceParamByName(bus, device, param string) (*netlink.DevlinkParam, error) {
+	return netlink.DevlinkGetDeviceParamByName(bus, device, param)
+}
+
+// DevlinkSetDeviceParam sets devlink device parameter value for the given configuration mode
+func (nlo *netlinkOps) DevlinkSetDeviceParam(bus, device, param string, cmode uint8, value interface{}) error {
+	return netlink.DevlinkSetDeviceParam(bus, device, param, cmode, value)
+}
+
+// DevlinkGetDeviceInfoByNameAsMap gets devlink device info (driver, serial number and versions) as a map
+func (nlo *netlinkOps) DevlinkGetDeviceInfoByNameAsMap(bus, device string) (map[string]string, error) {
+	return netlink.DevlinkGetDeviceInfoByNameAsMap(bus, device)
+}
+
+// DevlinkReload reloads a devlink device and returns the bitmask of the reload actions performed
+func (nlo *netlinkOps) DevlinkReload(bus, device string, attrs DevlinkReloadAttrs) (uint32, error) {
+	return devlinkReload(bus, device, attrs)
+}
+
+// DevlinkGetHealthReporters gets the health reporters of a devlink device and of its ports
+func (nlo *netlinkOps) DevlinkGetHealthReporters(bus, device string) ([]*DevlinkHealthReporter, error) {
+	return devlinkGetHealthReporters(bus, device)
+}
+
+// DevlinkGetDeviceResources gets the resources of a devlink device
+func (nlo *netlinkOps) DevlinkGetDeviceResources(bus, device string) (*netlink.DevlinkResources, error) {
+	return netlink.DevlinkGetDeviceResources(bus, device)
+}
+
+// DevlinkPortFnSet sets devlink port function attributes (hardware address, state)
+func (nlo *netlinkOps) DevlinkPortFnSet(bus, device string, portIndex uint32,
+	fnAttrs netlink.DevlinkPortFnSetAttrs) error {
+	return netlink.DevlinkPortFnSet(bus, device, portIndex, fnAttrs)
+}
+
+// DevLinkPortAdd adds a devlink port of the given flavour and returns it
+func (nlo *netlinkOps) DevLinkPortAdd(bus, device string, flavour uint16,
+	attrs netlink.DevLinkPortAddAttrs) (*netlink.DevlinkPort, error) {
+	return netlink.DevLinkPortAdd(bus, device, flavour, attrs)
+}
+
+// DevLinkPortDel deletes the devlink port with the given index
+func (nlo *netlinkOps) DevLinkPortDel(bus, device string, portIndex uint32) error {
+	return netlink.DevLinkPortDel(bus, device, portIndex)
+}
+
+// DevLinkPortSplit splits the physical devlink port with the given index into count ports
+func (nlo *netlinkOps) DevLinkPortSplit(bus, device string, portIndex, count uint32) error {
+	return devLinkPortSplit(bus, device, portIndex, count)
+}
+
+// DevLinkPortUnsplit unsplits the devlink port group the split port with the given index belongs to
+func (nlo *netlinkOps) DevLinkPortUnsplit(bus, device string, portIndex uint32) error {
+	return devLinkPortUnsplit(bus, device, portIndex)
+}
+
+// DevlinkPortFnSetCaps sets the port function capabilities selected by mask (e.g DevlinkPortFnCapRoce)
+func (nlo *netlinkOps) DevlinkPortFnSetCaps(bus, device string, portIndex, caps, mask uint32) error {
+	return devlinkPortFnSetCaps(bus, device, portIndex, caps, mask)
+}
+
+// DevlinkPortFnGetCaps gets the port function capabilities
+func (nlo *netlinkOps) DevlinkPortFnGetCaps(bus, device string, portIndex uint32) (uint32, error) {
+	return devlinkPortFnGetCaps(bus, device, portIndex)
+}
+
+// DevlinkGetRates gets the rate objects (port leaves and nodes) of a devlink device
+func (nlo *netlinkOps) DevlinkGetRates(bus, device string) ([]*DevlinkRate, error) {
+	return devlinkGetRates(bus, device)
+}
+
+// DevlinkRateNodeNew creates a devlink rate node
+func (nlo *netlinkOps) DevlinkRateNodeNew(bus, device, name string) error {
+	return devlinkRateNodeNew(bus, device, name)
+}
+
+// DevlinkRateNodeDel deletes a devlink rate node
+func (nlo *netlinkOps) DevlinkRateNodeDel(bus, device, name string) error {
+	return devlinkRateNodeDel(bus, device, name)
+}
+
+// DevlinkRateNodeSet sets the rate attributes of a devlink rate node
+func (nlo *netlinkOps) DevlinkRateNodeSet(bus, device, name string, attrs DevlinkRateSetAttrs) error {
+	return devlinkRateNodeSet(bus, device, name, attrs)
+}
+
+// DevlinkPortRateSet sets the rate attributes of a devlink port
+func (nlo *netlinkOps) DevlinkPortRateSet(bus, device string, portIndex uint32, attrs DevlinkRateSetAttrs) error {
+	return devlinkPortRateSet(bus, device, portIndex, attrs)
+}
+
+// DevlinkPortSubscribe subscribes to devlink port notifications, updates are sent on ch until done is closed.
+// done is required.
+func (nlo *netlinkOps) DevlinkPortSubscribe(ch chan<- DevlinkPortUpdate, done <-chan struct{}) error {
+	return devlinkPortSubscribe(ch, done)
+}
+
+// LinkListAt gets the links of the network namespace at the given path
+func (nlo *netlinkOps) LinkListAt(netnsPath string) ([]netlink.Link, error) {
+	return linkListAt(netnsPath)
+}
+
+// LinkByNameAt gets link by netdev name in the network namespace at the given path
+func (nlo *netlinkOps) LinkByNameAt(netnsPath, name string) (netlink.Link, error) {
+	return linkByNameAt(netnsPath, name)
+}
+
+// LinkSetNameAt renames Link in the network namespace at the given path
+func (nlo *netlinkOps) LinkSetNameAt(netnsPath string, link netlink.Link, name string) error {
+	return linkSetNameAt(netnsPath, link, name)
+}
+
+// LinkSetNsCurrentFrom moves Link from the network namespace at the given path to the current one
+func (nlo *netlinkOps) LinkSetNsCurrentFrom(netnsPath string, link netlink.Link) error {
+	return linkSetNsCurrentFrom(netnsPath, link)
+}
+
+// LinkBusInfoAt gets the bus info (e.g PCI address) of a netdev in the network namespace at the given path
+func (nlo *netlinkOps) LinkBusInfoAt(netnsPath, netdev string) (string, error) {
+	return linkBusInfoAt(netnsPath, netdev)
+}
@@ -0,0 +1,132 @@
+package netlinkops
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+	"golang.org/x/sys/unix"
+)
+
+// newHandleAt returns a netlink handle operating in the network namespace at the given path
+// (e.g /var/run/netns/ns1). The handle must be released with Delete().
+func newHandleAt(netnsPath string) (*netlink.Handle, error) {
+	ns, err := netns.GetFromPath(netnsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open netns %s: %v", netnsPath, err)
+	}
+	defer ns.Close()
+
+	handle, err := netlink.NewHandleAt(ns)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create netlink handle in netns %s: %v", netnsPath, err)
+	}
+	return handle, nil
+}
+
+// linkListAt returns the links of the network namespace at the given path
+func linkListAt(netnsPath string) ([]netlink.Link, error) {
+	handle, err := newHandleAt(netnsPath)
+	if err != nil {
+		return nil, err
+	}
+	defer handle.Delete()
+	return handle.LinkList()
+}
+
+// linkByNameAt returns the link with the given name in the network namespace at the given path
+func linkByNameAt(netnsPath, name string) (netlink.Link, error) {
+	handle, err := newHandleAt(netnsPath)
+	if err != nil {
+		return nil, err
+	}
+	defer handle.Delete()
+	return handle.LinkByName(name)
+}
+
+// linkSetNameAt renames a link of the network namespace at the given path
+func linkSetNameAt(netnsPath string, link netlink.Link, name string) error {
+	handle, err := newHandleAt(netnsPath)
+	if err != nil {
+		return err
+	}
+	defer handle.Delete()
+	return handle.LinkSetName(link, name)
+}
+
+// linkSetNs moves a link of the current network namespace to the network namespace at the given path.
+// Equivalent to: `ip link set $link netns $ns`
+func linkSetNs(link netlink.Link, netnsPath string) error {
+	ns, err := netns.GetFromPath(netnsPath)
+	if err != nil {
+		return fmt.Errorf("failed to open netns %s: %v", netnsPath, err)
+	}
+	defer ns.Close()
+	return netlink.LinkSetNsFd(link, int(ns))
+}
+
+// linkSetNsCurrentFrom moves a link of the network namespace at the given path to the current network namespace
+func linkSetNsCurrentFrom(netnsPath string, link netlink.Link) error {
+	curNs, err := netns.Get()
+	if err != nil {
+		return fmt.Errorf("failed to get current netns: %v", err)
+	}
+	defer curNs.Close()
+
+	handle, err := newHandleAt(netnsPath)
+	if err != nil {
+		return err
+	}
+	defer handle.Delete()
+	return handle.LinkSetNsFd(link, int(curNs))
+}
+
+// socketAt creates a socket in the network namespace at the given path. The calling thread
+// switches to the namespace only for the duration of the socket creation.
+func socketAt(netnsPath string, domain, typ, proto int) (int, error) {
+	ns, err := netns.GetFromPath(netnsPath)
+	if err != nil {
+		return -1, fmt.Errorf("failed to open netns %s: %v", netnsPath, err)
+	}
+	defer ns.Close()
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	origNs, err := netns.Get()
+	if err != nil {
+		return -1, fmt.Errorf("failed to get current netns: %v", err)
+	}
+	defer origNs.Close()
+
+	if err = netns.Set(ns); err != nil {
+		return -1, fmt.Errorf("failed to enter netns %s: %v", netnsPath, err)
+	}
+	fd, sockErr := unix.Socket(domain, typ, proto)
+	if err = netns.Set(origNs); err != nil {
+		// the thread is left in an unknown netns, keep it locked so it is terminated with the goroutine
+		runtime.LockOSThread()
+		if sockErr == nil {
+			unix.Close(fd)
+		}
+		return -1, fmt.Errorf("failed to restore netns: %v", err)
+	}
+	return fd, sockErr
+}
+
+// linkBusInfoAt returns the bus info (e.g the PCI address) reported by ethtool for the given netdev
+// in the network namespace at the given path.
+// Equivalent to: `ip netns exec $ns ethtool -i $netdev`
+func linkBusInfoAt(netnsPath, netdev string) (string, error) {
+	fd, err := socketAt(netnsPath, unix.AF_INET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return "", err
+	}
+	defer unix.Close(fd)
+
+	info, err := unix.IoctlGetEthtoolDrvinfo(fd, netdev)
+	if err != nil {
+		return "", fmt.Errorf("failed to get driver info of %s in netns %s: %v", netdev, netnsPath, err)
+	}
+	return newEthtoolDriverInfo(info).BusInfo, nil
+}
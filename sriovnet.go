@@ -17,10 +17,9 @@ limitations under the License.
 package sriovnet
 
 import (
+	"bytes"
 	"fmt"
-	"log"
 	"net"
-	"os"
 	"path"
 	"path/filepath"
 	"regexp"
@@ -38,11 +37,24 @@ const (
 	// Used locally
 	etherEncapType = "ether"
 	ibEncapType    = "infiniband"
+
+	netdevTypeFile = "type"
+	// ARP hardware types, see include/uapi/linux/if_arp.h
+	arphrdEther      = 1
+	arphrdInfiniband = 32
+)
+
+// LinkType is the link layer type of a netdev
+type LinkType string
+
+const (
+	LinkTypeEther      LinkType = etherEncapType
+	LinkTypeInfiniband LinkType = ibEncapType
 )
 
 var (
 	virtFnRe          = regexp.MustCompile(`virtfn(\d+)`)
-	pciAddressRe      = regexp.MustCompile(`^[0-9a-f]{4}:[0-9a-f]{2}:[01][0-9a-f].[0-7]$`)
+	pciAddressRe      = regexp.MustCompile(`^[0-9a-f]{4,8}:[0-9a-f]{2}:[01][0-9a-f]\.[0-7]$`)
 	auxiliaryDeviceRe = regexp.MustCompile(`^(\S+\.){2}\d+$`)
 )
 
@@ -51,6 +63,8 @@ type VfObj struct {
 	PciAddress string
 	Bound      bool
 	Allocated  bool
+	// NumaNode is the NUMA node of the VF, NoNumaNode if it has no NUMA affinity or it could not be read
+	NumaNode int
 }
 
 type PfNetdevHandle struct {
@@ -70,13 +84,7 @@ func SetPFLinkUp(pfNetdevName string) error {
 }
 
 func IsVfPciVfioBound(pciAddr string) bool {
-	driverLink := filepath.Join(PciSysDir, pciAddr, "driver")
-	driverPath, err := utilfs.Fs.Readlink(driverLink)
-	if err != nil {
-		return false
-	}
-	driverName := filepath.Base(driverPath)
-	return driverName == "vfio-pci"
+	return IsPciBoundToDriver(pciAddr, vfioPciDriver)
 }
 
 func IsSriovSupported(netdevName string) bool {
@@ -95,9 +103,9 @@ func IsSriovEnabled(netdevName string) bool {
 	return true
 }
 
-func EnableSriov(pfNetdevName string) error {
+func EnableSriov(pfNetdevName string) (err error) {
+	defer startTrace("EnableSriov", "pf", pfNetdevName)(&err)
 	var maxVfCount int
-	var err error
 
 	devDirName := netDevDeviceDir(pfNetdevName)
 
@@ -108,7 +116,7 @@ func EnableSriov(pfNetdevName string) error {
 
 	maxVfCount, err = getMaxVfCount(pfNetdevName)
 	if err != nil {
-		log.Println("Fail to read max vf count of PF", pfNetdevName)
+		logError(err, "failed to read max VF count", "netdev", pfNetdevName)
 		return err
 	}
 
@@ -118,7 +126,7 @@ func EnableSriov(pfNetdevName string) error {
 
 	curVfCount, err2 := getCurrentVfCount(pfNetdevName)
 	if err2 != nil {
-		log.Println("Fail to read current vf count of PF", pfNetdevName)
+		logError(err2, "failed to read current VF count", "netdev", pfNetdevName)
 		return err
 	}
 	if curVfCount == 0 {
@@ -127,7 +135,8 @@ func EnableSriov(pfNetdevName string) error {
 	return nil
 }
 
-func DisableSriov(pfNetdevName string) error {
+func DisableSriov(pfNetdevName string) (err error) {
+	defer startTrace("DisableSriov", "pf", pfNetdevName)(&err)
 	devDirName := netDevDeviceDir(pfNetdevName)
 
 	devExist := dirExists(devDirName)
@@ -160,13 +169,17 @@ func GetPfNetdevHandle(pfNetdevName string) (*PfNetdevHandle, error) {
 		vfNetdevName := vfNetdevNameFromParent(pfNetdevName, vfIndex)
 		pciAddress, err := vfPCIDevNameFromVfIndex(pfNetdevName, vfIndex)
 		if err != nil {
-			log.Printf("Failed to read PCI Address for VF %v from PF %v: %v\n",
-				vfNetdevName, pfNetdevName, err)
+			logError(err, "failed to read VF PCI address", "netdev", pfNetdevName, "vf", vfIndex)
 			continue
 		}
+		numaNode, err := GetPciNumaNode(pciAddress)
+		if err != nil {
+			numaNode = NoNumaNode
+		}
 		vfObj := VfObj{
 			Index:      vfIndex,
 			PciAddress: pciAddress,
+			NumaNode:   numaNode,
 		}
 		if vfNetdevName != "" {
 			vfObj.Bound = true
@@ -213,7 +226,32 @@ func GetVfDefaultMacAddr(vfNetdevName string) (string, error) {
 	return ethAttr.HardwareAddr.String(), nil
 }
 
-func SetVfDefaultMacAddress(handle *PfNetdevHandle, vf *VfObj) error {
+// GetNetdevMacAddress returns the current MAC address of the given netdev, which may have been set
+// administratively (e.g by SetVfDefaultMacAddress).
+func GetNetdevMacAddress(netdev string) (net.HardwareAddr, error) {
+	link, err := netlinkops.GetNetlinkOps().LinkByName(netdev)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get netdev %s: %v", netdev, err)
+	}
+	return link.Attrs().HardwareAddr, nil
+}
+
+// GetNetdevPermMacAddress returns the permanent (burned-in) MAC address of the given netdev as reported by
+// ethtool. Returns ErrNotSupported if the driver reports no permanent address, as commonly done for VFs.
+// Equivalent to: `ethtool -P $netdev`
+func GetNetdevPermMacAddress(netdev string) (net.HardwareAddr, error) {
+	mac, err := netlinkops.GetNetlinkOps().LinkPermHardwareAddr(netdev)
+	if err != nil {
+		return nil, err
+	}
+	if len(mac) == 0 || bytes.Equal(mac, make(net.HardwareAddr, len(mac))) {
+		return nil, fmt.Errorf("%w: netdev %s has no permanent MAC address", ErrNotSupported, netdev)
+	}
+	return mac, nil
+}
+
+func SetVfDefaultMacAddress(handle *PfNetdevHandle, vf *VfObj) (err error) {
+	defer startTrace("SetVfDefaultMacAddress", "pf", handle.PfNetdevName, "vf", strconv.Itoa(vf.Index))(&err)
 	netdevName := vfNetdevNameFromParent(handle.PfNetdevName, vf.Index)
 	ethHandle, err1 := netlinkops.GetNetlinkOps().LinkByName(netdevName)
 	if err1 != nil {
@@ -223,7 +261,9 @@ func SetVfDefaultMacAddress(handle *PfNetdevHandle, vf *VfObj) error {
 	return netlinkops.GetNetlinkOps().LinkSetVfHardwareAddr(handle.pfLinkHandle, vf.Index, ethAttr.HardwareAddr)
 }
 
-func SetVfVlan(handle *PfNetdevHandle, vf *VfObj, vlan int) error {
+func SetVfVlan(handle *PfNetdevHandle, vf *VfObj, vlan int) (err error) {
+	defer startTrace("SetVfVlan", "pf", handle.PfNetdevName, "vf", strconv.Itoa(vf.Index),
+		"vlan", strconv.Itoa(vlan))(&err)
 	return netlinkops.GetNetlinkOps().LinkSetVfVlan(handle.pfLinkHandle, vf.Index, vlan)
 }
 
@@ -253,13 +293,23 @@ func setVfPortGUID(handle *PfNetdevHandle, vf *VfObj, guid []byte) error {
 	return err
 }
 
-func SetVfDefaultGUID(handle *PfNetdevHandle, vf *VfObj) error {
+// generateVfGUID returns a random GUID for the VF with the given index
+func generateVfGUID(vfIndex int) ([]byte, error) {
 	randUUID, err := uuid.NewRandom()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	guid := randUUID[0:8]
-	guid[7] = byte(vf.Index)
+	guid[7] = byte(vfIndex)
+	return guid, nil
+}
+
+func SetVfDefaultGUID(handle *PfNetdevHandle, vf *VfObj) (err error) {
+	defer startTrace("SetVfDefaultGUID", "pf", handle.PfNetdevName, "vf", strconv.Itoa(vf.Index))(&err)
+	guid, err := generateVfGUID(vf.Index)
+	if err != nil {
+		return err
+	}
 
 	err = setVfNodeGUID(handle, vf, guid)
 	if err != nil {
@@ -270,7 +320,8 @@ func SetVfDefaultGUID(handle *PfNetdevHandle, vf *VfObj) error {
 	return err
 }
 
-func SetVfPrivileged(handle *PfNetdevHandle, vf *VfObj, privileged bool) error {
+func SetVfPrivileged(handle *PfNetdevHandle, vf *VfObj, privileged bool) (err error) {
+	defer startTrace("SetVfPrivileged", "pf", handle.PfNetdevName, "vf", strconv.Itoa(vf.Index))(&err)
 	var spoofChk bool
 	var trusted bool
 
@@ -317,22 +368,21 @@ func setPortAdminState(handle *PfNetdevHandle, vf *VfObj) error {
 		if err2 != nil {
 			return nil
 		}
-		log.Printf("Admin state = %v", state)
+		logDebug("VF port admin state", "netdev", handle.PfNetdevName, "vf", vf.Index, "state", state)
 		err2 = ibSetPortAdminState(handle.PfNetdevName, vf.Index, ibSriovPortAdminStateFollow)
 		if err2 != nil {
 			// If file exist, we must be able to write
-			log.Printf("Admin state setting error = %v", err2)
+			logError(err2, "failed to set VF port admin state", "netdev", handle.PfNetdevName, "vf", vf.Index)
 			return err2
 		}
 	}
 	return nil
 }
 
-func ConfigVfs(handle *PfNetdevHandle, privileged bool) error {
-	var err error
-
+func ConfigVfs(handle *PfNetdevHandle, privileged bool) (err error) {
+	defer startTrace("ConfigVfs", "pf", handle.PfNetdevName)(&err)
 	for _, vf := range handle.List {
-		log.Printf("vf = %v\n", vf)
+		logDebug("configuring VF", "netdev", handle.PfNetdevName, "vf", vf.Index, "pci", vf.PciAddress)
 		err = setPortAdminState(handle, vf)
 		if err != nil {
 			break
@@ -358,16 +408,91 @@ func ConfigVfs(handle *PfNetdevHandle, privileged bool) error {
 
 		err = UnbindVf(handle, vf)
 		if err != nil {
-			log.Printf("Fail to unbind err=%v\n", err)
+			logError(err, "failed to unbind VF", "pci", vf.PciAddress, "vf", vf.Index)
 			break
 		}
 
 		err = BindVf(handle, vf)
 		if err != nil {
-			log.Printf("Fail to bind err=%v\n", err)
+			logError(err, "failed to bind VF", "pci", vf.PciAddress, "vf", vf.Index)
 			break
 		}
-		log.Printf("vf = %v unbind/bind completed", vf)
+		logDebug("VF rebound", "pci", vf.PciAddress, "vf", vf.Index)
+	}
+	return nil
+}
+
+// IbVfConfig is the VF configuration applied by ConfigIbVfs
+type IbVfConfig struct {
+	// GUIDs maps a VF index to the node and port GUID (8 bytes) to assign to the VF.
+	// VFs missing from the map are assigned a random GUID.
+	GUIDs map[int]net.HardwareAddr
+	// PortPolicy is the VF port state policy (IbVfPortPolicyFollow, IbVfPortPolicyUp or IbVfPortPolicyDown),
+	// IbVfPortPolicyFollow is used if empty
+	PortPolicy string
+}
+
+// ConfigIbVfs configures the VFs of an InfiniBand PF: it sets the VF port state policy and the VF
+// node and port GUIDs, then rebinds bound VFs for the GUIDs to take effect.
+// Unlike ConfigVfs, VFs without a netdev (e.g unbound VFs destined for vfio passthrough) are configured too.
+// A nil cfg assigns random GUIDs to all VFs with the IbVfPortPolicyFollow policy.
+func ConfigIbVfs(handle *PfNetdevHandle, cfg *IbVfConfig) (err error) {
+	defer startTrace("ConfigIbVfs", "pf", handle.PfNetdevName)(&err)
+	if cfg == nil {
+		cfg = &IbVfConfig{}
+	}
+	policy := cfg.PortPolicy
+	switch policy {
+	case "":
+		policy = IbVfPortPolicyFollow
+	case IbVfPortPolicyFollow, IbVfPortPolicyUp, IbVfPortPolicyDown:
+	default:
+		return fmt.Errorf("invalid VF port policy %q, expected one of %s, %s or %s",
+			policy, IbVfPortPolicyFollow, IbVfPortPolicyUp, IbVfPortPolicyDown)
+	}
+	for vfIndex, guid := range cfg.GUIDs {
+		if len(guid) != ibGUIDLen {
+			return fmt.Errorf("invalid GUID %s for VF %d, expected %d bytes", guid.String(), vfIndex, ibGUIDLen)
+		}
+	}
+	if encapType := handle.pfLinkHandle.Attrs().EncapType; encapType != ibEncapType {
+		return fmt.Errorf("PF %s is not an InfiniBand device, encap type %s", handle.PfNetdevName, encapType)
+	}
+
+	for _, vf := range handle.List {
+		// Ignore the error where the policy file is not available
+		if _, err := ibGetPortAdminState(handle.PfNetdevName, vf.Index); err == nil {
+			if err = ibSetPortAdminState(handle.PfNetdevName, vf.Index, policy); err != nil {
+				return fmt.Errorf("failed to set port policy of VF %d: %v", vf.Index, err)
+			}
+		}
+
+		guid, ok := cfg.GUIDs[vf.Index]
+		if !ok {
+			randGUID, err := generateVfGUID(vf.Index)
+			if err != nil {
+				return err
+			}
+			guid = randGUID
+		}
+		if err := setVfNodeGUID(handle, vf, guid); err != nil {
+			return fmt.Errorf("failed to set node GUID of VF %d: %v", vf.Index, err)
+		}
+		if err := setVfPortGUID(handle, vf, guid); err != nil {
+			return fmt.Errorf("failed to set port GUID of VF %d: %v", vf.Index, err)
+		}
+	}
+
+	for _, vf := range handle.List {
+		if !vf.Bound {
+			continue
+		}
+		if err := UnbindVf(handle, vf); err != nil {
+			return fmt.Errorf("failed to unbind VF %d: %v", vf.Index, err)
+		}
+		if err := BindVf(handle, vf); err != nil {
+			return fmt.Errorf("failed to bind VF %d: %v", vf.Index, err)
+		}
 	}
 	return nil
 }
@@ -378,7 +503,7 @@ func AllocateVf(handle *PfNetdevHandle) (*VfObj, error) {
 			continue
 		}
 		vf.Allocated = true
-		log.Printf("Allocated vf = %v\n", *vf)
+		logInfo("allocated VF", "netdev", handle.PfNetdevName, "vf", vf.Index, "pci", vf.PciAddress)
 		return vf, nil
 	}
 	return nil, fmt.Errorf("all Vfs for %v are allocated", handle.PfNetdevName)
@@ -396,7 +521,8 @@ func AllocateVfByMacAddress(handle *PfNetdevHandle, vfMacAddress string) (*VfObj
 			continue
 		}
 		vf.Allocated = true
-		log.Printf("Allocated vf by mac = %v\n", *vf)
+		logInfo("allocated VF by MAC address", "netdev", handle.PfNetdevName, "vf", vf.Index, "pci", vf.PciAddress,
+			"mac", vfMacAddress)
 		return vf, nil
 	}
 	return nil, fmt.Errorf("all Vfs for %v are allocated for mac address %v",
@@ -405,7 +531,7 @@ func AllocateVfByMacAddress(handle *PfNetdevHandle, vfMacAddress string) (*VfObj
 
 func FreeVf(_ *PfNetdevHandle, vf *VfObj) {
 	vf.Allocated = false
-	log.Printf("Free vf = %v\n", *vf)
+	logInfo("freed VF", "vf", vf.Index, "pci", vf.PciAddress)
 }
 
 func FreeVfByNetdevName(handle *PfNetdevHandle, vfIndex int) error {
@@ -427,22 +553,21 @@ func GetVfNetdevName(handle *PfNetdevHandle, vf *VfObj) string {
 // GetVfIndexByPciAddress gets a VF PCI address (e.g '0000:03:00.4') and
 // returns the correlate VF index.
 func GetVfIndexByPciAddress(vfPciAddress string) (int, error) {
-	vfPath := filepath.Join(PciSysDir, vfPciAddress, "physfn", "virtfn*")
-	matches, err := filepath.Glob(vfPath)
+	vfPciAddress, err := normalizePciAddress(vfPciAddress)
 	if err != nil {
 		return -1, err
 	}
-	for _, match := range matches {
-		tmp, err := os.Readlink(match)
-		if err != nil {
-			continue
-		}
-		if strings.Contains(tmp, vfPciAddress) {
-			result := virtFnRe.FindStringSubmatch(match)
-			vfIndex, err := strconv.Atoi(result[1])
-			if err != nil {
-				continue
-			}
+	pfPciAddress, err := GetPfPciFromVfPci(vfPciAddress)
+	if err != nil {
+		return -1, err
+	}
+	vfs, err := getVfPciAddressMapFromPfPci(pfPciAddress)
+	if err != nil {
+		return -1, err
+	}
+	// Compare full addresses, a substring match would confuse e.g 0000:03:00.1 with 10000:03:00.1
+	for vfIndex, vf := range vfs {
+		if vf == vfPciAddress {
 			return vfIndex, nil
 		}
 	}
@@ -451,24 +576,23 @@ func GetVfIndexByPciAddress(vfPciAddress string) (int, error) {
 
 // gets the PF index that's associated with a VF PCI address (e.g '0000:03:00.4')
 func GetPfIndexByVfPciAddress(vfPciAddress string) (int, error) {
-	const pciParts = 4
 	pfPciAddress, err := GetPfPciFromVfPci(vfPciAddress)
 	if err != nil {
 		return -1, err
 	}
-	var domain, bus, dev, fn int
-	parsed, err := fmt.Sscanf(pfPciAddress, "%04x:%02x:%02x.%d", &domain, &bus, &dev, &fn)
+	pfAddr, err := ParsePciAddress(pfPciAddress)
 	if err != nil {
 		return -1, fmt.Errorf("error trying to parse PF PCI address %s: %v", pfPciAddress, err)
 	}
-	if parsed != pciParts {
-		return -1, fmt.Errorf("failed to parse PF PCI address %s. Unexpected format", pfPciAddress)
-	}
-	return fn, err
+	return int(pfAddr.Function), nil
 }
 
 // GetPfPciFromVfPci retrieves the parent PF PCI address of the provided VF PCI address in D:B:D.f format
 func GetPfPciFromVfPci(vfPciAddress string) (string, error) {
+	vfPciAddress, err := normalizePciAddress(vfPciAddress)
+	if err != nil {
+		return "", err
+	}
 	pfPath := filepath.Join(PciSysDir, vfPciAddress, "physfn")
 	pciDevDir, err := utilfs.Fs.Readlink(pfPath)
 	if err != nil {
@@ -485,44 +609,112 @@ func GetPfPciFromVfPci(vfPciAddress string) (string, error) {
 // GetNetDevicesFromPci gets a PCI address (e.g '0000:03:00.1') and
 // returns the correlate list of netdevices
 func GetNetDevicesFromPci(pciAddress string) ([]string, error) {
+	pciAddress, err := normalizePciAddress(pciAddress)
+	if err != nil {
+		return nil, err
+	}
 	pciDir := filepath.Join(PciSysDir, pciAddress, "net")
 	return getFileNamesFromPath(pciDir)
 }
 
-// GetPciFromNetDevice returns the PCI address associated with a network device name
-func GetPciFromNetDevice(name string) (string, error) {
-	devPath := filepath.Join(NetSysDir, name)
-
-	realPath, err := utilfs.Fs.Readlink(devPath)
-	if err != nil {
-		return "", fmt.Errorf("device %s not found: %s", name, err)
-	}
-
+// pciAddressFromSysfsPath returns the PCI address of the PCI device a sysfs device path belongs to.
+// Devices can have their PCI device sysfs entry at different levels:
+// PF, VF, SF representor:
+//
+//	/sys/devices/pci0000:00/.../0000:03:00.0/net/p0
+//	/sys/devices/pci0000:00/.../0000:03:00.0/net/pf0hpf
+//	/sys/devices/pci0000:00/.../0000:03:00.0/net/pf0vf0
+//	/sys/devices/pci0000:00/.../0000:03:00.0/net/pf0sf0
+//
+// SF port:
+//
+//	/sys/devices/pci0000:00/.../0000:03:00.0/mlx5_core.sf.3/net/enp3s0f0s1
+//
+// RDMA device:
+//
+//	/sys/devices/pci0000:00/.../0000:03:00.0/infiniband/mlx5_0
+func pciAddressFromSysfsPath(realPath string) (string, bool) {
 	parent := filepath.Dir(realPath)
 	base := filepath.Base(parent)
-	// Devices can have their PCI device sysfs entry at different levels:
-	// PF, VF, SF representor:
-	//   /sys/devices/pci0000:00/.../0000:03:00.0/net/p0
-	//   /sys/devices/pci0000:00/.../0000:03:00.0/net/pf0hpf
-	//   /sys/devices/pci0000:00/.../0000:03:00.0/net/pf0vf0
-	//   /sys/devices/pci0000:00/.../0000:03:00.0/net/pf0sf0
-	// SF port:
-	//   /sys/devices/pci0000:00/.../0000:03:00.0/mlx5_core.sf.3/net/enp3s0f0s1
 	// This loop allows detecting any of them.
 	for parent != "/" && !pciAddressRe.MatchString(base) {
 		parent = filepath.Dir(parent)
 		base = filepath.Base(parent)
 	}
 	// If we stopped on '/' and the base was never a proper PCI address,
-	// then 'netdev' is not a PCI device.
+	// then the device is not a PCI device.
 	if !pciAddressRe.MatchString(base) {
+		return "", false
+	}
+	return base, true
+}
+
+// GetPciFromNetDevice returns the PCI address associated with a network device name
+func GetPciFromNetDevice(name string) (string, error) {
+	devPath := filepath.Join(NetSysDir, name)
+
+	realPath, err := utilfs.Fs.Readlink(devPath)
+	if err != nil {
+		return "", fmt.Errorf("device %s not found: %s", name, err)
+	}
+
+	pciAddress, ok := pciAddressFromSysfsPath(realPath)
+	if !ok {
 		return "", fmt.Errorf("device %s is not a PCI device: %s", name, realPath)
 	}
-	return base, nil
+	return pciAddress, nil
+}
+
+// GetPciFromRdmaDevice returns the PCI address associated with a RDMA device name (e.g 'mlx5_0').
+// For SF RDMA devices, the PCI address of the parent PF is returned.
+func GetPciFromRdmaDevice(rdmaDevice string) (string, error) {
+	devPath := filepath.Join(InfinibandSysDir, rdmaDevice)
+
+	realPath, err := utilfs.Fs.Readlink(devPath)
+	if err != nil {
+		return "", fmt.Errorf("RDMA device %s not found: %s", rdmaDevice, err)
+	}
+
+	pciAddress, ok := pciAddressFromSysfsPath(realPath)
+	if !ok {
+		return "", fmt.Errorf("RDMA device %s is not a PCI device: %s", rdmaDevice, realPath)
+	}
+	return pciAddress, nil
+}
+
+// GetNetdevFromRdmaDevice gets a RDMA device name (e.g 'mlx5_0') and
+// returns the correlate list of netdevices
+func GetNetdevFromRdmaDevice(rdmaDevice string) ([]string, error) {
+	netDir := filepath.Join(InfinibandSysDir, rdmaDevice, pcidevPrefix, "net")
+	return getFileNamesFromPath(netDir)
+}
+
+// GetNetdevLinkType returns the link layer type (e.g LinkTypeEther, LinkTypeInfiniband) of the given netdev.
+// The type is read from sysfs, netlink is used for types other than Ethernet and InfiniBand.
+func GetNetdevLinkType(netdev string) (LinkType, error) {
+	typeFile := filepath.Join(NetSysDir, netdev, netdevTypeFile)
+	if content, err := utilfs.Fs.ReadFile(typeFile); err == nil {
+		switch strings.TrimSpace(string(content)) {
+		case strconv.Itoa(arphrdEther):
+			return LinkTypeEther, nil
+		case strconv.Itoa(arphrdInfiniband):
+			return LinkTypeInfiniband, nil
+		}
+	}
+
+	link, err := netlinkops.GetNetlinkOps().LinkByName(netdev)
+	if err != nil {
+		return "", fmt.Errorf("failed to get link type of %s: %v", netdev, err)
+	}
+	return LinkType(link.Attrs().EncapType), nil
 }
 
 // GetPKeyByIndexFromPci returns the PKey stored under given index for the IB PCI device
 func GetPKeyByIndexFromPci(pciAddress string, index int) (string, error) {
+	pciAddress, err := normalizePciAddress(pciAddress)
+	if err != nil {
+		return "", err
+	}
 	pciDir := filepath.Join(PciSysDir, pciAddress, "infiniband")
 	dirEntries, err := utilfs.Fs.ReadDir(pciDir)
 	if err != nil {
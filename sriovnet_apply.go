@@ -0,0 +1,356 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sriovnet
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const applyDefaultTimeout = 30 * time.Second
+
+// PfSpec is the desired SR-IOV configuration of a PF, unset fields are left unchanged
+type PfSpec struct {
+	// PciAddress of the PF, e.g 0000:03:00.0
+	PciAddress string `json:"pciAddress" yaml:"pciAddress"`
+	// NumVfs is the desired number of VFs
+	NumVfs *int `json:"numVfs,omitempty" yaml:"numVfs,omitempty"`
+	// EswitchMode is the desired eswitch mode
+	EswitchMode EswitchMode `json:"eswitchMode,omitempty" yaml:"eswitchMode,omitempty"`
+	// VfDrivers maps a VF index to the driver to bind the VF to (e.g 'vfio-pci'), VFs missing from the map are
+	// left bound to their current driver
+	VfDrivers map[int]string `json:"vfDrivers,omitempty" yaml:"vfDrivers,omitempty"`
+	// Sfs is the desired list of SFs, requires switchdev mode. When set (even empty) SFs missing from the list
+	// are deleted, SFs which cannot be identified (i.e without a representor) are left untouched.
+	// Existing SFs are not reconfigured.
+	Sfs []SfSpec `json:"sfs,omitempty" yaml:"sfs,omitempty"`
+}
+
+// SfSpec is a SF of a PfSpec
+type SfSpec struct {
+	PfNum int `json:"pfNum" yaml:"pfNum"`
+	SfNum int `json:"sfNum" yaml:"sfNum"`
+	// MacAddress of a created SF, left to the driver default if empty
+	MacAddress string `json:"macAddress,omitempty" yaml:"macAddress,omitempty"`
+}
+
+// ApplyOptions are the options of ApplySpec
+type ApplyOptions struct {
+	// Timeout of each operation waiting for the kernel (eswitch mode change, SF probe or removal),
+	// defaults to 30s
+	Timeout time.Duration
+}
+
+// applyStep is an operation of an apply plan along with the operation reverting it
+type applyStep struct {
+	description string
+	do          func() error
+	// undo reverts do, nil if the step cannot be reverted
+	undo func() error
+}
+
+// ApplySpec reconciles the node to the given PF specs. The operations required by all the specs are planned
+// upfront, then run in order, per PF:
+//  1. delete the SFs missing from the spec
+//  2. switch the eswitch to legacy if the number of VFs changes in switchdev mode
+//  3. change the number of VFs
+//  4. set the eswitch mode (switching back to switchdev if it was switched to legacy in 2)
+//  5. bind the VFs to their driver
+//  6. create the missing SFs
+//
+// If an operation fails, the operations already performed (including those of previous PFs) are reverted in
+// reverse order and the error is returned along with the rollback failures, if any.
+// Note: rollback is best effort, e.g deleted SFs are recreated with their pfnum, sfnum and MAC address only.
+func ApplySpec(specs []*PfSpec, opts *ApplyOptions) (err error) {
+	defer startTrace("ApplySpec")(&err)
+	timeout := applyDefaultTimeout
+	if opts != nil && opts.Timeout > 0 {
+		timeout = opts.Timeout
+	}
+	var steps []*applyStep
+	for i, spec := range specs {
+		if spec == nil {
+			return fmt.Errorf("invalid spec at index %d: spec is nil", i)
+		}
+		pfSteps, err := planPfSpec(spec, timeout)
+		if err != nil {
+			return fmt.Errorf("invalid spec for PF %s: %v", spec.PciAddress, err)
+		}
+		steps = append(steps, pfSteps...)
+	}
+	return runApplySteps(steps)
+}
+
+// runApplySteps runs steps in order, reverting the performed steps in reverse order on failure
+func runApplySteps(steps []*applyStep) error {
+	for i, step := range steps {
+		logDebug("applying step", "step", step.description)
+		err := step.do()
+		if err == nil {
+			continue
+		}
+		err = fmt.Errorf("failed to %s: %w", step.description, err)
+		var rollbackErrs []error
+		for j := i - 1; j >= 0; j-- {
+			if steps[j].undo == nil {
+				continue
+			}
+			logInfo("reverting step", "step", steps[j].description)
+			if undoErr := steps[j].undo(); undoErr != nil {
+				rollbackErrs = append(rollbackErrs, fmt.Errorf("failed to revert %s: %w", steps[j].description,
+					undoErr))
+			}
+		}
+		if len(rollbackErrs) > 0 {
+			return errors.Join(append([]error{err}, rollbackErrs...)...)
+		}
+		return err
+	}
+	return nil
+}
+
+// planPfSpec returns the steps reconciling the PF of the given spec from its current state
+func planPfSpec(spec *PfSpec, timeout time.Duration) ([]*applyStep, error) {
+	pci, err := normalizePciAddress(spec.PciAddress)
+	if err != nil {
+		return nil, err
+	}
+	totalVfs, err := readPciIntAttr(pci, netDevMaxVfCountFile)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s is not an SR-IOV PF", ErrNotSupported, pci)
+	}
+	curNumVfs, err := readPciIntAttr(pci, netDevCurrentVfCountFile)
+	if err != nil {
+		return nil, err
+	}
+	numVfs := curNumVfs
+	if spec.NumVfs != nil {
+		numVfs = *spec.NumVfs
+		if numVfs < 0 || numVfs > totalVfs {
+			return nil, fmt.Errorf("invalid number of VFs %d, the PF supports up to %d VFs", numVfs, totalVfs)
+		}
+	}
+	for vfIndex := range spec.VfDrivers {
+		if vfIndex < 0 || vfIndex >= numVfs {
+			return nil, fmt.Errorf("invalid VF index %d, the PF has %d VFs", vfIndex, numVfs)
+		}
+	}
+	if spec.EswitchMode != "" && spec.EswitchMode != EswitchModeLegacy && spec.EswitchMode != EswitchModeSwitchdev {
+		return nil, fmt.Errorf("invalid eswitch mode %q", spec.EswitchMode)
+	}
+
+	// devices without a devlink eswitch (e.g non mlx5 NICs) are considered legacy
+	curMode := EswitchModeLegacy
+	if spec.EswitchMode != "" || numVfs != curNumVfs || spec.Sfs != nil {
+		if mode, err := GetEswitchMode(pci); err == nil {
+			curMode = mode
+		}
+	}
+	mode := curMode
+	if spec.EswitchMode != "" {
+		mode = spec.EswitchMode
+	}
+	if len(spec.Sfs) > 0 && mode != EswitchModeSwitchdev {
+		return nil, fmt.Errorf("SFs require switchdev eswitch mode")
+	}
+
+	var steps []*applyStep
+	if spec.Sfs != nil && curMode == EswitchModeSwitchdev {
+		sfSteps, err := planSfDeletion(pci, spec.Sfs, timeout)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, sfSteps...)
+	}
+	if numVfs != curNumVfs {
+		if curMode == EswitchModeSwitchdev {
+			steps = append(steps, newEswitchModeStep(pci, EswitchModeLegacy, curMode, timeout))
+			curMode = EswitchModeLegacy
+		}
+		steps = append(steps, &applyStep{
+			description: fmt.Sprintf("set number of VFs of %s to %d", pci, numVfs),
+			do:          func() error { return setSriovNumVfs(pci, numVfs) },
+			undo:        func() error { return setSriovNumVfs(pci, curNumVfs) },
+		})
+	}
+	if mode != curMode {
+		steps = append(steps, newEswitchModeStep(pci, mode, curMode, timeout))
+	}
+
+	vfIndexes := make([]int, 0, len(spec.VfDrivers))
+	for vfIndex := range spec.VfDrivers {
+		vfIndexes = append(vfIndexes, vfIndex)
+	}
+	sort.Ints(vfIndexes)
+	for _, vfIndex := range vfIndexes {
+		steps = append(steps, newVfDriverStep(pci, vfIndex, spec.VfDrivers[vfIndex]))
+	}
+
+	if len(spec.Sfs) > 0 {
+		sfSteps, err := planSfCreation(pci, spec.Sfs, curMode == EswitchModeSwitchdev, timeout)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, sfSteps...)
+	}
+	return steps, nil
+}
+
+func newEswitchModeStep(pci string, mode, curMode EswitchMode, timeout time.Duration) *applyStep {
+	return &applyStep{
+		description: fmt.Sprintf("set eswitch mode of %s to %s", pci, mode),
+		do:          func() error { return SetEswitchMode(pci, mode, timeout) },
+		undo:        func() error { return SetEswitchMode(pci, curMode, timeout) },
+	}
+}
+
+// newVfDriverStep returns a step binding the VF with the given index to driver. The VF PCI address and its
+// current driver are resolved when the step runs, as the VF may be created by a previous step.
+func newVfDriverStep(pci string, vfIndex int, driver string) *applyStep {
+	var vfPci, oldDriver string
+	step := &applyStep{description: fmt.Sprintf("bind VF %d of %s to %s", vfIndex, pci, driver)}
+	step.do = func() error {
+		var err error
+		if vfPci, err = readPCIsymbolicLink(filepath.Join(PciSysDir, pci,
+			netDevVfDevicePrefix+strconv.Itoa(vfIndex))); err != nil {
+			return err
+		}
+		oldDriver, err = GetDriverNameByPciAddress(vfPci)
+		if err != nil && !errors.Is(err, ErrNoDriver) {
+			return err
+		}
+		return bindVfDriver(vfPci, oldDriver, driver)
+	}
+	step.undo = func() error {
+		return bindVfDriver(vfPci, driver, oldDriver)
+	}
+	return step
+}
+
+// bindVfDriver rebinds the VF with the given PCI address from curDriver to driver, an empty driver leaves
+// the VF unbound
+func bindVfDriver(vfPci, curDriver, driver string) error {
+	if curDriver == driver {
+		return nil
+	}
+	if driver == vfioPciDriver {
+		return BindVfToVfio(vfPci)
+	}
+	if curDriver == vfioPciDriver && hasPciDriverOverride(vfPci) {
+		if err := ClearPciDriverOverride(vfPci); err != nil {
+			return err
+		}
+	}
+	if err := UnbindDriverByPciAddress(vfPci); err != nil {
+		return err
+	}
+	if driver == "" {
+		return nil
+	}
+	return BindDriverByPciAddress(vfPci, driver)
+}
+
+// planSfDeletion returns the steps deleting the identifiable SFs of the PF which are missing from sfs
+func planSfDeletion(pci string, sfs []SfSpec, timeout time.Duration) ([]*applyStep, error) {
+	existing, err := ListSFs(pci)
+	if err != nil {
+		return nil, err
+	}
+	desired := make(map[[2]int]bool, len(sfs))
+	for _, sf := range sfs {
+		desired[[2]int{sf.PfNum, sf.SfNum}] = true
+	}
+	var steps []*applyStep
+	for _, sf := range existing {
+		if sf.SfNum < 0 || desired[[2]int{sf.PfNum, sf.SfNum}] {
+			continue
+		}
+		pfNum, sfNum, hwAddr := sf.PfNum, sf.SfNum, sf.HwAddr
+		steps = append(steps, &applyStep{
+			description: fmt.Sprintf("delete SF pfnum %d sfnum %d of %s", pfNum, sfNum, pci),
+			do:          func() error { return DeleteSF(pci, pfNum, sfNum, timeout) },
+			undo: func() error {
+				_, err := DeploySF(pci, &SfConfig{PfNum: pfNum, SfNum: &sfNum, HwAddr: hwAddr, Timeout: timeout})
+				return err
+			},
+		})
+	}
+	return steps, nil
+}
+
+// planSfCreation returns the steps creating the SFs missing on the PF. If the PF is not yet in switchdev mode,
+// all the SFs are created.
+func planSfCreation(pci string, sfs []SfSpec, switchdev bool, timeout time.Duration) ([]*applyStep, error) {
+	existing := make(map[[2]int]bool)
+	if switchdev {
+		sfInfos, err := ListSFs(pci)
+		if err != nil {
+			return nil, err
+		}
+		for _, sf := range sfInfos {
+			existing[[2]int{sf.PfNum, sf.SfNum}] = true
+		}
+	}
+	var steps []*applyStep
+	for _, sf := range sfs {
+		if existing[[2]int{sf.PfNum, sf.SfNum}] {
+			continue
+		}
+		pfNum, sfNum := sf.PfNum, sf.SfNum
+		var hwAddr net.HardwareAddr
+		if sf.MacAddress != "" {
+			var err error
+			if hwAddr, err = net.ParseMAC(sf.MacAddress); err != nil {
+				return nil, fmt.Errorf("invalid MAC address of SF %d: %v", sfNum, err)
+			}
+		}
+		steps = append(steps, &applyStep{
+			description: fmt.Sprintf("create SF pfnum %d sfnum %d on %s", pfNum, sfNum, pci),
+			do: func() error {
+				_, err := DeploySF(pci, &SfConfig{PfNum: pfNum, SfNum: &sfNum, HwAddr: hwAddr, Timeout: timeout})
+				return err
+			},
+			undo: func() error { return DeleteSF(pci, pfNum, sfNum, timeout) },
+		})
+	}
+	return steps, nil
+}
+
+// setSriovNumVfs sets the number of VFs of the PF with the given PCI address. The kernel does not allow to
+// change a non-zero number of VFs, so VFs are disabled first.
+func setSriovNumVfs(pfPciAddress string, numVfs int) error {
+	numVfsFile := filepath.Join(PciSysDir, pfPciAddress, netDevCurrentVfCountFile)
+	curNumVfs, err := readPciIntAttr(pfPciAddress, netDevCurrentVfCountFile)
+	if err != nil {
+		return err
+	}
+	if curNumVfs == numVfs {
+		return nil
+	}
+	defer InvalidateRepresentorCache()
+	if curNumVfs != 0 && numVfs != 0 {
+		if err = writePciSysfsFile(numVfsFile, "0"); err != nil {
+			return err
+		}
+	}
+	return writePciSysfsFile(numVfsFile, strconv.Itoa(numVfs))
+}
@@ -0,0 +1,202 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sriovnet
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vishvananda/netlink"
+
+	"github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/netlinkops"
+	netlinkopsMocks "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/netlinkops/mocks"
+)
+
+// recordSysfsWrites records the values written to sysfs files through the audit hook
+func recordSysfsWrites(t *testing.T) (writes *[]string, teardown func()) {
+	t.Helper()
+	writes = &[]string{}
+	SetAuditHook(func(record *AuditRecord) {
+		if record.Kind == AuditKindSysfs && record.Err == nil {
+			*writes = append(*writes, fmt.Sprintf("%s=%s", filepath.Base(record.Target), record.NewValue))
+		}
+	})
+	return writes, func() { SetAuditHook(nil) }
+}
+
+func newFakeApplyStep(name string, fail bool, calls *[]string) *applyStep {
+	return &applyStep{
+		description: name,
+		do: func() error {
+			*calls = append(*calls, "do "+name)
+			if fail {
+				return errors.New("failure")
+			}
+			return nil
+		},
+		undo: func() error {
+			*calls = append(*calls, "undo "+name)
+			return nil
+		},
+	}
+}
+
+func TestRunApplyStepsRollback(t *testing.T) {
+	var calls []string
+	steps := []*applyStep{
+		newFakeApplyStep("a", false, &calls),
+		{description: "b", do: func() error { calls = append(calls, "do b"); return nil }},
+		newFakeApplyStep("c", false, &calls),
+		newFakeApplyStep("d", true, &calls),
+		newFakeApplyStep("e", false, &calls),
+	}
+	err := runApplySteps(steps)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to d")
+	assert.Equal(t, []string{"do a", "do b", "do c", "do d", "undo c", "undo a"}, calls)
+}
+
+func TestRunApplyStepsRollbackError(t *testing.T) {
+	var calls []string
+	steps := []*applyStep{
+		newFakeApplyStep("a", false, &calls),
+		newFakeApplyStep("b", true, &calls),
+	}
+	steps[0].undo = func() error { return errors.New("undo failure") }
+	err := runApplySteps(steps)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to b")
+	assert.Contains(t, err.Error(), "failed to revert a")
+}
+
+func TestSetSriovNumVfs(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+	setUpPciAttrs(t, "0000:03:00.0", map[string]string{netDevCurrentVfCountFile: "2\n"})
+	writes, unhook := recordSysfsWrites(t)
+	defer unhook()
+
+	assert.NoError(t, setSriovNumVfs("0000:03:00.0", 4))
+	assert.NoError(t, setSriovNumVfs("0000:03:00.0", 4))
+	assert.NoError(t, setSriovNumVfs("0000:03:00.0", 0))
+	assert.Equal(t, []string{"sriov_numvfs=0", "sriov_numvfs=4", "sriov_numvfs=0"}, *writes)
+}
+
+func TestApplySpecNumVfs(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+	teardown := setupFakeFs(t)
+	defer teardown()
+	setUpPciAttrs(t, "0000:03:00.0", map[string]string{netDevMaxVfCountFile: "8\n", netDevCurrentVfCountFile: "0\n"})
+	writes, unhook := recordSysfsWrites(t)
+	defer unhook()
+
+	nlOpsMock.On("DevLinkGetDeviceByName", "pci", "0000:03:00.0").Return(
+		newDevlinkDevice("0000:03:00.0", netlink.DevlinkDevEswitchAttr{Mode: "legacy"}), nil)
+
+	numVfs := 2
+	err := ApplySpec([]*PfSpec{{PciAddress: "0000:03:00.0", NumVfs: &numVfs}}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"sriov_numvfs=2"}, *writes)
+	assert.Equal(t, "2", readFakeFile(t, filepath.Join(PciSysDir, "0000:03:00.0", netDevCurrentVfCountFile)))
+}
+
+func TestApplySpecInvalid(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+	setUpPciAttrs(t, "0000:03:00.0", map[string]string{netDevMaxVfCountFile: "8\n", netDevCurrentVfCountFile: "2\n"})
+	writes, unhook := recordSysfsWrites(t)
+	defer unhook()
+
+	numVfs := 16
+	err := ApplySpec([]*PfSpec{{PciAddress: "0000:03:00.0", NumVfs: &numVfs}}, nil)
+	assert.Error(t, err)
+	err = ApplySpec([]*PfSpec{{PciAddress: "0000:03:00.0", VfDrivers: map[int]string{2: vfioPciDriver}}}, nil)
+	assert.Error(t, err)
+	err = ApplySpec([]*PfSpec{{PciAddress: "0000:03:00.1"}}, nil)
+	assert.Error(t, err)
+	numVfs = 4
+	err = ApplySpec([]*PfSpec{{PciAddress: "0000:03:00.0", NumVfs: &numVfs}, nil}, nil)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "spec is nil")
+	assert.Empty(t, *writes)
+}
+
+func TestApplySpecRollback(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+	teardown := setupFakeFs(t)
+	defer teardown()
+	setUpPciAttrs(t, "0000:03:00.0", map[string]string{netDevMaxVfCountFile: "8\n", netDevCurrentVfCountFile: "0\n"})
+	writes, unhook := recordSysfsWrites(t)
+	defer unhook()
+
+	dev := newDevlinkDevice("0000:03:00.0", netlink.DevlinkDevEswitchAttr{Mode: "legacy"})
+	nlOpsMock.On("DevLinkGetDeviceByName", "pci", "0000:03:00.0").Return(dev, nil)
+	nlOpsMock.On("DevLinkSetEswitchMode", dev, "switchdev").Return(fmt.Errorf("operation not supported"))
+
+	numVfs := 2
+	err := ApplySpec([]*PfSpec{{PciAddress: "0000:03:00.0", NumVfs: &numVfs, EswitchMode: EswitchModeSwitchdev}}, nil)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "set eswitch mode of 0000:03:00.0 to switchdev")
+	assert.Equal(t, []string{"sriov_numvfs=2", "sriov_numvfs=0"}, *writes)
+	nlOpsMock.AssertExpectations(t)
+}
+
+func TestPlanPfSpecSwitchdevOrder(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+	teardown := setupFakeFs(t)
+	defer teardown()
+	setUpPciAttrs(t, "0000:03:00.0", map[string]string{netDevMaxVfCountFile: "8\n", netDevCurrentVfCountFile: "2\n"})
+
+	nlOpsMock.On("DevLinkGetDeviceByName", "pci", "0000:03:00.0").Return(
+		newDevlinkDevice("0000:03:00.0", netlink.DevlinkDevEswitchAttr{Mode: "switchdev"}), nil)
+
+	numVfs := 4
+	steps, err := planPfSpec(&PfSpec{
+		PciAddress: "0000:03:00.0",
+		NumVfs:     &numVfs,
+		VfDrivers:  map[int]string{3: vfioPciDriver, 0: "mlx5_core"},
+	}, applyDefaultTimeout)
+	assert.NoError(t, err)
+	var descriptions []string
+	for _, step := range steps {
+		descriptions = append(descriptions, step.description)
+	}
+	assert.Equal(t, []string{
+		"set eswitch mode of 0000:03:00.0 to legacy",
+		"set number of VFs of 0000:03:00.0 to 4",
+		"set eswitch mode of 0000:03:00.0 to switchdev",
+		"bind VF 0 of 0000:03:00.0 to mlx5_core",
+		"bind VF 3 of 0000:03:00.0 to vfio-pci",
+	}, descriptions)
+
+	// SFs require switchdev mode
+	_, err = planPfSpec(&PfSpec{
+		PciAddress:  "0000:03:00.0",
+		EswitchMode: EswitchModeLegacy,
+		Sfs:         []SfSpec{{PfNum: 0, SfNum: 1}},
+	}, applyDefaultTimeout)
+	assert.Error(t, err)
+}
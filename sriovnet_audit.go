@@ -0,0 +1,112 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sriovnet
+
+import (
+	"strings"
+	"time"
+
+	utilfs "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/filesystem"
+	"github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/netlinkops"
+)
+
+// AuditKind is the kind of a mutating operation reported to the audit hook
+type AuditKind string
+
+const (
+	// AuditKindSysfs is a write to a sysfs attribute
+	AuditKindSysfs AuditKind = "sysfs"
+	// AuditKindNetlink is a netlink (rtnetlink, devlink or ethtool) mutation
+	AuditKindNetlink AuditKind = "netlink"
+)
+
+// AuditRecord describes a mutating operation performed by sriovnet
+type AuditRecord struct {
+	// Time the operation completed
+	Time time.Time
+	// Kind of the operation
+	Kind AuditKind
+	// Op is 'write' for sysfs writes, the NetlinkOps method name (e.g 'LinkSetVfVlan') for netlink mutations
+	Op string
+	// Target is the sysfs file path or the mutated netlink object (e.g 'p0/vf1' or 'pci/0000:03:00.0')
+	Target string
+	// OldValue is the value before the operation, empty if unknown
+	OldValue string
+	// NewValue is the written value
+	NewValue string
+	// Err is the result of the operation, nil on success
+	Err error
+}
+
+// AuditHook is called after every sysfs write and netlink mutation performed by sriovnet,
+// including failed ones. The hook is called synchronously and must not call back into sriovnet.
+type AuditHook func(record *AuditRecord)
+
+var auditHook AuditHook
+
+// SetAuditHook sets the hook called for every mutating operation, e.g to feed an audit trail.
+// A nil hook disables auditing. It is not safe to change the hook while sriovnet calls are in flight,
+// it is meant to be set once at startup.
+func SetAuditHook(hook AuditHook) {
+	auditHook = hook
+	if hook == nil {
+		netlinkops.SetMutationHook(nil)
+		return
+	}
+	netlinkops.SetMutationHook(func(record *netlinkops.MutationRecord) {
+		hook(&AuditRecord{
+			Time:     time.Now(),
+			Kind:     AuditKindNetlink,
+			Op:       record.Op,
+			Target:   record.Target,
+			OldValue: record.OldValue,
+			NewValue: record.NewValue,
+			Err:      record.Err,
+		})
+	})
+}
+
+// auditSysfsWrite calls write, which writes value to the sysfs file at path, and reports it to the audit hook
+func auditSysfsWrite(path, value string, write func() error) (err error) {
+	defer startTrace("sysfs.write", "path", path)(&err)
+	hook := auditHook
+	if hook == nil {
+		return write()
+	}
+	oldValue := ""
+	if content, err := utilfs.Fs.ReadFile(path); err == nil {
+		oldValue = strings.TrimSpace(string(content))
+	}
+	err = write()
+	hook(&AuditRecord{
+		Time:     time.Now(),
+		Kind:     AuditKindSysfs,
+		Op:       "write",
+		Target:   path,
+		OldValue: oldValue,
+		NewValue: strings.TrimSpace(value),
+		Err:      err,
+	})
+	return err
+}
+
+// writeSysfsFile writes value to the sysfs file at path, reporting it to the audit hook
+func writeSysfsFile(path, value string) error {
+	return auditSysfsWrite(path, value, func() error {
+		return utilfs.Fs.WriteFile(path, []byte(value), 0)
+	})
+}
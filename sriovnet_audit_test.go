@@ -0,0 +1,83 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sriovnet
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vishvananda/netlink"
+
+	"github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/netlinkops"
+	netlinkopsMocks "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/netlinkops/mocks"
+)
+
+func TestAuditHookSysfsWrite(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+	setUpPciAttrs(t, "0000:03:00.2", map[string]string{pciDevDriverOverride: "(null)\n"})
+
+	var records []*AuditRecord
+	SetAuditHook(func(record *AuditRecord) { records = append(records, record) })
+	defer SetAuditHook(nil)
+
+	assert.NoError(t, SetPciDriverOverride("0000:03:00.2", vfioPciDriver))
+	assert.Error(t, SetPciDriverOverride("0000:03:00.3", vfioPciDriver))
+	// the missing file is detected before writing
+	assert.Len(t, records, 1)
+	overrideFile := filepath.Join(PciSysDir, "0000:03:00.2", pciDevDriverOverride)
+	assert.Equal(t, AuditKindSysfs, records[0].Kind)
+	assert.Equal(t, "write", records[0].Op)
+	assert.Equal(t, overrideFile, records[0].Target)
+	assert.Equal(t, "(null)", records[0].OldValue)
+	assert.Equal(t, vfioPciDriver, records[0].NewValue)
+	assert.NoError(t, records[0].Err)
+
+	SetAuditHook(nil)
+	assert.NoError(t, SetPciDriverOverride("0000:03:00.2", "mlx5_core"))
+	assert.Len(t, records, 1)
+}
+
+func TestAuditHookNetlinkMutation(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+
+	var records []*AuditRecord
+	SetAuditHook(func(record *AuditRecord) { records = append(records, record) })
+	defer SetAuditHook(nil)
+
+	link := &netlink.Device{LinkAttrs: netlink.LinkAttrs{Name: "p0", Vfs: []netlink.VfInfo{{ID: 1, Vlan: 10}}}}
+	setErr := errors.New("operation not permitted")
+	nlOpsMock.On("LinkGetMTUByName", "p0").Return(1500, nil)
+	nlOpsMock.On("LinkSetMTUByName", "p0", 9000).Return(nil)
+	nlOpsMock.On("LinkSetVfVlan", link, 1, 20).Return(setErr)
+
+	assert.NoError(t, SetNetdevMtu("p0", 9000))
+	assert.ErrorIs(t, netlinkops.GetNetlinkOps().LinkSetVfVlan(link, 1, 20), setErr)
+	assert.Len(t, records, 2)
+	assert.Equal(t, AuditKindNetlink, records[0].Kind)
+	assert.Equal(t, "LinkSetMTUByName", records[0].Op)
+	assert.Equal(t, "p0", records[0].Target)
+	assert.Equal(t, "1500", records[0].OldValue)
+	assert.Equal(t, "9000", records[0].NewValue)
+	assert.NoError(t, records[0].Err)
+	assert.Equal(t, &AuditRecord{Time: records[1].Time, Kind: AuditKindNetlink, Op: "LinkSetVfVlan",
+		Target: "p0/vf1", OldValue: "10", NewValue: "20", Err: setErr}, records[1])
+}
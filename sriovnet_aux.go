@@ -17,16 +17,33 @@ limitations under the License.
 package sriovnet
 
 import (
+	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	utilfs "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/filesystem"
 )
 
 const (
-	u32Mask uint32 = 0xffffffff
+	AuxDriversSysDir = "/sys/bus/auxiliary/drivers"
+
+	u32Mask             uint32 = 0xffffffff
+	auxDevDriverLink           = "driver"
+	auxDriversProbeFile        = "/sys/bus/auxiliary/drivers_probe"
+
+	auxPollInterval = 100 * time.Millisecond
+)
+
+// Auxiliary device types, as found in auxiliary device names (e.g 'mlx5_core.sf.2')
+const (
+	AuxDevTypeSf     = "sf"
+	AuxDevTypeEth    = "eth"
+	AuxDevTypeEthRep = "eth-rep"
+	AuxDevTypeRdma   = "rdma"
 )
 
 // GetNetDeviceFromAux gets auxiliary device name (e.g 'mlx5_core.sf.2') and
@@ -36,6 +53,13 @@ func GetNetDevicesFromAux(auxDev string) ([]string, error) {
 	return getFileNamesFromPath(auxDir)
 }
 
+// GetRdmaDevicesFromAux gets auxiliary device name (e.g 'mlx5_core.sf.2') and
+// returns the correlate RDMA devices
+func GetRdmaDevicesFromAux(auxDev string) ([]string, error) {
+	auxDir := filepath.Join(AuxSysDir, auxDev, "infiniband")
+	return getFileNamesFromPath(auxDir)
+}
+
 // GetSfIndexByAuxDev gets a SF device name (e.g 'mlx5_core.sf.2') and
 // returns the correlate SF index.
 func GetSfIndexByAuxDev(auxDev string) (int, error) {
@@ -89,8 +113,53 @@ func GetUplinkRepresentorFromAux(auxDev string) (string, error) {
 	return GetUplinkRepresentor(pfPci)
 }
 
+// GetSfRepresentorFromAux gets a SF auxiliary device name (e.g 'mlx5_core.sf.2') and
+// returns the correlate SF representor netdev name.
+func GetSfRepresentorFromAux(auxDev string) (string, error) {
+	sfIndex, err := GetSfIndexByAuxDev(auxDev)
+	if err != nil {
+		return "", err
+	}
+	uplink, err := GetUplinkRepresentorFromAux(auxDev)
+	if err != nil {
+		return "", err
+	}
+	return GetSfRepresentor(uplink, sfIndex)
+}
+
+// GetSfRepresentorsFromEthRepAux gets an eth-rep auxiliary device name (e.g 'mlx5_core.eth-rep.0') and
+// returns the SF representor netdev names of its eswitch, keyed by sfnum.
+func GetSfRepresentorsFromEthRepAux(auxDev string) (map[int]string, error) {
+	if auxDevType(auxDev) != AuxDevTypeEthRep {
+		return nil, fmt.Errorf("%s is not an eth-rep auxiliary device", auxDev)
+	}
+	uplink, err := GetUplinkRepresentorFromAux(auxDev)
+	if err != nil {
+		return nil, err
+	}
+	devices, err := utilfs.Fs.ReadDir(filepath.Join(NetSysDir, uplink, pcidevPrefix, "net"))
+	if err != nil {
+		return nil, err
+	}
+	sfReps := make(map[int]string)
+	for _, device := range devices {
+		physPortName, err := GetPhysPortName(device.Name())
+		if err != nil {
+			continue
+		}
+		if sfIndex, err := sfIndexFromPortName(physPortName); err == nil {
+			sfReps[sfIndex] = device.Name()
+		}
+	}
+	return sfReps, nil
+}
+
 // GetAuxNetDevicesFromPci returns a list of auxiliary devices names for the specified PCI network device
 func GetAuxNetDevicesFromPci(pciAddr string) ([]string, error) {
+	pciAddr, err := normalizePciAddress(pciAddr)
+	if err != nil {
+		return nil, err
+	}
 	baseDev := filepath.Join(PciSysDir, pciAddr)
 	// ensure that "net" folder exists, meaning it is network PCI device
 	if _, err := utilfs.Fs.Stat(filepath.Join(baseDev, "net")); err != nil {
@@ -115,20 +184,69 @@ func GetAuxNetDevicesFromPci(pciAddr string) ([]string, error) {
 	return auxDevs, nil
 }
 
+// AuxDeviceName is a parsed auxiliary device name in <driver>.<type>.<id> format, e.g mlx5_core.sf.2
+type AuxDeviceName struct {
+	// Driver is the name of the driver which created the device, e.g mlx5_core
+	Driver string
+	// Type is the device type, e.g AuxDevTypeSf
+	Type string
+	ID   int
+}
+
+// ParseAuxDeviceName parses an auxiliary device name (e.g 'mlx5_core.sf.2')
+func ParseAuxDeviceName(auxDev string) (AuxDeviceName, error) {
+	if !auxiliaryDeviceRe.MatchString(auxDev) {
+		return AuxDeviceName{}, fmt.Errorf("invalid auxiliary device name %q", auxDev)
+	}
+	idSep := strings.LastIndex(auxDev, ".")
+	typeSep := strings.LastIndex(auxDev[:idSep], ".")
+	id, err := strconv.Atoi(auxDev[idSep+1:])
+	if err != nil {
+		return AuxDeviceName{}, fmt.Errorf("invalid auxiliary device name %q: %v", auxDev, err)
+	}
+	return AuxDeviceName{Driver: auxDev[:typeSep], Type: auxDev[typeSep+1 : idSep], ID: id}, nil
+}
+
+// String returns the auxiliary device name in <driver>.<type>.<id> format
+func (n AuxDeviceName) String() string {
+	return fmt.Sprintf("%s.%s.%d", n.Driver, n.Type, n.ID)
+}
+
+// auxDevType returns the type of the given auxiliary device (e.g 'sf' for 'mlx5_core.sf.2'),
+// or an empty string if the name is invalid
+func auxDevType(auxDev string) string {
+	name, err := ParseAuxDeviceName(auxDev)
+	if err != nil {
+		return ""
+	}
+	return name.Type
+}
+
+// GetAuxNetDevicesFromPciByType returns a list of auxiliary devices names of the given type (e.g AuxDevTypeSf)
+// for the specified PCI network device
+func GetAuxNetDevicesFromPciByType(pciAddr, devType string) ([]string, error) {
+	devs, err := GetAuxNetDevicesFromPci(pciAddr)
+	if err != nil {
+		return nil, err
+	}
+	auxDevs := make([]string, 0, len(devs))
+	for _, dev := range devs {
+		if auxDevType(dev) == devType {
+			auxDevs = append(auxDevs, dev)
+		}
+	}
+	return auxDevs, nil
+}
+
 // GetAuxSFDevByPciAndSFIndex returns auxiliary SF device name which is associated with the given parent PCI address
 // and SF index. returns error if an error occurred. returns ErrDeviceNotFound error if device is not found.
 func GetAuxSFDevByPciAndSFIndex(pciAddress string, sfIndex uint32) (string, error) {
-	devs, err := GetAuxNetDevicesFromPci(pciAddress)
+	devs, err := GetAuxNetDevicesFromPciByType(pciAddress, AuxDevTypeSf)
 	if err != nil {
 		return "", err
 	}
 
 	for _, dev := range devs {
-		// skip non sf devices
-		if !strings.Contains(dev, ".sf.") {
-			continue
-		}
-
 		idx, err := GetSfIndexByAuxDev(dev)
 		if err != nil || idx < 0 {
 			continue
@@ -140,3 +258,126 @@ func GetAuxSFDevByPciAndSFIndex(pciAddress string, sfIndex uint32) (string, erro
 	}
 	return "", ErrDeviceNotFound
 }
+
+// GetSfNetdevByPfAndSfIndex returns the netdev name of the SF with the given sfnum on the PF with the given
+// PCI address. returns ErrDeviceNotFound error if the SF auxiliary device is not found and ErrNoNetDevice error
+// if the SF has no netdev (yet), e.g when the SF is not probed by its driver.
+func GetSfNetdevByPfAndSfIndex(pfPciAddress string, sfIndex uint32) (string, error) {
+	auxDev, err := GetAuxSFDevByPciAndSFIndex(pfPciAddress, sfIndex)
+	if err != nil {
+		return "", err
+	}
+	netDevs, err := GetNetDevicesFromAux(auxDev)
+	if err != nil || len(netDevs) == 0 {
+		return "", fmt.Errorf("%w: SF %d auxiliary device %s", ErrNoNetDevice, sfIndex, auxDev)
+	}
+	return netDevs[0], nil
+}
+
+// GetSfIndexByNetdev returns the sfnum of the SF associated with the given netdev, which is either the SF netdev
+// (e.g 'enp3s0f0s88') or the SF representor (e.g 'en3f0pf0sf88')
+func GetSfIndexByNetdev(netdev string) (int, error) {
+	// SF representor, sfnum is part of its phys_port_name
+	if physPortName, err := GetPhysPortName(netdev); err == nil {
+		if sfIndex, err := sfIndexFromPortName(physPortName); err == nil {
+			return sfIndex, nil
+		}
+	}
+
+	// SF netdev, its device is the SF auxiliary device
+	devLink, err := utilfs.Fs.Readlink(filepath.Join(NetSysDir, netdev, pcidevPrefix))
+	if err != nil {
+		return -1, fmt.Errorf("failed to find device of netdev %s: %v", netdev, err)
+	}
+	auxDev := filepath.Base(devLink)
+	if !auxiliaryDeviceRe.MatchString(auxDev) || auxDevType(auxDev) != AuxDevTypeSf {
+		return -1, fmt.Errorf("netdev %s is neither a SF netdev nor a SF representor", netdev)
+	}
+	return GetSfIndexByAuxDev(auxDev)
+}
+
+// isAuxDevProbed returns true if the given auxiliary device exists and has a netdev
+func isAuxDevProbed(auxDev string) bool {
+	netDevs, err := GetNetDevicesFromAux(auxDev)
+	return err == nil && len(netDevs) > 0
+}
+
+// WaitForAuxDev waits up to timeout for the given auxiliary device (e.g 'mlx5_core.sf.2') to exist
+// and to be probed, i.e to have a netdev
+func WaitForAuxDev(auxDev string, timeout time.Duration) error {
+	if !pollUntil(timeout, auxPollInterval, func() bool { return isAuxDevProbed(auxDev) }) {
+		return fmt.Errorf("timed out waiting for auxiliary device %s to be probed", auxDev)
+	}
+	return nil
+}
+
+// WaitForSfAuxDev waits up to timeout for the auxiliary device of the SF with the given sfnum on the PF with
+// the given PCI address to exist and to be probed, i.e to have a netdev. Returns the auxiliary device name.
+func WaitForSfAuxDev(pfPciAddress string, sfIndex uint32, timeout time.Duration) (string, error) {
+	pfPciAddress, err := normalizePciAddress(pfPciAddress)
+	if err != nil {
+		return "", err
+	}
+	var auxDev string
+	probed := pollUntil(timeout, auxPollInterval, func() bool {
+		auxDev, err = GetAuxSFDevByPciAndSFIndex(pfPciAddress, sfIndex)
+		return err == nil && isAuxDevProbed(auxDev)
+	})
+	if !probed {
+		return "", fmt.Errorf("timed out waiting for auxiliary device of SF %d on %s to be probed",
+			sfIndex, pfPciAddress)
+	}
+	return auxDev, nil
+}
+
+// GetDriverNameByAuxDev returns the name of the driver (e.g 'mlx5_core.sf') the given auxiliary device
+// (e.g 'mlx5_core.sf.2') is bound to. Returns ErrNoDriver if the device is not bound to any driver.
+func GetDriverNameByAuxDev(auxDev string) (string, error) {
+	auxPath := filepath.Join(AuxSysDir, auxDev)
+	if _, err := utilfs.Fs.Stat(auxPath); err != nil {
+		return "", fmt.Errorf("auxiliary device %s not found: %v", auxDev, err)
+	}
+	driverPath, err := utilfs.Fs.Readlink(filepath.Join(auxPath, auxDevDriverLink))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", ErrNoDriver
+		}
+		return "", fmt.Errorf("failed to read driver link of %s: %v", auxDev, err)
+	}
+	return filepath.Base(driverPath), nil
+}
+
+// BindAuxDevToDriver binds the given auxiliary device (e.g 'mlx5_core.sf.2') to the specified
+// auxiliary driver (e.g 'mlx5_core.sf').
+func BindAuxDevToDriver(auxDev, driver string) error {
+	bindFile := filepath.Join(AuxDriversSysDir, driver, netdevBindFile)
+	if err := writePciSysfsFile(bindFile, auxDev); err != nil {
+		return fmt.Errorf("failed to bind %s to driver %s: %v", auxDev, driver, err)
+	}
+	return nil
+}
+
+// UnbindAuxDev unbinds the given auxiliary device (e.g 'mlx5_core.sf.2') from its current driver.
+// It is a no-op if the device is not bound to any driver.
+func UnbindAuxDev(auxDev string) error {
+	if _, err := GetDriverNameByAuxDev(auxDev); err != nil {
+		if errors.Is(err, ErrNoDriver) {
+			return nil
+		}
+		return err
+	}
+	unbindFile := filepath.Join(AuxSysDir, auxDev, auxDevDriverLink, netdevUnbindFile)
+	if err := writePciSysfsFile(unbindFile, auxDev); err != nil {
+		return fmt.Errorf("failed to unbind %s: %v", auxDev, err)
+	}
+	return nil
+}
+
+// ProbeAuxDriver asks the kernel to probe drivers for the given auxiliary device (e.g 'mlx5_core.sf.2'),
+// e.g to rebind it to its default driver after UnbindAuxDev.
+func ProbeAuxDriver(auxDev string) error {
+	if err := writePciSysfsFile(auxDriversProbeFile, auxDev); err != nil {
+		return fmt.Errorf("failed to probe driver for %s: %v", auxDev, err)
+	}
+	return nil
+}
@@ -20,6 +20,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -66,6 +67,21 @@ func TestGetNetDevicesFromAuxSuccess(t *testing.T) {
 	assert.Equal(t, netDevName, devNames[0])
 }
 
+func TestGetRdmaDevicesFromAux(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+	auxDevName := "mlx5_core.sf.0"
+	path := filepath.Join(AuxSysDir, auxDevName, "infiniband", "mlx5_2")
+	_ = utilfs.Fs.MkdirAll(path, os.FileMode(0755))
+
+	rdmaDevs, err := GetRdmaDevicesFromAux(auxDevName)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"mlx5_2"}, rdmaDevs)
+
+	_, err = GetRdmaDevicesFromAux("mlx5_core.sf.1")
+	assert.Error(t, err)
+}
+
 func TestGetNetDevicesFromAuxErrorNoAux(t *testing.T) {
 	teardown := setupFakeFs(t)
 	defer teardown()
@@ -232,6 +248,26 @@ func TestGetAuxNetDevicesFromPciSuccess(t *testing.T) {
 	assert.Equal(t, auxDevs, devs)
 }
 
+func TestGetAuxNetDevicesFromPciByType(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+	pciAddr := "0000:03:00.0"
+	createPciDevicePaths(t, pciAddr, []string{"net", "mlx5_core.eth.0", "mlx5_core.eth-rep.0",
+		"mlx5_core.rdma.0", "mlx5_core.sf.2", "mlx5_core.sf.3"})
+
+	auxDevs, err := GetAuxNetDevicesFromPciByType(pciAddr, AuxDevTypeSf)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"mlx5_core.sf.2", "mlx5_core.sf.3"}, auxDevs)
+
+	auxDevs, err = GetAuxNetDevicesFromPciByType(pciAddr, AuxDevTypeEthRep)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"mlx5_core.eth-rep.0"}, auxDevs)
+
+	auxDevs, err = GetAuxNetDevicesFromPciByType(pciAddr, "foo")
+	assert.NoError(t, err)
+	assert.Empty(t, auxDevs)
+}
+
 func TestGetAuxNetDevicesFromPciSuccessNoDevices(t *testing.T) {
 	teardown := setupFakeFs(t)
 	defer teardown()
@@ -324,3 +360,196 @@ func TestGetAuxSFDevByPciAndSFIndexPCIAddressNotFound(t *testing.T) {
 	assert.Error(t, err)
 	assert.NotEqual(t, ErrDeviceNotFound, err)
 }
+
+func TestGetSfNetdevByPfAndSfIndex(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+
+	pciAddr := "0000:03:00.0"
+	setUpAuxDevEnv(t, []auxDevContext{
+		{parent: pciAddr, sfNum: "88", name: "mlx5_core.sf.2"},
+		{parent: pciAddr, sfNum: "89", name: "mlx5_core.sf.3"},
+	})
+	createPciDevicePaths(t, pciAddr, []string{"net", "mlx5_core.sf.2/net/enp3s0f0s88"})
+
+	netDev, err := GetSfNetdevByPfAndSfIndex(pciAddr, 88)
+	assert.NoError(t, err)
+	assert.Equal(t, "enp3s0f0s88", netDev)
+
+	_, err = GetSfNetdevByPfAndSfIndex(pciAddr, 89)
+	assert.ErrorIs(t, err, ErrNoNetDevice)
+
+	_, err = GetSfNetdevByPfAndSfIndex(pciAddr, 90)
+	assert.ErrorIs(t, err, ErrDeviceNotFound)
+}
+
+func TestGetSfIndexByNetdev(t *testing.T) {
+	teardown := setupSfRepresentorEnv(t, []*repContext{
+		{Name: "en3f0pf0sf88", PhysPortName: "pf0sf88"},
+		{Name: "pf0vf1", PhysPortName: "pf0vf1"},
+	})
+	defer teardown()
+
+	pciAddr := "0000:03:00.0"
+	setUpAuxDevEnv(t, []auxDevContext{{parent: pciAddr, sfNum: "89", name: "mlx5_core.sf.3"}})
+	assert.NoError(t, utilfs.Fs.MkdirAll(filepath.Join(NetSysDir, "enp3s0f0s89"), os.FileMode(0755)))
+	assert.NoError(t, utilfs.Fs.Symlink(filepath.Join(PciSysDir, pciAddr, "mlx5_core.sf.3"),
+		filepath.Join(NetSysDir, "enp3s0f0s89", "device")))
+
+	sfIndex, err := GetSfIndexByNetdev("en3f0pf0sf88")
+	assert.NoError(t, err)
+	assert.Equal(t, 88, sfIndex)
+
+	sfIndex, err = GetSfIndexByNetdev("enp3s0f0s89")
+	assert.NoError(t, err)
+	assert.Equal(t, 89, sfIndex)
+
+	_, err = GetSfIndexByNetdev("pf0vf1")
+	assert.Error(t, err)
+}
+
+func TestWaitForAuxDev(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+
+	pciAddr := "0000:03:00.0"
+	setUpAuxDevEnv(t, []auxDevContext{
+		{parent: pciAddr, sfNum: "88", name: "mlx5_core.sf.2"},
+		{parent: pciAddr, sfNum: "89", name: "mlx5_core.sf.3"},
+	})
+	createPciDevicePaths(t, pciAddr, []string{"net"})
+	// the goroutine must not outlive the fake FS, nor read utilfs.Fs which is reset by the next test
+	fs := utilfs.Fs
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		time.Sleep(200 * time.Millisecond)
+		_ = fs.MkdirAll(filepath.Join(PciSysDir, pciAddr, "mlx5_core.sf.2", "net", "enp3s0f0s88"),
+			os.FileMode(0755))
+	}()
+	defer func() { <-done }()
+
+	assert.NoError(t, WaitForAuxDev("mlx5_core.sf.2", 2*time.Second))
+	assert.Error(t, WaitForAuxDev("mlx5_core.sf.3", 200*time.Millisecond))
+
+	auxDev, err := WaitForSfAuxDev(pciAddr, 88, time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, "mlx5_core.sf.2", auxDev)
+	_, err = WaitForSfAuxDev(pciAddr, 90, 200*time.Millisecond)
+	assert.Error(t, err)
+}
+
+// setUpAuxDriverEnv creates a (fake) auxiliary driver and binds the given auxiliary devices of parent to it.
+// Note: auxiliary devices should be created beforehand with setUpAuxDevEnv.
+func setUpAuxDriverEnv(t *testing.T, driver, parent string, boundDevs ...string) {
+	driverPath := filepath.Join(AuxDriversSysDir, driver)
+	assert.NoError(t, utilfs.Fs.MkdirAll(driverPath, os.FileMode(0755)))
+	for _, f := range []string{netdevBindFile, netdevUnbindFile} {
+		assert.NoError(t, utilfs.Fs.WriteFile(filepath.Join(driverPath, f), []byte(""), os.FileMode(0644)))
+	}
+	assert.NoError(t, utilfs.Fs.WriteFile(auxDriversProbeFile, []byte(""), os.FileMode(0644)))
+
+	for _, dev := range boundDevs {
+		devPath := filepath.Join(PciSysDir, parent, dev)
+		assert.NoError(t, utilfs.Fs.Symlink(driverPath, filepath.Join(devPath, auxDevDriverLink)))
+	}
+}
+
+func TestAuxDevDriverBinding(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+
+	pciAddr := "0000:03:00.0"
+	setUpAuxDevEnv(t, []auxDevContext{
+		{parent: pciAddr, sfNum: "88", name: "mlx5_core.sf.2"},
+		{parent: pciAddr, sfNum: "89", name: "mlx5_core.sf.3"},
+	})
+	setUpAuxDriverEnv(t, "mlx5_core.sf", pciAddr, "mlx5_core.sf.2")
+	driverPath := filepath.Join(AuxDriversSysDir, "mlx5_core.sf")
+
+	driver, err := GetDriverNameByAuxDev("mlx5_core.sf.2")
+	assert.NoError(t, err)
+	assert.Equal(t, "mlx5_core.sf", driver)
+	_, err = GetDriverNameByAuxDev("mlx5_core.sf.3")
+	assert.ErrorIs(t, err, ErrNoDriver)
+	_, err = GetDriverNameByAuxDev("mlx5_core.sf.4")
+	assert.Error(t, err)
+
+	assert.NoError(t, UnbindAuxDev("mlx5_core.sf.2"))
+	assert.Equal(t, "mlx5_core.sf.2", readFakeFile(t, filepath.Join(driverPath, netdevUnbindFile)))
+	assert.NoError(t, UnbindAuxDev("mlx5_core.sf.3"))
+
+	assert.NoError(t, BindAuxDevToDriver("mlx5_core.sf.3", "mlx5_core.sf"))
+	assert.Equal(t, "mlx5_core.sf.3", readFakeFile(t, filepath.Join(driverPath, netdevBindFile)))
+	assert.Error(t, BindAuxDevToDriver("mlx5_core.sf.3", "foo"))
+
+	assert.NoError(t, ProbeAuxDriver("mlx5_core.sf.3"))
+	assert.Equal(t, "mlx5_core.sf.3", readFakeFile(t, auxDriversProbeFile))
+}
+
+func TestParseAuxDeviceName(t *testing.T) {
+	tcases := []struct {
+		name       string
+		expected   AuxDeviceName
+		shouldFail bool
+	}{
+		{name: "mlx5_core.sf.2", expected: AuxDeviceName{Driver: "mlx5_core", Type: AuxDevTypeSf, ID: 2}},
+		{name: "mlx5_core.eth-rep.0", expected: AuxDeviceName{Driver: "mlx5_core", Type: AuxDevTypeEthRep, ID: 0}},
+		{name: "mlx5_core.sf.2.rdma.12", expected: AuxDeviceName{Driver: "mlx5_core.sf.2", Type: "rdma", ID: 12}},
+		{name: "mlx5_core.sf", shouldFail: true},
+		{name: "mlx5_core.sf.a", shouldFail: true},
+		{name: "foo", shouldFail: true},
+	}
+
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			auxName, err := ParseAuxDeviceName(tc.name)
+			if tc.shouldFail {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, auxName)
+			assert.Equal(t, tc.name, auxName.String())
+		})
+	}
+}
+
+func setUpSfAuxRepresentorEnv(t *testing.T, pciAddr string) func() {
+	teardown := setupSfRepresentorEnv(t, []*repContext{
+		{Name: "en3f0pf0sf88", PhysPortName: "pf0sf88"},
+		{Name: "en3f0pf0sf89", PhysPortName: "pf0sf89"},
+		{Name: "pf0vf0", PhysPortName: "pf0vf0"},
+	})
+	assert.NoError(t, setUpRepPhysFiles(&repContext{Name: "p0", PhysPortName: "p0", PhysSwitchID: "c2cfc60003a1420c"}))
+	createPciDevicePaths(t, pciAddr, []string{"net/p0"})
+	setUpAuxDevEnv(t, []auxDevContext{
+		{parent: pciAddr, sfNum: "88", name: "mlx5_core.sf.2"},
+		{parent: pciAddr, name: "mlx5_core.eth-rep.0"},
+	})
+	return teardown
+}
+
+func TestGetSfRepresentorFromAux(t *testing.T) {
+	teardown := setUpSfAuxRepresentorEnv(t, "0000:03:00.0")
+	defer teardown()
+
+	rep, err := GetSfRepresentorFromAux("mlx5_core.sf.2")
+	assert.NoError(t, err)
+	assert.Equal(t, "en3f0pf0sf88", rep)
+
+	_, err = GetSfRepresentorFromAux("mlx5_core.eth-rep.0")
+	assert.Error(t, err)
+}
+
+func TestGetSfRepresentorsFromEthRepAux(t *testing.T) {
+	teardown := setUpSfAuxRepresentorEnv(t, "0000:03:00.0")
+	defer teardown()
+
+	reps, err := GetSfRepresentorsFromEthRepAux("mlx5_core.eth-rep.0")
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]string{88: "en3f0pf0sf88", 89: "en3f0pf0sf89"}, reps)
+
+	_, err = GetSfRepresentorsFromEthRepAux("mlx5_core.sf.2")
+	assert.Error(t, err)
+}
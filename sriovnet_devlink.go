@@ -0,0 +1,542 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sriovnet
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+
+	"github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/netlinkops"
+)
+
+// DevlinkParamCMode is the configuration mode of a devlink parameter value
+type DevlinkParamCMode string
+
+const (
+	// DevlinkParamCModeRuntime values take effect immediately
+	DevlinkParamCModeRuntime DevlinkParamCMode = "runtime"
+	// DevlinkParamCModeDriverinit values take effect on the next driver initialization, e.g after devlink reload
+	DevlinkParamCModeDriverinit DevlinkParamCMode = "driverinit"
+	// DevlinkParamCModePermanent values are stored in the device non-volatile memory
+	DevlinkParamCModePermanent DevlinkParamCMode = "permanent"
+)
+
+// devlink dev info keys, see https://www.kernel.org/doc/html/latest/networking/devlink/devlink-info.html
+const (
+	devlinkInfoDriver       = "driver"
+	devlinkInfoSerialNumber = "serialNumber"
+	devlinkInfoBoardID      = "board.id"
+	devlinkInfoFwVersion    = "fw.version"
+	devlinkInfoFw           = "fw"
+	devlinkInfoFwPsid       = "fw.psid"
+)
+
+var devlinkParamCModes = map[DevlinkParamCMode]uint8{
+	DevlinkParamCModeRuntime:    nl.DEVLINK_PARAM_CMODE_RUNTIME,
+	DevlinkParamCModeDriverinit: nl.DEVLINK_PARAM_CMODE_DRIVERINIT,
+	DevlinkParamCModePermanent:  nl.DEVLINK_PARAM_CMODE_PERMANENT,
+}
+
+// DevlinkGetParam returns the value of the devlink parameter param (e.g 'flow_steering_mode') of the device
+// identified by bus and device (e.g 'pci', '0000:03:00.0') for the given configuration mode.
+// The value is of type uint8, uint16, uint32, string or bool depending on the parameter type.
+func DevlinkGetParam(bus, device, param string, cmode DevlinkParamCMode) (interface{}, error) {
+	cmodeValue, ok := devlinkParamCModes[cmode]
+	if !ok {
+		return nil, fmt.Errorf("invalid devlink param cmode %q", cmode)
+	}
+	p, err := netlinkops.GetNetlinkOps().DevlinkGetDeviceParamByName(bus, device, param)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get devlink param %s of %s/%s: %v", param, bus, device, err)
+	}
+	for _, value := range p.Values {
+		if value.CMODE == cmodeValue {
+			return value.Data, nil
+		}
+	}
+	return nil, fmt.Errorf("devlink param %s of %s/%s has no %s value", param, bus, device, cmode)
+}
+
+// DevlinkSetParam sets the value of the devlink parameter param (e.g 'flow_steering_mode') of the device
+// identified by bus and device (e.g 'pci', '0000:03:00.0') for the given configuration mode.
+// value may either match the parameter type (uint8, uint16, uint32, string or bool) or be its string
+// representation (e.g "16", "true") in which case it is converted according to the parameter type.
+// Values set in driverinit mode take effect after devlink reload.
+func DevlinkSetParam(bus, device, param string, cmode DevlinkParamCMode, value interface{}) error {
+	cmodeValue, ok := devlinkParamCModes[cmode]
+	if !ok {
+		return fmt.Errorf("invalid devlink param cmode %q", cmode)
+	}
+	p, err := netlinkops.GetNetlinkOps().DevlinkGetDeviceParamByName(bus, device, param)
+	if err != nil {
+		return fmt.Errorf("failed to get devlink param %s of %s/%s: %v", param, bus, device, err)
+	}
+	if strValue, isString := value.(string); isString {
+		if value, err = parseDevlinkParamValue(p.Type, strValue); err != nil {
+			return fmt.Errorf("invalid value for devlink param %s: %v", param, err)
+		}
+	}
+	if err = netlinkops.GetNetlinkOps().DevlinkSetDeviceParam(bus, device, param, cmodeValue, value); err != nil {
+		return fmt.Errorf("failed to set devlink param %s of %s/%s to %v: %v", param, bus, device, value, err)
+	}
+	return nil
+}
+
+// parseDevlinkParamValue converts the string representation of a devlink parameter value to the given param type
+func parseDevlinkParamValue(paramType uint8, value string) (interface{}, error) {
+	switch paramType {
+	case nl.DEVLINK_PARAM_TYPE_U8:
+		v, err := strconv.ParseUint(value, 0, 8)
+		return uint8(v), err
+	case nl.DEVLINK_PARAM_TYPE_U16:
+		v, err := strconv.ParseUint(value, 0, 16)
+		return uint16(v), err
+	case nl.DEVLINK_PARAM_TYPE_U32:
+		v, err := strconv.ParseUint(value, 0, 32)
+		return uint32(v), err
+	case nl.DEVLINK_PARAM_TYPE_BOOL:
+		return strconv.ParseBool(value)
+	case nl.DEVLINK_PARAM_TYPE_STRING:
+		return value, nil
+	default:
+		return nil, fmt.Errorf("unsupported devlink param type %d", paramType)
+	}
+}
+
+// DevlinkReloadAction is a devlink reload action, as accepted by `devlink dev reload $dev action`
+type DevlinkReloadAction string
+
+const (
+	// DevlinkReloadActionDriverReinit reinitializes the driver, applying driverinit parameter values
+	DevlinkReloadActionDriverReinit DevlinkReloadAction = "driver_reinit"
+	// DevlinkReloadActionFwActivate activates a previously flashed firmware
+	DevlinkReloadActionFwActivate DevlinkReloadAction = "fw_activate"
+
+	devlinkReloadPollInterval = 100 * time.Millisecond
+)
+
+var devlinkReloadActions = map[DevlinkReloadAction]uint8{
+	DevlinkReloadActionDriverReinit: netlinkops.DevlinkReloadActionDriverReinit,
+	DevlinkReloadActionFwActivate:   netlinkops.DevlinkReloadActionFwActivate,
+}
+
+// DevlinkReloadOptions holds the options of DevlinkReload
+type DevlinkReloadOptions struct {
+	// NetnsPath is the network namespace (e.g '/var/run/netns/ns1') to reload the device into,
+	// empty to stay in the current network namespace
+	NetnsPath string
+	// Timeout is the time to wait for the device netdevs, and its representors when in switchdev mode,
+	// to come back after the reload. Zero does not wait. Waiting is not supported with NetnsPath.
+	Timeout time.Duration
+}
+
+// DevlinkReload reloads the devlink instance of the PCI device with the given address (e.g '0000:03:00.0'),
+// e.g to apply driverinit parameters such as flow_steering_mode. opts may be nil.
+// Equivalent to: `devlink dev reload pci/0000:03:00.0 netns ns1 action driver_reinit`
+func DevlinkReload(pciAddress string, action DevlinkReloadAction, opts *DevlinkReloadOptions) error {
+	nlAction, ok := devlinkReloadActions[action]
+	if !ok {
+		return fmt.Errorf("invalid devlink reload action %q", action)
+	}
+	if opts == nil {
+		opts = &DevlinkReloadOptions{}
+	}
+	if opts.NetnsPath != "" && opts.Timeout > 0 {
+		return fmt.Errorf("waiting for a device reloaded into another network namespace is not supported")
+	}
+	pciAddress, err := normalizePciAddress(pciAddress)
+	if err != nil {
+		return err
+	}
+
+	nlOps := netlinkops.GetNetlinkOps()
+	var ready func() bool
+	if opts.Timeout > 0 {
+		// the eswitch mode and the number of VFs are preserved across driver reinit
+		dev, err := nlOps.DevLinkGetDeviceByName(devlinkPciBus, pciAddress)
+		if err != nil {
+			return fmt.Errorf("failed to get devlink device %s: %v", pciAddress, err)
+		}
+		numVfs := 0
+		if EswitchMode(dev.Attrs.Eswitch.Mode) == EswitchModeSwitchdev {
+			if numVfs, err = readPciIntAttr(pciAddress, netDevCurrentVfCountFile); err != nil {
+				return fmt.Errorf("failed to get number of VFs of %s: %v", pciAddress, err)
+			}
+		}
+		ready = func() bool {
+			return devlinkReloadDone(pciAddress, EswitchMode(dev.Attrs.Eswitch.Mode), numVfs)
+		}
+	}
+
+	attrs := netlinkops.DevlinkReloadAttrs{Action: nlAction, NetnsPath: opts.NetnsPath}
+	if _, err = nlOps.DevlinkReload(devlinkPciBus, pciAddress, attrs); err != nil {
+		return fmt.Errorf("failed to reload devlink device %s: %v", pciAddress, err)
+	}
+	if ready != nil && !pollUntil(opts.Timeout, devlinkReloadPollInterval, ready) {
+		return fmt.Errorf("timed out waiting for %s to come back after devlink reload", pciAddress)
+	}
+	return nil
+}
+
+// devlinkReloadDone returns true if the devlink instance and the netdevs of the PCI device with the given
+// address are back after a reload, along with its uplink and VF representors when in switchdev mode
+func devlinkReloadDone(pciAddress string, mode EswitchMode, numVfs int) bool {
+	if _, err := netlinkops.GetNetlinkOps().DevLinkGetDeviceByName(devlinkPciBus, pciAddress); err != nil {
+		return false
+	}
+	if netdevs, err := GetNetDevicesFromPci(pciAddress); err != nil || len(netdevs) == 0 {
+		return false
+	}
+	if mode == EswitchModeSwitchdev {
+		return switchdevRepresentorsReady(pciAddress, numVfs)
+	}
+	return true
+}
+
+// DevlinkDeviceInfo holds the devlink dev info of a device
+type DevlinkDeviceInfo struct {
+	// Driver is the name of the driver of the device, e.g mlx5_core
+	Driver string
+	// SerialNumber is the serial number of the device, on ConnectX NICs this is the board serial number
+	SerialNumber string
+	// BoardID is the board design identifier, e.g MT_0000000359
+	BoardID string
+	// FwVersion is the running firmware version
+	FwVersion string
+	// FwPsid is the parameter set identifier of the firmware, e.g MT_0000000359
+	FwPsid string
+	// Versions holds every version reported by the device keyed by name, e.g fw.version, fw.psid
+	Versions map[string]string
+}
+
+// GetDevlinkDeviceInfo returns the devlink dev info (driver, serial number, firmware versions)
+// of the PCI device with the given address (e.g '0000:03:00.0').
+// Equivalent to: `devlink dev info pci/0000:03:00.0`
+func GetDevlinkDeviceInfo(pciAddress string) (*DevlinkDeviceInfo, error) {
+	pciAddress, err := normalizePciAddress(pciAddress)
+	if err != nil {
+		return nil, err
+	}
+	infoMap, err := netlinkops.GetNetlinkOps().DevlinkGetDeviceInfoByNameAsMap(devlinkPciBus, pciAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get devlink device info of %s: %v", pciAddress, err)
+	}
+
+	info := &DevlinkDeviceInfo{
+		Driver:       infoMap[devlinkInfoDriver],
+		SerialNumber: infoMap[devlinkInfoSerialNumber],
+		BoardID:      infoMap[devlinkInfoBoardID],
+		FwVersion:    infoMap[devlinkInfoFwVersion],
+		FwPsid:       infoMap[devlinkInfoFwPsid],
+		Versions:     make(map[string]string),
+	}
+	if info.FwVersion == "" {
+		// some drivers report the firmware version under the generic "fw" name
+		info.FwVersion = infoMap[devlinkInfoFw]
+	}
+	for key, value := range infoMap {
+		if key != devlinkInfoDriver && key != devlinkInfoSerialNumber {
+			info.Versions[key] = value
+		}
+	}
+	return info, nil
+}
+
+// DevlinkHealthState is the state of a devlink health reporter
+type DevlinkHealthState string
+
+const (
+	DevlinkHealthStateHealthy DevlinkHealthState = "healthy"
+	DevlinkHealthStateError   DevlinkHealthState = "error"
+	DevlinkHealthStateUnknown DevlinkHealthState = "unknown"
+)
+
+// DevlinkHealthReporter describes a devlink health reporter (e.g fw_fatal) of a device or of one of its ports
+type DevlinkHealthReporter struct {
+	Name string
+	// PortIndex is the devlink port index of port reporters, nil for device reporters
+	PortIndex    *uint32
+	State        DevlinkHealthState
+	ErrorCount   uint64
+	RecoverCount uint64
+	// GracefulPeriod is the minimal time between two auto recoveries
+	GracefulPeriod time.Duration
+	AutoRecover    bool
+	// LastDump is the time of the last error dump, zero if no dump is available
+	LastDump time.Time
+}
+
+// GetDevlinkHealthReporters returns the devlink health reporters of the PCI device with the given address
+// (e.g '0000:03:00.0') and of its ports.
+// Equivalent to: `devlink health show pci/0000:03:00.0`
+func GetDevlinkHealthReporters(pciAddress string) ([]*DevlinkHealthReporter, error) {
+	pciAddress, err := normalizePciAddress(pciAddress)
+	if err != nil {
+		return nil, err
+	}
+	nlReporters, err := netlinkops.GetNetlinkOps().DevlinkGetHealthReporters(devlinkPciBus, pciAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get devlink health reporters of %s: %v", pciAddress, err)
+	}
+
+	reporters := make([]*DevlinkHealthReporter, 0, len(nlReporters))
+	for _, nlReporter := range nlReporters {
+		reporter := &DevlinkHealthReporter{
+			Name:           nlReporter.Name,
+			State:          DevlinkHealthStateUnknown,
+			ErrorCount:     nlReporter.ErrorCount,
+			RecoverCount:   nlReporter.RecoverCount,
+			GracefulPeriod: time.Duration(nlReporter.GracefulPeriod) * time.Millisecond,
+			AutoRecover:    nlReporter.AutoRecover,
+		}
+		switch nlReporter.State {
+		case netlinkops.DevlinkHealthReporterStateHealthy:
+			reporter.State = DevlinkHealthStateHealthy
+		case netlinkops.DevlinkHealthReporterStateError:
+			reporter.State = DevlinkHealthStateError
+		}
+		if nlReporter.IsPortReporter {
+			portIndex := nlReporter.PortIndex
+			reporter.PortIndex = &portIndex
+		}
+		if nlReporter.DumpTimestampNs != 0 {
+			reporter.LastDump = time.Unix(0, int64(nlReporter.DumpTimestampNs))
+		}
+		reporters = append(reporters, reporter)
+	}
+	return reporters, nil
+}
+
+// Well known devlink resource paths
+const (
+	// DevlinkResourceMaxLocalSFs is the maximum number of SFs which can be created on the local host (mlx5)
+	DevlinkResourceMaxLocalSFs = "/max_local_SFs"
+	// DevlinkResourceMaxExternalSFs is the maximum number of SFs which can be created for the external host (mlx5)
+	DevlinkResourceMaxExternalSFs = "/max_external_SFs"
+)
+
+// DevlinkResource describes a devlink resource of a device, e.g the maximum number of SFs
+type DevlinkResource struct {
+	// Path of the resource in the resources tree, e.g /max_local_SFs or /kvd/linear
+	Path string
+	Name string
+	ID   uint64
+	// Size is the current size of the resource
+	Size uint64
+	// SizeNew is the size that will be applied on the next devlink reload, equals Size if no change is pending
+	SizeNew         uint64
+	SizeMin         uint64
+	SizeMax         uint64
+	SizeGranularity uint64
+	PendingChange   bool
+	// Occupancy is the current occupancy of the resource, nil if not reported
+	Occupancy *uint64
+	Children  []*DevlinkResource
+}
+
+// newDevlinkResource converts a netlink devlink resource and its children to DevlinkResource
+func newDevlinkResource(parentPath string, nlResource *netlink.DevlinkResource) *DevlinkResource {
+	resource := &DevlinkResource{
+		Path:            path.Join(parentPath, nlResource.Name),
+		Name:            nlResource.Name,
+		ID:              nlResource.ID,
+		Size:            nlResource.Size,
+		SizeNew:         nlResource.SizeNew,
+		SizeMin:         nlResource.SizeMin,
+		SizeMax:         nlResource.SizeMax,
+		SizeGranularity: nlResource.SizeGranularity,
+		PendingChange:   nlResource.PendingChange,
+		Children:        make([]*DevlinkResource, 0, len(nlResource.Children)),
+	}
+	if nlResource.OCCValid {
+		occupancy := nlResource.OCCSize
+		resource.Occupancy = &occupancy
+	}
+	for i := range nlResource.Children {
+		resource.Children = append(resource.Children, newDevlinkResource(resource.Path, &nlResource.Children[i]))
+	}
+	return resource
+}
+
+// GetDevlinkResources returns the devlink resources tree of the PCI device with the given address
+// (e.g '0000:03:00.0').
+// Equivalent to: `devlink resource show pci/0000:03:00.0`
+func GetDevlinkResources(pciAddress string) ([]*DevlinkResource, error) {
+	pciAddress, err := normalizePciAddress(pciAddress)
+	if err != nil {
+		return nil, err
+	}
+	nlResources, err := netlinkops.GetNetlinkOps().DevlinkGetDeviceResources(devlinkPciBus, pciAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get devlink resources of %s: %v", pciAddress, err)
+	}
+	resources := make([]*DevlinkResource, 0, len(nlResources.Resources))
+	for i := range nlResources.Resources {
+		resources = append(resources, newDevlinkResource("/", &nlResources.Resources[i]))
+	}
+	return resources, nil
+}
+
+// GetDevlinkResource returns the devlink resource with the given path (e.g DevlinkResourceMaxLocalSFs)
+// of the PCI device with the given address (e.g '0000:03:00.0')
+func GetDevlinkResource(pciAddress, resourcePath string) (*DevlinkResource, error) {
+	resources, err := GetDevlinkResources(pciAddress)
+	if err != nil {
+		return nil, err
+	}
+	if resource := findDevlinkResource(resources, resourcePath); resource != nil {
+		return resource, nil
+	}
+	return nil, fmt.Errorf("devlink resource %s not found for %s", resourcePath, pciAddress)
+}
+
+// findDevlinkResource returns the resource with the given path in the resources tree, nil if not found
+func findDevlinkResource(resources []*DevlinkResource, resourcePath string) *DevlinkResource {
+	for len(resources) > 0 {
+		var children []*DevlinkResource
+		for _, resource := range resources {
+			if resource.Path == resourcePath {
+				return resource
+			}
+			children = append(children, resource.Children...)
+		}
+		resources = children
+	}
+	return nil
+}
+
+// DevlinkRate describes a devlink rate object of a device, either a port (leaf) rate or a rate group (node)
+type DevlinkRate struct {
+	// Group is the name of rate groups, empty for port rates
+	Group string
+	// PortIndex is the devlink port index of port rates, nil for rate groups
+	PortIndex *uint32
+	// TxShare is the guaranteed tx rate in bytes per second
+	TxShare uint64
+	// TxMax is the maximal tx rate in bytes per second, 0 if unlimited
+	TxMax uint64
+	// Parent is the name of the rate group the rate object is assigned to, empty if none
+	Parent string
+}
+
+// GetDevlinkRates returns the devlink rate objects (port rates and rate groups) of the PCI device with the
+// given address (e.g '0000:03:00.0').
+// Equivalent to: `devlink port function rate show pci/0000:03:00.0`
+func GetDevlinkRates(pciAddress string) ([]*DevlinkRate, error) {
+	pciAddress, err := normalizePciAddress(pciAddress)
+	if err != nil {
+		return nil, err
+	}
+	nlRates, err := netlinkops.GetNetlinkOps().DevlinkGetRates(devlinkPciBus, pciAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get devlink rates of %s: %v", pciAddress, err)
+	}
+
+	rates := make([]*DevlinkRate, 0, len(nlRates))
+	for _, nlRate := range nlRates {
+		rate := &DevlinkRate{TxShare: nlRate.TxShare, TxMax: nlRate.TxMax, Parent: nlRate.ParentNodeName}
+		if nlRate.Type == netlinkops.DevlinkRateTypeNode {
+			rate.Group = nlRate.NodeName
+		} else {
+			portIndex := nlRate.PortIndex
+			rate.PortIndex = &portIndex
+		}
+		rates = append(rates, rate)
+	}
+	return rates, nil
+}
+
+// CreateDevlinkRateGroup creates a devlink rate group on the PCI device with the given address
+// (e.g '0000:03:00.0'). VF and SF representors can then be assigned to it with SetRepresentorRateGroup.
+// Equivalent to: `devlink port function rate add pci/0000:03:00.0/group1`
+func CreateDevlinkRateGroup(pciAddress, group string) error {
+	pciAddress, err := normalizePciAddress(pciAddress)
+	if err != nil {
+		return err
+	}
+	if err = netlinkops.GetNetlinkOps().DevlinkRateNodeNew(devlinkPciBus, pciAddress, group); err != nil {
+		return fmt.Errorf("failed to create devlink rate group %s on %s: %v", group, pciAddress, err)
+	}
+	return nil
+}
+
+// DeleteDevlinkRateGroup deletes a devlink rate group of the PCI device with the given address.
+// Equivalent to: `devlink port function rate del pci/0000:03:00.0/group1`
+func DeleteDevlinkRateGroup(pciAddress, group string) error {
+	pciAddress, err := normalizePciAddress(pciAddress)
+	if err != nil {
+		return err
+	}
+	if err = netlinkops.GetNetlinkOps().DevlinkRateNodeDel(devlinkPciBus, pciAddress, group); err != nil {
+		return fmt.Errorf("failed to delete devlink rate group %s of %s: %v", group, pciAddress, err)
+	}
+	return nil
+}
+
+// SetDevlinkRateGroupLimits sets the guaranteed (txShare) and maximal (txMax, 0 for unlimited) tx rates
+// in bytes per second of a devlink rate group of the PCI device with the given address.
+// Equivalent to: `devlink port function rate set pci/0000:03:00.0/group1 tx_share 10Mbit tx_max 100Mbit`
+func SetDevlinkRateGroupLimits(pciAddress, group string, txShare, txMax uint64) error {
+	pciAddress, err := normalizePciAddress(pciAddress)
+	if err != nil {
+		return err
+	}
+	attrs := netlinkops.DevlinkRateSetAttrs{TxShare: &txShare, TxMax: &txMax}
+	if err = netlinkops.GetNetlinkOps().DevlinkRateNodeSet(devlinkPciBus, pciAddress, group, attrs); err != nil {
+		return fmt.Errorf("failed to set devlink rate group %s of %s limits: %v", group, pciAddress, err)
+	}
+	return nil
+}
+
+// setRepresentorRate sets the rate attributes of the devlink port of a representor
+func setRepresentorRate(repNetdev string, attrs netlinkops.DevlinkRateSetAttrs) error {
+	nlOps := netlinkops.GetNetlinkOps()
+	port, err := nlOps.DevLinkGetPortByNetdevName(repNetdev)
+	if err != nil {
+		return fmt.Errorf("failed to get devlink port of %s: %v", repNetdev, err)
+	}
+	if port.PortFlavour != uint16(PORT_FLAVOUR_PCI_VF) && port.PortFlavour != uint16(PORT_FLAVOUR_PCI_SF) {
+		return fmt.Errorf("unsupported port flavour for netdev %s", repNetdev)
+	}
+	return nlOps.DevlinkPortRateSet(port.BusName, port.DeviceName, port.PortIndex, attrs)
+}
+
+// SetRepresentorRateLimits sets the guaranteed (txShare) and maximal (txMax, 0 for unlimited) tx rates in
+// bytes per second of the function represented by the given VF or SF representor.
+// Equivalent to: `devlink port function rate set pci/0000:03:00.0/1 tx_share 10Mbit tx_max 100Mbit`
+func SetRepresentorRateLimits(repNetdev string, txShare, txMax uint64) error {
+	if err := setRepresentorRate(repNetdev,
+		netlinkops.DevlinkRateSetAttrs{TxShare: &txShare, TxMax: &txMax}); err != nil {
+		return fmt.Errorf("failed to set rate limits of %s: %v", repNetdev, err)
+	}
+	return nil
+}
+
+// SetRepresentorRateGroup assigns the function represented by the given VF or SF representor to a devlink
+// rate group, an empty group unassigns it.
+// Equivalent to: `devlink port function rate set pci/0000:03:00.0/1 parent group1`
+func SetRepresentorRateGroup(repNetdev, group string) error {
+	if err := setRepresentorRate(repNetdev, netlinkops.DevlinkRateSetAttrs{ParentNodeName: &group}); err != nil {
+		return fmt.Errorf("failed to set rate group of %s: %v", repNetdev, err)
+	}
+	return nil
+}
@@ -0,0 +1,327 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sriovnet
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+
+	"github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/netlinkops"
+	netlinkopsMocks "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/netlinkops/mocks"
+)
+
+func TestDevlinkGetParam(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+
+	nlOpsMock.On("DevlinkGetDeviceParamByName", "pci", "0000:03:00.0", "flow_steering_mode").Return(
+		&netlink.DevlinkParam{
+			Name: "flow_steering_mode",
+			Type: nl.DEVLINK_PARAM_TYPE_STRING,
+			Values: []netlink.DevlinkParamValue{
+				{Data: "smfs", CMODE: nl.DEVLINK_PARAM_CMODE_RUNTIME},
+			},
+		}, nil)
+
+	value, err := DevlinkGetParam("pci", "0000:03:00.0", "flow_steering_mode", DevlinkParamCModeRuntime)
+	assert.NoError(t, err)
+	assert.Equal(t, "smfs", value)
+
+	_, err = DevlinkGetParam("pci", "0000:03:00.0", "flow_steering_mode", DevlinkParamCModeDriverinit)
+	assert.Error(t, err)
+}
+
+func TestDevlinkGetParamError(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+
+	nlOpsMock.On("DevlinkGetDeviceParamByName", "pci", "0000:03:00.0", "foo").Return(nil, fmt.Errorf("no such param"))
+
+	_, err := DevlinkGetParam("pci", "0000:03:00.0", "foo", DevlinkParamCModeRuntime)
+	assert.Error(t, err)
+	_, err = DevlinkGetParam("pci", "0000:03:00.0", "foo", DevlinkParamCMode("boot"))
+	assert.Error(t, err)
+}
+
+func TestDevlinkSetParam(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+
+	nlOpsMock.On("DevlinkGetDeviceParamByName", "pci", "0000:03:00.0", "max_macs").Return(
+		&netlink.DevlinkParam{Name: "max_macs", Type: nl.DEVLINK_PARAM_TYPE_U32}, nil)
+	nlOpsMock.On("DevlinkSetDeviceParam", "pci", "0000:03:00.0", "max_macs",
+		uint8(nl.DEVLINK_PARAM_CMODE_DRIVERINIT), uint32(64)).Return(nil)
+	nlOpsMock.On("DevlinkGetDeviceParamByName", "pci", "0000:03:00.0", "enable_roce").Return(
+		&netlink.DevlinkParam{Name: "enable_roce", Type: nl.DEVLINK_PARAM_TYPE_BOOL}, nil)
+	nlOpsMock.On("DevlinkSetDeviceParam", "pci", "0000:03:00.0", "enable_roce",
+		uint8(nl.DEVLINK_PARAM_CMODE_DRIVERINIT), false).Return(nil)
+
+	err := DevlinkSetParam("pci", "0000:03:00.0", "max_macs", DevlinkParamCModeDriverinit, "64")
+	assert.NoError(t, err)
+	err = DevlinkSetParam("pci", "0000:03:00.0", "enable_roce", DevlinkParamCModeDriverinit, false)
+	assert.NoError(t, err)
+	nlOpsMock.AssertExpectations(t)
+
+	err = DevlinkSetParam("pci", "0000:03:00.0", "max_macs", DevlinkParamCModeDriverinit, "many")
+	assert.Error(t, err)
+}
+
+func TestGetDevlinkDeviceInfo(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+
+	nlOpsMock.On("DevlinkGetDeviceInfoByNameAsMap", "pci", "0000:03:00.0").Return(map[string]string{
+		"driver":       "mlx5_core",
+		"serialNumber": "MT2116X09299",
+		"fw.psid":      "MT_0000000359",
+		"fw.version":   "22.31.1014",
+		"fw":           "22.31.1014",
+	}, nil)
+
+	info, err := GetDevlinkDeviceInfo("0000:03:00.0")
+	assert.NoError(t, err)
+	assert.Equal(t, &DevlinkDeviceInfo{
+		Driver:       "mlx5_core",
+		SerialNumber: "MT2116X09299",
+		FwVersion:    "22.31.1014",
+		FwPsid:       "MT_0000000359",
+		Versions: map[string]string{
+			"fw.psid":    "MT_0000000359",
+			"fw.version": "22.31.1014",
+			"fw":         "22.31.1014",
+		},
+	}, info)
+}
+
+func TestGetDevlinkDeviceInfoError(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+
+	nlOpsMock.On("DevlinkGetDeviceInfoByNameAsMap", "pci", "0000:03:00.0").Return(nil, fmt.Errorf("not supported"))
+
+	_, err := GetDevlinkDeviceInfo("0000:03:00.0")
+	assert.Error(t, err)
+	_, err = GetDevlinkDeviceInfo("p0")
+	assert.ErrorIs(t, err, ErrInvalidPciAddress)
+}
+
+func TestGetDevlinkHealthReporters(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+
+	nlOpsMock.On("DevlinkGetHealthReporters", "pci", "0000:03:00.0").Return([]*netlinkops.DevlinkHealthReporter{
+		{
+			BusName:        "pci",
+			DeviceName:     "0000:03:00.0",
+			Name:           "fw_fatal",
+			State:          netlinkops.DevlinkHealthReporterStateError,
+			ErrorCount:     2,
+			RecoverCount:   1,
+			GracefulPeriod: 60000,
+			AutoRecover:    true,
+		},
+		{
+			BusName:         "pci",
+			DeviceName:      "0000:03:00.0",
+			PortIndex:       65535,
+			IsPortReporter:  true,
+			Name:            "tx",
+			State:           netlinkops.DevlinkHealthReporterStateHealthy,
+			DumpTimestampNs: 1700000000000000000,
+		},
+	}, nil)
+
+	reporters, err := GetDevlinkHealthReporters("0000:03:00.0")
+	assert.NoError(t, err)
+	assert.Len(t, reporters, 2)
+	assert.Equal(t, &DevlinkHealthReporter{
+		Name:           "fw_fatal",
+		State:          DevlinkHealthStateError,
+		ErrorCount:     2,
+		RecoverCount:   1,
+		GracefulPeriod: time.Minute,
+		AutoRecover:    true,
+	}, reporters[0])
+	assert.Equal(t, DevlinkHealthStateHealthy, reporters[1].State)
+	assert.Equal(t, uint32(65535), *reporters[1].PortIndex)
+	assert.Equal(t, time.Unix(0, 1700000000000000000), reporters[1].LastDump)
+}
+
+func TestGetDevlinkHealthReportersError(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+
+	nlOpsMock.On("DevlinkGetHealthReporters", "pci", "0000:03:00.0").Return(nil, fmt.Errorf("not supported"))
+
+	_, err := GetDevlinkHealthReporters("0000:03:00.0")
+	assert.Error(t, err)
+}
+
+func TestGetDevlinkResources(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+
+	nlOpsMock.On("DevlinkGetDeviceResources", "pci", "0000:03:00.0").Return(&netlink.DevlinkResources{
+		Bus:    "pci",
+		Device: "0000:03:00.0",
+		Resources: []netlink.DevlinkResource{
+			{Name: "max_local_SFs", ID: 1, Size: 128, SizeNew: 128, SizeMax: 256},
+			{Name: "kvd", ID: 2, Size: 1024, SizeNew: 1024, Children: []netlink.DevlinkResource{
+				{Name: "linear", ID: 3, Size: 512, SizeNew: 512, OCCValid: true, OCCSize: 10},
+			}},
+		},
+	}, nil)
+
+	resources, err := GetDevlinkResources("0000:03:00.0")
+	assert.NoError(t, err)
+	assert.Len(t, resources, 2)
+	assert.Equal(t, "/kvd/linear", resources[1].Children[0].Path)
+
+	resource, err := GetDevlinkResource("0000:03:00.0", DevlinkResourceMaxLocalSFs)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(128), resource.Size)
+	assert.Nil(t, resource.Occupancy)
+
+	resource, err = GetDevlinkResource("0000:03:00.0", "/kvd/linear")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(10), *resource.Occupancy)
+
+	_, err = GetDevlinkResource("0000:03:00.0", "/max_external_SFs")
+	assert.Error(t, err)
+}
+
+func TestGetDevlinkRates(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+
+	nlOpsMock.On("DevlinkGetRates", "pci", "0000:03:00.0").Return([]*netlinkops.DevlinkRate{
+		{BusName: "pci", DeviceName: "0000:03:00.0", Type: netlinkops.DevlinkRateTypeNode, NodeName: "group1",
+			TxMax: 12500000},
+		{BusName: "pci", DeviceName: "0000:03:00.0", Type: netlinkops.DevlinkRateTypeLeaf, PortIndex: 1,
+			TxShare: 1250000, ParentNodeName: "group1"},
+	}, nil)
+
+	rates, err := GetDevlinkRates("0000:03:00.0")
+	assert.NoError(t, err)
+	portIndex := uint32(1)
+	assert.Equal(t, []*DevlinkRate{
+		{Group: "group1", TxMax: 12500000},
+		{PortIndex: &portIndex, TxShare: 1250000, Parent: "group1"},
+	}, rates)
+}
+
+func TestDevlinkRateGroups(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+
+	txShare, txMax := uint64(1250000), uint64(12500000)
+	nlOpsMock.On("DevlinkRateNodeNew", "pci", "0000:03:00.0", "group1").Return(nil)
+	nlOpsMock.On("DevlinkRateNodeSet", "pci", "0000:03:00.0", "group1",
+		netlinkops.DevlinkRateSetAttrs{TxShare: &txShare, TxMax: &txMax}).Return(nil)
+	nlOpsMock.On("DevlinkRateNodeDel", "pci", "0000:03:00.0", "group1").Return(fmt.Errorf("resource busy"))
+
+	assert.NoError(t, CreateDevlinkRateGroup("0000:03:00.0", "group1"))
+	assert.NoError(t, SetDevlinkRateGroupLimits("0000:03:00.0", "group1", txShare, txMax))
+	assert.Error(t, DeleteDevlinkRateGroup("0000:03:00.0", "group1"))
+	assert.Error(t, CreateDevlinkRateGroup("03:00.0:1", "group1"))
+	nlOpsMock.AssertExpectations(t)
+}
+
+func TestSetRepresentorRate(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+
+	nlOpsMock.On("DevLinkGetPortByNetdevName", "pf0vf1").Return(&netlink.DevlinkPort{
+		BusName: "pci", DeviceName: "0000:03:00.0", PortIndex: 2, PortFlavour: nl.DEVLINK_PORT_FLAVOUR_PCI_VF}, nil)
+	nlOpsMock.On("DevLinkGetPortByNetdevName", "p0").Return(&netlink.DevlinkPort{
+		BusName: "pci", DeviceName: "0000:03:00.0", PortIndex: 1, PortFlavour: nl.DEVLINK_PORT_FLAVOUR_PHYSICAL}, nil)
+	txShare, txMax := uint64(0), uint64(12500000)
+	group := "group1"
+	nlOpsMock.On("DevlinkPortRateSet", "pci", "0000:03:00.0", uint32(2),
+		netlinkops.DevlinkRateSetAttrs{TxShare: &txShare, TxMax: &txMax}).Return(nil)
+	nlOpsMock.On("DevlinkPortRateSet", "pci", "0000:03:00.0", uint32(2),
+		netlinkops.DevlinkRateSetAttrs{ParentNodeName: &group}).Return(nil)
+
+	assert.NoError(t, SetRepresentorRateLimits("pf0vf1", txShare, txMax))
+	assert.NoError(t, SetRepresentorRateGroup("pf0vf1", group))
+	assert.Error(t, SetRepresentorRateGroup("p0", group))
+	nlOpsMock.AssertExpectations(t)
+}
+
+func TestDevlinkReload(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+	teardown := setUpSwitchdevInventoryEnv(t)
+	defer teardown()
+
+	nlOpsMock.On("DevLinkGetDeviceByName", "pci", "0000:03:00.0").Return(
+		newDevlinkDevice("0000:03:00.0", netlink.DevlinkDevEswitchAttr{Mode: "switchdev"}), nil)
+	nlOpsMock.On("DevlinkReload", "pci", "0000:03:00.0", netlinkops.DevlinkReloadAttrs{
+		Action: netlinkops.DevlinkReloadActionDriverReinit}).Return(uint32(1<<1), nil)
+	nlOpsMock.On("DevlinkReload", "pci", "0000:03:00.0", netlinkops.DevlinkReloadAttrs{
+		Action: netlinkops.DevlinkReloadActionFwActivate, NetnsPath: "/var/run/netns/ns1"}).Return(
+		uint32(0), fmt.Errorf("operation not supported"))
+
+	assert.NoError(t, DevlinkReload("0000:03:00.0", DevlinkReloadActionDriverReinit,
+		&DevlinkReloadOptions{Timeout: time.Second}))
+	assert.NoError(t, DevlinkReload("0000:03:00.0", DevlinkReloadActionDriverReinit, nil))
+	assert.Error(t, DevlinkReload("0000:03:00.0", DevlinkReloadActionFwActivate,
+		&DevlinkReloadOptions{NetnsPath: "/var/run/netns/ns1"}))
+	nlOpsMock.AssertExpectations(t)
+}
+
+func TestDevlinkReloadInvalidOptions(t *testing.T) {
+	assert.Error(t, DevlinkReload("0000:03:00.0", DevlinkReloadAction("bogus"), nil))
+	assert.Error(t, DevlinkReload("0000:03:00.0", DevlinkReloadActionDriverReinit,
+		&DevlinkReloadOptions{NetnsPath: "/var/run/netns/ns1", Timeout: time.Second}))
+}
+
+func TestDevlinkReloadTimeout(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+	teardown := setUpSwitchdevInventoryEnv(t)
+	defer teardown()
+
+	dev := newDevlinkDevice("0000:03:00.0", netlink.DevlinkDevEswitchAttr{Mode: "legacy"})
+	nlOpsMock.On("DevLinkGetDeviceByName", "pci", "0000:03:00.0").Return(dev, nil).Once()
+	nlOpsMock.On("DevlinkReload", "pci", "0000:03:00.0", mock.Anything).Return(uint32(0), nil)
+	// the devlink instance never comes back
+	nlOpsMock.On("DevLinkGetDeviceByName", "pci", "0000:03:00.0").Return(nil, fmt.Errorf("no such device"))
+
+	err := DevlinkReload("0000:03:00.0", DevlinkReloadActionDriverReinit,
+		&DevlinkReloadOptions{Timeout: 200 * time.Millisecond})
+	assert.Error(t, err)
+}
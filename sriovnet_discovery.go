@@ -0,0 +1,69 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sriovnet
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	utilfs "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/filesystem"
+)
+
+// SriovPf describes an SR-IOV capable physical function
+type SriovPf struct {
+	// PciAddress of the PF, e.g 0000:03:00.0
+	PciAddress string
+	// NetDevs are the network devices of the PF, empty if the PF has none, e.g when it is bound to vfio-pci
+	NetDevs []string
+	// TotalVfs is the maximum number of VFs supported by the PF
+	TotalVfs int
+}
+
+// ListSriovCapablePfs scans the PCI devices of the node and returns the SR-IOV capable PFs,
+// i.e devices exposing a non-zero sriov_totalvfs, ordered by PCI address.
+func ListSriovCapablePfs() ([]*SriovPf, error) {
+	devices, err := utilfs.Fs.ReadDir(PciSysDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list PCI devices: %v", err)
+	}
+
+	pfs := make([]*SriovPf, 0)
+	for _, device := range devices {
+		pciAddress := device.Name()
+		totalVfs, err := readPciIntAttr(pciAddress, netDevMaxVfCountFile)
+		if err != nil || totalVfs == 0 {
+			continue
+		}
+		netDevs, err := GetNetDevicesFromPci(pciAddress)
+		if err != nil {
+			netDevs = []string{}
+		}
+		pfs = append(pfs, &SriovPf{PciAddress: pciAddress, NetDevs: netDevs, TotalVfs: totalVfs})
+	}
+	return pfs, nil
+}
+
+// readPciIntAttr reads a decimal sysfs attribute (e.g sriov_totalvfs) of the PCI device with the given address
+func readPciIntAttr(pciAddress, attr string) (int, error) {
+	content, err := utilfs.Fs.ReadFile(filepath.Join(PciSysDir, pciAddress, attr))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(content)))
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sriovnet
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	utilfs "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/filesystem"
+)
+
+func TestListSriovCapablePfs(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+	setUpPciAttrs(t, "0000:03:00.0", map[string]string{netDevMaxVfCountFile: "8\n"})
+	assert.NoError(t, utilfs.Fs.MkdirAll(filepath.Join(PciSysDir, "0000:03:00.0", "net", "p0"), os.FileMode(0755)))
+	setUpPciAttrs(t, "00d5:01:00.1", map[string]string{netDevMaxVfCountFile: "16\n"})
+	// not SR-IOV capable
+	setUpPciAttrs(t, "0000:00:1f.0", map[string]string{})
+	setUpPciAttrs(t, "0000:03:00.1", map[string]string{netDevMaxVfCountFile: "0\n"})
+
+	pfs, err := ListSriovCapablePfs()
+	assert.NoError(t, err)
+	assert.Equal(t, []*SriovPf{
+		{PciAddress: "0000:03:00.0", NetDevs: []string{"p0"}, TotalVfs: 8},
+		{PciAddress: "00d5:01:00.1", NetDevs: []string{}, TotalVfs: 16},
+	}, pfs)
+}
+
+func TestListSriovCapablePfsNoPciDevices(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+
+	_, err := ListSriovCapablePfs()
+	assert.Error(t, err)
+}
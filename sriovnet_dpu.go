@@ -0,0 +1,151 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sriovnet
+
+import (
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	utilfs "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/filesystem"
+)
+
+const (
+	// DmiSysDir is the sysfs directory exposing the DMI identification (e.g product_name) of the platform
+	DmiSysDir = "/sys/class/dmi/id"
+
+	dmiProductNameFile = "product_name"
+	mellanoxVendorID   = 0x15b3
+)
+
+// DPU PCI device IDs (BlueField integrated ConnectX), these also show up on the host side of the DPU
+// hence they are only a hint when running on an ARM system.
+var dpuPciDeviceIDs = map[uint16]bool{
+	0xa2d2: true, // BlueField
+	0xa2d6: true, // BlueField-2
+	0xa2dc: true, // BlueField-3
+}
+
+// hostArch is the architecture the code runs on, a variable to allow unit testing
+var hostArch = runtime.GOARCH
+
+// DpuHint is an indication that the code runs on a DPU (SmartNIC ARM system)
+type DpuHint string
+
+const (
+	// DpuHintExternalController is reported when representors of external controllers (host PFs, VFs
+	// or SFs) exist on an eswitch
+	DpuHintExternalController DpuHint = "external-controller"
+	// DpuHintDmi is reported when the DMI product name is a DPU product
+	DpuHintDmi DpuHint = "dmi"
+	// DpuHintPciDevice is reported when DPU PCI functions are found on an ARM system
+	DpuHintPciDevice DpuHint = "pci-device"
+)
+
+// DpuInfo describes the DPU platform the code runs on
+type DpuInfo struct {
+	// Hints are the DPU indications found, the platform is a DPU if any
+	Hints []DpuHint
+	// ProductName is the DMI product name, empty if not available
+	ProductName string
+	// Controllers are the sorted external controller numbers with representors on the DPU eswitches
+	Controllers []int
+	// PciAddresses are the sorted addresses of the DPU PCI functions, set on ARM systems only
+	PciAddresses []string
+}
+
+// IsDpu returns true if any DPU indication was found
+func (i *DpuInfo) IsDpu() bool {
+	return len(i.Hints) > 0
+}
+
+// getExternalControllers returns the sorted external controller numbers of all the representors in the system
+func getExternalControllers() []int {
+	netdevs, err := utilfs.ReadDirNames(utilfs.Fs, NetSysDir)
+	if err != nil {
+		return nil
+	}
+	seen := make(map[int]bool)
+	controllers := make([]int, 0)
+	for _, meta := range getNetdevsSwitchMeta(netdevs) {
+		if len(meta.switchID) == 0 || meta.portName == "" {
+			continue
+		}
+		info, err := parseRepresentorPortName(meta.portName)
+		if err != nil || info.ControllerNum == 0 || seen[info.ControllerNum] {
+			continue
+		}
+		seen[info.ControllerNum] = true
+		controllers = append(controllers, info.ControllerNum)
+	}
+	sort.Ints(controllers)
+	return controllers
+}
+
+// getDpuPciAddresses returns the sorted addresses of the DPU PCI functions
+func getDpuPciAddresses() []string {
+	devices, err := utilfs.ReadDirNames(utilfs.Fs, PciSysDir)
+	if err != nil {
+		return nil
+	}
+	addresses := make([]string, 0)
+	for _, device := range devices {
+		vendor, err := readPciHexAttr(device, "vendor", 16)
+		if err != nil || vendor != mellanoxVendorID {
+			continue
+		}
+		deviceID, err := readPciHexAttr(device, "device", 16)
+		if err != nil || !dpuPciDeviceIDs[uint16(deviceID)] {
+			continue
+		}
+		addresses = append(addresses, device)
+	}
+	return addresses
+}
+
+// GetDpuInfo detects whether the code runs on a DPU (SmartNIC ARM system) using representors of external
+// controllers, the DMI product name and DPU PCI functions on ARM systems as hints.
+// Use DpuInfo.IsDpu() to select host or DPU code paths.
+func GetDpuInfo() *DpuInfo {
+	info := &DpuInfo{Hints: make([]DpuHint, 0)}
+
+	info.Controllers = getExternalControllers()
+	if len(info.Controllers) > 0 {
+		info.Hints = append(info.Hints, DpuHintExternalController)
+	}
+
+	if content, err := utilfs.Fs.ReadFile(filepath.Join(DmiSysDir, dmiProductNameFile)); err == nil {
+		info.ProductName = strings.TrimSpace(string(content))
+		if strings.Contains(strings.ToLower(info.ProductName), "bluefield") {
+			info.Hints = append(info.Hints, DpuHintDmi)
+		}
+	}
+
+	if hostArch == "arm64" {
+		info.PciAddresses = getDpuPciAddresses()
+		if len(info.PciAddresses) > 0 {
+			info.Hints = append(info.Hints, DpuHintPciDevice)
+		}
+	}
+	return info
+}
+
+// IsDpuPlatform returns true if the code runs on a DPU (SmartNIC ARM system), see GetDpuInfo
+func IsDpuPlatform() bool {
+	return GetDpuInfo().IsDpu()
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sriovnet
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	utilfs "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/filesystem"
+)
+
+// setUpPciIDs creates the vendor and device sysfs attributes of a PCI device
+func setUpPciIDs(t *testing.T, pciAddress, vendor, device string) {
+	pciPath := filepath.Join(PciSysDir, pciAddress)
+	assert.NoError(t, utilfs.Fs.MkdirAll(pciPath, os.FileMode(0755)))
+	assert.NoError(t, utilfs.Fs.WriteFile(filepath.Join(pciPath, "vendor"), []byte(vendor+"\n"), os.FileMode(0644)))
+	assert.NoError(t, utilfs.Fs.WriteFile(filepath.Join(pciPath, "device"), []byte(device+"\n"), os.FileMode(0644)))
+}
+
+func TestGetDpuInfoHost(t *testing.T) {
+	reps := []*repContext{
+		{"p0", "p0", "c2cfc60003a1420c"},
+		{"eth0", "pf0vf0", "c2cfc60003a1420c"},
+	}
+	teardown := setupRepresentorEnv(t, "", reps)
+	defer teardown()
+	// the DPU PCI functions are visible on the host too
+	setUpPciIDs(t, "0000:03:00.0", "0x15b3", "0xa2d6")
+	defer func(arch string) { hostArch = arch }(hostArch)
+	hostArch = "amd64"
+
+	info := GetDpuInfo()
+	assert.False(t, info.IsDpu())
+	assert.Empty(t, info.Controllers)
+	assert.Empty(t, info.PciAddresses)
+	assert.False(t, IsDpuPlatform())
+}
+
+func TestGetDpuInfoDpu(t *testing.T) {
+	reps := []*repContext{
+		{"p0", "p0", "c2cfc60003a1420c"},
+		{"pf0hpf", "c1pf0", "c2cfc60003a1420c"},
+		{"pf0vf0", "c1pf0vf0", "c2cfc60003a1420c"},
+		{"en3f0pf0sf0", "pf0sf0", "c2cfc60003a1420c"},
+		{"pf2vf0", "c2pf0vf0", "c2cfc60003a1420c"},
+	}
+	teardown := setupRepresentorEnv(t, "", reps)
+	defer teardown()
+	setUpPciIDs(t, "0000:03:00.0", "0x15b3", "0xa2d6")
+	setUpPciIDs(t, "0000:03:00.1", "0x15b3", "0xa2d6")
+	setUpPciIDs(t, "0000:00:00.0", "0x1b36", "0x0008")
+	assert.NoError(t, utilfs.Fs.MkdirAll(DmiSysDir, os.FileMode(0755)))
+	assert.NoError(t, utilfs.Fs.WriteFile(filepath.Join(DmiSysDir, dmiProductNameFile),
+		[]byte("BlueField-2 DPU 25GbE Dual-Port SFP56\n"), os.FileMode(0644)))
+	defer func(arch string) { hostArch = arch }(hostArch)
+	hostArch = "arm64"
+
+	info := GetDpuInfo()
+	assert.True(t, info.IsDpu())
+	assert.Equal(t, &DpuInfo{
+		Hints:        []DpuHint{DpuHintExternalController, DpuHintDmi, DpuHintPciDevice},
+		ProductName:  "BlueField-2 DPU 25GbE Dual-Port SFP56",
+		Controllers:  []int{1, 2},
+		PciAddresses: []string{"0000:03:00.0", "0000:03:00.1"},
+	}, info)
+	assert.True(t, IsDpuPlatform())
+}
@@ -0,0 +1,280 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sriovnet
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	utilfs "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/filesystem"
+	"github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/netlinkops"
+)
+
+// EswitchMode is the mode of the embedded switch of a PF
+type EswitchMode string
+
+// EswitchEncapMode is the encapsulation (tunnel offload) mode of the embedded switch of a PF
+type EswitchEncapMode string
+
+// EswitchInlineMode is the minimal packet header inlined in WQEs by the embedded switch of a PF,
+// required by ConnectX-4/ConnectX-4 Lx for offloads to work in switchdev mode
+type EswitchInlineMode string
+
+const (
+	EswitchModeLegacy    EswitchMode = "legacy"
+	EswitchModeSwitchdev EswitchMode = "switchdev"
+
+	EswitchEncapModeNone  EswitchEncapMode = netlinkops.DevlinkEswitchEncapModeNone
+	EswitchEncapModeBasic EswitchEncapMode = netlinkops.DevlinkEswitchEncapModeBasic
+
+	EswitchInlineModeNone      EswitchInlineMode = netlinkops.DevlinkEswitchInlineModeNone
+	EswitchInlineModeLink      EswitchInlineMode = netlinkops.DevlinkEswitchInlineModeLink
+	EswitchInlineModeNetwork   EswitchInlineMode = netlinkops.DevlinkEswitchInlineModeNetwork
+	EswitchInlineModeTransport EswitchInlineMode = netlinkops.DevlinkEswitchInlineModeTransport
+
+	devlinkPciBus = "pci"
+
+	// netdevCompatDevlinkDir holds the mlx5 sysfs compat devlink attributes used by kernels without devlink eswitch
+	netdevCompatDevlinkDir  = "compat/devlink"
+	compatDevlinkModeFile   = "mode"
+	compatDevlinkEncapFile  = "encap"
+	compatDevlinkInlineFile = "inline"
+
+	eswitchPollInterval = 100 * time.Millisecond
+)
+
+// devlinkEncapModes maps the encap mode names reported by the netlink library to EswitchEncapMode
+var devlinkEncapModes = map[string]EswitchEncapMode{
+	"disable": EswitchEncapModeNone,
+	"enable":  EswitchEncapModeBasic,
+}
+
+// isValidEswitchInlineMode returns true if the given inline mode is one of the supported inline modes
+func isValidEswitchInlineMode(inlineMode EswitchInlineMode) bool {
+	switch inlineMode {
+	case EswitchInlineModeNone, EswitchInlineModeLink, EswitchInlineModeNetwork, EswitchInlineModeTransport:
+		return true
+	}
+	return false
+}
+
+// resolvePfPciAddress returns the PCI address of a PF given either its PCI address or its netdev name
+func resolvePfPciAddress(pfPciOrNetdev string) (string, error) {
+	if pciAddress, err := normalizePciAddress(pfPciOrNetdev); err == nil {
+		return pciAddress, nil
+	}
+	return GetPciFromNetDevice(pfPciOrNetdev)
+}
+
+// readCompatDevlinkAttr reads the given sysfs compat devlink attribute of the PF with the given PCI address
+func readCompatDevlinkAttr(pfPciAddress, attr string) (string, error) {
+	netDevs, err := GetNetDevicesFromPci(pfPciAddress)
+	if err != nil {
+		return "", err
+	}
+	for _, netDev := range netDevs {
+		content, err := utilfs.Fs.ReadFile(filepath.Join(NetSysDir, netDev, netdevCompatDevlinkDir, attr))
+		if err == nil {
+			return strings.TrimSpace(string(content)), nil
+		}
+	}
+	return "", fmt.Errorf("no compat devlink %s attribute found for %s", attr, pfPciAddress)
+}
+
+// GetEswitchMode returns the eswitch mode of the PF with the given PCI address (e.g '0000:03:00.0')
+// or netdev name. The mode is retrieved via devlink, falling back to the sysfs compat devlink mode file.
+func GetEswitchMode(pfPciOrNetdev string) (EswitchMode, error) {
+	pciAddress, err := resolvePfPciAddress(pfPciOrNetdev)
+	if err != nil {
+		return "", err
+	}
+
+	dev, err := netlinkops.GetNetlinkOps().DevLinkGetDeviceByName(devlinkPciBus, pciAddress)
+	if err == nil && dev.Attrs.Eswitch.Mode != "" && dev.Attrs.Eswitch.Mode != "unknown" {
+		return EswitchMode(dev.Attrs.Eswitch.Mode), nil
+	}
+
+	mode, compatErr := readCompatDevlinkAttr(pciAddress, compatDevlinkModeFile)
+	if compatErr != nil {
+		if err == nil {
+			err = fmt.Errorf("eswitch mode not reported by devlink")
+		}
+		return "", fmt.Errorf("failed to get eswitch mode of %s: %v, %v", pciAddress, err, compatErr)
+	}
+	switch EswitchMode(mode) {
+	case EswitchModeLegacy, EswitchModeSwitchdev:
+		return EswitchMode(mode), nil
+	default:
+		return "", fmt.Errorf("unexpected eswitch mode %q for %s", mode, pciAddress)
+	}
+}
+
+// SetEswitchMode sets the eswitch mode of the PF with the given PCI address (e.g '0000:03:00.0') or netdev name
+// via devlink and waits up to timeout for the change to take effect. When switching to switchdev it waits for
+// the uplink representor and a representor for each of the currently enabled VFs to appear, when switching
+// to legacy it waits for devlink to report the legacy mode.
+func SetEswitchMode(pfPciOrNetdev string, mode EswitchMode, timeout time.Duration) (err error) {
+	defer startTrace("SetEswitchMode", "pf", pfPciOrNetdev, "mode", string(mode))(&err)
+	if mode != EswitchModeLegacy && mode != EswitchModeSwitchdev {
+		return fmt.Errorf("invalid eswitch mode %q", mode)
+	}
+	pciAddress, err := resolvePfPciAddress(pfPciOrNetdev)
+	if err != nil {
+		return err
+	}
+
+	dev, err := netlinkops.GetNetlinkOps().DevLinkGetDeviceByName(devlinkPciBus, pciAddress)
+	if err != nil {
+		return fmt.Errorf("failed to get devlink device %s: %v", pciAddress, err)
+	}
+	if EswitchMode(dev.Attrs.Eswitch.Mode) != mode {
+		if err = netlinkops.GetNetlinkOps().DevLinkSetEswitchMode(dev, string(mode)); err != nil {
+			return fmt.Errorf("failed to set eswitch mode of %s to %s: %v", pciAddress, mode, err)
+		}
+	}
+
+	var ready func() bool
+	if mode == EswitchModeSwitchdev {
+		numVfs, err := readPciIntAttr(pciAddress, netDevCurrentVfCountFile)
+		if err != nil {
+			return fmt.Errorf("failed to get number of VFs of %s: %v", pciAddress, err)
+		}
+		ready = func() bool { return switchdevRepresentorsReady(pciAddress, numVfs) }
+	} else {
+		ready = func() bool {
+			current, err := GetEswitchMode(pciAddress)
+			return err == nil && current == EswitchModeLegacy
+		}
+	}
+	if !pollUntil(timeout, eswitchPollInterval, ready) {
+		return fmt.Errorf("timed out waiting for %s to switch to %s eswitch mode", pciAddress, mode)
+	}
+	return nil
+}
+
+// switchdevRepresentorsReady returns true if the uplink representor and the representors
+// of the first numVfs VFs of the PF with the given PCI address exist
+func switchdevRepresentorsReady(pfPciAddress string, numVfs int) bool {
+	uplink, err := GetUplinkRepresentor(pfPciAddress)
+	if err != nil {
+		return false
+	}
+	for vfIndex := 0; vfIndex < numVfs; vfIndex++ {
+		if _, err := GetVfRepresentor(uplink, vfIndex); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// GetEswitchEncapMode returns the eswitch encapsulation mode of the PF with the given PCI address
+// (e.g '0000:03:00.0') or netdev name, falling back to the sysfs compat devlink encap file.
+func GetEswitchEncapMode(pfPciOrNetdev string) (EswitchEncapMode, error) {
+	pciAddress, err := resolvePfPciAddress(pfPciOrNetdev)
+	if err != nil {
+		return "", err
+	}
+
+	dev, err := netlinkops.GetNetlinkOps().DevLinkGetDeviceByName(devlinkPciBus, pciAddress)
+	if err == nil {
+		if encapMode, ok := devlinkEncapModes[dev.Attrs.Eswitch.EncapMode]; ok {
+			return encapMode, nil
+		}
+		err = fmt.Errorf("eswitch encap mode not reported by devlink")
+	}
+
+	encapMode, compatErr := readCompatDevlinkAttr(pciAddress, compatDevlinkEncapFile)
+	if compatErr != nil {
+		return "", fmt.Errorf("failed to get eswitch encap mode of %s: %v, %v", pciAddress, err, compatErr)
+	}
+	switch EswitchEncapMode(encapMode) {
+	case EswitchEncapModeNone, EswitchEncapModeBasic:
+		return EswitchEncapMode(encapMode), nil
+	default:
+		return "", fmt.Errorf("unexpected eswitch encap mode %q for %s", encapMode, pciAddress)
+	}
+}
+
+// SetEswitchEncapMode sets the eswitch encapsulation mode of the PF with the given PCI address
+// (e.g '0000:03:00.0') or netdev name. Encap mode should be set before switching the PF to switchdev mode.
+func SetEswitchEncapMode(pfPciOrNetdev string, encapMode EswitchEncapMode) error {
+	if encapMode != EswitchEncapModeNone && encapMode != EswitchEncapModeBasic {
+		return fmt.Errorf("invalid eswitch encap mode %q", encapMode)
+	}
+	pciAddress, err := resolvePfPciAddress(pfPciOrNetdev)
+	if err != nil {
+		return err
+	}
+
+	dev, err := netlinkops.GetNetlinkOps().DevLinkGetDeviceByName(devlinkPciBus, pciAddress)
+	if err != nil {
+		return fmt.Errorf("failed to get devlink device %s: %v", pciAddress, err)
+	}
+	if err = netlinkops.GetNetlinkOps().DevLinkSetEswitchEncapMode(dev, string(encapMode)); err != nil {
+		return fmt.Errorf("failed to set eswitch encap mode of %s to %s: %v", pciAddress, encapMode, err)
+	}
+	return nil
+}
+
+// GetEswitchInlineMode returns the eswitch inline mode of the PF with the given PCI address
+// (e.g '0000:03:00.0') or netdev name, falling back to the sysfs compat devlink inline file.
+func GetEswitchInlineMode(pfPciOrNetdev string) (EswitchInlineMode, error) {
+	pciAddress, err := resolvePfPciAddress(pfPciOrNetdev)
+	if err != nil {
+		return "", err
+	}
+
+	dev, err := netlinkops.GetNetlinkOps().DevLinkGetDeviceByName(devlinkPciBus, pciAddress)
+	if err == nil {
+		if inlineMode := EswitchInlineMode(dev.Attrs.Eswitch.InlineMode); isValidEswitchInlineMode(inlineMode) {
+			return inlineMode, nil
+		}
+		err = fmt.Errorf("eswitch inline mode not reported by devlink")
+	}
+
+	inlineMode, compatErr := readCompatDevlinkAttr(pciAddress, compatDevlinkInlineFile)
+	if compatErr != nil {
+		return "", fmt.Errorf("failed to get eswitch inline mode of %s: %v, %v", pciAddress, err, compatErr)
+	}
+	if !isValidEswitchInlineMode(EswitchInlineMode(inlineMode)) {
+		return "", fmt.Errorf("unexpected eswitch inline mode %q for %s", inlineMode, pciAddress)
+	}
+	return EswitchInlineMode(inlineMode), nil
+}
+
+// SetEswitchInlineMode sets the eswitch inline mode of the PF with the given PCI address
+// (e.g '0000:03:00.0') or netdev name.
+func SetEswitchInlineMode(pfPciOrNetdev string, inlineMode EswitchInlineMode) error {
+	if !isValidEswitchInlineMode(inlineMode) {
+		return fmt.Errorf("invalid eswitch inline mode %q", inlineMode)
+	}
+	pciAddress, err := resolvePfPciAddress(pfPciOrNetdev)
+	if err != nil {
+		return err
+	}
+
+	dev, err := netlinkops.GetNetlinkOps().DevLinkGetDeviceByName(devlinkPciBus, pciAddress)
+	if err != nil {
+		return fmt.Errorf("failed to get devlink device %s: %v", pciAddress, err)
+	}
+	if err = netlinkops.GetNetlinkOps().DevLinkSetEswitchInlineMode(dev, string(inlineMode)); err != nil {
+		return fmt.Errorf("failed to set eswitch inline mode of %s to %s: %v", pciAddress, inlineMode, err)
+	}
+	return nil
+}
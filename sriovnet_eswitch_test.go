@@ -0,0 +1,240 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sriovnet
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vishvananda/netlink"
+
+	utilfs "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/filesystem"
+	"github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/netlinkops"
+	netlinkopsMocks "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/netlinkops/mocks"
+)
+
+// setUpEswitchEnv creates a PF with the given PCI address and netdev, with the given compat devlink attributes
+func setUpEswitchEnv(t *testing.T, pfPciAddress, netDev string, compatAttrs map[string]string) func() {
+	teardown := setupGetPciFromNetDeviceEnv(t, []*devContext{{Name: netDev, PciAddr: pfPciAddress}})
+	assert.NoError(t, utilfs.Fs.MkdirAll(filepath.Join(PciSysDir, pfPciAddress, "net", netDev), os.FileMode(0755)))
+	compatDir := filepath.Join(NetSysDir, netDev, netdevCompatDevlinkDir)
+	assert.NoError(t, utilfs.Fs.MkdirAll(compatDir, os.FileMode(0755)))
+	for attr, value := range compatAttrs {
+		assert.NoError(t, utilfs.Fs.WriteFile(filepath.Join(compatDir, attr), []byte(value), os.FileMode(0644)))
+	}
+	return teardown
+}
+
+func newDevlinkDevice(pciAddress string, eswitch netlink.DevlinkDevEswitchAttr) *netlink.DevlinkDevice {
+	return &netlink.DevlinkDevice{
+		BusName:    devlinkPciBus,
+		DeviceName: pciAddress,
+		Attrs:      netlink.DevlinkDevAttrs{Eswitch: eswitch},
+	}
+}
+
+func TestGetEswitchModeDevlink(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+	teardown := setUpEswitchEnv(t, "0000:03:00.0", "p0", nil)
+	defer teardown()
+
+	nlOpsMock.On("DevLinkGetDeviceByName", "pci", "0000:03:00.0").Return(
+		newDevlinkDevice("0000:03:00.0", netlink.DevlinkDevEswitchAttr{Mode: "switchdev"}), nil)
+
+	mode, err := GetEswitchMode("0000:03:00.0")
+	assert.NoError(t, err)
+	assert.Equal(t, EswitchModeSwitchdev, mode)
+
+	mode, err = GetEswitchMode("p0")
+	assert.NoError(t, err)
+	assert.Equal(t, EswitchModeSwitchdev, mode)
+	nlOpsMock.AssertExpectations(t)
+}
+
+func TestGetEswitchModeSysfsFallback(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+	teardown := setUpEswitchEnv(t, "0000:03:00.0", "p0", map[string]string{compatDevlinkModeFile: "legacy\n"})
+	defer teardown()
+
+	nlOpsMock.On("DevLinkGetDeviceByName", "pci", "0000:03:00.0").Return(nil, fmt.Errorf("not supported"))
+
+	mode, err := GetEswitchMode("p0")
+	assert.NoError(t, err)
+	assert.Equal(t, EswitchModeLegacy, mode)
+}
+
+func TestGetEswitchModeError(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+	teardown := setUpEswitchEnv(t, "0000:03:00.0", "p0", nil)
+	defer teardown()
+
+	nlOpsMock.On("DevLinkGetDeviceByName", "pci", "0000:03:00.0").Return(nil, fmt.Errorf("not supported"))
+
+	_, err := GetEswitchMode("0000:03:00.0")
+	assert.Error(t, err)
+}
+
+func TestSetEswitchModeSwitchdev(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+	teardown := setUpSwitchdevInventoryEnv(t)
+	defer teardown()
+
+	dev := newDevlinkDevice("0000:03:00.0", netlink.DevlinkDevEswitchAttr{Mode: "legacy"})
+	nlOpsMock.On("DevLinkGetDeviceByName", "pci", "0000:03:00.0").Return(dev, nil)
+	nlOpsMock.On("DevLinkSetEswitchMode", dev, "switchdev").Return(nil)
+
+	err := SetEswitchMode("0000:03:00.0", EswitchModeSwitchdev, time.Second)
+	assert.NoError(t, err)
+	nlOpsMock.AssertExpectations(t)
+}
+
+func TestSetEswitchModeSwitchdevTimeout(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+	teardown := setUpSwitchdevInventoryEnv(t)
+	defer teardown()
+	// VF 1 representor never shows up
+	assert.NoError(t, utilfs.Fs.RemoveAll(filepath.Join(NetSysDir, "rep1")))
+
+	dev := newDevlinkDevice("0000:03:00.0", netlink.DevlinkDevEswitchAttr{Mode: "legacy"})
+	nlOpsMock.On("DevLinkGetDeviceByName", "pci", "0000:03:00.0").Return(dev, nil)
+	nlOpsMock.On("DevLinkSetEswitchMode", dev, "switchdev").Return(nil)
+
+	err := SetEswitchMode("0000:03:00.0", EswitchModeSwitchdev, 200*time.Millisecond)
+	assert.Error(t, err)
+}
+
+func TestSetEswitchModeLegacy(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+	teardown := setUpEswitchEnv(t, "0000:03:00.0", "p0", nil)
+	defer teardown()
+
+	dev := newDevlinkDevice("0000:03:00.0", netlink.DevlinkDevEswitchAttr{Mode: "switchdev"})
+	nlOpsMock.On("DevLinkGetDeviceByName", "pci", "0000:03:00.0").Return(dev, nil).Once()
+	nlOpsMock.On("DevLinkSetEswitchMode", dev, "legacy").Return(nil)
+	nlOpsMock.On("DevLinkGetDeviceByName", "pci", "0000:03:00.0").Return(
+		newDevlinkDevice("0000:03:00.0", netlink.DevlinkDevEswitchAttr{Mode: "legacy"}), nil)
+
+	err := SetEswitchMode("p0", EswitchModeLegacy, time.Second)
+	assert.NoError(t, err)
+	nlOpsMock.AssertExpectations(t)
+}
+
+func TestSetEswitchModeInvalidMode(t *testing.T) {
+	err := SetEswitchMode("0000:03:00.0", EswitchMode("bogus"), time.Second)
+	assert.Error(t, err)
+}
+
+func TestGetEswitchEncapMode(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+	teardown := setUpEswitchEnv(t, "0000:03:00.0", "p0", nil)
+	defer teardown()
+
+	nlOpsMock.On("DevLinkGetDeviceByName", "pci", "0000:03:00.0").Return(
+		newDevlinkDevice("0000:03:00.0", netlink.DevlinkDevEswitchAttr{Mode: "legacy", EncapMode: "enable"}), nil)
+
+	encapMode, err := GetEswitchEncapMode("p0")
+	assert.NoError(t, err)
+	assert.Equal(t, EswitchEncapModeBasic, encapMode)
+}
+
+func TestGetEswitchEncapModeSysfsFallback(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+	teardown := setUpEswitchEnv(t, "0000:03:00.0", "p0", map[string]string{compatDevlinkEncapFile: "none\n"})
+	defer teardown()
+
+	nlOpsMock.On("DevLinkGetDeviceByName", "pci", "0000:03:00.0").Return(nil, fmt.Errorf("not supported"))
+
+	encapMode, err := GetEswitchEncapMode("0000:03:00.0")
+	assert.NoError(t, err)
+	assert.Equal(t, EswitchEncapModeNone, encapMode)
+}
+
+func TestSetEswitchEncapMode(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+
+	dev := newDevlinkDevice("0000:03:00.0", netlink.DevlinkDevEswitchAttr{Mode: "legacy", EncapMode: "disable"})
+	nlOpsMock.On("DevLinkGetDeviceByName", "pci", "0000:03:00.0").Return(dev, nil)
+	nlOpsMock.On("DevLinkSetEswitchEncapMode", dev, "basic").Return(nil)
+
+	err := SetEswitchEncapMode("0000:03:00.0", EswitchEncapModeBasic)
+	assert.NoError(t, err)
+	nlOpsMock.AssertExpectations(t)
+
+	err = SetEswitchEncapMode("0000:03:00.0", EswitchEncapMode("enable"))
+	assert.Error(t, err)
+}
+
+func TestGetEswitchInlineMode(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+	teardown := setUpEswitchEnv(t, "0000:03:00.0", "p0", map[string]string{compatDevlinkInlineFile: "link\n"})
+	defer teardown()
+
+	nlOpsMock.On("DevLinkGetDeviceByName", "pci", "0000:03:00.0").Return(
+		newDevlinkDevice("0000:03:00.0", netlink.DevlinkDevEswitchAttr{Mode: "legacy", InlineMode: "transport"}),
+		nil).Once()
+	nlOpsMock.On("DevLinkGetDeviceByName", "pci", "0000:03:00.0").Return(nil, fmt.Errorf("not supported"))
+
+	inlineMode, err := GetEswitchInlineMode("p0")
+	assert.NoError(t, err)
+	assert.Equal(t, EswitchInlineModeTransport, inlineMode)
+
+	// sysfs compat fallback
+	inlineMode, err = GetEswitchInlineMode("p0")
+	assert.NoError(t, err)
+	assert.Equal(t, EswitchInlineModeLink, inlineMode)
+}
+
+func TestSetEswitchInlineMode(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+
+	dev := newDevlinkDevice("0000:03:00.0", netlink.DevlinkDevEswitchAttr{Mode: "legacy", InlineMode: "none"})
+	nlOpsMock.On("DevLinkGetDeviceByName", "pci", "0000:03:00.0").Return(dev, nil)
+	nlOpsMock.On("DevLinkSetEswitchInlineMode", dev, "transport").Return(nil)
+
+	err := SetEswitchInlineMode("0000:03:00.0", EswitchInlineModeTransport)
+	assert.NoError(t, err)
+	nlOpsMock.AssertExpectations(t)
+
+	err = SetEswitchInlineMode("0000:03:00.0", EswitchInlineMode("l2"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sriovnet
+
+import (
+	"fmt"
+
+	"github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/netlinkops"
+)
+
+// Netdev feature names, as reported by `ethtool -k`
+const (
+	// NetdevFeatureHwTcOffload enables offloading of TC flower rules, required on switchdev uplinks and
+	// representors for hardware offload of OVS or TC datapaths
+	NetdevFeatureHwTcOffload  = "hw-tc-offload"
+	NetdevFeatureRxVlanFilter = "rx-vlan-filter"
+	NetdevFeatureRxChecksum   = "rx-checksum"
+	NetdevFeatureTso          = "tx-tcp-segmentation"
+)
+
+// GetNetdevFeatures returns the active state of the ethtool features of the given netdev by name
+// (e.g NetdevFeatureHwTcOffload).
+// Equivalent to: `ethtool -k $netdev`
+func GetNetdevFeatures(netdev string) (map[string]bool, error) {
+	return netlinkops.GetNetlinkOps().LinkGetFeatures(netdev)
+}
+
+// GetNetdevFeature returns true if the given ethtool feature of the netdev is active
+func GetNetdevFeature(netdev, feature string) (bool, error) {
+	features, err := GetNetdevFeatures(netdev)
+	if err != nil {
+		return false, err
+	}
+	active, ok := features[feature]
+	if !ok {
+		return false, fmt.Errorf("%w: netdev %s has no feature %s", ErrNotSupported, netdev, feature)
+	}
+	return active, nil
+}
+
+// SetNetdevFeatures enables or disables the given ethtool features of the netdev, an error is returned
+// if the driver does not apply any of them.
+// Equivalent to: `ethtool -K $netdev hw-tc-offload on`
+func SetNetdevFeatures(netdev string, features map[string]bool) error {
+	return netlinkops.GetNetlinkOps().LinkSetFeatures(netdev, features)
+}
+
+// EnableHwTcOffload enables the hw-tc-offload feature of the given netdev if not already enabled
+func EnableHwTcOffload(netdev string) error {
+	enabled, err := GetNetdevFeature(netdev, NetdevFeatureHwTcOffload)
+	if err != nil || enabled {
+		return err
+	}
+	return SetNetdevFeatures(netdev, map[string]bool{NetdevFeatureHwTcOffload: true})
+}
+
+// EnableRepresentorsHwTcOffload enables the hw-tc-offload feature of the given uplink representor and of all
+// the representors of its eswitch, as part of preparing a PF in switchdev mode for TC offload.
+func EnableRepresentorsHwTcOffload(uplink string) error {
+	reps, err := ListRepresentors(uplink, nil)
+	if err != nil {
+		return err
+	}
+	netdevs := []string{uplink}
+	for _, rep := range reps {
+		if rep.Name != uplink {
+			netdevs = append(netdevs, rep.Name)
+		}
+	}
+	for _, netdev := range netdevs {
+		if err = EnableHwTcOffload(netdev); err != nil {
+			return fmt.Errorf("failed to enable %s on %s: %v", NetdevFeatureHwTcOffload, netdev, err)
+		}
+	}
+	return nil
+}
+
+// NetdevDriverInfo holds the driver information of a netdev
+type NetdevDriverInfo struct {
+	Driver    string
+	Version   string
+	FwVersion string
+	// BusInfo is the device bus address, e.g the PCI address
+	BusInfo string
+}
+
+// GetNetdevDriverInfo returns the driver name, driver version and firmware version of the given netdev.
+// Complements GetDevlinkDeviceInfo on devices or kernels without devlink info support.
+// Equivalent to: `ethtool -i $netdev`
+func GetNetdevDriverInfo(netdev string) (*NetdevDriverInfo, error) {
+	info, err := netlinkops.GetNetlinkOps().LinkDriverInfo(netdev)
+	if err != nil {
+		return nil, err
+	}
+	return &NetdevDriverInfo{
+		Driver:    info.Driver,
+		Version:   info.Version,
+		FwVersion: info.FwVersion,
+		BusInfo:   info.BusInfo,
+	}, nil
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sriovnet
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/netlinkops"
+	netlinkopsMocks "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/netlinkops/mocks"
+)
+
+func TestGetNetdevFeature(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+
+	nlOpsMock.On("LinkGetFeatures", "eth0").Return(map[string]bool{
+		NetdevFeatureHwTcOffload: true, NetdevFeatureRxChecksum: false}, nil)
+	nlOpsMock.On("LinkGetFeatures", "eth1").Return(nil, fmt.Errorf("no such device"))
+
+	enabled, err := GetNetdevFeature("eth0", NetdevFeatureHwTcOffload)
+	assert.NoError(t, err)
+	assert.True(t, enabled)
+	enabled, err = GetNetdevFeature("eth0", NetdevFeatureRxChecksum)
+	assert.NoError(t, err)
+	assert.False(t, enabled)
+	_, err = GetNetdevFeature("eth0", "bogus")
+	assert.ErrorIs(t, err, ErrNotSupported)
+	_, err = GetNetdevFeature("eth1", NetdevFeatureHwTcOffload)
+	assert.Error(t, err)
+}
+
+func TestEnableRepresentorsHwTcOffload(t *testing.T) {
+	teardown := setupRepresentorEnv(t, "", []*repContext{
+		{Name: "eth0", PhysPortName: "p0", PhysSwitchID: "111111"},
+		{Name: "pf0vf0", PhysPortName: "pf0vf0", PhysSwitchID: "111111"},
+		{Name: "pf0vf1", PhysPortName: "pf0vf1", PhysSwitchID: "111111"},
+		{Name: "pf1vf0", PhysPortName: "pf1vf0", PhysSwitchID: "222222"},
+	})
+	defer teardown()
+
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+
+	hwTcOffloadOn := map[string]bool{NetdevFeatureHwTcOffload: true}
+	hwTcOffloadOff := map[string]bool{NetdevFeatureHwTcOffload: false}
+	nlOpsMock.On("LinkGetFeatures", "eth0").Return(hwTcOffloadOn, nil)
+	nlOpsMock.On("LinkGetFeatures", "pf0vf0").Return(hwTcOffloadOff, nil)
+	nlOpsMock.On("LinkGetFeatures", "pf0vf1").Return(hwTcOffloadOff, nil)
+	nlOpsMock.On("LinkSetFeatures", "pf0vf0", hwTcOffloadOn).Return(nil)
+	nlOpsMock.On("LinkSetFeatures", "pf0vf1", hwTcOffloadOn).Return(fmt.Errorf("operation not supported"))
+
+	err := EnableRepresentorsHwTcOffload("eth0")
+	assert.Error(t, err)
+	nlOpsMock.AssertExpectations(t)
+	nlOpsMock.AssertNotCalled(t, "LinkGetFeatures", "pf1vf0")
+}
+
+func TestGetNetdevDriverInfo(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+
+	nlOpsMock.On("LinkDriverInfo", "eth0").Return(&netlinkops.EthtoolDriverInfo{Driver: "mlx5_core",
+		Version: "6.5.0", FwVersion: "22.36.1010 (MT_0000000359)", BusInfo: "0000:03:00.0"}, nil)
+	nlOpsMock.On("LinkDriverInfo", "eth1").Return(nil, fmt.Errorf("no such device"))
+
+	info, err := GetNetdevDriverInfo("eth0")
+	assert.NoError(t, err)
+	assert.Equal(t, &NetdevDriverInfo{Driver: "mlx5_core", Version: "6.5.0", FwVersion: "22.36.1010 (MT_0000000359)",
+		BusInfo: "0000:03:00.0"}, info)
+	_, err = GetNetdevDriverInfo("eth1")
+	assert.Error(t, err)
+}
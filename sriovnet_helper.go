@@ -18,15 +18,17 @@ package sriovnet
 
 import (
 	"fmt"
-	"log"
-	"os"
 	"path/filepath"
+	"time"
+
+	utilfs "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/filesystem"
 )
 
 const (
 	NetSysDir        = "/sys/class/net"
 	PciSysDir        = "/sys/bus/pci/devices"
 	AuxSysDir        = "/sys/bus/auxiliary/devices"
+	InfinibandSysDir = "/sys/class/infiniband"
 	pcidevPrefix     = "device"
 	netdevDriverDir  = "device/driver"
 	netdevUnbindFile = "unbind"
@@ -58,7 +60,7 @@ func getMaxVfCount(pfNetdevName string) (int, error) {
 	if err != nil {
 		return 0, err
 	}
-	log.Println("max_vfs = ", maxVfs)
+	logDebug("read max VF count", "netdev", pfNetdevName, "maxVfs", maxVfs)
 	return maxVfs, nil
 }
 
@@ -83,7 +85,7 @@ func getCurrentVfCount(pfNetdevName string) (int, error) {
 	if err != nil {
 		return 0, err
 	}
-	log.Println("cur_vfs = ", curVfs)
+	logDebug("read current VF count", "netdev", pfNetdevName, "numVfs", curVfs)
 	return curVfs, nil
 }
 
@@ -97,14 +99,18 @@ func vfNetdevNameFromParent(pfNetdevName string, vfIndex int) string {
 	return vfNetdev[0]
 }
 
+// readPCIsymbolicLink returns the PCI address a symbolic link (e.g '../0000:03:00.4') points to.
+// The link may be relative or absolute and the PCI address may have any domain.
 func readPCIsymbolicLink(symbolicLink string) (string, error) {
-	pciDevDir, err := os.Readlink(symbolicLink)
-	//nolint:gomnd
-	if len(pciDevDir) <= 3 {
-		return "", fmt.Errorf("could not find PCI Address")
+	pciDevDir, err := utilfs.Fs.Readlink(symbolicLink)
+	if err != nil {
+		return "", fmt.Errorf("could not find PCI Address: %v", err)
 	}
-
-	return pciDevDir[3:], err
+	pciAddress, err := normalizePciAddress(filepath.Base(pciDevDir))
+	if err != nil {
+		return "", fmt.Errorf("could not find PCI Address: %v", err)
+	}
+	return pciAddress, nil
 }
 func vfPCIDevNameFromVfIndex(pfNetdevName string, vfIndex int) (string, error) {
 	symbolicLink := filepath.Join(NetSysDir, pfNetdevName, pcidevPrefix, fmt.Sprintf("%s%v",
@@ -144,3 +150,18 @@ func GetVfPciDevList(pfNetdevName string) ([]string, error) {
 	}
 	return vfDirList, nil
 }
+
+// pollUntil calls condition every interval until it returns true or timeout expires.
+// Returns true if the condition was met.
+func pollUntil(timeout, interval time.Duration, condition func() bool) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		if condition() {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(interval)
+	}
+}
@@ -68,6 +68,25 @@ func TestGetPfHandle(t *testing.T) {
 	}
 }
 
+func TestConfigIbVfs(t *testing.T) {
+	err1 := EnableSriov("ib0")
+	if err1 != nil {
+		t.Fatal(err1)
+	}
+
+	handle, err2 := GetPfNetdevHandle("ib0")
+	if err2 != nil {
+		t.Fatal(err2)
+	}
+	err3 := ConfigIbVfs(handle, &IbVfConfig{PortPolicy: IbVfPortPolicyFollow})
+	if err3 != nil {
+		t.Fatal(err3)
+	}
+	for _, vf := range handle.List {
+		fmt.Printf("after config vf = %v\n", vf)
+	}
+}
+
 func TestConfigVfs(t *testing.T) {
 	err1 := EnableSriov("ens2f0")
 	if err1 != nil {
@@ -0,0 +1,205 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sriovnet
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	utilfs "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/filesystem"
+)
+
+const netdevAddressFile = "address"
+
+// SriovInventory is a snapshot of the SR-IOV devices of the node
+type SriovInventory struct {
+	Pfs []*PfInventory `json:"pfs" yaml:"pfs"`
+}
+
+// PfInventory describes an SR-IOV PF, its VFs and, in switchdev mode, its uplink representor, representors and SFs
+type PfInventory struct {
+	PciAddress string         `json:"pciAddress" yaml:"pciAddress"`
+	Driver     string         `json:"driver,omitempty" yaml:"driver,omitempty"`
+	NetDevs    []string       `json:"netDevs,omitempty" yaml:"netDevs,omitempty"`
+	MacAddress string         `json:"macAddress,omitempty" yaml:"macAddress,omitempty"`
+	NumaNode   int            `json:"numaNode" yaml:"numaNode"`
+	TotalVfs   int            `json:"totalVfs" yaml:"totalVfs"`
+	NumVfs     int            `json:"numVfs" yaml:"numVfs"`
+	Switchdev  bool           `json:"switchdev" yaml:"switchdev"`
+	Uplink     string         `json:"uplink,omitempty" yaml:"uplink,omitempty"`
+	Vfs        []*VfInventory `json:"vfs" yaml:"vfs"`
+	// Representors are the representors of the PF eswitch, including those of external controllers (e.g DPU host)
+	Representors []*RepresentorInventory `json:"representors,omitempty" yaml:"representors,omitempty"`
+	Sfs          []*SfInventory          `json:"sfs,omitempty" yaml:"sfs,omitempty"`
+}
+
+// VfInventory describes an SR-IOV VF and, in switchdev mode, its representor
+type VfInventory struct {
+	Index       int    `json:"index" yaml:"index"`
+	PciAddress  string `json:"pciAddress" yaml:"pciAddress"`
+	Driver      string `json:"driver,omitempty" yaml:"driver,omitempty"`
+	NetDev      string `json:"netDev,omitempty" yaml:"netDev,omitempty"`
+	MacAddress  string `json:"macAddress,omitempty" yaml:"macAddress,omitempty"`
+	NumaNode    int    `json:"numaNode" yaml:"numaNode"`
+	Representor string `json:"representor,omitempty" yaml:"representor,omitempty"`
+}
+
+// RepresentorInventory describes a representor of a switchdev PF eswitch
+type RepresentorInventory struct {
+	Name       string      `json:"name" yaml:"name"`
+	Flavour    PortFlavour `json:"flavour" yaml:"flavour"`
+	Controller int         `json:"controller" yaml:"controller"`
+	// PfID is the PF number (or physical port number for physical ports), -1 if unknown
+	PfID int `json:"pfId" yaml:"pfId"`
+	// FuncIndex is the VF or SF index, -1 for physical and PF representors
+	FuncIndex int `json:"funcIndex" yaml:"funcIndex"`
+}
+
+// SfInventory describes a SF of a switchdev PF
+type SfInventory struct {
+	PfNum       int           `json:"pfNum" yaml:"pfNum"`
+	SfNum       int           `json:"sfNum" yaml:"sfNum"`
+	PortIndex   uint32        `json:"portIndex" yaml:"portIndex"`
+	State       PortFnState   `json:"state,omitempty" yaml:"state,omitempty"`
+	OpState     PortFnOpState `json:"opState,omitempty" yaml:"opState,omitempty"`
+	MacAddress  string        `json:"macAddress,omitempty" yaml:"macAddress,omitempty"`
+	AuxDev      string        `json:"auxDev,omitempty" yaml:"auxDev,omitempty"`
+	Driver      string        `json:"driver,omitempty" yaml:"driver,omitempty"`
+	NetDev      string        `json:"netDev,omitempty" yaml:"netDev,omitempty"`
+	Representor string        `json:"representor,omitempty" yaml:"representor,omitempty"`
+}
+
+// GetNodeSriovInventory returns the SR-IOV PFs of the node along with their VFs and representors.
+// Attributes which cannot be retrieved (e.g the netdev of a VF bound to vfio-pci) are left empty,
+// an error is returned only if the PFs cannot be listed.
+func GetNodeSriovInventory() (*SriovInventory, error) {
+	pfs, err := ListSriovCapablePfs()
+	if err != nil {
+		return nil, err
+	}
+
+	inventory := &SriovInventory{Pfs: make([]*PfInventory, 0, len(pfs))}
+	for _, pf := range pfs {
+		inventory.Pfs = append(inventory.Pfs, getPfInventory(pf))
+	}
+	return inventory, nil
+}
+
+func getPfInventory(pf *SriovPf) *PfInventory {
+	pfInv := &PfInventory{
+		PciAddress: pf.PciAddress,
+		NetDevs:    pf.NetDevs,
+		TotalVfs:   pf.TotalVfs,
+		Vfs:        make([]*VfInventory, 0),
+	}
+	pfInv.Driver, _ = GetDriverNameByPciAddress(pf.PciAddress)
+	pfInv.NumaNode, _ = GetPciNumaNode(pf.PciAddress)
+	pfInv.NumVfs, _ = readPciIntAttr(pf.PciAddress, netDevCurrentVfCountFile)
+	if len(pf.NetDevs) > 0 {
+		pfInv.MacAddress = getNetDevMacAddress(pf.NetDevs[0])
+	}
+	if uplink, err := GetUplinkRepresentor(pf.PciAddress); err == nil {
+		pfInv.Switchdev = true
+		pfInv.Uplink = uplink
+		pfInv.Representors = getRepresentorsInventory(uplink)
+		pfInv.Sfs = getSfsInventory(pf.PciAddress)
+	}
+
+	vfs, err := getVfPciAddressMapFromPfPci(pf.PciAddress)
+	if err != nil {
+		return pfInv
+	}
+	indexes := make([]int, 0, len(vfs))
+	for vfIndex := range vfs {
+		indexes = append(indexes, vfIndex)
+	}
+	sort.Ints(indexes)
+	for _, vfIndex := range indexes {
+		pfInv.Vfs = append(pfInv.Vfs, getVfInventory(pfInv, vfIndex, vfs[vfIndex]))
+	}
+	return pfInv
+}
+
+func getVfInventory(pf *PfInventory, vfIndex int, vfPciAddress string) *VfInventory {
+	vfInv := &VfInventory{Index: vfIndex, PciAddress: vfPciAddress}
+	vfInv.Driver, _ = GetDriverNameByPciAddress(vfPciAddress)
+	vfInv.NumaNode, _ = GetPciNumaNode(vfPciAddress)
+	if netDevs, err := GetNetDevicesFromPci(vfPciAddress); err == nil && len(netDevs) > 0 {
+		vfInv.NetDev = netDevs[0]
+		vfInv.MacAddress = getNetDevMacAddress(vfInv.NetDev)
+	}
+	if pf.Switchdev {
+		vfInv.Representor, _ = GetVfRepresentor(pf.Uplink, vfIndex)
+	}
+	return vfInv
+}
+
+func getRepresentorsInventory(uplink string) []*RepresentorInventory {
+	reps, err := ListRepresentors(uplink, nil)
+	if err != nil {
+		return nil
+	}
+	repsInv := make([]*RepresentorInventory, 0, len(reps))
+	for _, rep := range reps {
+		repsInv = append(repsInv, &RepresentorInventory{
+			Name:       rep.Name,
+			Flavour:    rep.Flavour,
+			Controller: rep.ControllerNum,
+			PfID:       rep.PfID,
+			FuncIndex:  rep.FuncIndex,
+		})
+	}
+	return repsInv
+}
+
+func getSfsInventory(pfPciAddress string) []*SfInventory {
+	sfs, err := ListSFs(pfPciAddress)
+	if err != nil {
+		return nil
+	}
+	sfsInv := make([]*SfInventory, 0, len(sfs))
+	for _, sf := range sfs {
+		sfInv := &SfInventory{
+			PfNum:       sf.PfNum,
+			SfNum:       sf.SfNum,
+			PortIndex:   sf.PortIndex,
+			State:       sf.State,
+			OpState:     sf.OpState,
+			AuxDev:      sf.AuxDev,
+			NetDev:      sf.NetDev,
+			Representor: sf.Representor,
+		}
+		if len(sf.HwAddr) > 0 {
+			sfInv.MacAddress = sf.HwAddr.String()
+		}
+		if sf.AuxDev != "" {
+			sfInv.Driver, _ = GetDriverNameByAuxDev(sf.AuxDev)
+		}
+		sfsInv = append(sfsInv, sfInv)
+	}
+	return sfsInv
+}
+
+// getNetDevMacAddress returns the MAC address of the given netdev as exposed in sysfs, empty on failure
+func getNetDevMacAddress(netDev string) string {
+	content, err := utilfs.Fs.ReadFile(filepath.Join(NetSysDir, netDev, netdevAddressFile))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(content))
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sriovnet
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+
+	utilfs "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/filesystem"
+	"github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/netlinkops"
+	netlinkopsMocks "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/netlinkops/mocks"
+)
+
+// setUpSwitchdevInventoryEnv creates a switchdev PF 0000:03:00.0 with uplink p0 and two VFs, the first one
+// bound to mlx5_core with netdev eth2 and the second one bound to vfio-pci.
+func setUpSwitchdevInventoryEnv(t *testing.T) func() {
+	pf := "0000:03:00.0"
+	vfs := []string{"0000:03:00.2", "0000:03:00.3"}
+	teardown := setupRepresentorEnv(t, "", []*repContext{
+		{"p0", "p0", "c2cfc60003a1420c"},
+		{"rep0", "pf0vf0", "c2cfc60003a1420c"},
+		{"rep1", "pf0vf1", "c2cfc60003a1420c"},
+	})
+	setUpPfVfsPciEnv(t, pf, vfs)
+	setUpPciAttrs(t, pf, map[string]string{
+		netDevMaxVfCountFile:     "4\n",
+		netDevCurrentVfCountFile: "2\n",
+		"numa_node":              "1\n",
+	})
+	setUpPciDriverEnv(t, "mlx5_core", pf, vfs[0])
+	setUpPciDriverEnv(t, vfioPciDriver, vfs[1])
+
+	pfPciPath := filepath.Join(PciSysDir, pf)
+	assert.NoError(t, utilfs.Fs.MkdirAll(filepath.Join(pfPciPath, "net", "p0"), os.FileMode(0755)))
+	assert.NoError(t, utilfs.Fs.Symlink(pfPciPath, filepath.Join(NetSysDir, "p0", pcidevPrefix)))
+	assert.NoError(t, utilfs.Fs.Symlink(NetSysDir, filepath.Join(NetSysDir, "p0", "subsystem")))
+
+	assert.NoError(t, utilfs.Fs.MkdirAll(filepath.Join(PciSysDir, vfs[0], "net", "eth2"), os.FileMode(0755)))
+	assert.NoError(t, utilfs.Fs.MkdirAll(filepath.Join(NetSysDir, "eth2"), os.FileMode(0755)))
+	assert.NoError(t, utilfs.Fs.WriteFile(filepath.Join(NetSysDir, "eth2", netdevAddressFile),
+		[]byte("0c:42:a1:de:cf:7c\n"), os.FileMode(0644)))
+	return teardown
+}
+
+func TestGetNodeSriovInventory(t *testing.T) {
+	teardown := setUpSwitchdevInventoryEnv(t)
+	defer teardown()
+	assert.NoError(t, utilfs.Fs.WriteFile(filepath.Join(NetSysDir, "p0", netdevAddressFile),
+		[]byte("0c:42:a1:de:cf:7a\n"), os.FileMode(0644)))
+
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+	sfMac, _ := net.ParseMAC("00:00:00:00:88:88")
+	nlOpsMock.On("DevLinkGetAllPortList").Return([]*netlink.DevlinkPort{
+		newSfDevlinkPort("", 32768, &netlink.DevlinkPortFn{HwAddr: sfMac, State: nl.DEVLINK_PORT_FN_STATE_INACTIVE,
+			OpState: nl.DEVLINK_PORT_FN_OPSTATE_DETACHED}),
+	}, nil)
+
+	inventory, err := GetNodeSriovInventory()
+	assert.NoError(t, err)
+	assert.Equal(t, &SriovInventory{Pfs: []*PfInventory{{
+		PciAddress: "0000:03:00.0",
+		Driver:     "mlx5_core",
+		NetDevs:    []string{"p0"},
+		MacAddress: "0c:42:a1:de:cf:7a",
+		NumaNode:   1,
+		TotalVfs:   4,
+		NumVfs:     2,
+		Switchdev:  true,
+		Uplink:     "p0",
+		Vfs: []*VfInventory{
+			{
+				Index:       0,
+				PciAddress:  "0000:03:00.2",
+				Driver:      "mlx5_core",
+				NetDev:      "eth2",
+				MacAddress:  "0c:42:a1:de:cf:7c",
+				NumaNode:    NoNumaNode,
+				Representor: "rep0",
+			},
+			{
+				Index:       1,
+				PciAddress:  "0000:03:00.3",
+				Driver:      vfioPciDriver,
+				NumaNode:    NoNumaNode,
+				Representor: "rep1",
+			},
+		},
+		Representors: []*RepresentorInventory{
+			{Name: "p0", Flavour: PORT_FLAVOUR_PHYSICAL, PfID: 0, FuncIndex: -1},
+			{Name: "rep0", Flavour: PORT_FLAVOUR_PCI_VF, PfID: 0, FuncIndex: 0},
+			{Name: "rep1", Flavour: PORT_FLAVOUR_PCI_VF, PfID: 0, FuncIndex: 1},
+		},
+		Sfs: []*SfInventory{{PfNum: -1, SfNum: -1, PortIndex: 32768, State: PortFnStateInactive,
+			OpState: PortFnOpStateDetached, MacAddress: "00:00:00:00:88:88"}},
+	}}}, inventory)
+
+	out, err := json.Marshal(inventory)
+	assert.NoError(t, err)
+	assert.Contains(t, string(out), `"uplink":"p0"`)
+	assert.Contains(t, string(out), `"representor":"rep1"`)
+}
+
+func TestGetNodeSriovInventoryLegacy(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+	setUpPfVfsPciEnv(t, "0000:03:00.0", []string{"0000:03:00.2"})
+	setUpPciAttrs(t, "0000:03:00.0", map[string]string{
+		netDevMaxVfCountFile:     "8\n",
+		netDevCurrentVfCountFile: "1\n",
+	})
+
+	inventory, err := GetNodeSriovInventory()
+	assert.NoError(t, err)
+	assert.Len(t, inventory.Pfs, 1)
+	pf := inventory.Pfs[0]
+	assert.False(t, pf.Switchdev)
+	assert.Equal(t, "", pf.Uplink)
+	assert.Equal(t, NoNumaNode, pf.NumaNode)
+	assert.Equal(t, []*VfInventory{{Index: 0, PciAddress: "0000:03:00.2", NumaNode: NoNumaNode}}, pf.Vfs)
+	assert.Nil(t, pf.Representors)
+	assert.Nil(t, pf.Sfs)
+}
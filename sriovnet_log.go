@@ -0,0 +1,106 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sriovnet
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Logger is the structured logger used by sriovnet, keysAndValues are alternating key, value pairs
+// (e.g "pci", "0000:03:00.2", "vf", 1). It is satisfied by logr.Logger (github.com/go-logr/logr).
+type Logger interface {
+	Info(msg string, keysAndValues ...interface{})
+	Error(err error, msg string, keysAndValues ...interface{})
+}
+
+// Log verbosity levels, see SetLogVerbosity
+const (
+	// LogLevelInfo logs errors and VF allocation changes
+	LogLevelInfo = 0
+	// LogLevelDebug additionally logs the representor and VF resolution steps along with operation durations
+	LogLevelDebug = 1
+)
+
+var (
+	logger       Logger = stdLogger{}
+	logVerbosity        = LogLevelInfo
+)
+
+// SetLogger sets the logger used by sriovnet, the default logger writes to the standard log package.
+// A nil logger disables logging. It is meant to be set once at startup.
+func SetLogger(l Logger) {
+	if l == nil {
+		l = discardLogger{}
+	}
+	logger = l
+}
+
+// SetLogVerbosity sets the verbosity of sriovnet logs, e.g LogLevelDebug to debug "representor not found"
+// errors. Defaults to LogLevelInfo.
+func SetLogVerbosity(level int) {
+	logVerbosity = level
+}
+
+func logDebugEnabled() bool {
+	return logVerbosity >= LogLevelDebug
+}
+
+func logInfo(msg string, keysAndValues ...interface{}) {
+	logger.Info(msg, keysAndValues...)
+}
+
+func logDebug(msg string, keysAndValues ...interface{}) {
+	if logDebugEnabled() {
+		logger.Info(msg, keysAndValues...)
+	}
+}
+
+func logError(err error, msg string, keysAndValues ...interface{}) {
+	logger.Error(err, msg, keysAndValues...)
+}
+
+// stdLogger is a Logger writing key=value formatted messages to the standard log package
+type stdLogger struct{}
+
+func formatKeysAndValues(msg string, keysAndValues []interface{}) string {
+	var sb strings.Builder
+	sb.WriteString(msg)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		var value interface{} = "<missing>"
+		if i+1 < len(keysAndValues) {
+			value = keysAndValues[i+1]
+		}
+		fmt.Fprintf(&sb, " %v=%q", keysAndValues[i], fmt.Sprint(value))
+	}
+	return sb.String()
+}
+
+func (stdLogger) Info(msg string, keysAndValues ...interface{}) {
+	log.Print(formatKeysAndValues(msg, keysAndValues))
+}
+
+func (stdLogger) Error(err error, msg string, keysAndValues ...interface{}) {
+	log.Print(formatKeysAndValues(msg, append(keysAndValues, "error", err)))
+}
+
+type discardLogger struct{}
+
+func (discardLogger) Info(string, ...interface{}) {}
+
+func (discardLogger) Error(error, string, ...interface{}) {}
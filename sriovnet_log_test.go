@@ -0,0 +1,98 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sriovnet
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type logEntry struct {
+	msg           string
+	err           error
+	keysAndValues []interface{}
+}
+
+type fakeLogger struct {
+	entries []logEntry
+}
+
+func (l *fakeLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.entries = append(l.entries, logEntry{msg: msg, keysAndValues: keysAndValues})
+}
+
+func (l *fakeLogger) Error(err error, msg string, keysAndValues ...interface{}) {
+	l.entries = append(l.entries, logEntry{msg: msg, err: err, keysAndValues: keysAndValues})
+}
+
+func setUpFakeLogger(verbosity int) (*fakeLogger, func()) {
+	l := &fakeLogger{}
+	SetLogger(l)
+	SetLogVerbosity(verbosity)
+	return l, func() {
+		SetLogger(stdLogger{})
+		SetLogVerbosity(LogLevelInfo)
+	}
+}
+
+func TestLogVerbosity(t *testing.T) {
+	l, teardown := setUpFakeLogger(LogLevelInfo)
+	defer teardown()
+
+	logDebug("debug", "vf", 1)
+	logInfo("info", "vf", 2)
+	logError(fmt.Errorf("failure"), "error", "vf", 3)
+	assert.Equal(t, []logEntry{
+		{msg: "info", keysAndValues: []interface{}{"vf", 2}},
+		{msg: "error", err: fmt.Errorf("failure"), keysAndValues: []interface{}{"vf", 3}},
+	}, l.entries)
+
+	SetLogVerbosity(LogLevelDebug)
+	logDebug("debug", "vf", 1)
+	assert.Len(t, l.entries, 3)
+	assert.Equal(t, "debug", l.entries[2].msg)
+}
+
+func TestLogDebugRepresentorNotFound(t *testing.T) {
+	teardown := setUpSwitchdevInventoryEnv(t)
+	defer teardown()
+	l, teardownLogger := setUpFakeLogger(LogLevelDebug)
+	defer teardownLogger()
+
+	_, err := GetVfRepresentor("p0", 3)
+	assert.Error(t, err)
+	assert.Equal(t, logEntry{msg: "VF representor not found",
+		keysAndValues: []interface{}{"uplink", "p0", "vf", 3, "representors", map[int]string{0: "rep0", 1: "rep1"}}},
+		l.entries[len(l.entries)-2])
+	last := l.entries[len(l.entries)-1]
+	assert.Equal(t, "operation completed", last.msg)
+	assert.Equal(t, []interface{}{"op", "GetVfRepresentor", "uplink", "p0", "vf", "3", "duration"},
+		last.keysAndValues[:7])
+	assert.Equal(t, []interface{}{"error", err}, last.keysAndValues[8:])
+
+	SetLogger(nil)
+	_, err = GetVfRepresentor("p0", 3)
+	assert.Error(t, err)
+}
+
+func TestFormatKeysAndValues(t *testing.T) {
+	assert.Equal(t, `allocated VF netdev="p0" vf="1"`,
+		formatKeysAndValues("allocated VF", []interface{}{"netdev", "p0", "vf", 1}))
+	assert.Equal(t, `msg key="<missing>"`, formatKeysAndValues("msg", []interface{}{"key"}))
+}
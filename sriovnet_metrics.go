@@ -0,0 +1,255 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sriovnet
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MetricType is the type of a metric, following the Prometheus metric types
+type MetricType string
+
+const (
+	MetricTypeGauge   MetricType = "gauge"
+	MetricTypeCounter MetricType = "counter"
+)
+
+// Metric is a single metric sample
+type Metric struct {
+	// Name of the metric, e.g 'sriovnet_pf_num_vfs'
+	Name string
+	// Help describes the metric
+	Help   string
+	Type   MetricType
+	Labels map[string]string
+	Value  float64
+}
+
+// MetricDesc describes a metric returned by CollectMetrics
+type MetricDesc struct {
+	Name string
+	Help string
+	Type MetricType
+	// Labels are the names of the labels of the metric samples
+	Labels []string
+}
+
+var (
+	metricPfTotalVfs = &MetricDesc{"sriovnet_pf_total_vfs",
+		"Maximum number of VFs supported by the PF.", MetricTypeGauge, []string{"pf"}}
+	metricPfNumVfs = &MetricDesc{"sriovnet_pf_num_vfs",
+		"Number of VFs enabled on the PF.", MetricTypeGauge, []string{"pf"}}
+	metricPfAllocatedVfs = &MetricDesc{"sriovnet_pf_allocated_vfs",
+		"Number of VFs of the PF in use, i.e bound to vfio-pci or with their netdev out of the host namespace.",
+		MetricTypeGauge, []string{"pf"}}
+	metricPfSwitchdev = &MetricDesc{"sriovnet_pf_switchdev",
+		"Whether the eswitch of the PF is in switchdev mode.", MetricTypeGauge, []string{"pf"}}
+	metricPfSfs = &MetricDesc{"sriovnet_pf_sfs",
+		"Number of SFs of the PF by port function state and operational state.", MetricTypeGauge,
+		[]string{"pf", "state", "op_state"}}
+	metricVfRepresentorPresent = &MetricDesc{"sriovnet_vf_representor_present",
+		"Whether the representor netdev of the VF exists, switchdev PFs only.", MetricTypeGauge,
+		[]string{"pf", "vf"}}
+	metricVfRxBytes = &MetricDesc{"sriovnet_vf_rx_bytes_total",
+		"Bytes received by the VF, as counted by its representor.", MetricTypeCounter, vfCounterLabels}
+	metricVfTxBytes = &MetricDesc{"sriovnet_vf_tx_bytes_total",
+		"Bytes sent by the VF, as counted by its representor.", MetricTypeCounter, vfCounterLabels}
+	metricVfRxPackets = &MetricDesc{"sriovnet_vf_rx_packets_total",
+		"Packets received by the VF, as counted by its representor.", MetricTypeCounter, vfCounterLabels}
+	metricVfTxPackets = &MetricDesc{"sriovnet_vf_tx_packets_total",
+		"Packets sent by the VF, as counted by its representor.", MetricTypeCounter, vfCounterLabels}
+	metricVfRxDropped = &MetricDesc{"sriovnet_vf_rx_dropped_total",
+		"Packets to the VF dropped by its representor.", MetricTypeCounter, vfCounterLabels}
+	metricVfTxDropped = &MetricDesc{"sriovnet_vf_tx_dropped_total",
+		"Packets from the VF dropped by its representor.", MetricTypeCounter, vfCounterLabels}
+
+	vfCounterLabels = []string{"pf", "vf", "representor"}
+)
+
+// MetricDescs returns the descriptions of all the metrics CollectMetrics may return, e.g to describe them to
+// a Prometheus registry
+func MetricDescs() []*MetricDesc {
+	descs := make([]*MetricDesc, 0)
+	for _, d := range []*MetricDesc{metricPfTotalVfs, metricPfNumVfs, metricPfAllocatedVfs, metricPfSwitchdev,
+		metricPfSfs, metricVfRepresentorPresent, metricVfRxBytes, metricVfTxBytes, metricVfRxPackets,
+		metricVfTxPackets, metricVfRxDropped, metricVfTxDropped} {
+		desc := *d
+		desc.Labels = append([]string(nil), d.Labels...)
+		descs = append(descs, &desc)
+	}
+	return descs
+}
+
+// sample returns a sample of the metric, label values are given in the order of the metric labels
+func (d *MetricDesc) sample(value float64, labelValues ...string) *Metric {
+	m := &Metric{Name: d.Name, Help: d.Help, Type: d.Type, Labels: make(map[string]string), Value: value}
+	for i, label := range d.Labels {
+		if i < len(labelValues) {
+			m.Labels[label] = labelValues[i]
+		}
+	}
+	return m
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// isVfAllocated returns true if the VF is likely in use by a workload: bound to vfio-pci, or bound to a
+// netdev driver with its netdev moved out of the host network namespace
+func isVfAllocated(vf *VfInventory) bool {
+	return vf.Driver == vfioPciDriver || (vf.Driver != "" && vf.NetDev == "")
+}
+
+// CollectMetrics returns SR-IOV metrics of the node: VF counts per PF, allocated VFs, eswitch mode,
+// SF counts by state and, for switchdev PFs, representor presence and per VF traffic counters.
+// It is built on GetNodeSriovInventory, so attributes which cannot be retrieved are skipped;
+// an error is returned only if the PFs cannot be listed.
+// Use WriteMetrics to expose the samples in the Prometheus text format, e.g from a node exporter, or the
+// collector of pkg/promcollector to register them with a Prometheus registry.
+func CollectMetrics() ([]*Metric, error) {
+	inventory, err := GetNodeSriovInventory()
+	if err != nil {
+		return nil, err
+	}
+	metrics := make([]*Metric, 0)
+	for _, pf := range inventory.Pfs {
+		metrics = append(metrics, collectPfMetrics(pf)...)
+	}
+	return metrics, nil
+}
+
+func collectPfMetrics(pf *PfInventory) []*Metric {
+	allocated := 0
+	for _, vf := range pf.Vfs {
+		if isVfAllocated(vf) {
+			allocated++
+		}
+	}
+	metrics := []*Metric{
+		metricPfTotalVfs.sample(float64(pf.TotalVfs), pf.PciAddress),
+		metricPfNumVfs.sample(float64(pf.NumVfs), pf.PciAddress),
+		metricPfAllocatedVfs.sample(float64(allocated), pf.PciAddress),
+		metricPfSwitchdev.sample(boolToFloat(pf.Switchdev), pf.PciAddress),
+	}
+	if !pf.Switchdev {
+		return metrics
+	}
+
+	if sfs, err := ListSFs(pf.PciAddress); err == nil {
+		type sfStates struct {
+			state   PortFnState
+			opState PortFnOpState
+		}
+		counts := make(map[sfStates]int)
+		for _, sf := range sfs {
+			counts[sfStates{sf.State, sf.OpState}]++
+		}
+		keys := make([]sfStates, 0, len(counts))
+		for key := range counts {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if keys[i].state != keys[j].state {
+				return keys[i].state < keys[j].state
+			}
+			return keys[i].opState < keys[j].opState
+		})
+		for _, key := range keys {
+			metrics = append(metrics, metricPfSfs.sample(float64(counts[key]), pf.PciAddress,
+				string(key.state), string(key.opState)))
+		}
+	}
+
+	for _, vf := range pf.Vfs {
+		vfIndex := strconv.Itoa(vf.Index)
+		metrics = append(metrics, metricVfRepresentorPresent.sample(boolToFloat(vf.Representor != ""),
+			pf.PciAddress, vfIndex))
+		if vf.Representor == "" {
+			continue
+		}
+		stats, err := GetRepresentorStats(vf.Representor)
+		if err != nil {
+			continue
+		}
+		// representor RX is traffic sent by the VF and vice versa
+		for _, counter := range []struct {
+			desc  *MetricDesc
+			value uint64
+		}{
+			{metricVfRxBytes, stats.TxBytes},
+			{metricVfTxBytes, stats.RxBytes},
+			{metricVfRxPackets, stats.TxPackets},
+			{metricVfTxPackets, stats.RxPackets},
+			{metricVfRxDropped, stats.TxDropped},
+			{metricVfTxDropped, stats.RxDropped},
+		} {
+			metrics = append(metrics, counter.desc.sample(float64(counter.value), pf.PciAddress, vfIndex,
+				vf.Representor))
+		}
+	}
+	return metrics
+}
+
+var (
+	metricLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	metricHelpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+// WriteMetrics writes the given metrics to w in the Prometheus text exposition format.
+// Samples of the same metric are grouped under a single HELP and TYPE header, in order of first appearance.
+func WriteMetrics(w io.Writer, metrics []*Metric) error {
+	names := make([]string, 0)
+	byName := make(map[string][]*Metric)
+	for _, m := range metrics {
+		if _, ok := byName[m.Name]; !ok {
+			names = append(names, m.Name)
+		}
+		byName[m.Name] = append(byName[m.Name], m)
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, name := range names {
+		samples := byName[name]
+		fmt.Fprintf(bw, "# HELP %s %s\n", name, metricHelpEscaper.Replace(samples[0].Help))
+		fmt.Fprintf(bw, "# TYPE %s %s\n", name, samples[0].Type)
+		for _, m := range samples {
+			bw.WriteString(name)
+			if len(m.Labels) > 0 {
+				labels := make([]string, 0, len(m.Labels))
+				for label := range m.Labels {
+					labels = append(labels, label)
+				}
+				sort.Strings(labels)
+				for i, label := range labels {
+					labels[i] = fmt.Sprintf("%s=\"%s\"", label, metricLabelEscaper.Replace(m.Labels[label]))
+				}
+				fmt.Fprintf(bw, "{%s}", strings.Join(labels, ","))
+			}
+			fmt.Fprintf(bw, " %s\n", strconv.FormatFloat(m.Value, 'g', -1, 64))
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sriovnet
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+
+	utilfs "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/filesystem"
+	"github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/netlinkops"
+	netlinkopsMocks "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/netlinkops/mocks"
+)
+
+func TestCollectMetrics(t *testing.T) {
+	teardown := setUpSwitchdevInventoryEnv(t)
+	defer teardown()
+	statsDir := filepath.Join(NetSysDir, "rep0", netdevStatisticsDir)
+	assert.NoError(t, utilfs.Fs.MkdirAll(statsDir, os.FileMode(0755)))
+	for name, value := range map[string]string{"rx_bytes": "100", "tx_bytes": "200", "rx_packets": "1",
+		"tx_packets": "2", "rx_dropped": "0", "tx_dropped": "3"} {
+		assert.NoError(t, utilfs.Fs.WriteFile(filepath.Join(statsDir, name), []byte(value+"\n"), os.FileMode(0644)))
+	}
+
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+	active := &netlink.DevlinkPortFn{State: nl.DEVLINK_PORT_FN_STATE_ACTIVE,
+		OpState: nl.DEVLINK_PORT_FN_OPSTATE_ATTACHED}
+	inactive := &netlink.DevlinkPortFn{State: nl.DEVLINK_PORT_FN_STATE_INACTIVE,
+		OpState: nl.DEVLINK_PORT_FN_OPSTATE_DETACHED}
+	nlOpsMock.On("DevLinkGetAllPortList").Return([]*netlink.DevlinkPort{
+		newSfDevlinkPort("", 32768, active),
+		newSfDevlinkPort("", 32769, inactive),
+		newSfDevlinkPort("", 32770, active),
+	}, nil)
+
+	metrics, err := CollectMetrics()
+	assert.NoError(t, err)
+	values := make(map[string]float64)
+	for _, m := range metrics {
+		values[m.Name+m.Labels["vf"]+m.Labels["state"]] = m.Value
+	}
+	assert.Equal(t, map[string]float64{
+		"sriovnet_pf_total_vfs":            4,
+		"sriovnet_pf_num_vfs":              2,
+		"sriovnet_pf_allocated_vfs":        1,
+		"sriovnet_pf_switchdev":            1,
+		"sriovnet_pf_sfsactive":            2,
+		"sriovnet_pf_sfsinactive":          1,
+		"sriovnet_vf_representor_present0": 1,
+		"sriovnet_vf_representor_present1": 1,
+		"sriovnet_vf_rx_bytes_total0":      200,
+		"sriovnet_vf_tx_bytes_total0":      100,
+		"sriovnet_vf_rx_packets_total0":    2,
+		"sriovnet_vf_tx_packets_total0":    1,
+		"sriovnet_vf_rx_dropped_total0":    3,
+		"sriovnet_vf_tx_dropped_total0":    0,
+	}, values)
+}
+
+func TestCollectMetricsLegacy(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+	setUpPfVfsPciEnv(t, "0000:03:00.0", []string{"0000:03:00.2"})
+	setUpPciAttrs(t, "0000:03:00.0", map[string]string{
+		netDevMaxVfCountFile:     "8\n",
+		netDevCurrentVfCountFile: "1\n",
+	})
+
+	metrics, err := CollectMetrics()
+	assert.NoError(t, err)
+	assert.Len(t, metrics, 4)
+	assert.Equal(t, &Metric{Name: "sriovnet_pf_switchdev", Help: metricPfSwitchdev.Help, Type: MetricTypeGauge,
+		Labels: map[string]string{"pf": "0000:03:00.0"}, Value: 0}, metrics[3])
+}
+
+func TestWriteMetrics(t *testing.T) {
+	desc := &MetricDesc{"sriovnet_test", "Test metric,\nwith a \\ and a newline.", MetricTypeCounter, []string{"pf", "vf"}}
+	var out bytes.Buffer
+	assert.NoError(t, WriteMetrics(&out, []*Metric{
+		desc.sample(1, "0000:03:00.0", "0"),
+		metricPfNumVfs.sample(2.5),
+		desc.sample(1e9, "a\"b\\c\n"),
+	}))
+	assert.Equal(t, `# HELP sriovnet_test Test metric,\nwith a \\ and a newline.
+# TYPE sriovnet_test counter
+sriovnet_test{pf="0000:03:00.0",vf="0"} 1
+sriovnet_test{pf="a\"b\\c\n"} 1e+09
+# HELP sriovnet_pf_num_vfs Number of VFs enabled on the PF.
+# TYPE sriovnet_pf_num_vfs gauge
+sriovnet_pf_num_vfs 2.5
+`, out.String())
+}
+
+func TestMetricDescs(t *testing.T) {
+	descs := MetricDescs()
+	assert.Len(t, descs, 12)
+	assert.Equal(t, &MetricDesc{Name: "sriovnet_pf_sfs", Help: metricPfSfs.Help, Type: MetricTypeGauge,
+		Labels: []string{"pf", "state", "op_state"}}, descs[4])
+	// descriptions are copies
+	descs[0].Labels[0] = "modified"
+	assert.Equal(t, []string{"pf"}, metricPfTotalVfs.Labels)
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sriovnet
+
+import (
+	"fmt"
+)
+
+// Mlx5FlowSteeringMode is the mlx5 flow steering mode, as accepted by the flow_steering_mode devlink param
+type Mlx5FlowSteeringMode string
+
+const (
+	// Mlx5FlowSteeringModeDmfs is the device managed flow steering mode
+	Mlx5FlowSteeringModeDmfs Mlx5FlowSteeringMode = "dmfs"
+	// Mlx5FlowSteeringModeSmfs is the software managed flow steering mode
+	Mlx5FlowSteeringModeSmfs Mlx5FlowSteeringMode = "smfs"
+)
+
+// devlink params commonly used in mlx5 SR-IOV and SF deployments,
+// see https://www.kernel.org/doc/html/latest/networking/devlink/mlx5.html
+const (
+	devlinkParamEnableRoce    = "enable_roce"
+	devlinkParamEnableRdma    = "enable_rdma"
+	devlinkParamEnableEth     = "enable_eth"
+	mlx5ParamEswMultiport     = "esw_multiport"
+	mlx5ParamFlowSteeringMode = "flow_steering_mode"
+)
+
+// getDevlinkBoolParam returns the value of a boolean devlink param of the PCI device with the given address
+func getDevlinkBoolParam(pciAddress, param string, cmode DevlinkParamCMode) (bool, error) {
+	pciAddress, err := normalizePciAddress(pciAddress)
+	if err != nil {
+		return false, err
+	}
+	value, err := DevlinkGetParam(devlinkPciBus, pciAddress, param, cmode)
+	if err != nil {
+		return false, err
+	}
+	enabled, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("devlink param %s of %s is not a boolean", param, pciAddress)
+	}
+	return enabled, nil
+}
+
+// setDevlinkParam sets the value of a devlink param of the PCI device with the given address
+func setDevlinkParam(pciAddress, param string, cmode DevlinkParamCMode, value interface{}) error {
+	pciAddress, err := normalizePciAddress(pciAddress)
+	if err != nil {
+		return err
+	}
+	return DevlinkSetParam(devlinkPciBus, pciAddress, param, cmode, value)
+}
+
+// GetEnableRoce returns true if RoCE is enabled on the next driver initialization of the PCI device with the
+// given address (e.g '0000:03:00.0').
+func GetEnableRoce(pciAddress string) (bool, error) {
+	return getDevlinkBoolParam(pciAddress, devlinkParamEnableRoce, DevlinkParamCModeDriverinit)
+}
+
+// SetEnableRoce enables or disables RoCE on the PCI device with the given address.
+// Takes effect after DevlinkReload.
+// Equivalent to: `devlink dev param set pci/0000:03:00.0 name enable_roce value true cmode driverinit`
+func SetEnableRoce(pciAddress string, enable bool) error {
+	return setDevlinkParam(pciAddress, devlinkParamEnableRoce, DevlinkParamCModeDriverinit, enable)
+}
+
+// GetEnableRdma returns true if the RDMA auxiliary device is enabled on the next driver initialization of
+// the PCI device with the given address.
+func GetEnableRdma(pciAddress string) (bool, error) {
+	return getDevlinkBoolParam(pciAddress, devlinkParamEnableRdma, DevlinkParamCModeDriverinit)
+}
+
+// SetEnableRdma enables or disables the RDMA auxiliary device of the PCI device with the given address.
+// Takes effect after DevlinkReload.
+// Equivalent to: `devlink dev param set pci/0000:03:00.0 name enable_rdma value true cmode driverinit`
+func SetEnableRdma(pciAddress string, enable bool) error {
+	return setDevlinkParam(pciAddress, devlinkParamEnableRdma, DevlinkParamCModeDriverinit, enable)
+}
+
+// GetEnableEth returns true if the ethernet auxiliary device is enabled on the next driver initialization of
+// the PCI device with the given address.
+func GetEnableEth(pciAddress string) (bool, error) {
+	return getDevlinkBoolParam(pciAddress, devlinkParamEnableEth, DevlinkParamCModeDriverinit)
+}
+
+// SetEnableEth enables or disables the ethernet auxiliary device of the PCI device with the given address.
+// Takes effect after DevlinkReload.
+// Equivalent to: `devlink dev param set pci/0000:03:00.0 name enable_eth value true cmode driverinit`
+func SetEnableEth(pciAddress string, enable bool) error {
+	return setDevlinkParam(pciAddress, devlinkParamEnableEth, DevlinkParamCModeDriverinit, enable)
+}
+
+// GetMlx5EswMultiport returns true if the eswitch of the mlx5 PCI device with the given address is in
+// multiport mode, forwarding traffic between the uplinks of a LAG capable NIC without bonding.
+func GetMlx5EswMultiport(pciAddress string) (bool, error) {
+	return getDevlinkBoolParam(pciAddress, mlx5ParamEswMultiport, DevlinkParamCModeRuntime)
+}
+
+// SetMlx5EswMultiport enables or disables the eswitch multiport mode of the mlx5 PCI device with the given
+// address. The device must be in switchdev mode.
+// Equivalent to: `devlink dev param set pci/0000:03:00.0 name esw_multiport value true cmode runtime`
+func SetMlx5EswMultiport(pciAddress string, enable bool) error {
+	return setDevlinkParam(pciAddress, mlx5ParamEswMultiport, DevlinkParamCModeRuntime, enable)
+}
+
+// GetMlx5FlowSteeringMode returns the flow steering mode of the mlx5 PCI device with the given address
+func GetMlx5FlowSteeringMode(pciAddress string) (Mlx5FlowSteeringMode, error) {
+	pciAddress, err := normalizePciAddress(pciAddress)
+	if err != nil {
+		return "", err
+	}
+	value, err := DevlinkGetParam(devlinkPciBus, pciAddress, mlx5ParamFlowSteeringMode, DevlinkParamCModeRuntime)
+	if err != nil {
+		return "", err
+	}
+	mode, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("devlink param %s of %s is not a string", mlx5ParamFlowSteeringMode, pciAddress)
+	}
+	return Mlx5FlowSteeringMode(mode), nil
+}
+
+// SetMlx5FlowSteeringMode sets the flow steering mode of the mlx5 PCI device with the given address.
+// The mode can only be changed while the device is in legacy eswitch mode.
+// Equivalent to: `devlink dev param set pci/0000:03:00.0 name flow_steering_mode value smfs cmode runtime`
+func SetMlx5FlowSteeringMode(pciAddress string, mode Mlx5FlowSteeringMode) error {
+	if mode != Mlx5FlowSteeringModeDmfs && mode != Mlx5FlowSteeringModeSmfs {
+		return fmt.Errorf("invalid flow steering mode %q", mode)
+	}
+	return setDevlinkParam(pciAddress, mlx5ParamFlowSteeringMode, DevlinkParamCModeRuntime, string(mode))
+}
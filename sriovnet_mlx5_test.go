@@ -0,0 +1,115 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sriovnet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+
+	"github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/netlinkops"
+	netlinkopsMocks "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/netlinkops/mocks"
+)
+
+func newBoolDevlinkParam(name string, cmode uint8, value bool) *netlink.DevlinkParam {
+	return &netlink.DevlinkParam{
+		Name:   name,
+		Type:   nl.DEVLINK_PARAM_TYPE_BOOL,
+		Values: []netlink.DevlinkParamValue{{Data: value, CMODE: cmode}},
+	}
+}
+
+func TestMlx5DriverinitParams(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+
+	for _, param := range []string{"enable_roce", "enable_rdma", "enable_eth"} {
+		nlOpsMock.On("DevlinkGetDeviceParamByName", "pci", "0000:03:00.0", param).Return(
+			newBoolDevlinkParam(param, nl.DEVLINK_PARAM_CMODE_DRIVERINIT, true), nil)
+		nlOpsMock.On("DevlinkSetDeviceParam", "pci", "0000:03:00.0", param,
+			uint8(nl.DEVLINK_PARAM_CMODE_DRIVERINIT), false).Return(nil)
+	}
+
+	for _, get := range []func(string) (bool, error){GetEnableRoce, GetEnableRdma, GetEnableEth} {
+		enabled, err := get("0000:03:00.0")
+		assert.NoError(t, err)
+		assert.True(t, enabled)
+	}
+	for _, set := range []func(string, bool) error{SetEnableRoce, SetEnableRdma, SetEnableEth} {
+		assert.NoError(t, set("0000:03:00.0", false))
+	}
+	nlOpsMock.AssertExpectations(t)
+}
+
+func TestMlx5EswMultiport(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+
+	nlOpsMock.On("DevlinkGetDeviceParamByName", "pci", "0000:03:00.0", "esw_multiport").Return(
+		newBoolDevlinkParam("esw_multiport", nl.DEVLINK_PARAM_CMODE_RUNTIME, false), nil)
+	nlOpsMock.On("DevlinkSetDeviceParam", "pci", "0000:03:00.0", "esw_multiport",
+		uint8(nl.DEVLINK_PARAM_CMODE_RUNTIME), true).Return(nil)
+
+	enabled, err := GetMlx5EswMultiport("0000:03:00.0")
+	assert.NoError(t, err)
+	assert.False(t, enabled)
+	assert.NoError(t, SetMlx5EswMultiport("0000:03:00.0", true))
+	assert.Error(t, SetMlx5EswMultiport("03:00.0:1", true))
+	nlOpsMock.AssertExpectations(t)
+}
+
+func TestMlx5FlowSteeringMode(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+
+	nlOpsMock.On("DevlinkGetDeviceParamByName", "pci", "0000:03:00.0", "flow_steering_mode").Return(
+		&netlink.DevlinkParam{
+			Name:   "flow_steering_mode",
+			Type:   nl.DEVLINK_PARAM_TYPE_STRING,
+			Values: []netlink.DevlinkParamValue{{Data: "dmfs", CMODE: nl.DEVLINK_PARAM_CMODE_RUNTIME}},
+		}, nil)
+	nlOpsMock.On("DevlinkSetDeviceParam", "pci", "0000:03:00.0", "flow_steering_mode",
+		uint8(nl.DEVLINK_PARAM_CMODE_RUNTIME), "smfs").Return(nil)
+
+	mode, err := GetMlx5FlowSteeringMode("0000:03:00.0")
+	assert.NoError(t, err)
+	assert.Equal(t, Mlx5FlowSteeringModeDmfs, mode)
+	assert.NoError(t, SetMlx5FlowSteeringMode("0000:03:00.0", Mlx5FlowSteeringModeSmfs))
+	assert.Error(t, SetMlx5FlowSteeringMode("0000:03:00.0", Mlx5FlowSteeringMode("hmfs")))
+	nlOpsMock.AssertExpectations(t)
+}
+
+func TestMlx5ParamWrongType(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+
+	nlOpsMock.On("DevlinkGetDeviceParamByName", "pci", "0000:03:00.0", "enable_eth").Return(
+		&netlink.DevlinkParam{
+			Name:   "enable_eth",
+			Type:   nl.DEVLINK_PARAM_TYPE_U8,
+			Values: []netlink.DevlinkParamValue{{Data: uint8(1), CMODE: nl.DEVLINK_PARAM_CMODE_DRIVERINIT}},
+		}, nil)
+
+	_, err := GetEnableEth("0000:03:00.0")
+	assert.Error(t, err)
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sriovnet
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/netlinkops"
+)
+
+const (
+	// netdevNameMaxLen is the maximal length of a netdev name (IFNAMSIZ - 1)
+	netdevNameMaxLen = 15
+	// netnsMoveTmpNamePrefix prefixes the temporary name of a VF netdev moved to another network namespace
+	netnsMoveTmpNamePrefix = "sriovnet"
+)
+
+// The lookups of this file resolve netdevs inside a given network namespace (e.g the netns of a
+// container a VF was moved to). sysfs only shows the netdevs of the network namespace it was mounted
+// in, hence netdevs are listed via netlink and matched to their PCI device via ethtool from within
+// the namespace.
+
+// GetNetDevicesFromPciInNetns gets a PCI address (e.g '0000:03:00.4') and returns the netdevices
+// of that PCI device in the network namespace at the given path (e.g /var/run/netns/cni-1234).
+func GetNetDevicesFromPciInNetns(pciAddress, netnsPath string) ([]string, error) {
+	pciAddress, err := normalizePciAddress(pciAddress)
+	if err != nil {
+		return nil, err
+	}
+	nlOps := netlinkops.GetNetlinkOps()
+	links, err := nlOps.LinkListAt(netnsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	netdevs := make([]string, 0)
+	for _, link := range links {
+		busInfo, err := nlOps.LinkBusInfoAt(netnsPath, link.Attrs().Name)
+		if err != nil || busInfo != pciAddress {
+			continue
+		}
+		netdevs = append(netdevs, link.Attrs().Name)
+	}
+	return netdevs, nil
+}
+
+// GetPciFromNetDeviceInNetns returns the PCI address associated with a network device name in the
+// network namespace at the given path.
+func GetPciFromNetDeviceInNetns(name, netnsPath string) (string, error) {
+	busInfo, err := netlinkops.GetNetlinkOps().LinkBusInfoAt(netnsPath, name)
+	if err != nil {
+		return "", fmt.Errorf("device %s not found in netns %s: %v", name, netnsPath, err)
+	}
+	pciAddress, err := normalizePciAddress(busInfo)
+	if err != nil {
+		return "", fmt.Errorf("device %s in netns %s is not a PCI device: %v", name, netnsPath, err)
+	}
+	return pciAddress, nil
+}
+
+// GetVfRepresentorByNetdevInNetns returns the representor netdev name of the VF with the given netdev
+// name in the network namespace at the given path. The representor is looked up in the current
+// network namespace.
+func GetVfRepresentorByNetdevInNetns(vfNetdev, netnsPath string) (string, error) {
+	vfPciAddress, err := GetPciFromNetDeviceInNetns(vfNetdev, netnsPath)
+	if err != nil {
+		return "", err
+	}
+	return GetVfRepresentorByVfPciAddress(vfPciAddress)
+}
+
+// isValidNetdevName returns true if the kernel accepts the given name as a netdev name
+func isValidNetdevName(name string) bool {
+	if name == "" || len(name) > netdevNameMaxLen || name == "." || name == ".." {
+		return false
+	}
+	return !strings.ContainsAny(name, "/: \t\n")
+}
+
+// MoveVfToNetns moves the VF netdev of the current network namespace to the network namespace at the given path
+// and renames it to newName in there (the VF keeps its name if newName is empty). The netdev is left down in
+// the target namespace. On failure the VF is returned to the current namespace with its original name and
+// admin state. ErrNetDeviceExists is returned if newName is already used in the target namespace.
+func MoveVfToNetns(vfNetdev, netnsPath, newName string) error {
+	if newName == "" {
+		newName = vfNetdev
+	}
+	if !isValidNetdevName(newName) {
+		return fmt.Errorf("invalid netdev name %q", newName)
+	}
+
+	nlOps := netlinkops.GetNetlinkOps()
+	link, err := nlOps.LinkByName(vfNetdev)
+	if err != nil {
+		return fmt.Errorf("failed to get link of %s: %v", vfNetdev, err)
+	}
+	if _, err = nlOps.LinkByNameAt(netnsPath, newName); err == nil {
+		return fmt.Errorf("%w: %s in netns %s", ErrNetDeviceExists, newName, netnsPath)
+	}
+
+	wasUp := link.Attrs().Flags&net.FlagUp != 0
+	// netdevs can't be renamed while up
+	if wasUp {
+		if err = nlOps.LinkSetDown(link); err != nil {
+			return fmt.Errorf("failed to set %s down: %v", vfNetdev, err)
+		}
+	}
+	// the VF is moved under a temporary name as its current name may be used in the target namespace
+	tmpName := fmt.Sprintf("%s%d", netnsMoveTmpNamePrefix, link.Attrs().Index)
+	rollback := func(moveErr error, renamed, moved bool) error {
+		if moved {
+			nsLink, err := nlOps.LinkByNameAt(netnsPath, tmpName)
+			if err == nil {
+				err = nlOps.LinkSetNsCurrentFrom(netnsPath, nsLink)
+			}
+			if err != nil {
+				return fmt.Errorf("%v, failed to return %s from netns %s: %v", moveErr, tmpName, netnsPath, err)
+			}
+		}
+		if renamed {
+			tmpLink, err := nlOps.LinkByName(tmpName)
+			if err == nil {
+				err = nlOps.LinkSetName(tmpLink, vfNetdev)
+			}
+			if err != nil {
+				return fmt.Errorf("%v, failed to restore name %s of %s: %v", moveErr, vfNetdev, tmpName, err)
+			}
+		}
+		if wasUp {
+			upLink, err := nlOps.LinkByName(vfNetdev)
+			if err == nil {
+				err = nlOps.LinkSetUp(upLink)
+			}
+			if err != nil {
+				return fmt.Errorf("%v, failed to set %s up: %v", moveErr, vfNetdev, err)
+			}
+		}
+		return moveErr
+	}
+
+	if err = nlOps.LinkSetName(link, tmpName); err != nil {
+		return rollback(fmt.Errorf("failed to rename %s to %s: %v", vfNetdev, tmpName, err), false, false)
+	}
+	if err = nlOps.LinkSetNs(link, netnsPath); err != nil {
+		return rollback(fmt.Errorf("failed to move %s to netns %s: %v", vfNetdev, netnsPath, err), true, false)
+	}
+	nsLink, err := nlOps.LinkByNameAt(netnsPath, tmpName)
+	if err == nil {
+		err = nlOps.LinkSetNameAt(netnsPath, nsLink, newName)
+	}
+	if err != nil {
+		return rollback(fmt.Errorf("failed to rename %s to %s in netns %s: %v", vfNetdev, newName, netnsPath, err),
+			true, true)
+	}
+	return nil
+}
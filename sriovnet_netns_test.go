@@ -0,0 +1,176 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sriovnet
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/vishvananda/netlink"
+
+	utilfs "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/filesystem"
+	"github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/netlinkops"
+	netlinkopsMocks "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/netlinkops/mocks"
+)
+
+const testNetnsPath = "/var/run/netns/cni-1234"
+
+// setUpNetnsLinksMock sets a netlinkops mock reporting the given netdev to bus info map as the links of testNetnsPath
+func setUpNetnsLinksMock(busInfos map[string]string) *netlinkopsMocks.NetlinkOps {
+	nlOpsMock := &netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(nlOpsMock)
+	links := make([]netlink.Link, 0)
+	for _, name := range []string{"lo", "eth0", "net1", "net2"} {
+		links = append(links, &netlink.Device{LinkAttrs: netlink.LinkAttrs{Name: name}})
+		if busInfo, ok := busInfos[name]; ok {
+			nlOpsMock.On("LinkBusInfoAt", testNetnsPath, name).Return(busInfo, nil)
+		} else {
+			nlOpsMock.On("LinkBusInfoAt", testNetnsPath, name).Return("", fmt.Errorf("operation not supported"))
+		}
+	}
+	nlOpsMock.On("LinkListAt", testNetnsPath).Return(links, nil)
+	nlOpsMock.On("LinkListAt", "/var/run/netns/missing").Return(nil, fmt.Errorf("no such file or directory"))
+	return nlOpsMock
+}
+
+func TestGetNetDevicesFromPciInNetns(t *testing.T) {
+	defer netlinkops.ResetNetlinkOps()
+	setUpNetnsLinksMock(map[string]string{"eth0": "", "net1": "0000:03:00.2", "net2": "0000:03:00.3"})
+
+	netdevs, err := GetNetDevicesFromPciInNetns("0000:03:00.3", testNetnsPath)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"net2"}, netdevs)
+
+	netdevs, err = GetNetDevicesFromPciInNetns("0000:03:00.4", testNetnsPath)
+	assert.NoError(t, err)
+	assert.Empty(t, netdevs)
+
+	_, err = GetNetDevicesFromPciInNetns("0000:03:00.3", "/var/run/netns/missing")
+	assert.Error(t, err)
+	_, err = GetNetDevicesFromPciInNetns("03:00.3.1", testNetnsPath)
+	assert.Error(t, err)
+}
+
+func TestGetPciFromNetDeviceInNetns(t *testing.T) {
+	defer netlinkops.ResetNetlinkOps()
+	setUpNetnsLinksMock(map[string]string{"eth0": "", "net1": "0000:03:00.2"})
+
+	pciAddress, err := GetPciFromNetDeviceInNetns("net1", testNetnsPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "0000:03:00.2", pciAddress)
+
+	// veth devices report an empty bus info
+	_, err = GetPciFromNetDeviceInNetns("eth0", testNetnsPath)
+	assert.Error(t, err)
+	_, err = GetPciFromNetDeviceInNetns("lo", testNetnsPath)
+	assert.Error(t, err)
+}
+
+func TestGetVfRepresentorByNetdevInNetns(t *testing.T) {
+	pfPciAddress := "0000:03:00.0"
+	vfs := []string{"0000:03:00.2", "0000:03:00.3"}
+	uplinkRep := &repContext{"p0", "p0", "c2cfc60003a1420c"}
+	vfReps := []*repContext{
+		{"pf0vf0", "pf0vf0", "c2cfc60003a1420c"},
+		{"pf0vf1", "pf0vf1", "c2cfc60003a1420c"},
+	}
+	teardown := setupRepresentorEnv(t, "", append(vfReps, uplinkRep))
+	defer teardown()
+	setUpPfVfsPciEnv(t, pfPciAddress, vfs)
+	pfPciPath := filepath.Join(PciSysDir, pfPciAddress)
+	assert.NoError(t, utilfs.Fs.MkdirAll(filepath.Join(pfPciPath, "net", uplinkRep.Name), os.FileMode(0755)))
+	assert.NoError(t, utilfs.Fs.Symlink(pfPciPath, filepath.Join(NetSysDir, uplinkRep.Name, pcidevPrefix)))
+	assert.NoError(t, utilfs.Fs.Symlink(NetSysDir, filepath.Join(NetSysDir, uplinkRep.Name, "subsystem")))
+	defer netlinkops.ResetNetlinkOps()
+	setUpNetnsLinksMock(map[string]string{"net1": vfs[1]})
+
+	vfRep, err := GetVfRepresentorByNetdevInNetns("net1", testNetnsPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "pf0vf1", vfRep)
+
+	_, err = GetVfRepresentorByNetdevInNetns("net2", testNetnsPath)
+	assert.Error(t, err)
+}
+
+func TestMoveVfToNetns(t *testing.T) {
+	defer netlinkops.ResetNetlinkOps()
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	link := &netlink.Device{LinkAttrs: netlink.LinkAttrs{Index: 7, Name: "eth1", Flags: net.FlagUp}}
+	nsLink := &netlink.Device{LinkAttrs: netlink.LinkAttrs{Index: 3, Name: "sriovnet7"}}
+	nlOpsMock.On("LinkByName", "eth1").Return(link, nil)
+	nlOpsMock.On("LinkByNameAt", testNetnsPath, "net1").Return(nil, fmt.Errorf("link not found"))
+	nlOpsMock.On("LinkSetDown", link).Return(nil)
+	nlOpsMock.On("LinkSetName", link, "sriovnet7").Return(nil)
+	nlOpsMock.On("LinkSetNs", link, testNetnsPath).Return(nil)
+	nlOpsMock.On("LinkByNameAt", testNetnsPath, "sriovnet7").Return(nsLink, nil)
+	nlOpsMock.On("LinkSetNameAt", testNetnsPath, nsLink, "net1").Return(nil)
+
+	assert.NoError(t, MoveVfToNetns("eth1", testNetnsPath, "net1"))
+	nlOpsMock.AssertExpectations(t)
+}
+
+func TestMoveVfToNetnsNameExists(t *testing.T) {
+	defer netlinkops.ResetNetlinkOps()
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	link := &netlink.Device{LinkAttrs: netlink.LinkAttrs{Index: 7, Name: "eth0"}}
+	nlOpsMock.On("LinkByName", "eth0").Return(link, nil)
+	nlOpsMock.On("LinkByNameAt", testNetnsPath, "eth0").Return(
+		&netlink.Veth{LinkAttrs: netlink.LinkAttrs{Index: 2, Name: "eth0"}}, nil)
+
+	err := MoveVfToNetns("eth0", testNetnsPath, "")
+	assert.ErrorIs(t, err, ErrNetDeviceExists)
+	nlOpsMock.AssertNotCalled(t, "LinkSetName", link, mock.Anything)
+
+	for _, name := range []string{"net1/0", "averyveryverylongname", ".."} {
+		assert.Error(t, MoveVfToNetns("eth0", testNetnsPath, name), name)
+	}
+}
+
+func TestMoveVfToNetnsRollback(t *testing.T) {
+	defer netlinkops.ResetNetlinkOps()
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	link := &netlink.Device{LinkAttrs: netlink.LinkAttrs{Index: 7, Name: "eth1", Flags: net.FlagUp}}
+	nsLink := &netlink.Device{LinkAttrs: netlink.LinkAttrs{Index: 3, Name: "sriovnet7"}}
+	tmpLink := &netlink.Device{LinkAttrs: netlink.LinkAttrs{Index: 9, Name: "sriovnet7"}}
+	restoredLink := &netlink.Device{LinkAttrs: netlink.LinkAttrs{Index: 9, Name: "eth1"}}
+	nlOpsMock.On("LinkByName", "eth1").Return(link, nil).Once()
+	nlOpsMock.On("LinkByNameAt", testNetnsPath, "net1").Return(nil, fmt.Errorf("link not found"))
+	nlOpsMock.On("LinkSetDown", link).Return(nil)
+	nlOpsMock.On("LinkSetName", link, "sriovnet7").Return(nil)
+	nlOpsMock.On("LinkSetNs", link, testNetnsPath).Return(nil)
+	nlOpsMock.On("LinkByNameAt", testNetnsPath, "sriovnet7").Return(nsLink, nil)
+	nlOpsMock.On("LinkSetNameAt", testNetnsPath, nsLink, "net1").Return(fmt.Errorf("device or resource busy"))
+	// rollback
+	nlOpsMock.On("LinkSetNsCurrentFrom", testNetnsPath, nsLink).Return(nil)
+	nlOpsMock.On("LinkByName", "sriovnet7").Return(tmpLink, nil)
+	nlOpsMock.On("LinkSetName", tmpLink, "eth1").Return(nil)
+	nlOpsMock.On("LinkByName", "eth1").Return(restoredLink, nil)
+	nlOpsMock.On("LinkSetUp", restoredLink).Return(nil)
+
+	err := MoveVfToNetns("eth1", testNetnsPath, "net1")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "device or resource busy")
+	nlOpsMock.AssertExpectations(t)
+}
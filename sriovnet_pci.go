@@ -0,0 +1,708 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sriovnet
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+
+	utilfs "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/filesystem"
+)
+
+const (
+	PciDriversSysDir  = "/sys/bus/pci/drivers"
+	IommuGroupsSysDir = "/sys/kernel/iommu_groups"
+
+	pciDevDriverLink       = "driver"
+	pciDevIommuGroupLink   = "iommu_group"
+	pciDevDriverOverride   = "driver_override"
+	pciDevResetFile        = "reset"
+	pciDevSriovOffsetFile  = "sriov_offset"
+	pciDevSriovStrideFile  = "sriov_stride"
+	pciDriversProbeFile    = "/sys/bus/pci/drivers_probe"
+	pciDriverOverrideClear = "\n"
+	pciDriverNewIDFile     = "new_id"
+	pciDriverRemoveIDFile  = "remove_id"
+
+	vfioPciDriver = "vfio-pci"
+
+	// PCI class codes, see https://pci-ids.ucw.cz/read/PD
+	pciClassNetwork              = 0x02
+	pciSubclassNetworkEthernet   = 0x00
+	pciSubclassNetworkInfiniband = 0x07
+	pciClassShift                = 16
+	pciSubclassShift             = 8
+	pciSubclassMask              = 0xff
+
+	pciLinkWidthUnknown = 255
+
+	// NoNumaNode is returned as the NUMA node of PCI devices with no NUMA affinity
+	NoNumaNode = -1
+)
+
+// writePciSysfsFile writes value to an existing sysfs attribute file
+func writePciSysfsFile(path, value string) error {
+	if _, err := utilfs.Fs.Stat(path); err != nil {
+		return fmt.Errorf("couldn't stat sysfs file %s: %v", path, err)
+	}
+	if err := writeSysfsFile(path, value); err != nil {
+		return fmt.Errorf("failed to write %q to %s: %v", value, path, err)
+	}
+	return nil
+}
+
+// GetDriverNameByPciAddress returns the name of the driver (e.g 'mlx5_core') the PCI device with the given
+// address (e.g '0000:03:00.4') is bound to. Returns ErrNoDriver if the device is not bound to any driver.
+func GetDriverNameByPciAddress(pciAddress string) (string, error) {
+	pciAddress, err := normalizePciAddress(pciAddress)
+	if err != nil {
+		return "", err
+	}
+	pciPath := filepath.Join(PciSysDir, pciAddress)
+	if _, err := utilfs.Fs.Stat(pciPath); err != nil {
+		return "", fmt.Errorf("PCI device %s not found: %v", pciAddress, err)
+	}
+
+	driverLink := filepath.Join(pciPath, pciDevDriverLink)
+	driverPath, err := utilfs.Fs.Readlink(driverLink)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", ErrNoDriver
+		}
+		return "", fmt.Errorf("failed to read driver link of %s: %v", pciAddress, err)
+	}
+	return filepath.Base(driverPath), nil
+}
+
+// IsPciBoundToDriver returns true if the PCI device with the given address (e.g '0000:03:00.4')
+// is bound to the specified driver (e.g 'mlx5_core', 'vfio-pci', 'uio_pci_generic').
+func IsPciBoundToDriver(pciAddress, driver string) bool {
+	driverName, err := GetDriverNameByPciAddress(pciAddress)
+	if err != nil {
+		return false
+	}
+	return driverName == driver
+}
+
+// getVfPciAddressMapFromPfPci returns a map of VF index to VF PCI address for the PF with the given PCI address
+func getVfPciAddressMapFromPfPci(pfPciAddress string) (map[int]string, error) {
+	pfPath := filepath.Join(PciSysDir, pfPciAddress)
+	files, err := utilfs.Fs.ReadDir(pfPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PCI device directory of %s: %v", pfPciAddress, err)
+	}
+
+	vfs := make(map[int]string)
+	for _, file := range files {
+		matches := virtFnRe.FindStringSubmatch(file.Name())
+		if len(matches) != 2 || matches[0] != file.Name() {
+			continue
+		}
+		vfIndex, err := strconv.Atoi(matches[1])
+		if err != nil {
+			continue
+		}
+		vfPath, err := utilfs.Fs.Readlink(filepath.Join(pfPath, file.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s link of %s: %v", file.Name(), pfPciAddress, err)
+		}
+		vfs[vfIndex] = filepath.Base(vfPath)
+	}
+	return vfs, nil
+}
+
+// getVfPciAddressesFromPfPci returns the PCI addresses of the VFs of the PF with the given PCI address
+// ordered by VF index
+func getVfPciAddressesFromPfPci(pfPciAddress string) ([]string, error) {
+	vfs, err := getVfPciAddressMapFromPfPci(pfPciAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	indexes := make([]int, 0, len(vfs))
+	for vfIndex := range vfs {
+		indexes = append(indexes, vfIndex)
+	}
+	sort.Ints(indexes)
+	vfPciAddresses := make([]string, 0, len(indexes))
+	for _, vfIndex := range indexes {
+		vfPciAddresses = append(vfPciAddresses, vfs[vfIndex])
+	}
+	return vfPciAddresses, nil
+}
+
+// BindDriverByPciAddress binds the PCI device with the given address (e.g '0000:03:00.4')
+// to the specified driver (e.g 'mlx5_core', 'vfio-pci').
+func BindDriverByPciAddress(pciAddress, driver string) error {
+	pciAddress, err := normalizePciAddress(pciAddress)
+	if err != nil {
+		return err
+	}
+	bindFile := filepath.Join(PciDriversSysDir, driver, netdevBindFile)
+	if err := writePciSysfsFile(bindFile, pciAddress); err != nil {
+		return fmt.Errorf("failed to bind %s to driver %s: %v", pciAddress, driver, err)
+	}
+	return nil
+}
+
+// UnbindDriverByPciAddress unbinds the PCI device with the given address (e.g '0000:03:00.4')
+// from its current driver. It is a no-op if the device is not bound to any driver.
+func UnbindDriverByPciAddress(pciAddress string) error {
+	pciAddress, err := normalizePciAddress(pciAddress)
+	if err != nil {
+		return err
+	}
+	if _, err := GetDriverNameByPciAddress(pciAddress); err != nil {
+		if errors.Is(err, ErrNoDriver) {
+			return nil
+		}
+		return err
+	}
+
+	unbindFile := filepath.Join(PciSysDir, pciAddress, pciDevDriverLink, netdevUnbindFile)
+	if err := writePciSysfsFile(unbindFile, pciAddress); err != nil {
+		return fmt.Errorf("failed to unbind %s: %v", pciAddress, err)
+	}
+	return nil
+}
+
+// UnbindAllVfsFromDriver unbinds every VF of the PF with the given PCI address (e.g '0000:03:00.0')
+// from its driver. If driver is not empty, only VFs bound to that driver are unbound.
+// Failures are reported per VF, VFs are processed regardless of failures of other VFs.
+func UnbindAllVfsFromDriver(pfPciAddress, driver string) error {
+	pfPciAddress, err := normalizePciAddress(pfPciAddress)
+	if err != nil {
+		return err
+	}
+	vfs, err := getVfPciAddressesFromPfPci(pfPciAddress)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, vf := range vfs {
+		vfDriver, err := GetDriverNameByPciAddress(vf)
+		if err != nil {
+			if !errors.Is(err, ErrNoDriver) {
+				errs = append(errs, fmt.Errorf("VF %s: %w", vf, err))
+			}
+			continue
+		}
+		if driver != "" && vfDriver != driver {
+			continue
+		}
+		if err = UnbindDriverByPciAddress(vf); err != nil {
+			errs = append(errs, fmt.Errorf("VF %s: %w", vf, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SetPciDriverOverride sets the driver_override of the PCI device with the given address so that
+// only the specified driver may bind to it on the next probe.
+func SetPciDriverOverride(pciAddress, driver string) error {
+	pciAddress, err := normalizePciAddress(pciAddress)
+	if err != nil {
+		return err
+	}
+	overrideFile := filepath.Join(PciSysDir, pciAddress, pciDevDriverOverride)
+	if err := writePciSysfsFile(overrideFile, driver); err != nil {
+		return fmt.Errorf("failed to set driver override of %s to %s: %v", pciAddress, driver, err)
+	}
+	return nil
+}
+
+// ClearPciDriverOverride clears the driver_override of the PCI device with the given address,
+// allowing the kernel to match its default driver on the next probe.
+func ClearPciDriverOverride(pciAddress string) error {
+	pciAddress, err := normalizePciAddress(pciAddress)
+	if err != nil {
+		return err
+	}
+	overrideFile := filepath.Join(PciSysDir, pciAddress, pciDevDriverOverride)
+	if err := writePciSysfsFile(overrideFile, pciDriverOverrideClear); err != nil {
+		return fmt.Errorf("failed to clear driver override of %s: %v", pciAddress, err)
+	}
+	return nil
+}
+
+// GetPciDriverOverride returns the driver_override of the PCI device with the given address.
+// Returns an empty string if no override is set.
+func GetPciDriverOverride(pciAddress string) (string, error) {
+	pciAddress, err := normalizePciAddress(pciAddress)
+	if err != nil {
+		return "", err
+	}
+	overrideFile := filepath.Join(PciSysDir, pciAddress, pciDevDriverOverride)
+	content, err := utilfs.Fs.ReadFile(overrideFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read driver override of %s: %v", pciAddress, err)
+	}
+	driver := strings.TrimSpace(string(content))
+	if driver == "(null)" {
+		return "", nil
+	}
+	return driver, nil
+}
+
+// ProbePciDriver asks the kernel to probe drivers for the PCI device with the given address.
+// Combined with SetPciDriverOverride this deterministically binds the device to the override driver.
+func ProbePciDriver(pciAddress string) error {
+	pciAddress, err := normalizePciAddress(pciAddress)
+	if err != nil {
+		return err
+	}
+	if err := writePciSysfsFile(pciDriversProbeFile, pciAddress); err != nil {
+		return fmt.Errorf("failed to probe driver for %s: %v", pciAddress, err)
+	}
+	return nil
+}
+
+// PciDeviceIDs holds the identification attributes of a PCI device
+type PciDeviceIDs struct {
+	Vendor          uint16
+	Device          uint16
+	SubsystemVendor uint16
+	SubsystemDevice uint16
+	Revision        uint8
+}
+
+// readPciHexAttr reads a hexadecimal sysfs attribute (e.g '0x15b3') of the PCI device with the given address
+func readPciHexAttr(pciAddress, attr string, bitSize int) (uint64, error) {
+	pciAddress, err := normalizePciAddress(pciAddress)
+	if err != nil {
+		return 0, err
+	}
+	content, err := utilfs.Fs.ReadFile(filepath.Join(PciSysDir, pciAddress, attr))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s of %s: %v", attr, pciAddress, err)
+	}
+	value, err := strconv.ParseUint(strings.TrimPrefix(strings.TrimSpace(string(content)), "0x"), 16, bitSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %s of %s: %v", attr, pciAddress, err)
+	}
+	return value, nil
+}
+
+// GetPciDeviceIDs returns the vendor, device, subsystem vendor, subsystem device and revision IDs
+// of the PCI device with the given address (e.g '0000:03:00.4')
+func GetPciDeviceIDs(pciAddress string) (*PciDeviceIDs, error) {
+	ids := &PciDeviceIDs{}
+	u16Attrs := map[string]*uint16{
+		"vendor":           &ids.Vendor,
+		"device":           &ids.Device,
+		"subsystem_vendor": &ids.SubsystemVendor,
+		"subsystem_device": &ids.SubsystemDevice,
+	}
+	for attr, field := range u16Attrs {
+		value, err := readPciHexAttr(pciAddress, attr, 16)
+		if err != nil {
+			return nil, err
+		}
+		*field = uint16(value)
+	}
+
+	revision, err := readPciHexAttr(pciAddress, "revision", 8)
+	if err != nil {
+		return nil, err
+	}
+	ids.Revision = uint8(revision)
+	return ids, nil
+}
+
+// GetPciClass returns the class code of the PCI device with the given address (e.g '0000:03:00.4')
+// in the 0xCCSSPP format, where CC is the base class, SS the subclass and PP the programming interface.
+func GetPciClass(pciAddress string) (uint32, error) {
+	class, err := readPciHexAttr(pciAddress, "class", 32)
+	if err != nil {
+		return 0, err
+	}
+	return uint32(class), nil
+}
+
+// pciClassMatches returns true if the PCI device with the given address has the given base class and,
+// if subclass is not negative, the given subclass
+func pciClassMatches(pciAddress string, baseClass uint32, subclass int) bool {
+	class, err := GetPciClass(pciAddress)
+	if err != nil {
+		return false
+	}
+	if class>>pciClassShift != baseClass {
+		return false
+	}
+	return subclass < 0 || (class>>pciSubclassShift)&pciSubclassMask == uint32(subclass)
+}
+
+// IsPciNetworkDevice returns true if the PCI device with the given address is a network controller
+func IsPciNetworkDevice(pciAddress string) bool {
+	return pciClassMatches(pciAddress, pciClassNetwork, -1)
+}
+
+// IsPciEthernetDevice returns true if the PCI device with the given address is an Ethernet controller
+func IsPciEthernetDevice(pciAddress string) bool {
+	return pciClassMatches(pciAddress, pciClassNetwork, pciSubclassNetworkEthernet)
+}
+
+// IsPciInfinibandDevice returns true if the PCI device with the given address is an InfiniBand controller
+func IsPciInfinibandDevice(pciAddress string) bool {
+	return pciClassMatches(pciAddress, pciClassNetwork, pciSubclassNetworkInfiniband)
+}
+
+// GetPciNumaNode returns the NUMA node of the PCI device with the given address (e.g '0000:03:00.4').
+// Returns NoNumaNode if the device has no NUMA affinity, e.g on single NUMA node systems.
+func GetPciNumaNode(pciAddress string) (int, error) {
+	pciAddress, err := normalizePciAddress(pciAddress)
+	if err != nil {
+		return NoNumaNode, err
+	}
+	content, err := utilfs.Fs.ReadFile(filepath.Join(PciSysDir, pciAddress, "numa_node"))
+	if err != nil {
+		return NoNumaNode, fmt.Errorf("failed to read NUMA node of %s: %v", pciAddress, err)
+	}
+	numaNode, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil {
+		return NoNumaNode, fmt.Errorf("failed to parse NUMA node of %s: %v", pciAddress, err)
+	}
+	if numaNode < 0 {
+		return NoNumaNode, nil
+	}
+	return numaNode, nil
+}
+
+// GetPciIommuGroup returns the IOMMU group number of the PCI device with the given address (e.g '0000:03:00.4')
+func GetPciIommuGroup(pciAddress string) (int, error) {
+	pciAddress, err := normalizePciAddress(pciAddress)
+	if err != nil {
+		return -1, err
+	}
+	groupPath, err := utilfs.Fs.Readlink(filepath.Join(PciSysDir, pciAddress, pciDevIommuGroupLink))
+	if err != nil {
+		return -1, fmt.Errorf("failed to read IOMMU group link of %s: %v", pciAddress, err)
+	}
+	group, err := strconv.Atoi(filepath.Base(groupPath))
+	if err != nil {
+		return -1, fmt.Errorf("failed to parse IOMMU group of %s: %v", pciAddress, err)
+	}
+	return group, nil
+}
+
+// ListDevicesInIommuGroup returns the PCI addresses of all devices in the given IOMMU group
+func ListDevicesInIommuGroup(group int) ([]string, error) {
+	return getFileNamesFromPath(filepath.Join(IommuGroupsSysDir, strconv.Itoa(group), "devices"))
+}
+
+// readPciVendorDeviceIDs returns the vendor and device IDs of the PCI device with the given address
+// in the format expected by the driver new_id and remove_id files, e.g "15b3 101e"
+func readPciVendorDeviceIDs(pciAddress string) (string, error) {
+	vendor, err := readPciHexAttr(pciAddress, "vendor", 16)
+	if err != nil {
+		return "", err
+	}
+	device, err := readPciHexAttr(pciAddress, "device", 16)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%04x %04x", vendor, device), nil
+}
+
+// hasPciDriverOverride returns true if the PCI device with the given address supports driver_override
+func hasPciDriverOverride(pciAddress string) bool {
+	_, err := utilfs.Fs.Stat(filepath.Join(PciSysDir, pciAddress, pciDevDriverOverride))
+	return err == nil
+}
+
+// BindVfToVfio binds the VF with the given PCI address (e.g '0000:03:00.4') to the vfio-pci driver.
+// The VF is unbound from its current driver and re-probed with driver_override set to vfio-pci.
+// On kernels without driver_override support the VF IDs are registered with vfio-pci via new_id instead.
+// If binding fails once the VF was unbound, the VF is bound back to its original driver.
+func BindVfToVfio(vfPciAddress string) error {
+	if IsVfPciVfioBound(vfPciAddress) {
+		return nil
+	}
+
+	origDriver, err := GetDriverNameByPciAddress(vfPciAddress)
+	if err != nil && !errors.Is(err, ErrNoDriver) {
+		return err
+	}
+	if err = UnbindDriverByPciAddress(vfPciAddress); err != nil {
+		return err
+	}
+
+	if err = bindVfToVfio(vfPciAddress); err != nil {
+		if rbErr := restoreVfDriver(vfPciAddress, origDriver); rbErr != nil {
+			return errors.Join(err, fmt.Errorf("failed to restore driver %s of %s: %w", origDriver, vfPciAddress, rbErr))
+		}
+		return err
+	}
+	return nil
+}
+
+// bindVfToVfio binds the unbound VF with the given PCI address to the vfio-pci driver
+func bindVfToVfio(vfPciAddress string) error {
+	if hasPciDriverOverride(vfPciAddress) {
+		if err := SetPciDriverOverride(vfPciAddress, vfioPciDriver); err != nil {
+			return err
+		}
+		if err := ProbePciDriver(vfPciAddress); err != nil {
+			return err
+		}
+	} else {
+		ids, err := readPciVendorDeviceIDs(vfPciAddress)
+		if err != nil {
+			return err
+		}
+		// Writing new_id probes all unbound devices matching the IDs, an error is
+		// returned if the IDs are already registered, in which case bind explicitly.
+		newIDFile := filepath.Join(PciDriversSysDir, vfioPciDriver, pciDriverNewIDFile)
+		if err = writePciSysfsFile(newIDFile, ids); err == nil {
+			// drop the IDs we registered once done, so vfio-pci does not claim other matching devices
+			// probed later on
+			removeIDFile := filepath.Join(PciDriversSysDir, vfioPciDriver, pciDriverRemoveIDFile)
+			defer func() { _ = writePciSysfsFile(removeIDFile, ids) }()
+		}
+		if err != nil || !IsVfPciVfioBound(vfPciAddress) {
+			if err = BindDriverByPciAddress(vfPciAddress, vfioPciDriver); err != nil {
+				return err
+			}
+		}
+	}
+
+	if !IsVfPciVfioBound(vfPciAddress) {
+		return fmt.Errorf("failed to bind %s to %s", vfPciAddress, vfioPciDriver)
+	}
+	return nil
+}
+
+// restoreVfDriver clears the driver_override of the VF with the given PCI address and binds it to driver,
+// an empty driver leaves the VF unbound
+func restoreVfDriver(vfPciAddress, driver string) error {
+	if hasPciDriverOverride(vfPciAddress) {
+		if err := ClearPciDriverOverride(vfPciAddress); err != nil {
+			return err
+		}
+	}
+	if driver == "" {
+		return nil
+	}
+	// the VF may have been bound to vfio-pci in the meantime
+	if err := UnbindDriverByPciAddress(vfPciAddress); err != nil {
+		return err
+	}
+	return BindDriverByPciAddress(vfPciAddress, driver)
+}
+
+// UnbindVfFromVfio unbinds the VF with the given PCI address (e.g '0000:03:00.4') from the vfio-pci driver
+// and binds it to driver, typically the driver it was bound to before BindVfToVfio.
+// If driver is empty the VF is re-probed so the kernel binds it to its default driver.
+func UnbindVfFromVfio(vfPciAddress, driver string) error {
+	if !IsVfPciVfioBound(vfPciAddress) {
+		return fmt.Errorf("device %s is not bound to %s", vfPciAddress, vfioPciDriver)
+	}
+
+	if err := UnbindDriverByPciAddress(vfPciAddress); err != nil {
+		return err
+	}
+
+	if hasPciDriverOverride(vfPciAddress) {
+		if err := ClearPciDriverOverride(vfPciAddress); err != nil {
+			return err
+		}
+	} else {
+		ids, err := readPciVendorDeviceIDs(vfPciAddress)
+		if err != nil {
+			return err
+		}
+		removeIDFile := filepath.Join(PciDriversSysDir, vfioPciDriver, pciDriverRemoveIDFile)
+		// the IDs may have not been registered by us, ignore failures
+		_ = writePciSysfsFile(removeIDFile, ids)
+	}
+
+	if driver != "" {
+		return BindDriverByPciAddress(vfPciAddress, driver)
+	}
+	return ProbePciDriver(vfPciAddress)
+}
+
+// PciLinkStatus describes the speed and width of a PCIe link
+type PciLinkStatus struct {
+	// Speed is the link speed per lane in GT/s (e.g 16 for PCIe Gen4), 0 if unknown
+	Speed float64
+	// Width is the number of lanes (e.g 16 for x16), 0 if unknown
+	Width int
+}
+
+// PciLinkInfo holds the current and maximal (capable) PCIe link status of a PCI device
+type PciLinkInfo struct {
+	Current PciLinkStatus
+	Max     PciLinkStatus
+}
+
+// IsDegraded returns true if the PCIe link trained at a lower speed or width than the device supports,
+// which silently caps the throughput of the device and of its VFs
+func (l *PciLinkInfo) IsDegraded() bool {
+	return (l.Current.Speed > 0 && l.Current.Speed < l.Max.Speed) ||
+		(l.Current.Width > 0 && l.Current.Width < l.Max.Width)
+}
+
+// parsePciLinkSpeed parses a PCIe link speed sysfs attribute, e.g "16.0 GT/s PCIe", "8 GT/s" or "Unknown"
+func parsePciLinkSpeed(speed string) float64 {
+	fields := strings.Fields(speed)
+	if len(fields) == 0 {
+		return 0
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0
+	}
+	return value
+}
+
+// readPciLinkStatus reads the PCIe link speed and width attributes with the given prefix (current or max)
+func readPciLinkStatus(pciAddress, prefix string) (PciLinkStatus, error) {
+	status := PciLinkStatus{}
+	devPath := filepath.Join(PciSysDir, pciAddress)
+	speed, err := utilfs.Fs.ReadFile(filepath.Join(devPath, prefix+"_link_speed"))
+	if err != nil {
+		return status, fmt.Errorf("failed to read %s link speed of %s: %v", prefix, pciAddress, err)
+	}
+	status.Speed = parsePciLinkSpeed(string(speed))
+
+	width, err := utilfs.Fs.ReadFile(filepath.Join(devPath, prefix+"_link_width"))
+	if err != nil {
+		return status, fmt.Errorf("failed to read %s link width of %s: %v", prefix, pciAddress, err)
+	}
+	// unknown widths are reported as 0 or 255 depending on the kernel
+	if value, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(string(width)), "x")); err == nil &&
+		value != pciLinkWidthUnknown {
+		status.Width = value
+	}
+	return status, nil
+}
+
+// GetPciLinkInfo returns the current and maximal PCIe link speed and width of the PCI device with the given
+// address (e.g '0000:03:00.0'). Use IsDegraded to detect a link trained below the device capabilities.
+func GetPciLinkInfo(pciAddress string) (*PciLinkInfo, error) {
+	pciAddress, err := normalizePciAddress(pciAddress)
+	if err != nil {
+		return nil, err
+	}
+	info := &PciLinkInfo{}
+	if info.Current, err = readPciLinkStatus(pciAddress, "current"); err != nil {
+		return nil, err
+	}
+	if info.Max, err = readPciLinkStatus(pciAddress, "max"); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// ResetPciDevice resets the PCI device with the given address (e.g '0000:03:00.4'), e.g to clean a VF state
+// between tenant assignments. The kernel picks the reset method (FLR for VFs). Returns ErrDeviceNotFound if
+// the device does not exist and ErrNotSupported if the device supports no reset method.
+// Note: the device should not be in use, some drivers unbind or fail the reset while the device is busy.
+func ResetPciDevice(pciAddress string) error {
+	pciAddress, err := normalizePciAddress(pciAddress)
+	if err != nil {
+		return err
+	}
+	devPath := filepath.Join(PciSysDir, pciAddress)
+	if _, err = utilfs.Fs.Stat(devPath); err != nil {
+		return fmt.Errorf("%w: %s", ErrDeviceNotFound, pciAddress)
+	}
+	// the reset attribute is only created for devices with at least one reset method
+	resetFile := filepath.Join(devPath, pciDevResetFile)
+	if _, err = utilfs.Fs.Stat(resetFile); err != nil {
+		return fmt.Errorf("%w: %s has no reset method", ErrNotSupported, pciAddress)
+	}
+	if err = writeSysfsFile(resetFile, "1"); err != nil {
+		if errors.Is(err, unix.ENOTTY) {
+			return fmt.Errorf("%w: %s has no reset method", ErrNotSupported, pciAddress)
+		}
+		return fmt.Errorf("failed to reset %s: %v", pciAddress, err)
+	}
+	return nil
+}
+
+// readPfSriovIntAttr reads a decimal SR-IOV sysfs attribute of the PF with the given address
+func readPfSriovIntAttr(pfPciAddress, attr string) (int, error) {
+	pfPciAddress, err := normalizePciAddress(pfPciAddress)
+	if err != nil {
+		return 0, err
+	}
+	if _, err = utilfs.Fs.Stat(filepath.Join(PciSysDir, pfPciAddress)); err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrDeviceNotFound, pfPciAddress)
+	}
+	value, err := readPciIntAttr(pfPciAddress, attr)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, fmt.Errorf("%w: %s is not an SR-IOV PF", ErrNotSupported, pfPciAddress)
+		}
+		return 0, fmt.Errorf("failed to read %s of %s: %v", attr, pfPciAddress, err)
+	}
+	return value, nil
+}
+
+// GetSriovOffset returns the routing ID offset of the first VF of the PF with the given address
+// (e.g '0000:03:00.0'), as exposed by its sriov_offset sysfs attribute.
+// Returns ErrNotSupported if the device is not an SR-IOV PF.
+func GetSriovOffset(pfPciAddress string) (int, error) {
+	return readPfSriovIntAttr(pfPciAddress, pciDevSriovOffsetFile)
+}
+
+// GetSriovStride returns the routing ID distance between two consecutive VFs of the PF with the given
+// address (e.g '0000:03:00.0'), as exposed by its sriov_stride sysfs attribute.
+// Returns ErrNotSupported if the device is not an SR-IOV PF.
+func GetSriovStride(pfPciAddress string) (int, error) {
+	return readPfSriovIntAttr(pfPciAddress, pciDevSriovStrideFile)
+}
+
+// ComputeVfPciAddress computes the PCI address of the VF with the given index of the PF with the given
+// address from the PF offset and stride, per the SR-IOV spec. Unlike the virtfn links, the VF does not need
+// to be enabled, which allows to plan the VF addresses before setting sriov_numvfs.
+// Note: offset and stride may change with the number of enabled VFs (sriov_numvfs).
+func ComputeVfPciAddress(pfPciAddress string, vfIndex int) (string, error) {
+	pf, err := ParsePciAddress(pfPciAddress)
+	if err != nil {
+		return "", err
+	}
+	if vfIndex < 0 {
+		return "", fmt.Errorf("invalid VF index %d", vfIndex)
+	}
+	offset, err := GetSriovOffset(pfPciAddress)
+	if err != nil {
+		return "", err
+	}
+	stride, err := GetSriovStride(pfPciAddress)
+	if err != nil {
+		return "", err
+	}
+	// routing ID is bus(8 bits):device(5 bits):function(3 bits)
+	rid := int(pf.Bus)<<8 | int(pf.Device)<<3 | int(pf.Function)
+	rid += offset + vfIndex*stride
+	if rid > 0xffff {
+		return "", fmt.Errorf("VF %d of %s is out of the routing ID range", vfIndex, pfPciAddress)
+	}
+	vf := PciAddress{Domain: pf.Domain, Bus: uint8(rid >> 8), Device: uint8(rid>>3) & 0x1f, Function: uint8(rid) & 0x7}
+	return vf.String(), nil
+}
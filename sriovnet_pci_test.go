@@ -0,0 +1,574 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sriovnet
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	utilfs "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/filesystem"
+)
+
+// setUpPciDriverEnv creates a PCI driver directory with its bind/unbind files and
+// optionally binds the given PCI devices to it.
+// Note: should not be called directly as it expects FakeFs to be initialized beforehand.
+func setUpPciDriverEnv(t *testing.T, driver string, boundDevs ...string) {
+	driverPath := filepath.Join(PciDriversSysDir, driver)
+	assert.NoError(t, utilfs.Fs.MkdirAll(driverPath, os.FileMode(0755)))
+	for _, f := range []string{netdevBindFile, netdevUnbindFile} {
+		assert.NoError(t, utilfs.Fs.WriteFile(filepath.Join(driverPath, f), []byte(""), os.FileMode(0644)))
+	}
+
+	for _, dev := range boundDevs {
+		pciPath := filepath.Join(PciSysDir, dev)
+		assert.NoError(t, utilfs.Fs.MkdirAll(pciPath, os.FileMode(0755)))
+		assert.NoError(t, utilfs.Fs.Symlink(driverPath, filepath.Join(pciPath, pciDevDriverLink)))
+	}
+}
+
+func readFakeFile(t *testing.T, path string) string {
+	content, err := utilfs.Fs.ReadFile(path)
+	assert.NoError(t, err)
+	return string(content)
+}
+
+func TestBindDriverByPciAddress(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+	setUpPciDriverEnv(t, "mlx5_core")
+
+	err := BindDriverByPciAddress("0000:03:00.2", "mlx5_core")
+	assert.NoError(t, err)
+	assert.Equal(t, "0000:03:00.2", readFakeFile(t, filepath.Join(PciDriversSysDir, "mlx5_core", netdevBindFile)))
+}
+
+func TestBindDriverByPciAddressNoSuchDriver(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+
+	err := BindDriverByPciAddress("0000:03:00.2", "foo")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to bind 0000:03:00.2 to driver foo")
+}
+
+func TestUnbindDriverByPciAddress(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+	setUpPciDriverEnv(t, "mlx5_core", "0000:03:00.2")
+
+	err := UnbindDriverByPciAddress("0000:03:00.2")
+	assert.NoError(t, err)
+	assert.Equal(t, "0000:03:00.2", readFakeFile(t, filepath.Join(PciDriversSysDir, "mlx5_core", netdevUnbindFile)))
+}
+
+func TestUnbindDriverByPciAddressNotBound(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+	_ = utilfs.Fs.MkdirAll(filepath.Join(PciSysDir, "0000:03:00.2"), os.FileMode(0755))
+
+	err := UnbindDriverByPciAddress("0000:03:00.2")
+	assert.NoError(t, err)
+}
+
+// setUpPciDriverOverrideEnv creates the driver_override file of a PCI device and the drivers_probe file.
+// Note: should not be called directly as it expects FakeFs to be initialized beforehand.
+func setUpPciDriverOverrideEnv(t *testing.T, pciAddress, override string) {
+	pciPath := filepath.Join(PciSysDir, pciAddress)
+	assert.NoError(t, utilfs.Fs.MkdirAll(pciPath, os.FileMode(0755)))
+	assert.NoError(t, utilfs.Fs.WriteFile(
+		filepath.Join(pciPath, pciDevDriverOverride), []byte(override), os.FileMode(0644)))
+	assert.NoError(t, utilfs.Fs.WriteFile(pciDriversProbeFile, []byte(""), os.FileMode(0644)))
+}
+
+func TestSetPciDriverOverride(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+	setUpPciDriverOverrideEnv(t, "0000:03:00.2", "(null)\n")
+
+	err := SetPciDriverOverride("0000:03:00.2", "vfio-pci")
+	assert.NoError(t, err)
+	override, err := GetPciDriverOverride("0000:03:00.2")
+	assert.NoError(t, err)
+	assert.Equal(t, "vfio-pci", override)
+
+	err = ClearPciDriverOverride("0000:03:00.2")
+	assert.NoError(t, err)
+	override, err = GetPciDriverOverride("0000:03:00.2")
+	assert.NoError(t, err)
+	assert.Equal(t, "", override)
+}
+
+func TestGetPciDriverOverrideNotSet(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+	setUpPciDriverOverrideEnv(t, "0000:03:00.2", "(null)\n")
+
+	override, err := GetPciDriverOverride("0000:03:00.2")
+	assert.NoError(t, err)
+	assert.Equal(t, "", override)
+}
+
+func TestSetPciDriverOverrideNoSuchDevice(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+
+	err := SetPciDriverOverride("0000:03:00.2", "vfio-pci")
+	assert.Error(t, err)
+	err = ClearPciDriverOverride("0000:03:00.2")
+	assert.Error(t, err)
+}
+
+func TestProbePciDriver(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+	setUpPciDriverOverrideEnv(t, "0000:03:00.2", "vfio-pci\n")
+
+	err := ProbePciDriver("0000:03:00.2")
+	assert.NoError(t, err)
+	assert.Equal(t, "0000:03:00.2", readFakeFile(t, pciDriversProbeFile))
+}
+
+func TestBindVfToVfioAlreadyBound(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+	setUpPciDriverEnv(t, vfioPciDriver, "0000:03:00.2")
+
+	err := BindVfToVfio("0000:03:00.2")
+	assert.NoError(t, err)
+	assert.Equal(t, "", readFakeFile(t, filepath.Join(PciDriversSysDir, vfioPciDriver, netdevUnbindFile)))
+}
+
+func TestBindVfToVfioDriverOverride(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+	setUpPciDriverEnv(t, vfioPciDriver)
+	setUpPciDriverEnv(t, "mlx5_core", "0000:03:00.2")
+	setUpPciDriverOverrideEnv(t, "0000:03:00.2", "(null)\n")
+
+	// The fake FS does not emulate driver probing, hence binding fails and the original driver is restored
+	err := BindVfToVfio("0000:03:00.2")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to bind 0000:03:00.2 to vfio-pci")
+	assert.Equal(t, "0000:03:00.2", readFakeFile(t, filepath.Join(PciDriversSysDir, "mlx5_core", netdevUnbindFile)))
+	assert.Equal(t, "0000:03:00.2", readFakeFile(t, pciDriversProbeFile))
+	assert.Equal(t, "\n", readFakeFile(t, filepath.Join(PciSysDir, "0000:03:00.2", pciDevDriverOverride)))
+	assert.Equal(t, "0000:03:00.2", readFakeFile(t, filepath.Join(PciDriversSysDir, "mlx5_core", netdevBindFile)))
+}
+
+func TestBindVfToVfioProbeFailureRestoresDriver(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+	setUpPciDriverEnv(t, vfioPciDriver)
+	setUpPciDriverEnv(t, "mlx5_core", "0000:03:00.2")
+	setUpPciDriverOverrideEnv(t, "0000:03:00.2", "(null)\n")
+	assert.NoError(t, utilfs.Fs.Remove(pciDriversProbeFile))
+
+	err := BindVfToVfio("0000:03:00.2")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to probe driver for 0000:03:00.2")
+	assert.Equal(t, "\n", readFakeFile(t, filepath.Join(PciSysDir, "0000:03:00.2", pciDevDriverOverride)))
+	assert.Equal(t, "0000:03:00.2", readFakeFile(t, filepath.Join(PciDriversSysDir, "mlx5_core", netdevBindFile)))
+	assert.Equal(t, "", readFakeFile(t, filepath.Join(PciDriversSysDir, vfioPciDriver, netdevBindFile)))
+}
+
+func TestBindVfToVfioNewID(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+	setUpPciDriverEnv(t, vfioPciDriver)
+	setUpPciDriverEnv(t, "mlx5_core", "0000:03:00.2")
+	vfioPath := filepath.Join(PciDriversSysDir, vfioPciDriver)
+	for _, f := range []string{pciDriverNewIDFile, pciDriverRemoveIDFile} {
+		assert.NoError(t, utilfs.Fs.WriteFile(filepath.Join(vfioPath, f), []byte(""), os.FileMode(0644)))
+	}
+	pciPath := filepath.Join(PciSysDir, "0000:03:00.2")
+	assert.NoError(t, utilfs.Fs.WriteFile(filepath.Join(pciPath, "vendor"), []byte("0x15b3\n"), os.FileMode(0644)))
+	assert.NoError(t, utilfs.Fs.WriteFile(filepath.Join(pciPath, "device"), []byte("0x101e\n"), os.FileMode(0644)))
+
+	// The fake FS does not emulate driver probing, hence binding fails and the original driver is restored
+	err := BindVfToVfio("0000:03:00.2")
+	assert.Error(t, err)
+	assert.Equal(t, "15b3 101e", readFakeFile(t, filepath.Join(vfioPath, pciDriverNewIDFile)))
+	assert.Equal(t, "15b3 101e", readFakeFile(t, filepath.Join(vfioPath, pciDriverRemoveIDFile)))
+	assert.Equal(t, "0000:03:00.2", readFakeFile(t, filepath.Join(vfioPath, netdevBindFile)))
+	assert.Equal(t, "0000:03:00.2", readFakeFile(t, filepath.Join(PciDriversSysDir, "mlx5_core", netdevBindFile)))
+}
+
+func TestUnbindVfFromVfio(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+	setUpPciDriverEnv(t, vfioPciDriver, "0000:03:00.2")
+	setUpPciDriverOverrideEnv(t, "0000:03:00.2", "vfio-pci\n")
+
+	err := UnbindVfFromVfio("0000:03:00.2", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "0000:03:00.2", readFakeFile(t, filepath.Join(PciDriversSysDir, vfioPciDriver, netdevUnbindFile)))
+	assert.Equal(t, "\n", readFakeFile(t, filepath.Join(PciSysDir, "0000:03:00.2", pciDevDriverOverride)))
+	assert.Equal(t, "0000:03:00.2", readFakeFile(t, pciDriversProbeFile))
+}
+
+func TestUnbindVfFromVfioRestoreDriver(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+	setUpPciDriverEnv(t, "mlx5_core")
+	setUpPciDriverEnv(t, vfioPciDriver, "0000:03:00.2")
+	setUpPciDriverOverrideEnv(t, "0000:03:00.2", "vfio-pci\n")
+
+	err := UnbindVfFromVfio("0000:03:00.2", "mlx5_core")
+	assert.NoError(t, err)
+	assert.Equal(t, "0000:03:00.2", readFakeFile(t, filepath.Join(PciDriversSysDir, vfioPciDriver, netdevUnbindFile)))
+	assert.Equal(t, "\n", readFakeFile(t, filepath.Join(PciSysDir, "0000:03:00.2", pciDevDriverOverride)))
+	assert.Equal(t, "0000:03:00.2", readFakeFile(t, filepath.Join(PciDriversSysDir, "mlx5_core", netdevBindFile)))
+	assert.Equal(t, "", readFakeFile(t, pciDriversProbeFile))
+}
+
+func TestUnbindVfFromVfioNotBound(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+	setUpPciDriverEnv(t, "mlx5_core", "0000:03:00.2")
+
+	err := UnbindVfFromVfio("0000:03:00.2", "")
+	assert.Error(t, err)
+}
+
+// setUpPfVfsPciEnv creates the PCI device directories of a PF and its VFs along with the virtfn links.
+// Note: should not be called directly as it expects FakeFs to be initialized beforehand.
+func setUpPfVfsPciEnv(t *testing.T, pfPciAddress string, vfPciAddresses []string) {
+	pfPath := filepath.Join(PciSysDir, pfPciAddress)
+	assert.NoError(t, utilfs.Fs.MkdirAll(pfPath, os.FileMode(0755)))
+	for i, vf := range vfPciAddresses {
+		vfPath := filepath.Join(PciSysDir, vf)
+		assert.NoError(t, utilfs.Fs.MkdirAll(vfPath, os.FileMode(0755)))
+		assert.NoError(t, utilfs.Fs.Symlink(vfPath, filepath.Join(pfPath, fmt.Sprintf("virtfn%d", i))))
+		assert.NoError(t, utilfs.Fs.Symlink(pfPath, filepath.Join(vfPath, "physfn")))
+	}
+}
+
+func TestUnbindAllVfsFromDriver(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+	vfs := []string{"0000:03:00.2", "0000:03:00.3", "0000:03:00.4"}
+	setUpPfVfsPciEnv(t, "0000:03:00.0", vfs)
+	setUpPciDriverEnv(t, "mlx5_core", vfs[0], vfs[2])
+	setUpPciDriverEnv(t, vfioPciDriver, vfs[1])
+
+	err := UnbindAllVfsFromDriver("0000:03:00.0", "mlx5_core")
+	assert.NoError(t, err)
+	// the fake fs keeps only the last written value
+	assert.Equal(t, vfs[2], readFakeFile(t, filepath.Join(PciDriversSysDir, "mlx5_core", netdevUnbindFile)))
+	assert.Equal(t, "", readFakeFile(t, filepath.Join(PciDriversSysDir, vfioPciDriver, netdevUnbindFile)))
+
+	err = UnbindAllVfsFromDriver("0000:03:00.0", "")
+	assert.NoError(t, err)
+	assert.Equal(t, vfs[1], readFakeFile(t, filepath.Join(PciDriversSysDir, vfioPciDriver, netdevUnbindFile)))
+}
+
+func TestUnbindAllVfsFromDriverPerVfErrors(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+	vfs := []string{"0000:03:00.2", "0000:03:00.3"}
+	setUpPfVfsPciEnv(t, "0000:03:00.0", vfs)
+	setUpPciDriverEnv(t, "mlx5_core", vfs[1])
+	// driver without unbind file
+	brokenDriverPath := filepath.Join(PciDriversSysDir, "broken")
+	_ = utilfs.Fs.MkdirAll(brokenDriverPath, os.FileMode(0755))
+	_ = utilfs.Fs.Symlink(brokenDriverPath, filepath.Join(PciSysDir, vfs[0], pciDevDriverLink))
+
+	err := UnbindAllVfsFromDriver("0000:03:00.0", "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "VF 0000:03:00.2")
+	assert.NotContains(t, err.Error(), "VF 0000:03:00.3")
+	assert.Equal(t, vfs[1], readFakeFile(t, filepath.Join(PciDriversSysDir, "mlx5_core", netdevUnbindFile)))
+}
+
+func TestUnbindAllVfsFromDriverNoSuchPf(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+
+	err := UnbindAllVfsFromDriver("0000:03:00.0", "")
+	assert.Error(t, err)
+}
+
+func TestGetDriverNameByPciAddress(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+	setUpPciDriverEnv(t, "mlx5_core", "0000:03:00.2")
+	_ = utilfs.Fs.MkdirAll(filepath.Join(PciSysDir, "0000:03:00.3"), os.FileMode(0755))
+
+	driver, err := GetDriverNameByPciAddress("0000:03:00.2")
+	assert.NoError(t, err)
+	assert.Equal(t, "mlx5_core", driver)
+
+	driver, err = GetDriverNameByPciAddress("0000:03:00.3")
+	assert.ErrorIs(t, err, ErrNoDriver)
+	assert.Equal(t, "", driver)
+}
+
+func TestGetDriverNameByPciAddressNoSuchDevice(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+
+	_, err := GetDriverNameByPciAddress("0000:03:00.2")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrNoDriver)
+}
+
+func TestIsPciBoundToDriver(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+	setUpPciDriverEnv(t, "ice", "0000:03:00.2")
+	_ = utilfs.Fs.MkdirAll(filepath.Join(PciSysDir, "0000:03:00.3"), os.FileMode(0755))
+
+	assert.True(t, IsPciBoundToDriver("0000:03:00.2", "ice"))
+	assert.False(t, IsPciBoundToDriver("0000:03:00.2", "iavf"))
+	assert.False(t, IsPciBoundToDriver("0000:03:00.3", "ice"))
+	assert.False(t, IsPciBoundToDriver("0000:03:00.4", "ice"))
+}
+
+// setUpPciAttrs creates sysfs attribute files for the PCI device with the given address.
+// Note: should not be called directly as it expects FakeFs to be initialized beforehand.
+func setUpPciAttrs(t *testing.T, pciAddress string, attrs map[string]string) {
+	pciPath := filepath.Join(PciSysDir, pciAddress)
+	assert.NoError(t, utilfs.Fs.MkdirAll(pciPath, os.FileMode(0755)))
+	for attr, value := range attrs {
+		assert.NoError(t, utilfs.Fs.WriteFile(filepath.Join(pciPath, attr), []byte(value), os.FileMode(0644)))
+	}
+}
+
+func TestGetPciDeviceIDs(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+	setUpPciAttrs(t, "0000:03:00.2", map[string]string{
+		"vendor":           "0x15b3\n",
+		"device":           "0x101e\n",
+		"subsystem_vendor": "0x15b3\n",
+		"subsystem_device": "0x0016\n",
+		"revision":         "0x00\n",
+	})
+
+	ids, err := GetPciDeviceIDs("0000:03:00.2")
+	assert.NoError(t, err)
+	assert.Equal(t, &PciDeviceIDs{
+		Vendor:          0x15b3,
+		Device:          0x101e,
+		SubsystemVendor: 0x15b3,
+		SubsystemDevice: 0x0016,
+		Revision:        0,
+	}, ids)
+
+	newID, err := readPciVendorDeviceIDs("0000:03:00.2")
+	assert.NoError(t, err)
+	assert.Equal(t, "15b3 101e", newID)
+}
+
+func TestGetPciDeviceIDsErrors(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+	setUpPciAttrs(t, "0000:03:00.2", map[string]string{
+		"vendor":           "0x15b3\n",
+		"device":           "invalid\n",
+		"subsystem_vendor": "0x15b3\n",
+		"subsystem_device": "0x0016\n",
+		"revision":         "0x00\n",
+	})
+
+	_, err := GetPciDeviceIDs("0000:03:00.2")
+	assert.Error(t, err)
+	_, err = GetPciDeviceIDs("0000:03:00.3")
+	assert.Error(t, err)
+}
+
+func TestPciNetworkClass(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+	setUpPciAttrs(t, "0000:03:00.0", map[string]string{"class": "0x020000\n"})
+	setUpPciAttrs(t, "0000:04:00.0", map[string]string{"class": "0x020700\n"})
+	setUpPciAttrs(t, "0000:05:00.0", map[string]string{"class": "0x010802\n"})
+
+	tcases := []struct {
+		pciAddress string
+		network    bool
+		ethernet   bool
+		infiniband bool
+	}{
+		{pciAddress: "0000:03:00.0", network: true, ethernet: true, infiniband: false},
+		{pciAddress: "0000:04:00.0", network: true, ethernet: false, infiniband: true},
+		{pciAddress: "0000:05:00.0", network: false, ethernet: false, infiniband: false},
+		{pciAddress: "0000:06:00.0", network: false, ethernet: false, infiniband: false},
+	}
+	for _, tcase := range tcases {
+		assert.Equal(t, tcase.network, IsPciNetworkDevice(tcase.pciAddress), tcase.pciAddress)
+		assert.Equal(t, tcase.ethernet, IsPciEthernetDevice(tcase.pciAddress), tcase.pciAddress)
+		assert.Equal(t, tcase.infiniband, IsPciInfinibandDevice(tcase.pciAddress), tcase.pciAddress)
+	}
+
+	class, err := GetPciClass("0000:04:00.0")
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(0x020700), class)
+}
+
+func TestGetPciNumaNode(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+	setUpPciAttrs(t, "0000:03:00.0", map[string]string{"numa_node": "1\n"})
+	setUpPciAttrs(t, "0000:04:00.0", map[string]string{"numa_node": "-1\n"})
+	setUpPciAttrs(t, "0000:05:00.0", map[string]string{"numa_node": "foo\n"})
+
+	numaNode, err := GetPciNumaNode("0000:03:00.0")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, numaNode)
+
+	numaNode, err = GetPciNumaNode("0000:04:00.0")
+	assert.NoError(t, err)
+	assert.Equal(t, NoNumaNode, numaNode)
+
+	numaNode, err = GetPciNumaNode("0000:05:00.0")
+	assert.Error(t, err)
+	assert.Equal(t, NoNumaNode, numaNode)
+
+	numaNode, err = GetPciNumaNode("0000:06:00.0")
+	assert.Error(t, err)
+	assert.Equal(t, NoNumaNode, numaNode)
+}
+
+// setUpIommuGroupEnv creates an IOMMU group with the given PCI devices.
+// Note: should not be called directly as it expects FakeFs to be initialized beforehand.
+func setUpIommuGroupEnv(t *testing.T, group string, pciAddresses ...string) {
+	groupPath := filepath.Join(IommuGroupsSysDir, group)
+	assert.NoError(t, utilfs.Fs.MkdirAll(filepath.Join(groupPath, "devices"), os.FileMode(0755)))
+	for _, pciAddress := range pciAddresses {
+		pciPath := filepath.Join(PciSysDir, pciAddress)
+		assert.NoError(t, utilfs.Fs.MkdirAll(pciPath, os.FileMode(0755)))
+		assert.NoError(t, utilfs.Fs.Symlink(groupPath, filepath.Join(pciPath, pciDevIommuGroupLink)))
+		assert.NoError(t, utilfs.Fs.Symlink(pciPath, filepath.Join(groupPath, "devices", pciAddress)))
+	}
+}
+
+func TestGetPciIommuGroup(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+	setUpIommuGroupEnv(t, "42", "0000:03:00.0", "0000:03:00.1")
+
+	group, err := GetPciIommuGroup("0000:03:00.1")
+	assert.NoError(t, err)
+	assert.Equal(t, 42, group)
+
+	devs, err := ListDevicesInIommuGroup(group)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"0000:03:00.0", "0000:03:00.1"}, devs)
+}
+
+func TestGetPciIommuGroupNoIommu(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+	_ = utilfs.Fs.MkdirAll(filepath.Join(PciSysDir, "0000:03:00.0"), os.FileMode(0755))
+
+	group, err := GetPciIommuGroup("0000:03:00.0")
+	assert.Error(t, err)
+	assert.Equal(t, -1, group)
+
+	_, err = ListDevicesInIommuGroup(7)
+	assert.Error(t, err)
+}
+
+func TestGetPciLinkInfo(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+	setUpPciAttrs(t, "0000:03:00.0", map[string]string{"current_link_speed": "8.0 GT/s PCIe\n",
+		"current_link_width": "8\n", "max_link_speed": "16.0 GT/s PCIe\n", "max_link_width": "16\n"})
+	setUpPciAttrs(t, "0000:04:00.0", map[string]string{"current_link_speed": "8 GT/s\n",
+		"current_link_width": "16\n", "max_link_speed": "8 GT/s\n", "max_link_width": "16\n"})
+	setUpPciAttrs(t, "0000:05:00.0", map[string]string{"current_link_speed": "Unknown\n",
+		"current_link_width": "255\n", "max_link_speed": "16.0 GT/s PCIe\n", "max_link_width": "16\n"})
+
+	info, err := GetPciLinkInfo("0000:03:00.0")
+	assert.NoError(t, err)
+	assert.Equal(t, &PciLinkInfo{Current: PciLinkStatus{Speed: 8, Width: 8},
+		Max: PciLinkStatus{Speed: 16, Width: 16}}, info)
+	assert.True(t, info.IsDegraded())
+
+	info, err = GetPciLinkInfo("0000:04:00.0")
+	assert.NoError(t, err)
+	assert.Equal(t, PciLinkStatus{Speed: 8, Width: 16}, info.Current)
+	assert.False(t, info.IsDegraded())
+
+	info, err = GetPciLinkInfo("0000:05:00.0")
+	assert.NoError(t, err)
+	assert.Equal(t, PciLinkStatus{}, info.Current)
+	assert.False(t, info.IsDegraded())
+
+	_, err = GetPciLinkInfo("0000:06:00.0")
+	assert.Error(t, err)
+}
+
+func TestResetPciDevice(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+	setUpPciAttrs(t, "0000:03:00.2", map[string]string{"reset": ""})
+	setUpPciAttrs(t, "0000:03:00.3", map[string]string{})
+
+	assert.NoError(t, ResetPciDevice("0000:03:00.2"))
+	assert.Equal(t, "1", readFakeFile(t, filepath.Join(PciSysDir, "0000:03:00.2", "reset")))
+	assert.ErrorIs(t, ResetPciDevice("0000:03:00.3"), ErrNotSupported)
+	assert.ErrorIs(t, ResetPciDevice("0000:03:00.4"), ErrDeviceNotFound)
+	assert.ErrorIs(t, ResetPciDevice("03:00.4:1"), ErrInvalidPciAddress)
+}
+
+func TestGetSriovOffsetAndStride(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+	setUpPciAttrs(t, "0000:03:00.0", map[string]string{pciDevSriovOffsetFile: "2\n", pciDevSriovStrideFile: "1\n"})
+	setUpPciAttrs(t, "0000:00:1f.0", map[string]string{})
+
+	offset, err := GetSriovOffset("0000:03:00.0")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, offset)
+	stride, err := GetSriovStride("03:00.0")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, stride)
+
+	_, err = GetSriovOffset("0000:00:1f.0")
+	assert.ErrorIs(t, err, ErrNotSupported)
+	_, err = GetSriovStride("0000:04:00.0")
+	assert.ErrorIs(t, err, ErrDeviceNotFound)
+}
+
+func TestComputeVfPciAddress(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+	setUpPciAttrs(t, "0000:03:00.0", map[string]string{pciDevSriovOffsetFile: "2\n", pciDevSriovStrideFile: "1\n"})
+	setUpPciAttrs(t, "0000:03:00.1", map[string]string{pciDevSriovOffsetFile: "9\n", pciDevSriovStrideFile: "1\n"})
+	setUpPciAttrs(t, "0000:ff:00.0", map[string]string{pciDevSriovOffsetFile: "256\n", pciDevSriovStrideFile: "1\n"})
+
+	vf, err := ComputeVfPciAddress("0000:03:00.0", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "0000:03:00.2", vf)
+	vf, err = ComputeVfPciAddress("0000:03:00.0", 7)
+	assert.NoError(t, err)
+	assert.Equal(t, "0000:03:01.1", vf)
+	vf, err = ComputeVfPciAddress("0000:03:00.1", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "0000:03:01.2", vf)
+
+	_, err = ComputeVfPciAddress("0000:ff:00.0", 0)
+	assert.Error(t, err)
+	_, err = ComputeVfPciAddress("0000:03:00.0", -1)
+	assert.Error(t, err)
+}
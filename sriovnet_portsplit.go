@@ -0,0 +1,156 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sriovnet
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/vishvananda/netlink"
+
+	utilfs "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/filesystem"
+	"github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/netlinkops"
+)
+
+const portSplitPollInterval = 100 * time.Millisecond
+
+// Regex that matches on a split physical port name and captures the physical port number and the split index
+var splitPortRepRegex = regexp.MustCompile(`^p(\d+)s(\d+)$`)
+
+// physPort describes the physical devlink port of an uplink representor or of a split port
+type physPort struct {
+	port     *netlink.DevlinkPort
+	switchID []byte
+	portName string
+	portNum  int
+}
+
+// getPhysPort returns the physical devlink port of the given uplink representor or split port netdev
+func getPhysPort(netdev string) (*physPort, error) {
+	port, err := netlinkops.GetNetlinkOps().DevLinkGetPortByNetdevName(netdev)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get devlink port of %s: %v", netdev, err)
+	}
+	if port.PortFlavour != uint16(PORT_FLAVOUR_PHYSICAL) {
+		return nil, fmt.Errorf("netdev %s is not an uplink representor", netdev)
+	}
+	// read sysfs directly, the netdev names of a port group change on split and unsplit
+	meta := readNetdevSwitchMeta(netdev)
+	matches := physPortRepRegex.FindStringSubmatch(meta.portName)
+	if len(meta.switchID) == 0 || matches == nil {
+		return nil, fmt.Errorf("failed to get physical port name of %s", netdev)
+	}
+	portNum, _ := strconv.Atoi(matches[1])
+	return &physPort{port: port, switchID: meta.switchID, portName: meta.portName, portNum: portNum}, nil
+}
+
+// getPhysPortNetdevs returns the uplink representor of the physical port with the given number and eswitch,
+// empty if the port is split, and its split ports netdevs sorted by split index
+func getPhysPortNetdevs(switchID []byte, portNum int) (string, []string, error) {
+	netdevs, err := utilfs.ReadDirNames(utilfs.Fs, NetSysDir)
+	if err != nil {
+		return "", nil, err
+	}
+	uplink := ""
+	splitIndexes := make(map[string]int)
+	splits := make([]string, 0)
+	for i, meta := range getNetdevsSwitchMeta(netdevs) {
+		if !bytes.Equal(meta.switchID, switchID) {
+			continue
+		}
+		if meta.portName == fmt.Sprintf("p%d", portNum) {
+			uplink = netdevs[i]
+			continue
+		}
+		matches := splitPortRepRegex.FindStringSubmatch(meta.portName)
+		if matches == nil || matches[1] != strconv.Itoa(portNum) {
+			continue
+		}
+		splitIndexes[netdevs[i]], _ = strconv.Atoi(matches[2])
+		splits = append(splits, netdevs[i])
+	}
+	sort.Slice(splits, func(i, j int) bool { return splitIndexes[splits[i]] < splitIndexes[splits[j]] })
+	return uplink, splits, nil
+}
+
+// SplitUplink splits the physical port of the given uplink representor (e.g a 100G port into 4x25G ports)
+// and waits up to timeout for the uplink representors of the split ports (phys_port_name p0s0, p0s1 ...)
+// to appear. Returns the split ports uplink representors sorted by split index.
+// Equivalent to: `devlink port split pci/0000:03:00.0/1 count 4`
+func SplitUplink(uplink string, count int, timeout time.Duration) ([]string, error) {
+	if count < 2 {
+		return nil, fmt.Errorf("invalid split count %d", count)
+	}
+	phys, err := getPhysPort(uplink)
+	if err != nil {
+		return nil, err
+	}
+	if splitPortRepRegex.MatchString(phys.portName) {
+		return nil, fmt.Errorf("uplink %s is already split", uplink)
+	}
+
+	port := phys.port
+	if err = netlinkops.GetNetlinkOps().DevLinkPortSplit(port.BusName, port.DeviceName, port.PortIndex,
+		uint32(count)); err != nil {
+		return nil, fmt.Errorf("failed to split uplink %s: %v", uplink, err)
+	}
+	InvalidateRepresentorCache()
+
+	var splits []string
+	ready := func() bool {
+		_, splits, err = getPhysPortNetdevs(phys.switchID, phys.portNum)
+		return err == nil && len(splits) == count
+	}
+	if !pollUntil(timeout, portSplitPollInterval, ready) {
+		return nil, fmt.Errorf("timed out waiting for %d split ports of uplink %s", count, uplink)
+	}
+	return splits, nil
+}
+
+// UnsplitUplink unsplits the physical port the given split port uplink representor belongs to and waits up to
+// timeout for the uplink representor of the whole physical port to appear. Returns the uplink representor.
+// Equivalent to: `devlink port unsplit pci/0000:03:00.0/1`
+func UnsplitUplink(splitUplink string, timeout time.Duration) (string, error) {
+	phys, err := getPhysPort(splitUplink)
+	if err != nil {
+		return "", err
+	}
+	if !splitPortRepRegex.MatchString(phys.portName) {
+		return "", fmt.Errorf("uplink %s is not a split port", splitUplink)
+	}
+
+	port := phys.port
+	if err = netlinkops.GetNetlinkOps().DevLinkPortUnsplit(port.BusName, port.DeviceName,
+		port.PortIndex); err != nil {
+		return "", fmt.Errorf("failed to unsplit uplink %s: %v", splitUplink, err)
+	}
+	InvalidateRepresentorCache()
+
+	var uplink string
+	ready := func() bool {
+		uplink, _, err = getPhysPortNetdevs(phys.switchID, phys.portNum)
+		return err == nil && uplink != ""
+	}
+	if !pollUntil(timeout, portSplitPollInterval, ready) {
+		return "", fmt.Errorf("timed out waiting for the uplink of split port %s", splitUplink)
+	}
+	return uplink, nil
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sriovnet
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+
+	utilfs "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/filesystem"
+	"github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/netlinkops"
+	netlinkopsMocks "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/netlinkops/mocks"
+)
+
+func TestSplitAndUnsplitUplink(t *testing.T) {
+	teardown := setupRepresentorEnv(t, "", []*repContext{
+		{Name: "eth0", PhysPortName: "p0", PhysSwitchID: "111111"},
+		{Name: "eth1", PhysPortName: "p1", PhysSwitchID: "111111"},
+		{Name: "pf0vf0", PhysPortName: "pf0vf0", PhysSwitchID: "111111"},
+		{Name: "other", PhysPortName: "p0s0", PhysSwitchID: "222222"},
+	})
+	defer teardown()
+
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+
+	physPort := func(netdev string) *netlink.DevlinkPort {
+		return &netlink.DevlinkPort{BusName: "pci", DeviceName: "0000:03:00.0", PortIndex: 1,
+			PortFlavour: nl.DEVLINK_PORT_FLAVOUR_PHYSICAL, NetdeviceName: netdev}
+	}
+	nlOpsMock.On("DevLinkGetPortByNetdevName", "eth0").Return(physPort("eth0"), nil)
+	nlOpsMock.On("DevLinkGetPortByNetdevName", "eth0s1").Return(physPort("eth0s1"), nil)
+	nlOpsMock.On("DevLinkGetPortByNetdevName", "pf0vf0").Return(&netlink.DevlinkPort{
+		BusName: "pci", DeviceName: "0000:03:00.0", PortIndex: 2, PortFlavour: nl.DEVLINK_PORT_FLAVOUR_PCI_VF}, nil)
+	nlOpsMock.On("DevLinkPortSplit", "pci", "0000:03:00.0", uint32(1), uint32(2)).Return(nil).Run(
+		func(mock.Arguments) {
+			assert.NoError(t, utilfs.Fs.RemoveAll(filepath.Join(NetSysDir, "eth0")))
+			for i := 1; i >= 0; i-- {
+				assert.NoError(t, setUpRepresentorLayout("", &repContext{
+					Name: fmt.Sprintf("eth0s%d", i), PhysPortName: fmt.Sprintf("p0s%d", i), PhysSwitchID: "111111"}))
+			}
+		})
+	nlOpsMock.On("DevLinkPortUnsplit", "pci", "0000:03:00.0", uint32(1)).Return(nil).Run(
+		func(mock.Arguments) {
+			for i := 0; i < 2; i++ {
+				assert.NoError(t, utilfs.Fs.RemoveAll(filepath.Join(NetSysDir, fmt.Sprintf("eth0s%d", i))))
+			}
+			assert.NoError(t, setUpRepresentorLayout("", &repContext{
+				Name: "eth0", PhysPortName: "p0", PhysSwitchID: "111111"}))
+		})
+
+	_, err := SplitUplink("eth0", 1, time.Second)
+	assert.Error(t, err)
+	_, err = SplitUplink("pf0vf0", 2, time.Second)
+	assert.Error(t, err)
+	_, err = UnsplitUplink("eth0", time.Second)
+	assert.Error(t, err)
+
+	splits, err := SplitUplink("eth0", 2, time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"eth0s0", "eth0s1"}, splits)
+	_, err = SplitUplink("eth0s1", 2, time.Second)
+	assert.Error(t, err)
+
+	uplink, err := UnsplitUplink("eth0s1", time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, "eth0", uplink)
+	nlOpsMock.AssertExpectations(t)
+}
+
+func TestSplitUplinkTimeout(t *testing.T) {
+	teardown := setupRepresentorEnv(t, "", []*repContext{{Name: "eth0", PhysPortName: "p0", PhysSwitchID: "111111"}})
+	defer teardown()
+
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+
+	nlOpsMock.On("DevLinkGetPortByNetdevName", "eth0").Return(&netlink.DevlinkPort{BusName: "pci",
+		DeviceName: "0000:03:00.0", PortIndex: 1, PortFlavour: nl.DEVLINK_PORT_FLAVOUR_PHYSICAL}, nil)
+	nlOpsMock.On("DevLinkPortSplit", "pci", "0000:03:00.0", uint32(1), uint32(4)).Return(nil)
+
+	_, err := SplitUplink("eth0", 4, 200*time.Millisecond)
+	assert.Error(t, err)
+	nlOpsMock.AssertExpectations(t)
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sriovnet
+
+import (
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	utilfs "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/filesystem"
+)
+
+const (
+	// parallelScanThreshold is the number of netdevs from which their metadata is read concurrently
+	parallelScanThreshold = 64
+	// maxScanWorkers bounds the number of concurrent sysfs readers of a scan
+	maxScanWorkers = 16
+)
+
+// netdevSwitchMeta holds the eswitch related sysfs attributes of a netdev
+type netdevSwitchMeta struct {
+	// switchID is the content of phys_switch_id, empty if the netdev is not an eswitch port
+	switchID []byte
+	// portName is the content of phys_port_name, empty if not available
+	portName string
+}
+
+// representorCache caches netdevSwitchMeta across representor lookups when enabled
+var representorCache = struct {
+	sync.Mutex
+	enabled bool
+	entries map[string]*netdevSwitchMeta
+}{}
+
+// uplinkCache maps the net directory of a PF (e.g /sys/bus/pci/devices/0000:03:00.0/net) to its uplink
+// representor. Entries are validated on lookup so, unlike representorCache, the cache is always enabled.
+var uplinkCache = struct {
+	sync.Mutex
+	entries map[string]string
+}{}
+
+// EnableRepresentorCache enables or disables caching of the representors sysfs attributes (phys_switch_id and
+// phys_port_name) across representor lookups. The cache is disabled by default, when enabled the caller is
+// responsible to call InvalidateRepresentorCache whenever representors are added, removed or renamed.
+func EnableRepresentorCache(enabled bool) {
+	representorCache.Lock()
+	defer representorCache.Unlock()
+	representorCache.enabled = enabled
+	representorCache.entries = nil
+}
+
+// InvalidateRepresentorCache drops all the entries of the representor cache, along with the cached uplink
+// representors
+func InvalidateRepresentorCache() {
+	representorCache.Lock()
+	representorCache.entries = nil
+	representorCache.Unlock()
+	uplinkCache.Lock()
+	uplinkCache.entries = nil
+	uplinkCache.Unlock()
+}
+
+// getCachedUplink returns the cached uplink representor of the given PF net directory, empty if none
+func getCachedUplink(netDir string) string {
+	uplinkCache.Lock()
+	defer uplinkCache.Unlock()
+	return uplinkCache.entries[netDir]
+}
+
+// setCachedUplink caches the uplink representor of the given PF net directory
+func setCachedUplink(netDir, uplink string) {
+	uplinkCache.Lock()
+	defer uplinkCache.Unlock()
+	if uplinkCache.entries == nil {
+		uplinkCache.entries = make(map[string]string)
+	}
+	uplinkCache.entries[netDir] = uplink
+}
+
+// readNetdevSwitchMeta reads the eswitch related sysfs attributes of the given netdev
+func readNetdevSwitchMeta(netdev string) *netdevSwitchMeta {
+	meta := &netdevSwitchMeta{}
+	if switchID, err := utilfs.Fs.ReadFile(filepath.Join(NetSysDir, netdev, netdevPhysSwitchID)); err == nil {
+		meta.switchID = switchID
+	}
+	if portName, err := GetPhysPortName(netdev); err == nil {
+		meta.portName = portName
+	}
+	return meta
+}
+
+// getNetdevSwitchMeta returns the eswitch related sysfs attributes of the given netdev, from the representor
+// cache if enabled
+func getNetdevSwitchMeta(netdev string) *netdevSwitchMeta {
+	representorCache.Lock()
+	enabled := representorCache.enabled
+	meta, ok := representorCache.entries[netdev]
+	representorCache.Unlock()
+	if ok {
+		return meta
+	}
+
+	// sysfs is read without holding the lock to allow concurrent scans
+	meta = readNetdevSwitchMeta(netdev)
+	if enabled {
+		representorCache.Lock()
+		if representorCache.enabled {
+			if representorCache.entries == nil {
+				representorCache.entries = make(map[string]*netdevSwitchMeta)
+			}
+			representorCache.entries[netdev] = meta
+		}
+		representorCache.Unlock()
+	}
+	return meta
+}
+
+// getNetdevsSwitchMeta returns the eswitch related sysfs attributes of the given netdevs in the same order.
+// Large sets of netdevs (e.g thousands of SF representors on a DPU) are read by a bounded pool of workers.
+func getNetdevsSwitchMeta(netdevs []string) []*netdevSwitchMeta {
+	metas := make([]*netdevSwitchMeta, len(netdevs))
+	if len(netdevs) < parallelScanThreshold {
+		for i, netdev := range netdevs {
+			metas[i] = getNetdevSwitchMeta(netdev)
+		}
+		return metas
+	}
+
+	workers := runtime.NumCPU()
+	if workers > maxScanWorkers {
+		workers = maxScanWorkers
+	}
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				metas[i] = getNetdevSwitchMeta(netdevs[i])
+			}
+		}()
+	}
+	for i := range netdevs {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return metas
+}
+
+// getPfNetdevsSwitchMeta returns the eswitch related sysfs attributes of the netdevs of the PF with the given
+// PCI address, i.e its representors in switchdev mode, by netdev name out of a single scan of its net directory
+func getPfNetdevsSwitchMeta(pfPciAddress string) (map[string]*netdevSwitchMeta, error) {
+	netdevs, err := utilfs.ReadDirNames(utilfs.Fs, filepath.Join(PciSysDir, pfPciAddress, "net"))
+	if err != nil {
+		return nil, err
+	}
+	metas := getNetdevsSwitchMeta(netdevs)
+	byName := make(map[string]*netdevSwitchMeta, len(netdevs))
+	for i, netdev := range netdevs {
+		byName[netdev] = metas[i]
+	}
+	return byName, nil
+}
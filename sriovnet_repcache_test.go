@@ -0,0 +1,184 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sriovnet
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	utilfs "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/filesystem"
+)
+
+func TestRepresentorCache(t *testing.T) {
+	uplinkRep := &repContext{"p0", "p0", "c2cfc60003a1420c"}
+	vfReps := []*repContext{
+		{"eth0", "pf0vf0", "c2cfc60003a1420c"},
+		{"eth1", "pf0vf1", "c2cfc60003a1420c"},
+	}
+	teardown := setupRepresentorEnv(t, "", append(vfReps, uplinkRep))
+	defer teardown()
+	pfPciPath := filepath.Join(PciSysDir, "0000:03:00.0")
+	assert.NoError(t, utilfs.Fs.MkdirAll(pfPciPath, os.FileMode(0755)))
+	assert.NoError(t, utilfs.Fs.Symlink(pfPciPath, filepath.Join(NetSysDir, uplinkRep.Name, pcidevPrefix)))
+	assert.NoError(t, utilfs.Fs.Symlink(NetSysDir, filepath.Join(NetSysDir, uplinkRep.Name, "subsystem")))
+
+	EnableRepresentorCache(true)
+	defer EnableRepresentorCache(false)
+
+	reps, err := GetVfRepresentors("p0")
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]string{0: "eth0", 1: "eth1"}, reps)
+
+	// renumber eth1, the cached phys_port_name is used until the cache is invalidated
+	assert.NoError(t, utilfs.Fs.WriteFile(filepath.Join(NetSysDir, "eth1", netdevPhysPortName), []byte("pf0vf2"),
+		os.FileMode(0644)))
+	reps, err = GetVfRepresentors("p0")
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]string{0: "eth0", 1: "eth1"}, reps)
+
+	InvalidateRepresentorCache()
+	reps, err = GetVfRepresentors("p0")
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]string{0: "eth0", 2: "eth1"}, reps)
+
+	EnableRepresentorCache(false)
+	assert.NoError(t, utilfs.Fs.WriteFile(filepath.Join(NetSysDir, "eth1", netdevPhysPortName), []byte("pf0vf3"),
+		os.FileMode(0644)))
+	reps, err = GetVfRepresentors("p0")
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]string{0: "eth0", 3: "eth1"}, reps)
+}
+
+// newSfRepContexts returns count SF representors of pf0 with their sfnum matching their netdev index
+func newSfRepContexts(count int) []*repContext {
+	reps := make([]*repContext, 0, count)
+	for i := 0; i < count; i++ {
+		reps = append(reps, &repContext{Name: fmt.Sprintf("en3f0pf0sf%d", i), PhysPortName: fmt.Sprintf("pf0sf%d", i)})
+	}
+	return reps
+}
+
+func TestGetSfRepresentorsParallelScan(t *testing.T) {
+	count := parallelScanThreshold * 4
+	teardown := setupSfRepresentorEnv(t, newSfRepContexts(count))
+	defer teardown()
+
+	reps, err := GetSfRepresentors("p0")
+	assert.NoError(t, err)
+	assert.Len(t, reps, count)
+	for i := 0; i < count; i++ {
+		if assert.Contains(t, reps, i) {
+			assert.Equal(t, fmt.Sprintf("en3f0pf0sf%d", i), reps[i].Name)
+		}
+	}
+
+	rep, err := GetSfRepresentor("p0", count-1)
+	assert.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("en3f0pf0sf%d", count-1), rep)
+}
+
+func benchmarkGetSfRepresentors(b *testing.B, count int) {
+	teardown := setupSfRepresentorEnv(b, newSfRepContexts(count))
+	defer teardown()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := GetSfRepresentors("p0"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetSfRepresentors32(b *testing.B)   { benchmarkGetSfRepresentors(b, 32) }
+func BenchmarkGetSfRepresentors1024(b *testing.B) { benchmarkGetSfRepresentors(b, 1024) }
+func BenchmarkGetSfRepresentors4096(b *testing.B) { benchmarkGetSfRepresentors(b, 4096) }
+
+// setupManyRepsUplinkEnv creates PF 0000:03:00.0 in switchdev mode with uplink p0 and count VF
+// representors, all of them sorting before the uplink
+func setupManyRepsUplinkEnv(t testing.TB, count int) func() {
+	teardown := setupFakeFs(t)
+	reps := []*repContext{{Name: "p0", PhysPortName: "p0", PhysSwitchID: "c2cfc60003a1420c"}}
+	for i := 0; i < count; i++ {
+		reps = append(reps, &repContext{Name: fmt.Sprintf("en3f0pf0vf%d", i), PhysPortName: fmt.Sprintf("pf0vf%d", i),
+			PhysSwitchID: "c2cfc60003a1420c"})
+	}
+	for _, rep := range reps {
+		assert.NoError(t, utilfs.Fs.MkdirAll(filepath.Join(PciSysDir, "0000:03:00.0", "net", rep.Name),
+			os.FileMode(0755)))
+		assert.NoError(t, setUpRepresentorLayout("", rep))
+	}
+	return teardown
+}
+
+func TestGetUplinkRepresentorCache(t *testing.T) {
+	teardown := setupManyRepsUplinkEnv(t, 2)
+	defer teardown()
+	InvalidateRepresentorCache()
+	pfNetDir := filepath.Join(PciSysDir, "0000:03:00.0", "net")
+
+	uplink, err := GetUplinkRepresentor("0000:03:00.0")
+	assert.NoError(t, err)
+	assert.Equal(t, "p0", uplink)
+	assert.Equal(t, "p0", getCachedUplink(pfNetDir))
+
+	// the cached uplink is no longer an uplink
+	assert.NoError(t, utilfs.Fs.WriteFile(filepath.Join(NetSysDir, "p0", netdevPhysPortName), []byte("pf0vf9"),
+		os.FileMode(0644)))
+	_, err = GetUplinkRepresentor("0000:03:00.0")
+	assert.Error(t, err)
+
+	// the cached uplink was renamed
+	assert.NoError(t, utilfs.Fs.RemoveAll(filepath.Join(pfNetDir, "p0")))
+	assert.NoError(t, setUpRepresentorLayout("", &repContext{"p1", "p1", "c2cfc60003a1420c"}))
+	assert.NoError(t, utilfs.Fs.MkdirAll(filepath.Join(pfNetDir, "p1"), os.FileMode(0755)))
+	uplink, err = GetUplinkRepresentor("0000:03:00.0")
+	assert.NoError(t, err)
+	assert.Equal(t, "p1", uplink)
+	assert.Equal(t, "p1", getCachedUplink(pfNetDir))
+
+	InvalidateRepresentorCache()
+	assert.Equal(t, "", getCachedUplink(pfNetDir))
+}
+
+func benchmarkGetUplinkRepresentor(b *testing.B, count int, cached bool) {
+	teardown := setupManyRepsUplinkEnv(b, count)
+	defer teardown()
+	InvalidateRepresentorCache()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if !cached {
+			InvalidateRepresentorCache()
+		}
+		if _, err := GetUplinkRepresentor("0000:03:00.0"); err != nil {
+			b.Fatal(err)
+		}
+	}
+	// exclude the removal of the fake sysfs
+	b.StopTimer()
+}
+
+func BenchmarkGetUplinkRepresentorScan32(b *testing.B)   { benchmarkGetUplinkRepresentor(b, 32, false) }
+func BenchmarkGetUplinkRepresentorScan512(b *testing.B)  { benchmarkGetUplinkRepresentor(b, 512, false) }
+func BenchmarkGetUplinkRepresentorCached32(b *testing.B) { benchmarkGetUplinkRepresentor(b, 32, true) }
+func BenchmarkGetUplinkRepresentorCached512(b *testing.B) {
+	benchmarkGetUplinkRepresentor(b, 512, true)
+}
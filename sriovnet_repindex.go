@@ -0,0 +1,126 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sriovnet
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/netlinkops"
+)
+
+// repIndexKey identifies a representor within the eswitch of a devlink device
+type repIndexKey struct {
+	bus, device string
+	RepresentorInfo
+}
+
+// RepresentorIndex answers representor lookups in O(1) out of a snapshot of all devlink ports taken with a
+// single devlink port dump. The port controller, PF and function numbers are parsed from the representors
+// phys_port_name when the snapshot is taken, read in a single scan of the net devices of each PF. The snapshot is not updated automatically, call Refresh when
+// representors are added, removed or renamed (e.g upon devlink port notifications).
+// RepresentorIndex is safe for concurrent use.
+type RepresentorIndex struct {
+	mu    sync.RWMutex
+	ports map[repIndexKey]string
+}
+
+// NewRepresentorIndex returns a RepresentorIndex populated from the current devlink ports
+func NewRepresentorIndex() (*RepresentorIndex, error) {
+	idx := &RepresentorIndex{}
+	if err := idx.Refresh(); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+// Refresh replaces the index content with the current devlink ports
+func (idx *RepresentorIndex) Refresh() error {
+	ports, err := netlinkops.GetNetlinkOps().DevLinkGetAllPortList()
+	if err != nil {
+		return fmt.Errorf("failed to dump devlink ports: %v", err)
+	}
+	entries := make(map[repIndexKey]string, len(ports))
+	// switch attributes of the netdevs of each PCI devlink device, read at once on first use
+	pfMetas := make(map[string]map[string]*netdevSwitchMeta)
+	for _, port := range ports {
+		if port.NetdeviceName == "" {
+			continue
+		}
+		metas, ok := pfMetas[port.DeviceName]
+		if !ok && port.BusName == devlinkPciBus {
+			// a PF without net directory is not scanned again, its representors are read one by one
+			metas, _ = getPfNetdevsSwitchMeta(port.DeviceName)
+			pfMetas[port.DeviceName] = metas
+		}
+		meta, ok := metas[port.NetdeviceName]
+		if !ok {
+			// representor which is not a netdev of its devlink device
+			meta = getNetdevSwitchMeta(port.NetdeviceName)
+		}
+		info, err := parseRepresentorPortName(meta.portName)
+		if err != nil {
+			continue
+		}
+		info.Flavour = PortFlavour(port.PortFlavour)
+		entries[repIndexKey{bus: port.BusName, device: port.DeviceName, RepresentorInfo: *info}] = port.NetdeviceName
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.ports = entries
+	return nil
+}
+
+// Lookup returns the representor netdev of the port described by info on the eswitch of the given
+// devlink device (e.g 'pci', '0000:03:00.0')
+func (idx *RepresentorIndex) Lookup(bus, device string, info RepresentorInfo) (string, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	netdev, ok := idx.ports[repIndexKey{bus: bus, device: device, RepresentorInfo: info}]
+	return netdev, ok
+}
+
+// lookupLocal returns the representor netdev of a function of the local controller of the given PF
+func (idx *RepresentorIndex) lookupLocal(pfPciAddress string, flavour PortFlavour, fnIndex int) (string, error) {
+	pfPciAddress, err := normalizePciAddress(pfPciAddress)
+	if err != nil {
+		return "", err
+	}
+	pfAddr, err := ParsePciAddress(pfPciAddress)
+	if err != nil {
+		return "", err
+	}
+	info := RepresentorInfo{Flavour: flavour, PfID: int(pfAddr.Function), FuncIndex: fnIndex}
+	netdev, ok := idx.Lookup("pci", pfPciAddress, info)
+	if !ok {
+		return "", fmt.Errorf("%w: %s representor %d of %s", ErrDeviceNotFound, flavour, fnIndex, pfPciAddress)
+	}
+	return netdev, nil
+}
+
+// GetVfRepresentor returns the representor netdev of the VF with the given index of the PF with the given
+// PCI address (e.g '0000:03:00.0')
+func (idx *RepresentorIndex) GetVfRepresentor(pfPciAddress string, vfIndex int) (string, error) {
+	return idx.lookupLocal(pfPciAddress, PORT_FLAVOUR_PCI_VF, vfIndex)
+}
+
+// GetSfRepresentor returns the representor netdev of the SF with the given SF number of the PF with the
+// given PCI address (e.g '0000:03:00.0')
+func (idx *RepresentorIndex) GetSfRepresentor(pfPciAddress string, sfNum int) (string, error) {
+	return idx.lookupLocal(pfPciAddress, PORT_FLAVOUR_PCI_SF, sfNum)
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sriovnet
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vishvananda/netlink"
+
+	"github.com/k8snetworkplumbingwg/sriovnet/pkg/sriovnettest"
+	"github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/netlinkops"
+	netlinkopsMocks "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/netlinkops/mocks"
+)
+
+func TestRepresentorIndex(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+
+	env := sriovnettest.NewEnv(t)
+	env.AddPF(&sriovnettest.PF{PciAddress: "0000:03:00.0", Netdev: "p0", SwitchID: "c2cfc60003a1420c"})
+	env.AddPF(&sriovnettest.PF{PciAddress: "0000:03:00.1", Netdev: "p1", SwitchID: "aabbccddeeff0011",
+		PhysPortName: "p1"})
+	env.AddRepresentor("0000:03:00.0", &sriovnettest.Representor{Name: "eth0", PhysPortName: "pf0vf0"})
+	env.AddRepresentor("0000:03:00.0", &sriovnettest.Representor{Name: "eth1", PhysPortName: "pf0sf5"})
+	env.AddRepresentor("0000:03:00.0", &sriovnettest.Representor{Name: "eth2", PhysPortName: "c1pf0vf0"})
+	env.AddRepresentor("0000:03:00.1", &sriovnettest.Representor{Name: "eth3", PhysPortName: "pf1vf0"})
+	// representor which is not a netdev of its PF
+	env.WriteFile(filepath.Join(NetSysDir, "eth4", netdevPhysPortName), "pf0vf3\n")
+
+	ports := []*netlink.DevlinkPort{
+		{BusName: "pci", DeviceName: "0000:03:00.0", NetdeviceName: "p0", PortFlavour: PORT_FLAVOUR_PHYSICAL},
+		{BusName: "pci", DeviceName: "0000:03:00.0", NetdeviceName: "eth0", PortFlavour: PORT_FLAVOUR_PCI_VF},
+		{BusName: "pci", DeviceName: "0000:03:00.0", NetdeviceName: "eth1", PortFlavour: PORT_FLAVOUR_PCI_SF},
+		{BusName: "pci", DeviceName: "0000:03:00.0", NetdeviceName: "eth2", PortFlavour: PORT_FLAVOUR_PCI_VF},
+		{BusName: "pci", DeviceName: "0000:03:00.1", NetdeviceName: "eth3", PortFlavour: PORT_FLAVOUR_PCI_VF},
+		{BusName: "pci", DeviceName: "0000:03:00.0", NetdeviceName: "eth4", PortFlavour: PORT_FLAVOUR_PCI_VF},
+		{BusName: "pci", DeviceName: "0000:03:00.1", PortFlavour: PORT_FLAVOUR_PCI_VF},
+	}
+	nlOpsMock.On("DevLinkGetAllPortList").Return(ports, nil).Once()
+
+	idx, err := NewRepresentorIndex()
+	assert.NoError(t, err)
+
+	rep, err := idx.GetVfRepresentor("0000:03:00.0", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "eth0", rep)
+	rep, err = idx.GetSfRepresentor("0000:03:00.0", 5)
+	assert.NoError(t, err)
+	assert.Equal(t, "eth1", rep)
+	rep, err = idx.GetVfRepresentor("0000:03:00.1", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "eth3", rep)
+	rep, err = idx.GetVfRepresentor("0000:03:00.0", 3)
+	assert.NoError(t, err)
+	assert.Equal(t, "eth4", rep)
+	rep, ok := idx.Lookup("pci", "0000:03:00.0",
+		RepresentorInfo{Flavour: PORT_FLAVOUR_PCI_VF, ControllerNum: 1, PfID: 0, FuncIndex: 0})
+	assert.True(t, ok)
+	assert.Equal(t, "eth2", rep)
+	_, err = idx.GetVfRepresentor("0000:03:00.0", 1)
+	assert.ErrorIs(t, err, ErrDeviceNotFound)
+	_, err = idx.GetVfRepresentor("invalid", 0)
+	assert.Error(t, err)
+
+	nlOpsMock.On("DevLinkGetAllPortList").Return(ports[:1], nil).Once()
+	assert.NoError(t, idx.Refresh())
+	_, err = idx.GetVfRepresentor("0000:03:00.0", 0)
+	assert.ErrorIs(t, err, ErrDeviceNotFound)
+
+	nlOpsMock.On("DevLinkGetAllPortList").Return(nil, fmt.Errorf("no devlink support")).Once()
+	_, err = NewRepresentorIndex()
+	assert.Error(t, err)
+}
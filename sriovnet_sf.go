@@ -0,0 +1,477 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sriovnet
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"time"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+
+	"github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/netlinkops"
+)
+
+// PortFnState is the administrative state of a devlink port function
+type PortFnState string
+
+// PortFnOpState is the operational state of a devlink port function
+type PortFnOpState string
+
+const (
+	PortFnStateActive   PortFnState = "active"
+	PortFnStateInactive PortFnState = "inactive"
+
+	// PortFnOpStateAttached means the function is attached to its driver, e.g the SF auxiliary device exists
+	PortFnOpStateAttached PortFnOpState = "attached"
+	PortFnOpStateDetached PortFnOpState = "detached"
+
+	sfPollInterval        = 100 * time.Millisecond
+	sfDefaultProbeTimeout = 30 * time.Second
+)
+
+// SfPort describes a subfunction devlink port
+type SfPort struct {
+	PfPciAddress string
+	PfNum        int
+	SfNum        int
+	PortIndex    uint32
+	// Representor is the SF representor netdev name
+	Representor string
+}
+
+// SfConfig is the configuration of a SF deployed with DeploySF
+type SfConfig struct {
+	PfNum int
+	// SfNum is the sfnum of the SF, the lowest free sfnum is used if nil
+	SfNum *int
+	// HwAddr is the hardware address of the SF, left to the driver default if nil
+	HwAddr net.HardwareAddr
+	// Trust requests the SF to be trusted. It is not supported as devlink has no trust port function
+	// attribute, DeploySF returns ErrNotSupported if set.
+	Trust bool
+	// Timeout to wait for the SF auxiliary device to be probed, defaults to sfDefaultProbeTimeout
+	Timeout time.Duration
+}
+
+// DeployedSF describes a SF deployed with DeploySF
+type DeployedSF struct {
+	SfPort
+	// AuxDev is the SF auxiliary device name (e.g mlx5_core.sf.2)
+	AuxDev string
+	// NetDev is the SF netdev name
+	NetDev string
+	// RdmaDev is the SF RDMA device name, empty if the SF has no RDMA device
+	RdmaDev string
+}
+
+// SfInfo describes an existing SF
+type SfInfo struct {
+	SfPort
+	// State and OpState are empty if the port function is not reported by devlink
+	State   PortFnState
+	OpState PortFnOpState
+	HwAddr  net.HardwareAddr
+	// AuxDev is the SF auxiliary device name, empty if the SF is not active
+	AuxDev string
+	// NetDev is the SF netdev name, empty if the SF is not active or not probed
+	NetDev string
+}
+
+// resolveUplink returns the uplink representor given either its netdev name or the PF PCI address
+func resolveUplink(uplinkOrPci string) (string, error) {
+	if pciAddress, err := normalizePciAddress(uplinkOrPci); err == nil {
+		return GetUplinkRepresentor(pciAddress)
+	}
+	return uplinkOrPci, nil
+}
+
+// getSfDevlinkPort returns the devlink port of the SF with the given sfnum on the given uplink or PF PCI address
+func getSfDevlinkPort(uplinkOrPci string, sfIndex int) (*netlink.DevlinkPort, error) {
+	uplink, err := resolveUplink(uplinkOrPci)
+	if err != nil {
+		return nil, err
+	}
+	sfRep, err := GetSfRepresentor(uplink, sfIndex)
+	if err != nil {
+		return nil, err
+	}
+	port, err := netlinkops.GetNetlinkOps().DevLinkGetPortByNetdevName(sfRep)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get devlink port of SF representor %s: %v", sfRep, err)
+	}
+	return port, nil
+}
+
+// SetSfPortState activates or deactivates the SF with the given sfnum on the given uplink or PF PCI address.
+// Activating an SF creates its auxiliary device, deactivating it removes it.
+// Equivalent to: `devlink port function set pci/0000:03:00.0/32768 state active`
+func SetSfPortState(uplinkOrPci string, sfIndex int, active bool) error {
+	port, err := getSfDevlinkPort(uplinkOrPci, sfIndex)
+	if err != nil {
+		return err
+	}
+	fnAttrs := netlink.DevlinkPortFnSetAttrs{StateValid: true}
+	fnAttrs.FnAttrs.State = nl.DEVLINK_PORT_FN_STATE_INACTIVE
+	if active {
+		fnAttrs.FnAttrs.State = nl.DEVLINK_PORT_FN_STATE_ACTIVE
+	}
+	err = netlinkops.GetNetlinkOps().DevlinkPortFnSet(port.BusName, port.DeviceName, port.PortIndex, fnAttrs)
+	if err != nil {
+		return fmt.Errorf("failed to set state of SF %d port %s/%s/%d: %v",
+			sfIndex, port.BusName, port.DeviceName, port.PortIndex, err)
+	}
+	return nil
+}
+
+// GetSfPortState returns the administrative and operational state of the SF with the given sfnum
+// on the given uplink or PF PCI address
+func GetSfPortState(uplinkOrPci string, sfIndex int) (PortFnState, PortFnOpState, error) {
+	port, err := getSfDevlinkPort(uplinkOrPci, sfIndex)
+	if err != nil {
+		return "", "", err
+	}
+	if port.Fn == nil {
+		return "", "", fmt.Errorf("port function of SF %d is not reported by devlink", sfIndex)
+	}
+	state, opState := portFnStates(port.Fn)
+	return state, opState, nil
+}
+
+// portFnStates returns the administrative and operational state of the given port function
+func portFnStates(fn *netlink.DevlinkPortFn) (PortFnState, PortFnOpState) {
+	state := PortFnStateInactive
+	if fn.State == nl.DEVLINK_PORT_FN_STATE_ACTIVE {
+		state = PortFnStateActive
+	}
+	opState := PortFnOpStateDetached
+	if fn.OpState == nl.DEVLINK_PORT_FN_OPSTATE_ATTACHED {
+		opState = PortFnOpStateAttached
+	}
+	return state, opState
+}
+
+// CreateSF adds a subfunction devlink port with the given pfnum and sfnum on the PF with the given PCI address
+// and returns it. The SF is created in inactive state, see SetSfPortState.
+// Equivalent to: `devlink port add pci/0000:03:00.0 flavour pcisf pfnum 0 sfnum 88`
+func CreateSF(pfPciAddress string, pfNum, sfNum int) (*SfPort, error) {
+	pfPciAddress, err := normalizePciAddress(pfPciAddress)
+	if err != nil {
+		return nil, err
+	}
+	if pfNum < 0 || pfNum > math.MaxUint16 {
+		return nil, fmt.Errorf("invalid pfnum %d", pfNum)
+	}
+	if sfNum < 0 || int64(sfNum) > math.MaxUint32 {
+		return nil, fmt.Errorf("invalid sfnum %d", sfNum)
+	}
+
+	attrs := netlink.DevLinkPortAddAttrs{
+		PfNumber:      uint16(pfNum),
+		SfNumber:      uint32(sfNum),
+		SfNumberValid: true,
+	}
+	port, err := netlinkops.GetNetlinkOps().DevLinkPortAdd(devlinkPciBus, pfPciAddress, PORT_FLAVOUR_PCI_SF, attrs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add SF port pfnum %d sfnum %d on %s: %v", pfNum, sfNum, pfPciAddress, err)
+	}
+
+	sfPort := &SfPort{
+		PfPciAddress: pfPciAddress,
+		PfNum:        pfNum,
+		SfNum:        sfNum,
+		PortIndex:    port.PortIndex,
+		Representor:  port.NetdeviceName,
+	}
+	if sfPort.Representor == "" {
+		sfPort.Representor, err = GetSfRepresentor(pfPciAddressToUplink(pfPciAddress), sfNum)
+		if err != nil {
+			return nil, fmt.Errorf("SF port %s/%s/%d created but its representor was not found: %v",
+				devlinkPciBus, pfPciAddress, port.PortIndex, err)
+		}
+	}
+	return sfPort, nil
+}
+
+// CreateSFWithAutoSfNum adds a subfunction devlink port on the PF with the given PCI address using the lowest
+// sfnum not in use by an existing SF port of the given pfnum and returns it.
+// Note: sfnums of SF ports without a representor netdev cannot be resolved and are not taken into account.
+func CreateSFWithAutoSfNum(pfPciAddress string, pfNum int) (*SfPort, error) {
+	pfPciAddress, err := normalizePciAddress(pfPciAddress)
+	if err != nil {
+		return nil, err
+	}
+	used, err := getUsedSfNums(pfPciAddress, pfNum)
+	if err != nil {
+		return nil, err
+	}
+	sfNum := 0
+	for used[sfNum] {
+		sfNum++
+	}
+	return CreateSF(pfPciAddress, pfNum, sfNum)
+}
+
+// pfPciAddressToUplink returns the uplink representor of the given PF, or an empty string if not found
+func pfPciAddressToUplink(pfPciAddress string) string {
+	uplink, _ := GetUplinkRepresentor(pfPciAddress)
+	return uplink
+}
+
+// sfDevlinkPort is a SF devlink port along with its pfnum and sfnum, which are -1 if they could not be resolved
+type sfDevlinkPort struct {
+	*netlink.DevlinkPort
+	pfNum int
+	sfNum int
+}
+
+// listSfDevlinkPorts returns the SF devlink ports of the given PF. The pfnum and sfnum of a port are resolved
+// from the phys_port_name of its representor netdev.
+func listSfDevlinkPorts(pfPciAddress string) ([]sfDevlinkPort, error) {
+	ports, err := netlinkops.GetNetlinkOps().DevLinkGetAllPortList()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list devlink ports: %v", err)
+	}
+	sfPorts := make([]sfDevlinkPort, 0)
+	for _, port := range ports {
+		if port.BusName != devlinkPciBus || port.DeviceName != pfPciAddress ||
+			port.PortFlavour != PORT_FLAVOUR_PCI_SF {
+			continue
+		}
+		sfPort := sfDevlinkPort{DevlinkPort: port, pfNum: -1, sfNum: -1}
+		if port.NetdeviceName != "" {
+			physPortName, err := GetPhysPortName(port.NetdeviceName)
+			if err == nil {
+				sfPort.pfNum, sfPort.sfNum, _ = parseIndexFromPhysPortName(physPortName, sfPortRepRegex)
+			}
+		}
+		sfPorts = append(sfPorts, sfPort)
+	}
+	return sfPorts, nil
+}
+
+// getUsedSfNums returns the sfnums of the existing SF ports of the given pfnum on the given PF
+func getUsedSfNums(pfPciAddress string, pfNum int) (map[int]bool, error) {
+	sfPorts, err := listSfDevlinkPorts(pfPciAddress)
+	if err != nil {
+		return nil, err
+	}
+	used := make(map[int]bool)
+	for _, sfPort := range sfPorts {
+		if sfPort.sfNum >= 0 && sfPort.pfNum == pfNum {
+			used[sfPort.sfNum] = true
+		}
+	}
+	return used, nil
+}
+
+// DeleteSF deletes the SF port with the given pfnum and sfnum on the PF with the given PCI address.
+// The SF is deactivated first if it is active. If timeout is non-zero, DeleteSF waits up to timeout
+// for the SF auxiliary device (e.g mlx5_core.sf.2) to be removed.
+// Equivalent to: `devlink port function set pci/0000:03:00.0/32768 state inactive` followed by
+// `devlink port del pci/0000:03:00.0/32768`
+func DeleteSF(pfPciAddress string, pfNum, sfNum int, timeout time.Duration) error {
+	pfPciAddress, err := normalizePciAddress(pfPciAddress)
+	if err != nil {
+		return err
+	}
+	sfPorts, err := listSfDevlinkPorts(pfPciAddress)
+	if err != nil {
+		return err
+	}
+	for _, sfPort := range sfPorts {
+		if sfPort.pfNum == pfNum && sfPort.sfNum == sfNum {
+			return deleteSfPort(pfPciAddress, sfPort, timeout)
+		}
+	}
+	return fmt.Errorf("%w: SF port pfnum %d sfnum %d on %s", ErrDeviceNotFound, pfNum, sfNum, pfPciAddress)
+}
+
+// DeleteSFByPortIndex deletes the SF port with the given devlink port index on the PF with the given PCI address.
+// The SF is deactivated first if it is active. If timeout is non-zero, DeleteSFByPortIndex waits up to timeout
+// for the SF auxiliary device to be removed, this requires the SF representor to exist to resolve its sfnum.
+func DeleteSFByPortIndex(pfPciAddress string, portIndex uint32, timeout time.Duration) error {
+	pfPciAddress, err := normalizePciAddress(pfPciAddress)
+	if err != nil {
+		return err
+	}
+	sfPorts, err := listSfDevlinkPorts(pfPciAddress)
+	if err != nil {
+		return err
+	}
+	for _, sfPort := range sfPorts {
+		if sfPort.PortIndex == portIndex {
+			return deleteSfPort(pfPciAddress, sfPort, timeout)
+		}
+	}
+	return fmt.Errorf("%w: SF port %s/%s/%d", ErrDeviceNotFound, devlinkPciBus, pfPciAddress, portIndex)
+}
+
+func deleteSfPort(pfPciAddress string, sfPort sfDevlinkPort, timeout time.Duration) error {
+	nlOps := netlinkops.GetNetlinkOps()
+	if sfPort.Fn != nil && sfPort.Fn.State == nl.DEVLINK_PORT_FN_STATE_ACTIVE {
+		fnAttrs := netlink.DevlinkPortFnSetAttrs{StateValid: true}
+		fnAttrs.FnAttrs.State = nl.DEVLINK_PORT_FN_STATE_INACTIVE
+		if err := nlOps.DevlinkPortFnSet(devlinkPciBus, pfPciAddress, sfPort.PortIndex, fnAttrs); err != nil {
+			return fmt.Errorf("failed to deactivate SF port %s/%s/%d: %v",
+				devlinkPciBus, pfPciAddress, sfPort.PortIndex, err)
+		}
+	}
+	if err := nlOps.DevLinkPortDel(devlinkPciBus, pfPciAddress, sfPort.PortIndex); err != nil {
+		return fmt.Errorf("failed to delete SF port %s/%s/%d: %v", devlinkPciBus, pfPciAddress, sfPort.PortIndex, err)
+	}
+
+	if timeout == 0 || sfPort.sfNum < 0 {
+		return nil
+	}
+	removed := pollUntil(timeout, sfPollInterval, func() bool {
+		_, err := GetAuxSFDevByPciAndSFIndex(pfPciAddress, uint32(sfPort.sfNum))
+		return errors.Is(err, ErrDeviceNotFound)
+	})
+	if !removed {
+		return fmt.Errorf("timed out waiting for auxiliary device of SF %d on %s to be removed",
+			sfPort.sfNum, pfPciAddress)
+	}
+	return nil
+}
+
+// DeploySF creates a SF on the PF with the given PCI address, sets its hardware address, activates it and waits
+// for its auxiliary device to be probed. On failure, the SF port is deleted. A nil cfg deploys a SF with
+// the first free sfnum on pfnum 0 and the default settings.
+// ErrNotSupported is returned, before any SF is created, if trust is requested.
+func DeploySF(pfPciAddress string, cfg *SfConfig) (*DeployedSF, error) {
+	if cfg == nil {
+		cfg = &SfConfig{}
+	}
+	if cfg.Trust {
+		return nil, fmt.Errorf("%w: setting trust of SF on %s, devlink has no trust port function attribute",
+			ErrNotSupported, pfPciAddress)
+	}
+	var sfPort *SfPort
+	var err error
+	if cfg.SfNum != nil {
+		sfPort, err = CreateSF(pfPciAddress, cfg.PfNum, *cfg.SfNum)
+	} else {
+		sfPort, err = CreateSFWithAutoSfNum(pfPciAddress, cfg.PfNum)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	deployed, err := deploySfPort(sfPort, cfg)
+	if err != nil {
+		if delErr := DeleteSFByPortIndex(sfPort.PfPciAddress, sfPort.PortIndex, 0); delErr != nil {
+			return nil, fmt.Errorf("%v, failed to delete SF port: %v", err, delErr)
+		}
+		return nil, err
+	}
+	return deployed, nil
+}
+
+func deploySfPort(sfPort *SfPort, cfg *SfConfig) (*DeployedSF, error) {
+	nlOps := netlinkops.GetNetlinkOps()
+	portHandle := fmt.Sprintf("%s/%s/%d", devlinkPciBus, sfPort.PfPciAddress, sfPort.PortIndex)
+	if cfg.HwAddr != nil {
+		if err := SetPortFnHwAddr(portHandle, cfg.HwAddr); err != nil {
+			return nil, err
+		}
+	}
+
+	fnAttrs := netlink.DevlinkPortFnSetAttrs{StateValid: true}
+	fnAttrs.FnAttrs.State = nl.DEVLINK_PORT_FN_STATE_ACTIVE
+	if err := nlOps.DevlinkPortFnSet(devlinkPciBus, sfPort.PfPciAddress, sfPort.PortIndex, fnAttrs); err != nil {
+		return nil, fmt.Errorf("failed to activate SF port %s: %v", portHandle, err)
+	}
+
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = sfDefaultProbeTimeout
+	}
+	auxDev, err := WaitForSfAuxDev(sfPort.PfPciAddress, uint32(sfPort.SfNum), timeout)
+	if err != nil {
+		return nil, err
+	}
+	netDev, err := GetSfNetdevByPfAndSfIndex(sfPort.PfPciAddress, uint32(sfPort.SfNum))
+	if err != nil {
+		return nil, err
+	}
+	deployed := &DeployedSF{SfPort: *sfPort, NetDev: netDev}
+	deployed.AuxDev = auxDev
+	if rdmaDevs, err := GetRdmaDevicesFromAux(auxDev); err == nil && len(rdmaDevs) > 0 {
+		deployed.RdmaDev = rdmaDevs[0]
+	}
+	return deployed, nil
+}
+
+// GetMaxSfCount returns the maximum number of SFs which can be created on the local host for the PF with the
+// given PCI address, as reported by the max_local_SFs devlink resource.
+// Equivalent to: `devlink resource show pci/0000:03:00.0`
+// Returns ErrNotSupported if the driver does not report the resource, mlx5 does not expose this limit elsewhere
+// (it is a firmware configuration, e.g PF_TOTAL_SF).
+func GetMaxSfCount(pfPciAddress string) (int, error) {
+	resources, err := GetDevlinkResources(pfPciAddress)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get max SF count: %v", err)
+	}
+	resource := findDevlinkResource(resources, DevlinkResourceMaxLocalSFs)
+	if resource == nil {
+		return 0, fmt.Errorf("%w: devlink resource %s of %s", ErrNotSupported, DevlinkResourceMaxLocalSFs,
+			pfPciAddress)
+	}
+	return int(resource.Size), nil
+}
+
+// ListSFs returns the SFs of the PF with the given PCI address, merging their devlink port information
+// with their auxiliary devices.
+// Note: PfNum and SfNum are -1 and AuxDev and NetDev are not resolved for SFs without a representor netdev.
+func ListSFs(pfPciAddress string) ([]*SfInfo, error) {
+	pfPciAddress, err := normalizePciAddress(pfPciAddress)
+	if err != nil {
+		return nil, err
+	}
+	sfPorts, err := listSfDevlinkPorts(pfPciAddress)
+	if err != nil {
+		return nil, err
+	}
+	sfs := make([]*SfInfo, 0, len(sfPorts))
+	for _, sfPort := range sfPorts {
+		sf := &SfInfo{SfPort: SfPort{
+			PfPciAddress: pfPciAddress,
+			PfNum:        sfPort.pfNum,
+			SfNum:        sfPort.sfNum,
+			PortIndex:    sfPort.PortIndex,
+			Representor:  sfPort.NetdeviceName,
+		}}
+		if sfPort.Fn != nil {
+			sf.State, sf.OpState = portFnStates(sfPort.Fn)
+			sf.HwAddr = sfPort.Fn.HwAddr
+		}
+		if sfPort.sfNum >= 0 {
+			if auxDev, err := GetAuxSFDevByPciAndSFIndex(pfPciAddress, uint32(sfPort.sfNum)); err == nil {
+				sf.AuxDev = auxDev
+				if netDevs, err := GetNetDevicesFromAux(auxDev); err == nil && len(netDevs) > 0 {
+					sf.NetDev = netDevs[0]
+				}
+			}
+		}
+		sfs = append(sfs, sf)
+	}
+	return sfs, nil
+}
@@ -0,0 +1,381 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sriovnet
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+
+	utilfs "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/filesystem"
+	"github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/netlinkops"
+	netlinkopsMocks "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/netlinkops/mocks"
+)
+
+func newSfDevlinkPort(netdev string, portIndex uint32, fn *netlink.DevlinkPortFn) *netlink.DevlinkPort {
+	return &netlink.DevlinkPort{
+		BusName:       "pci",
+		DeviceName:    "0000:03:00.0",
+		PortIndex:     portIndex,
+		PortType:      2, // ETH
+		NetdeviceName: netdev,
+		PortFlavour:   PORT_FLAVOUR_PCI_SF,
+		Fn:            fn,
+	}
+}
+
+func TestSetSfPortState(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+	teardown := setupSfRepresentorEnv(t, []*repContext{
+		{Name: "en3f0pf0sf88", PhysPortName: "pf0sf88"},
+	})
+	defer teardown()
+
+	nlOpsMock.On("DevLinkGetPortByNetdevName", "en3f0pf0sf88").Return(
+		newSfDevlinkPort("en3f0pf0sf88", 32768, &netlink.DevlinkPortFn{}), nil)
+	activeAttrs := netlink.DevlinkPortFnSetAttrs{StateValid: true}
+	activeAttrs.FnAttrs.State = nl.DEVLINK_PORT_FN_STATE_ACTIVE
+	nlOpsMock.On("DevlinkPortFnSet", "pci", "0000:03:00.0", uint32(32768), activeAttrs).Return(nil)
+	inactiveAttrs := netlink.DevlinkPortFnSetAttrs{StateValid: true}
+	nlOpsMock.On("DevlinkPortFnSet", "pci", "0000:03:00.0", uint32(32768), inactiveAttrs).Return(
+		fmt.Errorf("busy"))
+
+	err := SetSfPortState("p0", 88, true)
+	assert.NoError(t, err)
+	err = SetSfPortState("p0", 88, false)
+	assert.Error(t, err)
+	nlOpsMock.AssertExpectations(t)
+
+	err = SetSfPortState("p0", 89, true)
+	assert.Error(t, err)
+}
+
+func TestGetSfPortState(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+	teardown := setupSfRepresentorEnv(t, []*repContext{
+		{Name: "en3f0pf0sf88", PhysPortName: "pf0sf88"},
+	})
+	defer teardown()
+
+	nlOpsMock.On("DevLinkGetPortByNetdevName", "en3f0pf0sf88").Return(
+		newSfDevlinkPort("en3f0pf0sf88", 32768, &netlink.DevlinkPortFn{
+			State:   nl.DEVLINK_PORT_FN_STATE_ACTIVE,
+			OpState: nl.DEVLINK_PORT_FN_OPSTATE_DETACHED,
+		}), nil)
+
+	state, opState, err := GetSfPortState("p0", 88)
+	assert.NoError(t, err)
+	assert.Equal(t, PortFnStateActive, state)
+	assert.Equal(t, PortFnOpStateDetached, opState)
+}
+
+func TestCreateSF(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+
+	attrs := netlink.DevLinkPortAddAttrs{PfNumber: 0, SfNumber: 88, SfNumberValid: true}
+	nlOpsMock.On("DevLinkPortAdd", "pci", "0000:03:00.0", uint16(PORT_FLAVOUR_PCI_SF), attrs).Return(
+		newSfDevlinkPort("en3f0pf0sf88", 32768, nil), nil)
+	attrs.SfNumber = 89
+	nlOpsMock.On("DevLinkPortAdd", "pci", "0000:03:00.0", uint16(PORT_FLAVOUR_PCI_SF), attrs).Return(
+		nil, fmt.Errorf("sfnum already in use"))
+
+	sfPort, err := CreateSF("03:00.0", 0, 88)
+	assert.NoError(t, err)
+	assert.Equal(t, &SfPort{
+		PfPciAddress: "0000:03:00.0",
+		PfNum:        0,
+		SfNum:        88,
+		PortIndex:    32768,
+		Representor:  "en3f0pf0sf88",
+	}, sfPort)
+
+	_, err = CreateSF("0000:03:00.0", 0, 89)
+	assert.Error(t, err)
+	_, err = CreateSF("0000:03:00.0", 0, -1)
+	assert.Error(t, err)
+	_, err = CreateSF("invalid", 0, 88)
+	assert.ErrorIs(t, err, ErrInvalidPciAddress)
+	nlOpsMock.AssertExpectations(t)
+}
+
+func TestCreateSFWithAutoSfNum(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+	teardown := setupSfRepresentorEnv(t, []*repContext{
+		{Name: "en3f0pf0sf0", PhysPortName: "pf0sf0"},
+		{Name: "en3f0pf0sf1", PhysPortName: "pf0sf1"},
+		{Name: "en3f0pf1sf2", PhysPortName: "pf1sf2"},
+	})
+	defer teardown()
+
+	nlOpsMock.On("DevLinkGetAllPortList").Return([]*netlink.DevlinkPort{
+		newSfDevlinkPort("en3f0pf0sf0", 32768, nil),
+		newSfDevlinkPort("en3f0pf0sf1", 32769, nil),
+		newSfDevlinkPort("en3f0pf1sf2", 32770, nil),
+	}, nil)
+	attrs := netlink.DevLinkPortAddAttrs{PfNumber: 0, SfNumber: 2, SfNumberValid: true}
+	nlOpsMock.On("DevLinkPortAdd", "pci", "0000:03:00.0", uint16(PORT_FLAVOUR_PCI_SF), attrs).Return(
+		newSfDevlinkPort("en3f0pf0sf2", 32771, nil), nil)
+
+	sfPort, err := CreateSFWithAutoSfNum("0000:03:00.0", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, sfPort.SfNum)
+	assert.Equal(t, uint32(32771), sfPort.PortIndex)
+	assert.Equal(t, "en3f0pf0sf2", sfPort.Representor)
+	nlOpsMock.AssertExpectations(t)
+}
+
+func TestDeleteSF(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+	teardown := setupSfRepresentorEnv(t, []*repContext{
+		{Name: "en3f0pf0sf88", PhysPortName: "pf0sf88"},
+	})
+	defer teardown()
+	pfPciAddress := "0000:03:00.0"
+	setUpAuxDevEnv(t, []auxDevContext{{parent: pfPciAddress, sfNum: "88", name: "mlx5_core.sf.2"}})
+	createPciDevicePaths(t, pfPciAddress, []string{"net"})
+
+	nlOpsMock.On("DevLinkGetAllPortList").Return([]*netlink.DevlinkPort{
+		newSfDevlinkPort("en3f0pf0sf88", 32768, &netlink.DevlinkPortFn{State: nl.DEVLINK_PORT_FN_STATE_ACTIVE}),
+	}, nil)
+	inactiveAttrs := netlink.DevlinkPortFnSetAttrs{StateValid: true}
+	nlOpsMock.On("DevlinkPortFnSet", "pci", pfPciAddress, uint32(32768), inactiveAttrs).Return(nil)
+	nlOpsMock.On("DevLinkPortDel", "pci", pfPciAddress, uint32(32768)).Return(nil).Run(func(mock.Arguments) {
+		_ = utilfs.Fs.RemoveAll(filepath.Join(PciSysDir, pfPciAddress, "mlx5_core.sf.2"))
+	})
+
+	err := DeleteSF(pfPciAddress, 0, 88, time.Second)
+	assert.NoError(t, err)
+	nlOpsMock.AssertExpectations(t)
+
+	err = DeleteSF(pfPciAddress, 0, 89, 0)
+	assert.ErrorIs(t, err, ErrDeviceNotFound)
+}
+
+func TestDeleteSFByPortIndex(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+	teardown := setupSfRepresentorEnv(t, []*repContext{
+		{Name: "en3f0pf0sf88", PhysPortName: "pf0sf88"},
+	})
+	defer teardown()
+	pfPciAddress := "0000:03:00.0"
+	setUpAuxDevEnv(t, []auxDevContext{{parent: pfPciAddress, sfNum: "88", name: "mlx5_core.sf.2"}})
+	createPciDevicePaths(t, pfPciAddress, []string{"net"})
+
+	nlOpsMock.On("DevLinkGetAllPortList").Return([]*netlink.DevlinkPort{
+		newSfDevlinkPort("en3f0pf0sf88", 32768, &netlink.DevlinkPortFn{}),
+	}, nil)
+	nlOpsMock.On("DevLinkPortDel", "pci", pfPciAddress, uint32(32768)).Return(nil)
+
+	// auxiliary device is never removed
+	err := DeleteSFByPortIndex(pfPciAddress, 32768, 200*time.Millisecond)
+	assert.Error(t, err)
+	nlOpsMock.AssertExpectations(t)
+	nlOpsMock.AssertNotCalled(t, "DevlinkPortFnSet")
+
+	err = DeleteSFByPortIndex(pfPciAddress, 32769, 0)
+	assert.ErrorIs(t, err, ErrDeviceNotFound)
+}
+
+func TestDeploySF(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+	teardown := setupFakeFs(t)
+	defer teardown()
+	pfPciAddress := "0000:03:00.0"
+	createPciDevicePaths(t, pfPciAddress, []string{"net"})
+
+	sfNum := 88
+	mac := net.HardwareAddr{0, 0, 0, 0, 0x88, 0x88}
+	addAttrs := netlink.DevLinkPortAddAttrs{PfNumber: 0, SfNumber: 88, SfNumberValid: true}
+	nlOpsMock.On("DevLinkPortAdd", "pci", pfPciAddress, uint16(PORT_FLAVOUR_PCI_SF), addAttrs).Return(
+		newSfDevlinkPort("en3f0pf0sf88", 32768, nil), nil)
+	hwAddrAttrs := netlink.DevlinkPortFnSetAttrs{HwAddrValid: true}
+	hwAddrAttrs.FnAttrs.HwAddr = mac
+	nlOpsMock.On("DevlinkPortFnSet", "pci", pfPciAddress, uint32(32768), hwAddrAttrs).Return(nil)
+	activeAttrs := netlink.DevlinkPortFnSetAttrs{StateValid: true}
+	activeAttrs.FnAttrs.State = nl.DEVLINK_PORT_FN_STATE_ACTIVE
+	nlOpsMock.On("DevlinkPortFnSet", "pci", pfPciAddress, uint32(32768), activeAttrs).Return(nil).Run(
+		func(mock.Arguments) {
+			setUpAuxDevEnv(t, []auxDevContext{{parent: pfPciAddress, sfNum: "88", name: "mlx5_core.sf.2"}})
+			createPciDevicePaths(t, pfPciAddress, []string{"mlx5_core.sf.2/net/enp3s0f0s88",
+				"mlx5_core.sf.2/infiniband/mlx5_2"})
+		})
+
+	deployed, err := DeploySF(pfPciAddress, &SfConfig{PfNum: 0, SfNum: &sfNum, HwAddr: mac, Timeout: time.Second})
+	assert.NoError(t, err)
+	assert.Equal(t, &DeployedSF{
+		SfPort: SfPort{
+			PfPciAddress: pfPciAddress,
+			PfNum:        0,
+			SfNum:        88,
+			PortIndex:    32768,
+			Representor:  "en3f0pf0sf88",
+		},
+		AuxDev:  "mlx5_core.sf.2",
+		NetDev:  "enp3s0f0s88",
+		RdmaDev: "mlx5_2",
+	}, deployed)
+	nlOpsMock.AssertExpectations(t)
+}
+
+func TestDeploySFRollback(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+	teardown := setupSfRepresentorEnv(t, []*repContext{
+		{Name: "en3f0pf0sf88", PhysPortName: "pf0sf88"},
+	})
+	defer teardown()
+	pfPciAddress := "0000:03:00.0"
+
+	sfNum := 88
+	addAttrs := netlink.DevLinkPortAddAttrs{PfNumber: 0, SfNumber: 88, SfNumberValid: true}
+	nlOpsMock.On("DevLinkPortAdd", "pci", pfPciAddress, uint16(PORT_FLAVOUR_PCI_SF), addAttrs).Return(
+		newSfDevlinkPort("en3f0pf0sf88", 32768, nil), nil)
+	activeAttrs := netlink.DevlinkPortFnSetAttrs{StateValid: true}
+	activeAttrs.FnAttrs.State = nl.DEVLINK_PORT_FN_STATE_ACTIVE
+	nlOpsMock.On("DevlinkPortFnSet", "pci", pfPciAddress, uint32(32768), activeAttrs).Return(
+		fmt.Errorf("no resources"))
+	nlOpsMock.On("DevLinkGetAllPortList").Return([]*netlink.DevlinkPort{
+		newSfDevlinkPort("en3f0pf0sf88", 32768, &netlink.DevlinkPortFn{}),
+	}, nil)
+	nlOpsMock.On("DevLinkPortDel", "pci", pfPciAddress, uint32(32768)).Return(nil)
+
+	_, err := DeploySF(pfPciAddress, &SfConfig{PfNum: 0, SfNum: &sfNum})
+	assert.Error(t, err)
+	nlOpsMock.AssertExpectations(t)
+}
+
+func TestDeploySFNilConfig(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+	pfPciAddress := "0000:03:00.0"
+
+	nlOpsMock.On("DevLinkGetAllPortList").Return([]*netlink.DevlinkPort{}, nil)
+	addAttrs := netlink.DevLinkPortAddAttrs{PfNumber: 0, SfNumber: 0, SfNumberValid: true}
+	nlOpsMock.On("DevLinkPortAdd", "pci", pfPciAddress, uint16(PORT_FLAVOUR_PCI_SF), addAttrs).Return(
+		nil, fmt.Errorf("no resources"))
+
+	_, err := DeploySF(pfPciAddress, nil)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "no resources")
+	nlOpsMock.AssertExpectations(t)
+}
+
+func TestDeploySFTrust(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+
+	_, err := DeploySF("0000:03:00.0", &SfConfig{Trust: true})
+	assert.ErrorIs(t, err, ErrNotSupported)
+	nlOpsMock.AssertNotCalled(t, "DevLinkPortAdd")
+}
+
+func TestGetMaxSfCount(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+
+	nlOpsMock.On("DevlinkGetDeviceResources", "pci", "0000:03:00.0").Return(&netlink.DevlinkResources{
+		Resources: []netlink.DevlinkResource{{Name: "max_local_SFs", ID: 1, Size: 256, SizeNew: 256}},
+	}, nil)
+	nlOpsMock.On("DevlinkGetDeviceResources", "pci", "0000:04:00.0").Return(&netlink.DevlinkResources{}, nil)
+
+	maxSfs, err := GetMaxSfCount("0000:03:00.0")
+	assert.NoError(t, err)
+	assert.Equal(t, 256, maxSfs)
+
+	_, err = GetMaxSfCount("0000:04:00.0")
+	assert.ErrorIs(t, err, ErrNotSupported)
+}
+
+func TestListSFs(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+	teardown := setupSfRepresentorEnv(t, []*repContext{
+		{Name: "en3f0pf0sf88", PhysPortName: "pf0sf88"},
+		{Name: "en3f0pf0sf89", PhysPortName: "pf0sf89"},
+	})
+	defer teardown()
+	pfPciAddress := "0000:03:00.0"
+	setUpAuxDevEnv(t, []auxDevContext{{parent: pfPciAddress, sfNum: "88", name: "mlx5_core.sf.2"}})
+	createPciDevicePaths(t, pfPciAddress, []string{"net", "mlx5_core.sf.2/net/enp3s0f0s88"})
+
+	mac := net.HardwareAddr{0, 0, 0, 0, 0x88, 0x88}
+	nlOpsMock.On("DevLinkGetAllPortList").Return([]*netlink.DevlinkPort{
+		{BusName: "pci", DeviceName: pfPciAddress, PortIndex: 65535, PortFlavour: PORT_FLAVOUR_PHYSICAL},
+		newSfDevlinkPort("en3f0pf0sf88", 32768, &netlink.DevlinkPortFn{
+			HwAddr:  mac,
+			State:   nl.DEVLINK_PORT_FN_STATE_ACTIVE,
+			OpState: nl.DEVLINK_PORT_FN_OPSTATE_ATTACHED,
+		}),
+		newSfDevlinkPort("en3f0pf0sf89", 32769, &netlink.DevlinkPortFn{}),
+	}, nil)
+
+	sfs, err := ListSFs(pfPciAddress)
+	assert.NoError(t, err)
+	assert.Equal(t, []*SfInfo{
+		{
+			SfPort: SfPort{
+				PfPciAddress: pfPciAddress,
+				PfNum:        0,
+				SfNum:        88,
+				PortIndex:    32768,
+				Representor:  "en3f0pf0sf88",
+			},
+			State:   PortFnStateActive,
+			OpState: PortFnOpStateAttached,
+			HwAddr:  mac,
+			AuxDev:  "mlx5_core.sf.2",
+			NetDev:  "enp3s0f0s88",
+		},
+		{
+			SfPort: SfPort{
+				PfPciAddress: pfPciAddress,
+				PfNum:        0,
+				SfNum:        89,
+				PortIndex:    32769,
+				Representor:  "en3f0pf0sf89",
+			},
+			State:   PortFnStateInactive,
+			OpState: PortFnOpStateDetached,
+		},
+	}, sfs)
+}
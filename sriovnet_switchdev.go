@@ -24,9 +24,12 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
+	"github.com/vishvananda/netlink"
+
 	utilfs "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/filesystem"
 	"github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/netlinkops"
 )
@@ -52,18 +55,101 @@ const (
 	PORT_FLAVOUR_UNKNOWN = 0xffff
 )
 
-// Regex that matches on the physical/upling port name
-var physPortRepRegex = regexp.MustCompile(`^p(\d+)$`)
+var portFlavourNames = map[PortFlavour]string{
+	PORT_FLAVOUR_PHYSICAL: "physical",
+	PORT_FLAVOUR_CPU:      "cpu",
+	PORT_FLAVOUR_DSA:      "dsa",
+	PORT_FLAVOUR_PCI_PF:   "pcipf",
+	PORT_FLAVOUR_PCI_VF:   "pcivf",
+	PORT_FLAVOUR_VIRTUAL:  "virtual",
+	PORT_FLAVOUR_UNUSED:   "unused",
+	PORT_FLAVOUR_PCI_SF:   "pcisf",
+	PORT_FLAVOUR_UNKNOWN:  "unknown",
+}
+
+// String returns the devlink name of the port flavour (e.g "pcivf")
+func (f PortFlavour) String() string {
+	if name, ok := portFlavourNames[f]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown(%d)", uint16(f))
+}
+
+// ParsePortFlavour parses a devlink port flavour name (e.g "pcivf") as returned by PortFlavour.String()
+func ParsePortFlavour(name string) (PortFlavour, error) {
+	for flavour, flavourName := range portFlavourNames {
+		if flavourName == name {
+			return flavour, nil
+		}
+	}
+	return PORT_FLAVOUR_UNKNOWN, fmt.Errorf("unknown port flavour %q", name)
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (f PortFlavour) MarshalText() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (f *PortFlavour) UnmarshalText(text []byte) error {
+	flavour, err := ParsePortFlavour(string(text))
+	if err != nil {
+		return err
+	}
+	*f = flavour
+	return nil
+}
+
+// Regex that matches on the physical/uplink port name, including split ports (e.g p0s1)
+var physPortRepRegex = regexp.MustCompile(`^p(\d+)(?:s\d+)?$`)
 
 // Regex that matches on PF representor port name. These ports exists on DPUs.
 var pfPortRepRegex = regexp.MustCompile(`^(?:c\d+)?pf(\d+)$`)
 
-// Regex that matches on VF representor port name
-var vfPortRepRegex = regexp.MustCompile(`^(?:c\d+)?pf(\d+)vf(\d+)$`)
+// Regex that matches on VF representor port name. Some drivers (e.g ice) use a "vfr" infix.
+var vfPortRepRegex = regexp.MustCompile(`^(?:c\d+)?pf(\d+)vfr?(\d+)$`)
+
+// Regex that matches on legacy VF representor port name which carries no PF index
+var legacyVfPortRepRegex = regexp.MustCompile(`^vf(\d+)$`)
 
 // Regex that matches on SF representor port name
 var sfPortRepRegex = regexp.MustCompile(`^(?:c\d+)?pf(\d+)sf(\d+)$`)
 
+// switchdevQuirks describes driver specific deviations in how an eswitch and its representors are exposed
+type switchdevQuirks struct {
+	// smartNicSysfs is set if the driver exposes the DPU smart_nic sysfs interface under the uplink
+	smartNicSysfs bool
+	// vfMacViaUplink is set if the MAC address of the VF behind a representor is configured through the
+	// uplink legacy VF interface, i.e `ip link set <uplink> vf <N> mac <mac>`
+	vfMacViaUplink bool
+}
+
+// defaultSwitchdevQuirks are used for eswitch manager drivers missing from switchdevDriverQuirks
+var defaultSwitchdevQuirks = switchdevQuirks{smartNicSysfs: true}
+
+// switchdevDriverQuirks maps eswitch manager PCI drivers to their quirks
+var switchdevDriverQuirks = map[string]switchdevQuirks{
+	"mlx5_core": {smartNicSysfs: true},
+	"ice":       {vfMacViaUplink: true},
+}
+
+// getSwitchdevQuirks returns the quirks of the driver of the PCI device backing the given uplink or
+// representor netdev
+func getSwitchdevQuirks(netdev string) switchdevQuirks {
+	pciAddress, err := getPCIFromDeviceName(netdev)
+	if err != nil {
+		return defaultSwitchdevQuirks
+	}
+	driver, err := GetDriverNameByPciAddress(pciAddress)
+	if err != nil {
+		return defaultSwitchdevQuirks
+	}
+	if quirks, ok := switchdevDriverQuirks[driver]; ok {
+		return quirks
+	}
+	return defaultSwitchdevQuirks
+}
+
 func parseIndexFromPhysPortName(portName string, regex *regexp.Regexp) (pfRepIndex, vfRepIndex int, err error) {
 	pfRepIndex = -1
 	vfRepIndex = -1
@@ -81,12 +167,38 @@ func parseIndexFromPhysPortName(portName string, regex *regexp.Regexp) (pfRepInd
 	return pfRepIndex, vfRepIndex, err
 }
 
+// parsePfIndexFromPhysPortName returns the PF index of a PF representor port name (e.g pf1 or c1pf1)
+func parsePfIndexFromPhysPortName(physPortName string) (int, error) {
+	matches := pfPortRepRegex.FindStringSubmatch(physPortName)
+	if matches == nil {
+		return -1, fmt.Errorf("failed to parse portName %s", physPortName)
+	}
+	return strconv.Atoi(matches[1])
+}
+
+// GetPfIndexFromPhysPortName returns the PF index encoded in a representor phys_port_name,
+// e.g 1 for p1, pf1, c1pf1, pf1vf2 or pf1sf3. For uplink port names the physical port number is returned,
+// which matches the index of the PF owning the uplink.
+func GetPfIndexFromPhysPortName(physPortName string) (int, error) {
+	info, err := parseRepresentorPortName(strings.TrimSpace(physPortName))
+	if err != nil {
+		return -1, err
+	}
+	if info.PfID < 0 {
+		return -1, fmt.Errorf("port name %s carries no PF index", physPortName)
+	}
+	return info.PfID, nil
+}
+
 func parsePortName(physPortName string) (pfRepIndex, vfRepIndex int, err error) {
 	// old kernel syntax of phys_port_name is vf index
 	physPortName = strings.TrimSpace(physPortName)
 	physPortNameInt, err := strconv.Atoi(physPortName)
 	if err == nil {
 		vfRepIndex = physPortNameInt
+	} else if matches := legacyVfPortRepRegex.FindStringSubmatch(physPortName); matches != nil {
+		pfRepIndex = -1
+		vfRepIndex, err = strconv.Atoi(matches[1])
 	} else {
 		pfRepIndex, vfRepIndex, err = parseIndexFromPhysPortName(physPortName, vfPortRepRegex)
 	}
@@ -111,107 +223,414 @@ func isSwitchdev(netdevice string) bool {
 	return false
 }
 
+// IsRepresentor returns true if the given netdev is an eswitch representor of any flavour (uplink, PF, VF or SF).
+// Only sysfs is consulted, phys_switch_id must be set and phys_port_name must be a representor port name.
+func IsRepresentor(netdev string) bool {
+	if !isSwitchdev(netdev) {
+		return false
+	}
+	portName, err := GetPhysPortName(netdev)
+	if err != nil {
+		return false
+	}
+	if _, err = parseRepresentorPortName(portName); err == nil {
+		return true
+	}
+	// old kernel syntax of phys_port_name is vf index
+	_, err = strconv.Atoi(portName)
+	return err == nil
+}
+
+// IsUplinkRepresentor returns true if the given netdev is the uplink (physical port) representor of an eswitch,
+// including split physical ports. Only sysfs is consulted.
+func IsUplinkRepresentor(netdev string) bool {
+	if !isSwitchdev(netdev) {
+		return false
+	}
+	portName, err := GetPhysPortName(netdev)
+	return err == nil && physPortRepRegex.MatchString(portName)
+}
+
+// isUplinkCandidate returns true if the given netdev is an eswitch port whose phys_port_name, if any, is an
+// uplink port name (e.g p0). Old kernels do not expose phys_port_name for the uplink. phys_port_name is read
+// first as most of the netdevs of a switchdev PF are VF or SF representors.
+func isUplinkCandidate(netdev string) bool {
+	if hasNonUplinkPhysPortName(netdev) {
+		logDebug("skipping eswitch port, not an uplink", "netdev", netdev)
+		return false
+	}
+	if !isSwitchdev(netdev) {
+		logDebug("skipping netdev, not an eswitch port", "netdev", netdev)
+		return false
+	}
+	return true
+}
+
 // GetUplinkRepresentor gets a VF or PF PCI address (e.g '0000:03:00.4') and
 // returns the uplink represntor netdev name for that VF or PF.
-func GetUplinkRepresentor(pciAddress string) (string, error) {
+// The uplink found for a PF is cached and validated on the next lookups, the netdevs of the PF (i.e its
+// representors in switchdev mode) are only scanned if the cached uplink is no longer valid.
+func GetUplinkRepresentor(pciAddress string) (uplink string, err error) {
+	defer startTrace("GetUplinkRepresentor", "pci", pciAddress)(&err)
+	pciAddress, err = normalizePciAddress(pciAddress)
+	if err != nil {
+		return "", err
+	}
 	devicePath := filepath.Join(PciSysDir, pciAddress, "physfn", "net")
 	if _, err := utilfs.Fs.Stat(devicePath); errors.Is(err, os.ErrNotExist) {
 		// If physfn symlink to the parent PF doesn't exist, use the current device's dir
 		devicePath = filepath.Join(PciSysDir, pciAddress, "net")
 	}
 
-	devices, err := utilfs.Fs.ReadDir(devicePath)
+	if cached := getCachedUplink(devicePath); cached != "" {
+		if _, err := utilfs.Fs.Stat(filepath.Join(devicePath, cached)); err == nil && isUplinkCandidate(cached) {
+			return cached, nil
+		}
+		logDebug("cached uplink representor is stale", "pci", pciAddress, "netdev", cached)
+	}
+
+	devices, err := utilfs.ReadDirNames(utilfs.Fs, devicePath)
 	if err != nil {
 		return "", fmt.Errorf("failed to lookup %s: %v", pciAddress, err)
 	}
 	for _, device := range devices {
-		if isSwitchdev(device.Name()) {
-			// Try to get the phys port name, if not exists then fallback to check without it
-			// phys_port_name should be in formant p<port-num> e.g p0,p1,p2 ...etc.
-			if devicePhysPortName, err := getNetDevPhysPortName(device.Name()); err == nil {
-				if !physPortRepRegex.MatchString(devicePhysPortName) {
-					continue
-				}
-			}
-
-			return device.Name(), nil
+		if isUplinkCandidate(device) {
+			setCachedUplink(devicePath, device)
+			return device, nil
 		}
 	}
+	logDebug("uplink representor not found", "pci", pciAddress, "path", devicePath, "netdevs", len(devices))
 	return "", fmt.Errorf("uplink for %s not found", pciAddress)
 }
 
-func GetVfRepresentor(uplink string, vfIndex int) (string, error) {
-	swIDFile := filepath.Join(NetSysDir, uplink, netdevPhysSwitchID)
-	physSwitchID, err := utilfs.Fs.ReadFile(swIDFile)
-	if err != nil || len(physSwitchID) == 0 {
-		return "", fmt.Errorf("cant get uplink %s switch id", uplink)
+// UplinkPortInfo describes the physical port of an uplink representor
+type UplinkPortInfo struct {
+	// PortNumber is the physical port number, e.g 1 for phys_port_name p1 (or split port p1s0)
+	PortNumber int
+	// PfPciAddress is the PCI address of the PF the uplink belongs to
+	PfPciAddress string
+	// PfIndex is the PCI function of the PF, comparable with GetPfIndexByVfPciAddress
+	PfIndex int
+}
+
+// GetUplinkPortInfo returns the physical port number of the given uplink representor along with the PF it
+// belongs to. Allows multi-port NIC users to match the uplink of a VF (see GetPfIndexByVfPciAddress).
+func GetUplinkPortInfo(uplink string) (*UplinkPortInfo, error) {
+	portName, err := GetPhysPortName(uplink)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get device %s physical port name: %v", uplink, err)
 	}
+	matches := physPortRepRegex.FindStringSubmatch(portName)
+	if matches == nil {
+		return nil, fmt.Errorf("netdev %s is not an uplink representor, port name %s", uplink, portName)
+	}
+	info := &UplinkPortInfo{}
+	info.PortNumber, _ = strconv.Atoi(matches[1])
 
-	pfSubsystemPath := filepath.Join(NetSysDir, uplink, "subsystem")
-	devices, err := utilfs.Fs.ReadDir(pfSubsystemPath)
+	if info.PfPciAddress, err = getPCIFromDeviceName(uplink); err != nil {
+		return nil, err
+	}
+	pfAddr, err := ParsePciAddress(info.PfPciAddress)
+	if err != nil {
+		return nil, fmt.Errorf("error trying to parse PF PCI address %s: %v", info.PfPciAddress, err)
+	}
+	info.PfIndex = int(pfAddr.Function)
+	return info, nil
+}
+
+// VfLag describes the uplink representors of a PF and the bond netdev aggregating them when in VF LAG
+type VfLag struct {
+	// Bond is the bond netdev enslaving the uplinks, empty if the PF is not in VF LAG
+	Bond string
+	// Uplinks are the uplink representors enslaved to Bond sorted by name, or only the PF uplink
+	// if not in VF LAG
+	Uplinks []string
+}
+
+// Enabled returns true if the uplinks are in VF LAG
+func (l *VfLag) Enabled() bool {
+	return l.Bond != ""
+}
+
+// GetVfLag gets a VF or PF PCI address (e.g '0000:03:00.4') and returns the uplink representors of the
+// eswitch in VF LAG along with the bond netdev enslaving them. If the uplink is not enslaved to a bond
+// only the PF uplink, as returned by GetUplinkRepresentor, is returned.
+func GetVfLag(pciAddress string) (*VfLag, error) {
+	uplink, err := GetUplinkRepresentor(pciAddress)
+	if err != nil {
+		return nil, err
+	}
+	vfLag := &VfLag{Uplinks: []string{uplink}}
+
+	masterPath, err := utilfs.Fs.Readlink(filepath.Join(NetSysDir, uplink, "master"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return vfLag, nil
+		}
+		return nil, fmt.Errorf("failed to read master of uplink %s: %v", uplink, err)
+	}
+	bond := filepath.Base(masterPath)
+	slaves, err := utilfs.Fs.ReadFile(filepath.Join(NetSysDir, bond, "bonding", "slaves"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			// master is not a bond (e.g OVS bridge)
+			return vfLag, nil
+		}
+		return nil, fmt.Errorf("failed to read slaves of bond %s: %v", bond, err)
+	}
+
+	uplinks := make([]string, 0)
+	for _, slave := range strings.Fields(string(slaves)) {
+		if !isSwitchdev(slave) {
+			continue
+		}
+		if hasNonUplinkPhysPortName(slave) {
+			continue
+		}
+		uplinks = append(uplinks, slave)
+	}
+	sort.Strings(uplinks)
+	vfLag.Bond = bond
+	vfLag.Uplinks = uplinks
+	return vfLag, nil
+}
+
+func GetVfRepresentor(uplink string, vfIndex int) (rep string, err error) {
+	defer startTrace("GetVfRepresentor", "uplink", uplink, "vf", strconv.Itoa(vfIndex))(&err)
+	reps, err := GetVfRepresentors(uplink)
 	if err != nil {
 		return "", err
 	}
-	for _, device := range devices {
-		devicePath := filepath.Join(NetSysDir, device.Name())
-		deviceSwIDFile := filepath.Join(devicePath, netdevPhysSwitchID)
-		deviceSwID, err := utilfs.Fs.ReadFile(deviceSwIDFile)
-		if err != nil || !bytes.Equal(deviceSwID, physSwitchID) {
+	if rep, ok := reps[vfIndex]; ok {
+		return rep, nil
+	}
+	logDebug("VF representor not found", "uplink", uplink, "vf", vfIndex, "representors", reps)
+	return "", fmt.Errorf("failed to find VF representor for uplink %s", uplink)
+}
+
+// GetVfRepresentorByVfPciAddress gets a VF PCI address (e.g '0000:03:00.4') and returns the
+// VF representor netdev name for that VF.
+func GetVfRepresentorByVfPciAddress(vfPciAddress string) (rep string, err error) {
+	defer startTrace("GetVfRepresentorByVfPciAddress", "pci", vfPciAddress)(&err)
+	uplink, err := GetUplinkRepresentor(vfPciAddress)
+	if err != nil {
+		return "", err
+	}
+	vfIndex, err := GetVfIndexByPciAddress(vfPciAddress)
+	if err != nil {
+		return "", err
+	}
+	return GetVfRepresentor(uplink, vfIndex)
+}
+
+// GetVfPciFromRepresentor gets a VF representor netdev and returns the PCI address of the VF it represents.
+// The PF is resolved via the representor's devlink port (Kernel >= 5.9.0) with a fallback to the
+// representor's parent device in sysfs. VFs of external controllers (e.g DPU host VFs) are not resolvable.
+func GetVfPciFromRepresentor(vfRep string) (vfPciAddress string, err error) {
+	defer startTrace("GetVfPciFromRepresentor", "representor", vfRep)(&err)
+	physPortName, err := GetPhysPortName(vfRep)
+	if err != nil {
+		return "", fmt.Errorf("failed to get phys_port_name for netdev %s: %v", vfRep, err)
+	}
+	_, vfIndex, err := parsePortName(physPortName)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse VF index of representor %s: %v", vfRep, err)
+	}
+
+	var pfPciAddress string
+	port, err := netlinkops.GetNetlinkOps().DevLinkGetPortByNetdevName(vfRep)
+	if err == nil && port.BusName == "pci" {
+		pfPciAddress = port.DeviceName
+	} else {
+		logDebug("devlink port of representor not found, resolving its PF from sysfs", "netdev", vfRep,
+			"error", err)
+		pfPciAddress, err = getPCIFromDeviceName(vfRep)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	virtFn := filepath.Join(PciSysDir, pfPciAddress, fmt.Sprintf("%s%d", netDevVfDevicePrefix, vfIndex))
+	vfPciAddress, err = readPCIsymbolicLink(virtFn)
+	if err != nil {
+		return "", fmt.Errorf("%v for VF %d of PF %s represented by %s", err, vfIndex, pfPciAddress, vfRep)
+	}
+	return vfPciAddress, nil
+}
+
+// GetVfRepresentors returns a map of VF index to VF representor netdev for the given uplink. If several
+// representors match a VF index, the first one in netdev name order is returned.
+// Representors are resolved in a single pass over the uplink PF net devices. Only if no VF representor is
+// found there, e.g. representors which are not parented to the PF device, the uplink's net subsystem is scanned.
+func GetVfRepresentors(uplink string) (vfReps map[int]string, err error) {
+	defer startTrace("GetVfRepresentors", "uplink", uplink)(&err)
+	physSwitchID := getNetdevSwitchMeta(uplink).switchID
+	if len(physSwitchID) == 0 {
+		return nil, fmt.Errorf("cant get uplink %s switch id", uplink)
+	}
+
+	pfNetPath := filepath.Join(NetSysDir, uplink, pcidevPrefix, "net")
+	if devices, err := utilfs.ReadDirNames(utilfs.Fs, pfNetPath); err == nil {
+		if reps := scanVfRepresentors(uplink, physSwitchID, devices); len(reps) > 0 {
+			return reps, nil
+		}
+	}
+	logDebug("no VF representors under the uplink PF device, scanning the net subsystem", "uplink", uplink)
+
+	pfSubsystemPath := filepath.Join(NetSysDir, uplink, "subsystem")
+	devices, err := utilfs.ReadDirNames(utilfs.Fs, pfSubsystemPath)
+	if err != nil {
+		return nil, err
+	}
+	return scanVfRepresentors(uplink, physSwitchID, devices), nil
+}
+
+// scanVfRepresentors returns a map of VF index to VF representor netdev of the given uplink out of devices
+func scanVfRepresentors(uplink string, physSwitchID []byte, devices []string) map[int]string {
+	// PF function number of the uplink, resolved on first use
+	uplinkPfNum := -1
+	reps := make(map[int]string)
+	metas := getNetdevsSwitchMeta(devices)
+	for i, device := range devices {
+		meta := metas[i]
+		if !bytes.Equal(meta.switchID, physSwitchID) || meta.portName == "" {
 			continue
 		}
-		physPortNameStr, err := getNetDevPhysPortName(device.Name())
+		pfRepIndex, vfRepIndex, err := parsePortName(meta.portName)
 		if err != nil {
 			continue
 		}
-		pfRepIndex, vfRepIndex, _ := parsePortName(physPortNameStr)
 		if pfRepIndex != -1 {
-			pfPCIAddress, err := getPCIFromDeviceName(uplink)
-			if err != nil {
-				continue
+			if uplinkPfNum == -1 {
+				pfPCIAddress, err := getPCIFromDeviceName(uplink)
+				if err != nil {
+					continue
+				}
+				pfAddr, err := ParsePciAddress(pfPCIAddress)
+				if err != nil {
+					continue
+				}
+				uplinkPfNum = int(pfAddr.Function)
 			}
-			PCIFuncAddress, err := strconv.Atoi(string((pfPCIAddress[len(pfPCIAddress)-1])))
-			if pfRepIndex != PCIFuncAddress || err != nil {
+			if pfRepIndex != uplinkPfNum {
 				continue
 			}
 		}
-		// At this point we're confident we have a representor.
-		if vfRepIndex == vfIndex {
-			return device.Name(), nil
+		// At this point we're confident we have a representor, keep the first one found for a VF index
+		// (e.g representors of several controllers with the same pfXvfY), as GetVfRepresentor always did.
+		if _, ok := reps[vfRepIndex]; !ok {
+			reps[vfRepIndex] = device
 		}
 	}
-	return "", fmt.Errorf("failed to find VF representor for uplink %s", uplink)
+	return reps
 }
 
 func GetSfRepresentor(uplink string, sfNum int) (string, error) {
 	pfNetPath := filepath.Join(NetSysDir, uplink, "device", "net")
-	devices, err := utilfs.Fs.ReadDir(pfNetPath)
+	devices, err := utilfs.ReadDirNames(utilfs.Fs, pfNetPath)
 	if err != nil {
 		return "", err
 	}
 
-	for _, device := range devices {
-		physPortNameStr, err := getNetDevPhysPortName(device.Name())
-		if err != nil {
-			continue
-		}
-		sfRepIndex, err := sfIndexFromPortName(physPortNameStr)
+	metas := getNetdevsSwitchMeta(devices)
+	for i, device := range devices {
+		sfRepIndex, err := sfIndexFromPortName(metas[i].portName)
 		if err != nil {
 			continue
 		}
 		if sfRepIndex == sfNum {
-			return device.Name(), nil
+			return device, nil
 		}
 	}
 	return "", fmt.Errorf("failed to find SF representor for uplink %s", uplink)
 }
 
-func getNetDevPhysPortName(netDev string) (string, error) {
-	devicePortNameFile := filepath.Join(NetSysDir, netDev, netdevPhysPortName)
-	physPortName, err := utilfs.Fs.ReadFile(devicePortNameFile)
+// ListControllers returns the sorted external controller numbers (i.e hosts attached to a DPU) that have
+// representors on the eswitch of the given uplink. Controllers are derived from the representors
+// phys_port_name (e.g c1pf0vf2), hence external functions using legacy port names without a controller
+// prefix are not accounted for.
+func ListControllers(uplink string) ([]int, error) {
+	reps, err := ListRepresentors(uplink, nil)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	seen := make(map[int]bool)
+	controllers := make([]int, 0)
+	for _, rep := range reps {
+		if rep.ControllerNum == 0 || seen[rep.ControllerNum] {
+			continue
+		}
+		seen[rep.ControllerNum] = true
+		controllers = append(controllers, rep.ControllerNum)
+	}
+	sort.Ints(controllers)
+	return controllers, nil
+}
+
+// GetSfRepresentors returns a map of SF number to SF representor for the given uplink, resolved in
+// a single pass over the uplink's netdevs. The returned Representor carries the controller the SF
+// belongs to. SF numbers may overlap across controllers on DPUs, in which case the entry with the
+// lowest controller number is kept; use ListRepresentors with a controller filter to get all of them.
+func GetSfRepresentors(uplink string) (map[int]*Representor, error) {
+	pfNetPath := filepath.Join(NetSysDir, uplink, "device", "net")
+	devices, err := utilfs.ReadDirNames(utilfs.Fs, pfNetPath)
+	if err != nil {
+		return nil, err
+	}
+
+	reps := make(map[int]*Representor)
+	metas := getNetdevsSwitchMeta(devices)
+	for i, device := range devices {
+		info, err := parseRepresentorPortName(metas[i].portName)
+		if err != nil || info.Flavour != PORT_FLAVOUR_PCI_SF {
+			continue
+		}
+		if existing, ok := reps[info.FuncIndex]; ok && existing.ControllerNum <= info.ControllerNum {
+			continue
+		}
+		reps[info.FuncIndex] = &Representor{Name: device, RepresentorInfo: *info}
+	}
+	return reps, nil
+}
+
+// readNetdevPhysAttr returns the trimmed content of a phys_* sysfs attribute of the given netdev,
+// errNoAttr is returned if the netdev does not report the attribute
+func readNetdevPhysAttr(netdev, attr string, errNoAttr error) (string, error) {
+	value, err := utilfs.Fs.ReadFile(filepath.Join(NetSysDir, netdev, attr))
+	if err != nil {
+		if _, statErr := utilfs.Fs.Stat(filepath.Join(NetSysDir, netdev)); errors.Is(statErr, os.ErrNotExist) {
+			return "", fmt.Errorf("%w: netdev %s", ErrDeviceNotFound, netdev)
+		}
+		// drivers without switchdev support fail the read with EOPNOTSUPP
+		return "", fmt.Errorf("%w: netdev %s: %v", errNoAttr, netdev, err)
 	}
-	return strings.TrimSpace(string(physPortName)), nil
+	trimmed := strings.TrimSpace(string(value))
+	if trimmed == "" {
+		return "", fmt.Errorf("%w: netdev %s", errNoAttr, netdev)
+	}
+	return trimmed, nil
+}
+
+// GetPhysPortName returns the phys_port_name of the given netdev (e.g p0 or pf0vf1).
+// Returns ErrDeviceNotFound if the netdev does not exist and ErrNoPhysPortName if it has no phys_port_name,
+// including an empty one.
+func GetPhysPortName(netdev string) (string, error) {
+	return readNetdevPhysAttr(netdev, netdevPhysPortName, ErrNoPhysPortName)
+}
+
+// GetPhysSwitchID returns the phys_switch_id of the given netdev, shared by the uplink and the representors
+// of an eswitch. Returns ErrDeviceNotFound if the netdev does not exist and ErrNoPhysSwitchID if it has no
+// phys_switch_id, including an empty one, e.g it is not an eswitch port.
+func GetPhysSwitchID(netdev string) (string, error) {
+	return readNetdevPhysAttr(netdev, netdevPhysSwitchID, ErrNoPhysSwitchID)
+}
+
+// hasNonUplinkPhysPortName returns true if the given netdev reports a phys_port_name which is not an uplink
+// one (e.g p0). Unlike with GetPhysPortName an empty phys_port_name is not an uplink one, only netdevs which
+// fail to report phys_port_name are looked up without it.
+func hasNonUplinkPhysPortName(netdev string) bool {
+	portName, err := utilfs.Fs.ReadFile(filepath.Join(NetSysDir, netdev, netdevPhysPortName))
+	return err == nil && !physPortRepRegex.MatchString(strings.TrimSpace(string(portName)))
 }
 
 // findNetdevWithPortNameCriteria returns representor netdev that matches a criteria function on the
@@ -231,7 +650,7 @@ func findNetdevWithPortNameCriteria(criteria func(string) bool) (string, error)
 			continue
 		}
 
-		portName, err := getNetDevPhysPortName(netdevName)
+		portName, err := GetPhysPortName(netdevName)
 		if err != nil {
 			continue
 		}
@@ -244,7 +663,8 @@ func findNetdevWithPortNameCriteria(criteria func(string) bool) (string, error)
 }
 
 // GetPortIndexFromRepresentor finds the index of a representor from its network device name.
-// Supports VF and SF. For multiple port flavors, the same ID could be returned, i.e.
+// Supports PF (e.g host PF representor pf0hpf, the PF index is returned), VF and SF.
+// For multiple port flavors, the same ID could be returned, i.e.
 //
 //	pf0vf10 and pf0sf10
 //
@@ -255,21 +675,24 @@ func GetPortIndexFromRepresentor(repNetDev string) (int, error) {
 		return 0, err
 	}
 
-	if flavor != PORT_FLAVOUR_PCI_VF && flavor != PORT_FLAVOUR_PCI_SF {
+	if flavor != PORT_FLAVOUR_PCI_PF && flavor != PORT_FLAVOUR_PCI_VF && flavor != PORT_FLAVOUR_PCI_SF {
 		return 0, fmt.Errorf("unsupported port flavor for netdev %s", repNetDev)
 	}
 
-	physPortName, err := getNetDevPhysPortName(repNetDev)
+	physPortName, err := GetPhysPortName(repNetDev)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get device %s physical port name: %v", repNetDev, err)
 	}
 
-	typeToRegex := map[PortFlavour]*regexp.Regexp{
-		PORT_FLAVOUR_PCI_VF: vfPortRepRegex,
-		PORT_FLAVOUR_PCI_SF: sfPortRepRegex,
+	var repIndex int
+	switch flavor {
+	case PORT_FLAVOUR_PCI_PF:
+		repIndex, err = parsePfIndexFromPhysPortName(physPortName)
+	case PORT_FLAVOUR_PCI_VF:
+		_, repIndex, err = parsePortName(physPortName)
+	default:
+		_, repIndex, err = parseIndexFromPhysPortName(physPortName, sfPortRepRegex)
 	}
-
-	_, repIndex, err := parseIndexFromPhysPortName(physPortName, typeToRegex[flavor])
 	if err != nil {
 		return 0, fmt.Errorf("failed to parse the physical port name of device %s: %v", repNetDev, err)
 	}
@@ -277,8 +700,31 @@ func GetPortIndexFromRepresentor(repNetDev string) (int, error) {
 	return repIndex, nil
 }
 
+// GetRepresentorInfo returns the port information of a representor from its network device name.
+// Supports physical, PF, VF and SF representors. The flavour is taken from devlink when available,
+// the controller, PF and function indexes are parsed from the phys_port_name.
+func GetRepresentorInfo(repNetDev string) (*RepresentorInfo, error) {
+	flavour, err := GetRepresentorPortFlavour(repNetDev)
+	if err != nil {
+		return nil, err
+	}
+	portName, err := GetPhysPortName(repNetDev)
+	if err != nil {
+		return nil, err
+	}
+	info, err := parseRepresentorPortName(portName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse representor %s port name: %v", repNetDev, err)
+	}
+	if flavour != PORT_FLAVOUR_UNKNOWN {
+		info.Flavour = flavour
+	}
+	return info, nil
+}
+
 // GetVfRepresentorDPU returns VF representor on DPU for a host VF identified by pfID and vfIndex
-func GetVfRepresentorDPU(pfID, vfIndex string) (string, error) {
+func GetVfRepresentorDPU(pfID, vfIndex string) (rep string, err error) {
+	defer startTrace("GetVfRepresentorDPU", "pf", pfID, "vf", vfIndex)(&err)
 	// TODO(Adrianc): This method should change to get switchID and vfIndex as input, then common logic can
 	// be shared with GetVfRepresentor, backward compatibility should be preserved when this happens.
 
@@ -313,6 +759,102 @@ func GetVfRepresentorDPU(pfID, vfIndex string) (string, error) {
 	return netdev, nil
 }
 
+// GetHostPfRepresentor returns the representor on DPU (e.g pf0hpf) of the host PF identified by pfID
+// Note: no support for Multi-Chassis DPUs
+func GetHostPfRepresentor(pfID int) (string, error) {
+	netdev, err := findNetdevWithPortNameCriteria(func(portName string) bool {
+		// if phys port name == pf<pfIndex> or c1pf<pfIndex> we have a match
+		info, err := parseRepresentorPortName(portName)
+		if err != nil {
+			return false
+		}
+		return info.Flavour == PORT_FLAVOUR_PCI_PF && info.ControllerNum <= 1 && info.PfID == pfID
+	})
+	if err != nil {
+		return "", fmt.Errorf("host pf representor for pfID:%d not found", pfID)
+	}
+	return netdev, nil
+}
+
+// HostPfRepresentors groups the representors on DPU of a host PF and of its functions
+type HostPfRepresentors struct {
+	PfID int
+	// PF is the host PF representor (e.g pf0hpf)
+	PF string
+	// VFs maps the host VF indexes to their representor
+	VFs map[int]string
+	// SFs maps the host SF numbers to their representor
+	SFs map[int]string
+}
+
+// GetRepresentorsForHostPf returns the representors on DPU of the host PF identified by pfID, of its VFs and of
+// its SFs, e.g to plug every port of a host PF into a bridge at once. The functions are matched to the host
+// PF by eswitch and controller, when port names carry no controller prefix (older kernels) functions of the
+// DPU's own PF with the same pfID are included too.
+// Note: no support for Multi-Chassis DPUs
+func GetRepresentorsForHostPf(pfID int) (*HostPfRepresentors, error) {
+	netdevs, err := utilfs.ReadDirNames(utilfs.Fs, NetSysDir)
+	if err != nil {
+		return nil, err
+	}
+	metas := getNetdevsSwitchMeta(netdevs)
+	infos := make([]*RepresentorInfo, len(netdevs))
+	reps := &HostPfRepresentors{PfID: pfID, VFs: make(map[int]string), SFs: make(map[int]string)}
+	var pfMeta *netdevSwitchMeta
+	var pfController int
+	for i, meta := range metas {
+		if len(meta.switchID) == 0 || meta.portName == "" {
+			continue
+		}
+		info, err := parseRepresentorPortName(meta.portName)
+		if err != nil || info.PfID != pfID || info.ControllerNum > 1 {
+			continue
+		}
+		infos[i] = info
+		if info.Flavour == PORT_FLAVOUR_PCI_PF && reps.PF == "" {
+			reps.PF = netdevs[i]
+			pfMeta = meta
+			pfController = info.ControllerNum
+		}
+	}
+	if reps.PF == "" {
+		return nil, fmt.Errorf("host pf representor for pfID:%d not found", pfID)
+	}
+
+	for i, info := range infos {
+		if info == nil || info.ControllerNum != pfController || !bytes.Equal(metas[i].switchID, pfMeta.switchID) {
+			continue
+		}
+		switch info.Flavour {
+		case PORT_FLAVOUR_PCI_VF:
+			reps.VFs[info.FuncIndex] = netdevs[i]
+		case PORT_FLAVOUR_PCI_SF:
+			reps.SFs[info.FuncIndex] = netdevs[i]
+		}
+	}
+	return reps, nil
+}
+
+// GetVfRepresentorDPUForController returns VF representor on DPU for a VF identified by pfID and vfIndex
+// of the given controller (e.g 1 for phys_port_name c1pf0vf2). Use on multi-host DPUs where several
+// external controllers share one eswitch.
+// Note: port names without a controller prefix (e.g pf0vf2) are treated as controller 0.
+func GetVfRepresentorDPUForController(controller, pfID, vfIndex int) (string, error) {
+	netdev, err := findNetdevWithPortNameCriteria(func(portName string) bool {
+		info, err := parseRepresentorPortName(portName)
+		if err != nil {
+			return false
+		}
+		return info.Flavour == PORT_FLAVOUR_PCI_VF && info.ControllerNum == controller &&
+			info.PfID == pfID && info.FuncIndex == vfIndex
+	})
+	if err != nil {
+		return "", fmt.Errorf("vf representor for controller:%d, pfID:%d, vfIndex:%d not found",
+			controller, pfID, vfIndex)
+	}
+	return netdev, nil
+}
+
 // GetSfRepresentorDPU returns SF representor on DPU for a host SF identified by pfID and sfIndex
 func GetSfRepresentorDPU(pfID, sfIndex string) (string, error) {
 	// pfID should be 0 or 1
@@ -362,7 +904,7 @@ func GetRepresentorPortFlavour(netdev string) (PortFlavour, error) {
 
 	// Fallback to Get PortFlavour by phys_port_name
 	// read phy_port_name
-	portName, err := getNetDevPhysPortName(netdev)
+	portName, err := GetPhysPortName(netdev)
 	if err != nil {
 		return PORT_FLAVOUR_UNKNOWN, err
 	}
@@ -378,38 +920,183 @@ func GetRepresentorPortFlavour(netdev string) (PortFlavour, error) {
 			return flavour, nil
 		}
 	}
+	if legacyVfPortRepRegex.MatchString(portName) {
+		return PORT_FLAVOUR_PCI_VF, nil
+	}
 	return PORT_FLAVOUR_UNKNOWN, nil
 }
 
-// parseDPUConfigFileOutput parses the config file content of a DPU
-// representor port. The format of the file is a set of <key>:<value> pairs as follows:
-//
-// ```
-//
-//	MAC        : 0c:42:a1:c6:cf:7c
-//	MaxTxRate  : 0
-//	State      : Follow
-//
-// ```
-func parseDPUConfigFileOutput(out string) map[string]string {
-	configMap := make(map[string]string)
-	for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
-		entry := strings.SplitN(line, ":", 2)
-		if len(entry) != 2 {
-			// unexpected line format
-			continue
-		}
-		configMap[strings.Trim(entry[0], " \t\n")] = strings.Trim(entry[1], " \t\n")
-	}
+// Regex that matches on any PF, VF or SF representor port name and captures the
+// optional controller number, the PF number, the function type and the function index.
+var repPortNameRegex = regexp.MustCompile(`^(?:c(\d+))?pf(\d+)(?:(vfr?|sf)(\d+))?$`)
+
+// RepresentorInfo describes the eswitch port a representor netdev stands for.
+type RepresentorInfo struct {
+	// Flavour is the port flavour of the representor
+	Flavour PortFlavour
+	// ControllerNum is the controller number the represented function belongs to, 0 for the local controller
+	ControllerNum int
+	// PfID is the PF number (or physical port number for PORT_FLAVOUR_PHYSICAL), -1 if the port name
+	// carries no PF number
+	PfID int
+	// FuncIndex is the VF or SF index, -1 for physical and PF representors
+	FuncIndex int
+}
+
+// Representor describes a representor netdev of an eswitch.
+type Representor struct {
+	// Name is the representor netdev name
+	Name string
+	RepresentorInfo
+}
+
+// RepresentorFilter narrows down the representors returned by ListRepresentors.
+// A nil field matches any value.
+type RepresentorFilter struct {
+	Flavour    *PortFlavour
+	Controller *int
+}
+
+func (f *RepresentorFilter) match(rep *Representor) bool {
+	if f == nil {
+		return true
+	}
+	if f.Flavour != nil && *f.Flavour != rep.Flavour {
+		return false
+	}
+	if f.Controller != nil && *f.Controller != rep.ControllerNum {
+		return false
+	}
+	return true
+}
+
+// parseRepresentorPortName parses a representor phys_port_name into a RepresentorInfo.
+func parseRepresentorPortName(portName string) (*RepresentorInfo, error) {
+	info := &RepresentorInfo{FuncIndex: -1}
+	if matches := physPortRepRegex.FindStringSubmatch(portName); matches != nil {
+		info.Flavour = PORT_FLAVOUR_PHYSICAL
+		info.PfID, _ = strconv.Atoi(matches[1])
+		return info, nil
+	}
+	if matches := legacyVfPortRepRegex.FindStringSubmatch(portName); matches != nil {
+		info.Flavour = PORT_FLAVOUR_PCI_VF
+		info.PfID = -1
+		info.FuncIndex, _ = strconv.Atoi(matches[1])
+		return info, nil
+	}
+
+	matches := repPortNameRegex.FindStringSubmatch(portName)
+	if matches == nil {
+		return nil, fmt.Errorf("failed to parse portName %s", portName)
+	}
+	if matches[1] != "" {
+		info.ControllerNum, _ = strconv.Atoi(matches[1])
+	}
+	info.PfID, _ = strconv.Atoi(matches[2])
+	switch matches[3] {
+	case "vf", "vfr":
+		info.Flavour = PORT_FLAVOUR_PCI_VF
+	case "sf":
+		info.Flavour = PORT_FLAVOUR_PCI_SF
+	default:
+		info.Flavour = PORT_FLAVOUR_PCI_PF
+	}
+	if matches[4] != "" {
+		info.FuncIndex, _ = strconv.Atoi(matches[4])
+	}
+	return info, nil
+}
+
+// ListRepresentors returns the representors sharing the eswitch of the given uplink, sorted by name.
+// The uplink itself is included as a PORT_FLAVOUR_PHYSICAL entry. Representor attributes are derived
+// from phys_port_name, netdevs with unrecognized port names (e.g old kernel <vf_num> syntax) are skipped.
+// If filter is not nil, only representors matching it are returned.
+func ListRepresentors(uplink string, filter *RepresentorFilter) ([]*Representor, error) {
+	physSwitchID := getNetdevSwitchMeta(uplink).switchID
+	if len(physSwitchID) == 0 {
+		return nil, fmt.Errorf("cant get uplink %s switch id", uplink)
+	}
+
+	devices, err := utilfs.ReadDirNames(utilfs.Fs, NetSysDir)
+	if err != nil {
+		return nil, err
+	}
+	reps := make([]*Representor, 0)
+	metas := getNetdevsSwitchMeta(devices)
+	for i, device := range devices {
+		meta := metas[i]
+		if !bytes.Equal(meta.switchID, physSwitchID) || meta.portName == "" {
+			continue
+		}
+		info, err := parseRepresentorPortName(meta.portName)
+		if err != nil {
+			continue
+		}
+		rep := &Representor{Name: device, RepresentorInfo: *info}
+		if filter.match(rep) {
+			reps = append(reps, rep)
+		}
+	}
+	sort.Slice(reps, func(i, j int) bool { return reps[i].Name < reps[j].Name })
+	return reps, nil
+}
+
+// parseDPUConfigFileOutput parses the config file content of a DPU
+// representor port. The format of the file is a set of <key>:<value> pairs as follows:
+//
+// ```
+//
+//	MAC        : 0c:42:a1:c6:cf:7c
+//	MaxTxRate  : 0
+//	State      : Follow
+//
+// ```
+func parseDPUConfigFileOutput(out string) map[string]string {
+	configMap := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		entry := strings.SplitN(line, ":", 2)
+		if len(entry) != 2 {
+			// unexpected line format
+			continue
+		}
+		configMap[strings.Trim(entry[0], " \t\n")] = strings.Trim(entry[1], " \t\n")
+	}
 	return configMap
 }
 
+// getDPUConfigPath returns the path of the smart_nic config file of the port represented by the given
+// PF or VF representor netdev
+func getDPUConfigPath(netdev string) (string, error) {
+	portName, err := GetPhysPortName(netdev)
+	if err != nil {
+		return "", err
+	}
+	info, err := parseRepresentorPortName(portName)
+	if err != nil || (info.Flavour != PORT_FLAVOUR_PCI_PF && info.Flavour != PORT_FLAVOUR_PCI_VF) || info.PfID < 0 {
+		return "", fmt.Errorf("failed to extract physical port number from port name %s of netdev %s",
+			portName, netdev)
+	}
+	uplinkPhysPortName := fmt.Sprintf("p%d", info.PfID)
+	// Find uplink netdev for that port
+	// Note(adrianc): As we support only DPUs ATM we do not need to deal with netdevs from different
+	// eswitch (i.e different switch IDs).
+	uplinkNetdev, err := findNetdevWithPortNameCriteria(func(pname string) bool { return pname == uplinkPhysPortName })
+	if err != nil {
+		return "", fmt.Errorf("failed to find uplink port for netdev %s. %v", netdev, err)
+	}
+	portDir := "pf"
+	if info.Flavour == PORT_FLAVOUR_PCI_VF {
+		portDir = fmt.Sprintf("vf%d", info.FuncIndex)
+	}
+	return filepath.Join(NetSysDir, uplinkNetdev, "smart_nic", portDir, "config"), nil
+}
+
 // GetRepresentorPeerMacAddress returns the MAC address of the peer netdev associated with the given
 // representor netdev
 // Note:
 //
-//	This method functionality is currently supported only on DPUs.
-//	Currently only netdev representors with PORT_FLAVOUR_PCI_PF are supported
+//	PF, VF and SF representors are supported via devlink port function (Kernel >= 5.9.0).
+//	Without devlink support, only PF and VF representors on DPUs are supported via smart_nic sysfs.
 func GetRepresentorPeerMacAddress(netdev string) (net.HardwareAddr, error) {
 	flavor, err := GetRepresentorPortFlavour(netdev)
 	if err != nil {
@@ -418,7 +1105,7 @@ func GetRepresentorPeerMacAddress(netdev string) (net.HardwareAddr, error) {
 	if flavor == PORT_FLAVOUR_UNKNOWN {
 		return nil, fmt.Errorf("unknown port flavour for netdev %s", netdev)
 	}
-	if flavor != PORT_FLAVOUR_PCI_PF {
+	if flavor != PORT_FLAVOUR_PCI_PF && flavor != PORT_FLAVOUR_PCI_VF && flavor != PORT_FLAVOUR_PCI_SF {
 		return nil, fmt.Errorf("unsupported port flavour for netdev %s", netdev)
 	}
 
@@ -429,50 +1116,90 @@ func GetRepresentorPeerMacAddress(netdev string) (net.HardwareAddr, error) {
 			return port.Fn.HwAddr, nil
 		}
 	}
+	if flavor == PORT_FLAVOUR_PCI_SF {
+		return nil, fmt.Errorf("failed to get peer MAC address of SF representor %s via devlink", netdev)
+	}
 
 	// Get information via sysfs
-	// read phy_port_name
-	portName, err := getNetDevPhysPortName(netdev)
+	config, err := GetRepresentorPeerConfig(netdev)
 	if err != nil {
 		return nil, err
 	}
-	// Extract port num
-	portNum := pfPortRepRegex.FindStringSubmatch(portName)
-	if len(portNum) < 2 {
-		return nil, fmt.Errorf("failed to extract physical port number from port name %s of netdev %s",
-			portName, netdev)
+	if config.MAC == nil {
+		return nil, fmt.Errorf("MAC address not found for %s", netdev)
 	}
-	uplinkPhysPortName := "p" + portNum[1]
-	// Find uplink netdev for that port
-	// Note(adrianc): As we support only DPUs ATM we do not need to deal with netdevs from different
-	// eswitch (i.e different switch IDs).
-	uplinkNetdev, err := findNetdevWithPortNameCriteria(func(pname string) bool { return pname == uplinkPhysPortName })
-	if err != nil {
-		return nil, fmt.Errorf("failed to find uplink port for netdev %s. %v", netdev, err)
+	return config.MAC, nil
+}
+
+// GetRepresentorByMac returns the representor netdev whose peer function has the given MAC address.
+// The peer MAC address is looked up via devlink port function attributes (Kernel >= 5.9.0) with a
+// fallback to the DPU smart_nic config of PF and VF representors.
+func GetRepresentorByMac(mac net.HardwareAddr) (string, error) {
+	ports, err := netlinkops.GetNetlinkOps().DevLinkGetAllPortList()
+	if err == nil {
+		for _, port := range ports {
+			if port.NetdeviceName != "" && port.Fn != nil && bytes.Equal(port.Fn.HwAddr, mac) {
+				return port.NetdeviceName, nil
+			}
+		}
 	}
-	// get MAC address for netdev
-	configPath := filepath.Join(NetSysDir, uplinkNetdev, "smart_nic", "pf", "config")
-	out, err := utilfs.Fs.ReadFile(configPath)
+
+	// Fallback to sysfs
+	netdevs, err := utilfs.Fs.ReadDir(NetSysDir)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read DPU config via uplink %s for %s. %v",
-			uplinkNetdev, netdev, err)
+		return "", err
 	}
-	config := parseDPUConfigFileOutput(string(out))
-	macStr, ok := config["MAC"]
-	if !ok {
-		return nil, fmt.Errorf("MAC address not found for %s", netdev)
+	uplinks := make(map[int]string)
+	type smartNicPort struct {
+		pfNum     int
+		configDir string
 	}
-	mac, err := net.ParseMAC(macStr)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse MAC address \"%s\" for %s. %v", macStr, netdev, err)
+	// maps representor netdev to its uplink port number and smart_nic config directory
+	candidates := make(map[string]smartNicPort)
+	for _, netdev := range netdevs {
+		netdevName := netdev.Name()
+		if !isSwitchdev(netdevName) {
+			continue
+		}
+		portName, err := GetPhysPortName(netdevName)
+		if err != nil {
+			continue
+		}
+		info, err := parseRepresentorPortName(portName)
+		if err != nil {
+			continue
+		}
+		switch info.Flavour {
+		case PORT_FLAVOUR_PHYSICAL:
+			uplinks[info.PfID] = netdevName
+		case PORT_FLAVOUR_PCI_PF:
+			candidates[netdevName] = smartNicPort{info.PfID, "pf"}
+		case PORT_FLAVOUR_PCI_VF:
+			candidates[netdevName] = smartNicPort{info.PfID, fmt.Sprintf("vf%d", info.FuncIndex)}
+		}
 	}
-	return mac, nil
+	for netdevName, port := range candidates {
+		uplink, ok := uplinks[port.pfNum]
+		if !ok {
+			continue
+		}
+		out, err := utilfs.Fs.ReadFile(filepath.Join(NetSysDir, uplink, "smart_nic", port.configDir, "config"))
+		if err != nil {
+			continue
+		}
+		config, err := ParseRepConfig(string(out))
+		if err == nil && config.MAC != nil && bytes.Equal(config.MAC, mac) {
+			return netdevName, nil
+		}
+	}
+	return "", fmt.Errorf("failed to find representor with peer MAC address %s", mac)
 }
 
 // SetRepresentorPeerMacAddress sets the given MAC addresss of the peer netdev associated with the given
 // representor netdev.
-// Note: This method functionality is currently supported only for DPUs.
-// Currently only netdev representors with PORT_FLAVOUR_PCI_VF are supported
+// Note: PF, VF and SF representors are supported via devlink port function (Kernel >= 5.9.0) which is
+// preferred when available. Otherwise VF representors of drivers configuring the VF MAC through the uplink
+// (e.g ice) and PF and VF representors on DPUs (smart_nic sysfs) are supported.
 func SetRepresentorPeerMacAddress(netdev string, mac net.HardwareAddr) error {
 	flavor, err := GetRepresentorPortFlavour(netdev)
 	if err != nil {
@@ -481,35 +1208,507 @@ func SetRepresentorPeerMacAddress(netdev string, mac net.HardwareAddr) error {
 	if flavor == PORT_FLAVOUR_UNKNOWN {
 		return fmt.Errorf("unknown port flavour for netdev %s", netdev)
 	}
-	if flavor != PORT_FLAVOUR_PCI_VF {
+	if flavor != PORT_FLAVOUR_PCI_PF && flavor != PORT_FLAVOUR_PCI_VF && flavor != PORT_FLAVOUR_PCI_SF {
 		return fmt.Errorf("unsupported port flavour for netdev %s", netdev)
 	}
 
-	physPortNameStr, err := getNetDevPhysPortName(netdev)
+	// Attempt to set via devlink port function (Kernel >= 5.9.0)
+	devlinkErr := SetPortFnHwAddr(netdev, mac)
+	if devlinkErr == nil {
+		return nil
+	}
+	if flavor == PORT_FLAVOUR_PCI_SF {
+		return fmt.Errorf("failed to set peer MAC address of SF representor %s: %v", netdev, devlinkErr)
+	}
+
+	if err = setRepresentorPeerMacAddressLegacy(netdev, flavor, mac); err != nil {
+		return fmt.Errorf("%w (devlink port function: %v)", err, devlinkErr)
+	}
+	return nil
+}
+
+// setRepresentorPeerMacAddressLegacy sets the given MAC address of the peer netdev associated with the given
+// PF or VF representor netdev without devlink, through the uplink VF configuration or the smart_nic sysfs
+func setRepresentorPeerMacAddressLegacy(netdev string, flavor PortFlavour, mac net.HardwareAddr) error {
+	quirks := getSwitchdevQuirks(netdev)
+	if quirks.vfMacViaUplink && flavor == PORT_FLAVOUR_PCI_VF {
+		uplinkLink, vfIndex, err := getVfRepresentorUplinkAndIndex(netdev)
+		if err != nil {
+			return err
+		}
+		if err = netlinkops.GetNetlinkOps().LinkSetVfHardwareAddr(uplinkLink, vfIndex, mac); err != nil {
+			return fmt.Errorf("failed to set MAC address of VF %d of %s: %v", vfIndex, uplinkLink.Attrs().Name, err)
+		}
+		return nil
+	}
+	if !quirks.smartNicSysfs {
+		return fmt.Errorf("%w: setting peer MAC address of %s", ErrNotSupported, netdev)
+	}
+
+	// Set via sysfs
+	configPath, err := getDPUConfigPath(netdev)
+	if err != nil {
+		return err
+	}
+	sysfsRepMacFile := filepath.Join(filepath.Dir(configPath), "mac")
+	_, err = utilfs.Fs.Stat(sysfsRepMacFile)
+	if err != nil {
+		return fmt.Errorf("couldn't stat representor's sysfs file %s: %v", sysfsRepMacFile, err)
+	}
+	err = writeSysfsFile(sysfsRepMacFile, mac.String())
+	if err != nil {
+		return fmt.Errorf("failed to write the MAC address %s to representor %s",
+			mac.String(), sysfsRepMacFile)
+	}
+	return nil
+}
+
+// RepConfig is the smart_nic config of the peer function of a DPU representor
+type RepConfig struct {
+	MAC net.HardwareAddr
+	// MaxTxRate is the max TX rate in Mbps, 0 means unlimited, nil if not reported
+	MaxTxRate *uint
+	State     RepPeerState
+}
+
+// ParseRepConfig parses the content of a DPU representor port smart_nic config file (see
+// parseDPUConfigFileOutput for the format). Fields missing from the content are left zero.
+func ParseRepConfig(content string) (*RepConfig, error) {
+	entries := parseDPUConfigFileOutput(content)
+	config := &RepConfig{}
+	if macStr, ok := entries["MAC"]; ok {
+		mac, err := net.ParseMAC(macStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse MAC address \"%s\". %v", macStr, err)
+		}
+		config.MAC = mac
+	}
+	if rateStr, ok := entries["MaxTxRate"]; ok {
+		rate, err := strconv.ParseUint(rateStr, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse MaxTxRate \"%s\". %v", rateStr, err)
+		}
+		maxTxRate := uint(rate)
+		config.MaxTxRate = &maxTxRate
+	}
+	config.State = RepPeerState(entries["State"])
+	return config, nil
+}
+
+// GetRepresentorPeerConfig returns the smart_nic config of the peer function associated with the given
+// PF or VF representor netdev.
+// Note: This method functionality is currently supported only on DPUs.
+func GetRepresentorPeerConfig(netdev string) (*RepConfig, error) {
+	configPath, err := getDPUConfigPath(netdev)
+	if err != nil {
+		return nil, err
+	}
+	out, err := utilfs.Fs.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read DPU config %s for %s. %v", configPath, netdev, err)
+	}
+	config, err := ParseRepConfig(string(out))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse DPU config %s for %s. %v", configPath, netdev, err)
+	}
+	return config, nil
+}
+
+// writeDPUConfigAttr writes value to the smart_nic attribute file (e.g 'max_tx_rate') of the port
+// represented by the given PF or VF representor netdev
+func writeDPUConfigAttr(netdev, attr, value string) error {
+	configPath, err := getDPUConfigPath(netdev)
+	if err != nil {
+		return err
+	}
+	attrFile := filepath.Join(filepath.Dir(configPath), attr)
+	if _, err = utilfs.Fs.Stat(attrFile); err != nil {
+		return fmt.Errorf("couldn't stat representor's sysfs file %s: %v", attrFile, err)
+	}
+	if err = writeSysfsFile(attrFile, value); err != nil {
+		return fmt.Errorf("failed to write %s to representor sysfs file %s: %v", value, attrFile, err)
+	}
+	return nil
+}
+
+// GetRepresentorPeerMaxTxRate returns the max TX rate in Mbps (0 means unlimited) of the peer function
+// associated with the given PF or VF representor netdev. An error is returned if the rate is not reported.
+// Note: This method functionality is currently supported only on DPUs.
+func GetRepresentorPeerMaxTxRate(netdev string) (uint, error) {
+	config, err := GetRepresentorPeerConfig(netdev)
+	if err != nil {
+		return 0, err
+	}
+	if config.MaxTxRate == nil {
+		return 0, fmt.Errorf("MaxTxRate not found for %s", netdev)
+	}
+	return *config.MaxTxRate, nil
+}
+
+// SetRepresentorPeerMaxTxRate sets the max TX rate in Mbps (0 means unlimited) of the peer function
+// associated with the given PF or VF representor netdev.
+// Note: This method functionality is currently supported only on DPUs.
+func SetRepresentorPeerMaxTxRate(netdev string, rate uint) error {
+	return writeDPUConfigAttr(netdev, "max_tx_rate", strconv.FormatUint(uint64(rate), 10))
+}
+
+// RepPeerState is the administrative link state of the peer function of a DPU representor
+type RepPeerState string
+
+const (
+	// RepPeerStateFollow makes the peer function link state follow the physical port link state
+	RepPeerStateFollow RepPeerState = "Follow"
+	// RepPeerStateUp forces the peer function link state up
+	RepPeerStateUp RepPeerState = "Up"
+	// RepPeerStateDown forces the peer function link state down
+	RepPeerStateDown RepPeerState = "Down"
+)
+
+// GetRepresentorPeerState returns the administrative link state of the peer function associated with the
+// given PF or VF representor netdev.
+// Note: This method functionality is currently supported only on DPUs.
+func GetRepresentorPeerState(netdev string) (RepPeerState, error) {
+	config, err := GetRepresentorPeerConfig(netdev)
+	if err != nil {
+		return "", err
+	}
+	if config.State == "" {
+		return "", fmt.Errorf("State not found for %s", netdev)
+	}
+	return config.State, nil
+}
+
+// SetRepresentorPeerState sets the administrative link state of the peer function associated with the
+// given PF or VF representor netdev.
+// Note: This method functionality is currently supported only on DPUs.
+func SetRepresentorPeerState(netdev string, state RepPeerState) error {
+	switch state {
+	case RepPeerStateFollow, RepPeerStateUp, RepPeerStateDown:
+	default:
+		return fmt.Errorf("invalid peer state %q, expected one of %s, %s or %s", state,
+			RepPeerStateFollow, RepPeerStateUp, RepPeerStateDown)
+	}
+	return writeDPUConfigAttr(netdev, "state", string(state))
+}
+
+// getDevlinkPortByRef returns the devlink port referenced by portRef which is either a representor
+// netdev name (e.g 'pf0vf1') or a devlink port handle (e.g 'pci/0000:03:00.0/32768')
+func getDevlinkPortByRef(portRef string) (bus, device string, portIndex uint32, err error) {
+	if parts := strings.Split(portRef, "/"); len(parts) == 3 {
+		index, err := strconv.ParseUint(parts[2], 10, 32)
+		if err != nil {
+			return "", "", 0, fmt.Errorf("invalid devlink port handle %s: %v", portRef, err)
+		}
+		return parts[0], parts[1], uint32(index), nil
+	}
+	port, err := netlinkops.GetNetlinkOps().DevLinkGetPortByNetdevName(portRef)
+	if err != nil {
+		return "", "", 0, fmt.Errorf("failed to get devlink port of netdev %s: %v", portRef, err)
+	}
+	return port.BusName, port.DeviceName, port.PortIndex, nil
+}
+
+// SetPortFnHwAddr sets the hardware address of the function (VF or SF) behind the given devlink port.
+// portRef is either the port representor netdev name (e.g 'pf0vf1') or a devlink port handle
+// (e.g 'pci/0000:03:00.0/32768').
+// Equivalent to: `devlink port function set pci/0000:03:00.0/32768 hw_addr 00:00:00:00:88:88`
+// Requires Kernel >= 5.9.0
+func SetPortFnHwAddr(portRef string, mac net.HardwareAddr) error {
+	bus, device, portIndex, err := getDevlinkPortByRef(portRef)
 	if err != nil {
-		return fmt.Errorf("failed to get phys_port_name for netdev %s: %v", netdev, err)
+		return err
 	}
-	pfID, vfIndex, err := parsePortName(physPortNameStr)
+	fnAttrs := netlink.DevlinkPortFnSetAttrs{HwAddrValid: true}
+	fnAttrs.FnAttrs.HwAddr = mac
+	err = netlinkops.GetNetlinkOps().DevlinkPortFnSet(bus, device, portIndex, fnAttrs)
 	if err != nil {
-		return fmt.Errorf("failed to get the pf and vf index for netdev %s "+
-			"with phys_port_name %s: %v", netdev, physPortNameStr, err)
+		return fmt.Errorf("failed to set hw_addr %s of devlink port %s/%s/%d: %v",
+			mac.String(), bus, device, portIndex, err)
 	}
+	return nil
+}
 
+// findUplinkOfRepresentor returns the uplink netdev of physical port pfID on the eswitch of the given
+// representor. The lookup is scoped to the representor's switch ID to tell apart uplinks of different
+// NICs sharing the same physical port name.
+func findUplinkOfRepresentor(rep string, pfID int) (string, error) {
+	physSwitchID, err := utilfs.Fs.ReadFile(filepath.Join(NetSysDir, rep, netdevPhysSwitchID))
+	if err != nil || len(physSwitchID) == 0 {
+		return "", fmt.Errorf("cant get representor %s switch id", rep)
+	}
+	netdevs, err := utilfs.Fs.ReadDir(NetSysDir)
+	if err != nil {
+		return "", err
+	}
 	uplinkPhysPortName := fmt.Sprintf("p%d", pfID)
-	uplinkNetdev, err := findNetdevWithPortNameCriteria(func(pname string) bool { return pname == uplinkPhysPortName })
+	for _, netdev := range netdevs {
+		swID, err := utilfs.Fs.ReadFile(filepath.Join(NetSysDir, netdev.Name(), netdevPhysSwitchID))
+		if err != nil || !bytes.Equal(swID, physSwitchID) {
+			continue
+		}
+		if portName, err := GetPhysPortName(netdev.Name()); err == nil && portName == uplinkPhysPortName {
+			return netdev.Name(), nil
+		}
+	}
+	return "", fmt.Errorf("no uplink with physical port name %s", uplinkPhysPortName)
+}
+
+// getVfRepresentorUplinkAndIndex returns the uplink netdev and the VF index of the given VF representor
+func getVfRepresentorUplinkAndIndex(vfRep string) (netlink.Link, int, error) {
+	physPortName, err := GetPhysPortName(vfRep)
+	if err != nil {
+		return nil, -1, err
+	}
+	pfID, vfIndex, err := parseIndexFromPhysPortName(physPortName, vfPortRepRegex)
+	if err != nil {
+		return nil, -1, fmt.Errorf("%w: %s is not a VF representor", ErrNotSupported, vfRep)
+	}
+	uplink, err := findUplinkOfRepresentor(vfRep, pfID)
+	if err != nil {
+		return nil, -1, fmt.Errorf("failed to find uplink of VF representor %s: %v", vfRep, err)
+	}
+	uplinkLink, err := netlinkops.GetNetlinkOps().LinkByName(uplink)
 	if err != nil {
-		return fmt.Errorf("failed to find netdev for physical port name %s. %v", uplinkPhysPortName, err)
+		return nil, -1, fmt.Errorf("failed to get link of uplink %s: %v", uplink, err)
 	}
-	vfRepName := fmt.Sprintf("vf%d", vfIndex)
-	sysfsVfRepMacFile := filepath.Join(NetSysDir, uplinkNetdev, "smart_nic", vfRepName, "mac")
-	_, err = utilfs.Fs.Stat(sysfsVfRepMacFile)
+	return uplinkLink, vfIndex, nil
+}
+
+// getLocalVfRepresentorUplinkAndIndex returns the uplink netdev and the VF index of the given representor
+// of a VF of the local host. ErrNotSupported is returned for other representors, including representors
+// of external controllers (e.g host VFs on a DPU) which are not VFs of the uplink.
+func getLocalVfRepresentorUplinkAndIndex(vfRep string) (netlink.Link, int, error) {
+	physPortName, err := GetPhysPortName(vfRep)
 	if err != nil {
-		return fmt.Errorf("couldn't stat VF representor's sysfs file %s: %v", sysfsVfRepMacFile, err)
+		return nil, -1, err
 	}
-	err = utilfs.Fs.WriteFile(sysfsVfRepMacFile, []byte(mac.String()), 0)
+	info, err := parseRepresentorPortName(physPortName)
+	if err != nil || info.Flavour != PORT_FLAVOUR_PCI_VF {
+		return nil, -1, fmt.Errorf("%w: %s is not a VF representor", ErrNotSupported, vfRep)
+	}
+	if info.ControllerNum != 0 || IsDpuPlatform() {
+		return nil, -1, fmt.Errorf("%w: %s does not represent a VF of the local host", ErrNotSupported, vfRep)
+	}
+	return getVfRepresentorUplinkAndIndex(vfRep)
+}
+
+// SetRepresentorPeerTrust sets the trust mode of the local VF represented by the given VF representor
+// (e.g 'pf0vf1').
+// Equivalent to: `ip link set p0 vf 1 trust on`
+// Note: trust is set through the uplink legacy VF configuration as devlink has no trust port function
+// attribute, hence only VFs of the local host are supported. ErrNotSupported is returned for SF representors
+// and for representors of external controllers (e.g host VFs on a DPU).
+func SetRepresentorPeerTrust(vfRep string, trusted bool) error {
+	uplinkLink, vfIndex, err := getLocalVfRepresentorUplinkAndIndex(vfRep)
 	if err != nil {
-		return fmt.Errorf("failed to write the MAC address %s to VF reprentor %s",
-			mac.String(), sysfsVfRepMacFile)
+		return err
+	}
+	if err = netlinkops.GetNetlinkOps().LinkSetVfTrust(uplinkLink, vfIndex, trusted); err != nil {
+		return fmt.Errorf("failed to set trust of VF %d of %s: %v", vfIndex, uplinkLink.Attrs().Name, err)
 	}
 	return nil
 }
+
+// GetRepresentorPeerTrust returns the trust mode of the local VF represented by the given VF representor
+// (e.g 'pf0vf1').
+// Note: only VFs of the local host are supported, see SetRepresentorPeerTrust.
+func GetRepresentorPeerTrust(vfRep string) (bool, error) {
+	uplinkLink, vfIndex, err := getLocalVfRepresentorUplinkAndIndex(vfRep)
+	if err != nil {
+		return false, err
+	}
+	for _, vf := range uplinkLink.Attrs().Vfs {
+		if vf.ID == vfIndex {
+			return vf.Trust != 0, nil
+		}
+	}
+	return false, fmt.Errorf("VF %d not found on %s", vfIndex, uplinkLink.Attrs().Name)
+}
+
+// setPortFnCap enables or disables the given port function capability (e.g netlinkops.DevlinkPortFnCapRoce)
+// of the devlink port referenced by portRef
+func setPortFnCap(portRef string, capability uint32, enabled bool) error {
+	bus, device, portIndex, err := getDevlinkPortByRef(portRef)
+	if err != nil {
+		return err
+	}
+	var caps uint32
+	if enabled {
+		caps = capability
+	}
+	err = netlinkops.GetNetlinkOps().DevlinkPortFnSetCaps(bus, device, portIndex, caps, capability)
+	if err != nil {
+		return fmt.Errorf("failed to set capabilities of devlink port %s/%s/%d: %v", bus, device, portIndex, err)
+	}
+	return nil
+}
+
+// getPortFnCap returns whether the given port function capability is enabled on the devlink port referenced
+// by portRef
+func getPortFnCap(portRef string, capability uint32) (bool, error) {
+	bus, device, portIndex, err := getDevlinkPortByRef(portRef)
+	if err != nil {
+		return false, err
+	}
+	caps, err := netlinkops.GetNetlinkOps().DevlinkPortFnGetCaps(bus, device, portIndex)
+	if err != nil {
+		return false, fmt.Errorf("failed to get capabilities of devlink port %s/%s/%d: %v", bus, device, portIndex, err)
+	}
+	return caps&capability != 0, nil
+}
+
+// SetPortFnRoce enables or disables RoCE on the function (VF or SF) behind the given devlink port.
+// portRef is either the port representor netdev name or a devlink port handle (e.g 'pci/0000:03:00.0/32768').
+// The function must be inactive (SF) or unbound from its driver (VF) for the change to be accepted.
+// Equivalent to: `devlink port function set pci/0000:03:00.0/32768 roce enable`
+// Requires Kernel >= 6.2.0
+func SetPortFnRoce(portRef string, enabled bool) error {
+	return setPortFnCap(portRef, netlinkops.DevlinkPortFnCapRoce, enabled)
+}
+
+// GetPortFnRoce returns whether RoCE is enabled on the function (VF or SF) behind the given devlink port
+func GetPortFnRoce(portRef string) (bool, error) {
+	return getPortFnCap(portRef, netlinkops.DevlinkPortFnCapRoce)
+}
+
+// getRepresentorLink returns the netlink link of the given representor netdev
+func getRepresentorLink(netdev string) (netlink.Link, error) {
+	if !isSwitchdev(netdev) {
+		return nil, fmt.Errorf("net device %s does not represent an eswitch port", netdev)
+	}
+	link, err := netlinkops.GetNetlinkOps().LinkByName(netdev)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get link of representor %s: %v", netdev, err)
+	}
+	return link, nil
+}
+
+// SetRepresentorUp sets the administrative state of the given representor netdev to up
+func SetRepresentorUp(netdev string) error {
+	link, err := getRepresentorLink(netdev)
+	if err != nil {
+		return err
+	}
+	if err = netlinkops.GetNetlinkOps().LinkSetUp(link); err != nil {
+		return fmt.Errorf("failed to set representor %s up: %v", netdev, err)
+	}
+	return nil
+}
+
+// SetRepresentorDown sets the administrative state of the given representor netdev to down
+func SetRepresentorDown(netdev string) error {
+	link, err := getRepresentorLink(netdev)
+	if err != nil {
+		return err
+	}
+	if err = netlinkops.GetNetlinkOps().LinkSetDown(link); err != nil {
+		return fmt.Errorf("failed to set representor %s down: %v", netdev, err)
+	}
+	return nil
+}
+
+// SetRepresentorMtu sets the MTU of the given representor netdev, e.g to match the MTU of the VF it represents
+func SetRepresentorMtu(netdev string, mtu int) error {
+	link, err := getRepresentorLink(netdev)
+	if err != nil {
+		return err
+	}
+	if err = netlinkops.GetNetlinkOps().LinkSetMTU(link, mtu); err != nil {
+		return fmt.Errorf("failed to set MTU %d on representor %s: %v", mtu, netdev, err)
+	}
+	return nil
+}
+
+// GetNetdevMtu returns the MTU of the given netdev
+func GetNetdevMtu(netdev string) (int, error) {
+	mtu, err := netlinkops.GetNetlinkOps().LinkGetMTUByName(netdev)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get MTU of %s: %v", netdev, err)
+	}
+	return mtu, nil
+}
+
+// SetNetdevMtu sets the MTU of the given netdev
+func SetNetdevMtu(netdev string, mtu int) error {
+	if err := netlinkops.GetNetlinkOps().LinkSetMTUByName(netdev, mtu); err != nil {
+		return fmt.Errorf("failed to set MTU %d on %s: %v", mtu, netdev, err)
+	}
+	return nil
+}
+
+// SetVfAndRepresentorMtu sets the MTU of the netdev of the VF with the given PCI address (e.g '0000:03:00.4')
+// and of its representor. The MTU is raised on the representor first and lowered on the VF first so that the
+// representor MTU never drops below the VF MTU.
+func SetVfAndRepresentorMtu(vfPciAddress string, mtu int) error {
+	rep, err := GetVfRepresentorByVfPciAddress(vfPciAddress)
+	if err != nil {
+		return err
+	}
+	vfNetdevs, err := GetNetDevicesFromPci(vfPciAddress)
+	if err != nil {
+		return err
+	}
+	if len(vfNetdevs) == 0 {
+		return fmt.Errorf("%w: VF %s", ErrNoNetDevice, vfPciAddress)
+	}
+	vfMtu, err := GetNetdevMtu(vfNetdevs[0])
+	if err != nil {
+		return err
+	}
+
+	setMtus := []func() error{
+		func() error { return SetRepresentorMtu(rep, mtu) },
+		func() error { return SetNetdevMtu(vfNetdevs[0], mtu) },
+	}
+	if mtu < vfMtu {
+		setMtus[0], setMtus[1] = setMtus[1], setMtus[0]
+	}
+	for _, setMtu := range setMtus {
+		if err = setMtu(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const netdevStatisticsDir = "statistics"
+
+// RepresentorStats holds the traffic counters of a representor netdev.
+// Note: counters are from the representor point of view, i.e RX counts traffic sent by the represented function.
+type RepresentorStats struct {
+	RxBytes   uint64
+	TxBytes   uint64
+	RxPackets uint64
+	TxPackets uint64
+	RxDropped uint64
+	TxDropped uint64
+}
+
+// GetRepresentorStats returns the traffic counters of the given representor netdev as read from
+// /sys/class/net/<netdev>/statistics
+func GetRepresentorStats(netdev string) (*RepresentorStats, error) {
+	if !isSwitchdev(netdev) {
+		return nil, fmt.Errorf("net device %s does not represent an eswitch port", netdev)
+	}
+	stats := &RepresentorStats{}
+	counters := map[string]*uint64{
+		"rx_bytes":   &stats.RxBytes,
+		"tx_bytes":   &stats.TxBytes,
+		"rx_packets": &stats.RxPackets,
+		"tx_packets": &stats.TxPackets,
+		"rx_dropped": &stats.RxDropped,
+		"tx_dropped": &stats.TxDropped,
+	}
+	for name, counter := range counters {
+		path := filepath.Join(NetSysDir, netdev, netdevStatisticsDir, name)
+		data, err := utilfs.Fs.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s of representor %s: %v", name, netdev, err)
+		}
+		*counter, err = strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s of representor %s: %v", name, netdev, err)
+		}
+	}
+	return stats, nil
+}
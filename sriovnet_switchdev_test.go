@@ -17,6 +17,7 @@ limitations under the License.
 package sriovnet
 
 import (
+	"encoding/json"
 	"fmt"
 	"net"
 	"os"
@@ -186,6 +187,19 @@ func TestGetUplinkRepresentorForPfSuccess(t *testing.T) {
 	assert.Equal(t, "eth0", uplinkNetdev)
 }
 
+func TestGetUplinkRepresentorEmptyPhysPortName(t *testing.T) {
+	vfPciAddress := "0000:03:00.4"
+	uplinkRep := &repContext{"p0", "p0", "111111"}
+	// an empty phys_port_name is not an uplink one, as opposed to a missing phys_port_name
+	vfsReps := []*repContext{{"eth0", "\n", "111111"}}
+
+	teardown := setupUplinkRepresentorEnv(t, uplinkRep, vfPciAddress, vfsReps)
+	defer teardown()
+	uplinkNetdev, err := GetUplinkRepresentor(vfPciAddress)
+	assert.NoError(t, err)
+	assert.Equal(t, "p0", uplinkNetdev)
+}
+
 func TestGetUplinkRepresentorWithPhysPortNameFailed(t *testing.T) {
 	vfPciAddress := "0000:03:00.4"
 	uplinkRep := &repContext{"eth0", "invalid", "111111"}
@@ -272,7 +286,86 @@ func TestGetVfRepresentorDPU(t *testing.T) {
 	assert.Equal(t, "eth2", vfRep)
 }
 
-func setupSfRepresentorEnv(t *testing.T, sfReps []*repContext) func() {
+func TestGetHostPfRepresentor(t *testing.T) {
+	reps := []*repContext{
+		{"p0", "p0", "c2cfc60003a1420c"},
+		{"pf0hpf", "c1pf0", "c2cfc60003a1420c"},
+		{"pf1hpf", "pf1", "c2cfc60003a1420c"},
+		{"pf0vf0", "c1pf0vf0", "c2cfc60003a1420c"},
+		{"c2pf1", "c2pf1", "c2cfc60003a1420c"},
+	}
+	teardown := setupRepresentorEnv(t, "", reps)
+	defer teardown()
+
+	rep, err := GetHostPfRepresentor(0)
+	assert.NoError(t, err)
+	assert.Equal(t, "pf0hpf", rep)
+
+	rep, err = GetHostPfRepresentor(1)
+	assert.NoError(t, err)
+	assert.Equal(t, "pf1hpf", rep)
+
+	_, err = GetHostPfRepresentor(2)
+	assert.Error(t, err)
+}
+
+func TestGetRepresentorsForHostPf(t *testing.T) {
+	reps := []*repContext{
+		{"p0", "p0", "c2cfc60003a1420c"},
+		{"pf0hpf", "c1pf0", "c2cfc60003a1420c"},
+		{"pf0vf0", "c1pf0vf0", "c2cfc60003a1420c"},
+		{"pf0vf1", "c1pf0vf1", "c2cfc60003a1420c"},
+		{"pf0sf3", "c1pf0sf3", "c2cfc60003a1420c"},
+		{"en3f0pf0sf0", "pf0sf0", "c2cfc60003a1420c"},
+		{"pf1hpf", "c1pf1", "c2cfc60003a1420c"},
+		{"pf1vf0", "c1pf1vf0", "c2cfc60003a1420c"},
+	}
+	teardown := setupRepresentorEnv(t, "", reps)
+	defer teardown()
+
+	hostReps, err := GetRepresentorsForHostPf(0)
+	assert.NoError(t, err)
+	assert.Equal(t, &HostPfRepresentors{
+		PfID: 0,
+		PF:   "pf0hpf",
+		VFs:  map[int]string{0: "pf0vf0", 1: "pf0vf1"},
+		SFs:  map[int]string{3: "pf0sf3"},
+	}, hostReps)
+
+	hostReps, err = GetRepresentorsForHostPf(1)
+	assert.NoError(t, err)
+	assert.Equal(t, &HostPfRepresentors{
+		PfID: 1,
+		PF:   "pf1hpf",
+		VFs:  map[int]string{0: "pf1vf0"},
+		SFs:  map[int]string{},
+	}, hostReps)
+
+	_, err = GetRepresentorsForHostPf(2)
+	assert.Error(t, err)
+}
+
+func TestGetRepresentorsForHostPfNoController(t *testing.T) {
+	reps := []*repContext{
+		{"pf0hpf", "pf0", "c2cfc60003a1420c"},
+		{"pf0vf0", "pf0vf0", "c2cfc60003a1420c"},
+		{"pf0sf3", "pf0sf3", "c2cfc60003a1420c"},
+		{"eth9", "pf0vf1", "a2cfc60003a1420c"},
+	}
+	teardown := setupRepresentorEnv(t, "", reps)
+	defer teardown()
+
+	hostReps, err := GetRepresentorsForHostPf(0)
+	assert.NoError(t, err)
+	assert.Equal(t, &HostPfRepresentors{
+		PfID: 0,
+		PF:   "pf0hpf",
+		VFs:  map[int]string{0: "pf0vf0"},
+		SFs:  map[int]string{3: "pf0sf3"},
+	}, hostReps)
+}
+
+func setupSfRepresentorEnv(t testing.TB, sfReps []*repContext) func() {
 	var err error
 	teardown := setupFakeFs(t)
 
@@ -369,6 +462,94 @@ func TestGetSfRepresentorErrorNotExistingUplink(t *testing.T) {
 	assert.Contains(t, err.Error(), expectedError)
 }
 
+func TestIsRepresentor(t *testing.T) {
+	teardown := setupRepresentorEnv(t, "", []*repContext{
+		{Name: "eth0", PhysPortName: "p0", PhysSwitchID: "111111"},
+		{Name: "eth0s1", PhysPortName: "p0s1", PhysSwitchID: "111111"},
+		{Name: "pf0hpf", PhysPortName: "pf0", PhysSwitchID: "111111"},
+		{Name: "pf0vf1", PhysPortName: "pf0vf1", PhysSwitchID: "111111"},
+		{Name: "en3f0pf0sf88", PhysPortName: "pf0sf88", PhysSwitchID: "111111"},
+		{Name: "eth_0", PhysPortName: "0", PhysSwitchID: "111111"},
+		{Name: "ens1f0v1", PhysPortName: "pf0vf1"},
+		{Name: "bond0"},
+	})
+	defer teardown()
+
+	for netdev, expected := range map[string][2]bool{
+		"eth0":         {true, true},
+		"eth0s1":       {true, true},
+		"pf0hpf":       {true, false},
+		"pf0vf1":       {true, false},
+		"en3f0pf0sf88": {true, false},
+		"eth_0":        {true, false},
+		"ens1f0v1":     {false, false},
+		"bond0":        {false, false},
+		"missing":      {false, false},
+	} {
+		assert.Equal(t, expected[0], IsRepresentor(netdev), netdev)
+		assert.Equal(t, expected[1], IsUplinkRepresentor(netdev), netdev)
+	}
+}
+
+func TestGetPhysPortNameAndSwitchID(t *testing.T) {
+	teardown := setupRepresentorEnv(t, "", []*repContext{
+		{Name: "pf0vf1", PhysPortName: "pf0vf1\n", PhysSwitchID: "111111\n"},
+		{Name: "eth0"},
+		{Name: "eth1", PhysPortName: " ", PhysSwitchID: ""},
+	})
+	defer teardown()
+
+	portName, err := GetPhysPortName("pf0vf1")
+	assert.NoError(t, err)
+	assert.Equal(t, "pf0vf1", portName)
+	switchID, err := GetPhysSwitchID("pf0vf1")
+	assert.NoError(t, err)
+	assert.Equal(t, "111111", switchID)
+
+	_, err = GetPhysPortName("eth0")
+	assert.ErrorIs(t, err, ErrNoPhysPortName)
+	_, err = GetPhysSwitchID("eth0")
+	assert.ErrorIs(t, err, ErrNoPhysSwitchID)
+	_, err = GetPhysPortName("eth1")
+	assert.ErrorIs(t, err, ErrNoPhysPortName)
+
+	_, err = GetPhysPortName("eth2")
+	assert.ErrorIs(t, err, ErrDeviceNotFound)
+	_, err = GetPhysSwitchID("eth2")
+	assert.ErrorIs(t, err, ErrDeviceNotFound)
+}
+
+func TestGetPfIndexFromPhysPortName(t *testing.T) {
+	for portName, pfIndex := range map[string]int{"p1": 1, "p1s0": 1, "pf1": 1, "c1pf1": 1, "pf1vf2": 1,
+		"pf1sf3": 1, "pf0vf1\n": 0} {
+		index, err := GetPfIndexFromPhysPortName(portName)
+		assert.NoError(t, err)
+		assert.Equal(t, pfIndex, index, portName)
+	}
+	for _, portName := range []string{"vf1", "eth0", ""} {
+		_, err := GetPfIndexFromPhysPortName(portName)
+		assert.Error(t, err, portName)
+	}
+}
+
+func TestGetUplinkPortInfo(t *testing.T) {
+	teardown := setupRepresentorEnv(t, "", []*repContext{
+		{Name: "eth1", PhysPortName: "p1", PhysSwitchID: "111111"},
+		{Name: "pf1vf0", PhysPortName: "pf1vf0", PhysSwitchID: "111111"},
+	})
+	defer teardown()
+	pfPath := filepath.Join(PciSysDir, "0000:03:00.1")
+	assert.NoError(t, utilfs.Fs.MkdirAll(pfPath, os.FileMode(0755)))
+	assert.NoError(t, utilfs.Fs.Symlink(pfPath, filepath.Join(NetSysDir, "eth1", pcidevPrefix)))
+
+	info, err := GetUplinkPortInfo("eth1")
+	assert.NoError(t, err)
+	assert.Equal(t, &UplinkPortInfo{PortNumber: 1, PfPciAddress: "0000:03:00.1", PfIndex: 1}, info)
+
+	_, err = GetUplinkPortInfo("pf1vf0")
+	assert.Error(t, err)
+}
+
 func TestGetPortIndexFromRepresentor(t *testing.T) {
 	vfReps := []*repContext{
 		{
@@ -381,6 +562,11 @@ func TestGetPortIndexFromRepresentor(t *testing.T) {
 			PhysPortName: "pf0",
 			PhysSwitchID: "c2cfc60003a1420c",
 		},
+		{
+			Name:         "pf1hpf",
+			PhysPortName: "c1pf1",
+			PhysSwitchID: "c2cfc60003a1420c",
+		},
 		{
 			Name:         "pf0vf10",
 			PhysPortName: "pf0vf10",
@@ -414,7 +600,8 @@ func TestGetPortIndexFromRepresentor(t *testing.T) {
 		{netdev: "pf0vf10", expectedID: 10, expectedError: "", shouldFail: false},
 		{netdev: "pf0sf50", expectedID: 50, expectedError: "", shouldFail: false},
 		{netdev: "p0", expectedID: 0, expectedError: "unsupported port flavor", shouldFail: true},
-		{netdev: "pf0hpf", expectedID: 0, expectedError: "unsupported port flavor", shouldFail: true},
+		{netdev: "pf0hpf", expectedID: 0, expectedError: "", shouldFail: false},
+		{netdev: "pf1hpf", expectedID: 1, expectedError: "", shouldFail: false},
 		{netdev: "eth3", expectedID: 0, expectedError: "no such file or directory", shouldFail: true},
 		{netdev: "notswitchdev", expectedID: 0, expectedError: "does not represent an eswitch port", shouldFail: true},
 	}
@@ -431,6 +618,49 @@ func TestGetPortIndexFromRepresentor(t *testing.T) {
 	}
 }
 
+func TestGetRepresentorInfo(t *testing.T) {
+	vfReps := []*repContext{
+		{"p0", "p0", "c2cfc60003a1420c"},
+		{"pf0hpf", "pf0", "c2cfc60003a1420c"},
+		{"pf0vf10", "pf0vf10", "c2cfc60003a1420c"},
+		{"c1pf1sf50", "c1pf1sf50", "c2cfc60003a1420c"},
+		{"eth3", "", "c2cfc60003a1420c"},
+		{"eth4", "3", "c2cfc60003a1420c"},
+	}
+	teardown := setupRepresentorEnv(t, "", vfReps)
+	defer teardown()
+	defer netlinkops.ResetNetlinkOps()
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	nlOpsMock.On("DevLinkGetPortByNetdevName", mock.AnythingOfType("string")).Return(
+		nil, fmt.Errorf("failed to get devlink port"))
+
+	tcases := []struct {
+		netdev     string
+		expected   *RepresentorInfo
+		shouldFail bool
+	}{
+		{netdev: "p0", expected: &RepresentorInfo{Flavour: PORT_FLAVOUR_PHYSICAL, PfID: 0, FuncIndex: -1}},
+		{netdev: "pf0hpf", expected: &RepresentorInfo{Flavour: PORT_FLAVOUR_PCI_PF, PfID: 0, FuncIndex: -1}},
+		{netdev: "pf0vf10", expected: &RepresentorInfo{Flavour: PORT_FLAVOUR_PCI_VF, PfID: 0, FuncIndex: 10}},
+		{netdev: "c1pf1sf50", expected: &RepresentorInfo{Flavour: PORT_FLAVOUR_PCI_SF, ControllerNum: 1, PfID: 1,
+			FuncIndex: 50}},
+		{netdev: "eth3", shouldFail: true},
+		{netdev: "eth4", shouldFail: true},
+		{netdev: "notswitchdev", shouldFail: true},
+	}
+
+	for _, tcase := range tcases {
+		info, err := GetRepresentorInfo(tcase.netdev)
+		if tcase.shouldFail {
+			assert.Error(t, err, tcase.netdev)
+		} else {
+			assert.NoError(t, err)
+			assert.Equal(t, tcase.expected, info)
+		}
+	}
+}
+
 func TestGetVfRepresentorDPUNoRep(t *testing.T) {
 	vfReps := []*repContext{
 		{
@@ -464,6 +694,29 @@ func TestGetVfRepresentorDPUInvalidVfIndex(t *testing.T) {
 	assert.Equal(t, "", vfRep)
 }
 
+func TestGetVfRepresentorDPUForController(t *testing.T) {
+	vfReps := []*repContext{
+		{Name: "eth0", PhysPortName: "pf0vf2", PhysSwitchID: "c2cfc60003a1420c"},
+		{Name: "eth1", PhysPortName: "c1pf0vf2", PhysSwitchID: "c2cfc60003a1420c"},
+		{Name: "eth2", PhysPortName: "c2pf0vf2", PhysSwitchID: "c2cfc60003a1420c"},
+		{Name: "eth3", PhysPortName: "c2pf0sf2", PhysSwitchID: "c2cfc60003a1420c"},
+	}
+	teardown := setupRepresentorEnv(t, "", vfReps)
+	defer teardown()
+
+	vfRep, err := GetVfRepresentorDPUForController(2, 0, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, "eth2", vfRep)
+	vfRep, err = GetVfRepresentorDPUForController(1, 0, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, "eth1", vfRep)
+	vfRep, err = GetVfRepresentorDPUForController(0, 0, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, "eth0", vfRep)
+	_, err = GetVfRepresentorDPUForController(3, 0, 2)
+	assert.Error(t, err)
+}
+
 func TestGetSfRepresentorDPUSuccess(t *testing.T) {
 	sfReps := []*repContext{
 		{
@@ -541,6 +794,16 @@ func TestGetVfRepresentorPortFlavour(t *testing.T) {
 			PhysPortName: "unknown",
 			PhysSwitchID: "c2cfc60003a1420c",
 		},
+		{
+			Name:         "eth11",
+			PhysPortName: "pf0vfr3",
+			PhysSwitchID: "c2cfc60003a1420c",
+		},
+		{
+			Name:         "eth12",
+			PhysPortName: "vf4",
+			PhysSwitchID: "c2cfc60003a1420c",
+		},
 	}
 	teardown := setupRepresentorEnv(t, "", vfReps)
 	defer teardown()
@@ -555,6 +818,8 @@ func TestGetVfRepresentorPortFlavour(t *testing.T) {
 		{netdev: "eth2", expected: PORT_FLAVOUR_PCI_VF, shouldFail: false},
 		{netdev: "eth44", expected: PORT_FLAVOUR_PCI_SF, shouldFail: false},
 		{netdev: "eth10", expected: PORT_FLAVOUR_UNKNOWN, shouldFail: false},
+		{netdev: "eth11", expected: PORT_FLAVOUR_PCI_VF, shouldFail: false},
+		{netdev: "eth12", expected: PORT_FLAVOUR_PCI_VF, shouldFail: false},
 		{netdev: "foobar", expected: PORT_FLAVOUR_UNKNOWN, shouldFail: true},
 	}
 
@@ -689,6 +954,41 @@ func TestGetRepresentorPeerMacAddressDevlink(t *testing.T) {
 	assert.Equal(t, "0c:42:a1:de:cf:7c", mac.String())
 }
 
+func TestGetRepresentorPeerMacAddressVfSf(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+
+	teardown := setupRepresentorEnv(t, "", []*repContext{
+		{Name: "p0", PhysPortName: "p0", PhysSwitchID: "c2cfc60003a1420c"},
+		{Name: "pf0vf1", PhysPortName: "pf0vf1", PhysSwitchID: "c2cfc60003a1420c"},
+		{Name: "pf0sf2", PhysPortName: "pf0sf2", PhysSwitchID: "c2cfc60003a1420c"},
+		{Name: "pf0sf3", PhysPortName: "pf0sf3", PhysSwitchID: "c2cfc60003a1420c"},
+	})
+	defer teardown()
+	setupDPUConfigFileForPort(t, "p0", "vf1", "MAC        : 0c:42:a1:de:cf:7d\n")
+
+	sfPort := &netlink.DevlinkPort{
+		NetdeviceName: "pf0sf2",
+		PortFlavour:   PORT_FLAVOUR_PCI_SF,
+		Fn:            &netlink.DevlinkPortFn{HwAddr: net.HardwareAddr{0x0c, 0x42, 0xa1, 0xde, 0xcf, 0x7e}},
+	}
+	nlOpsMock.On("DevLinkGetPortByNetdevName", "pf0sf2").Return(sfPort, nil)
+	nlOpsMock.On("DevLinkGetPortByNetdevName", mock.AnythingOfType("string")).Return(
+		nil, fmt.Errorf("failed to get devlink port"))
+
+	mac, err := GetRepresentorPeerMacAddress("pf0vf1")
+	assert.NoError(t, err)
+	assert.Equal(t, "0c:42:a1:de:cf:7d", mac.String())
+
+	mac, err = GetRepresentorPeerMacAddress("pf0sf2")
+	assert.NoError(t, err)
+	assert.Equal(t, "0c:42:a1:de:cf:7e", mac.String())
+
+	_, err = GetRepresentorPeerMacAddress("pf0sf3")
+	assert.Error(t, err)
+}
+
 func TestSetRepresentorPeerMacAddress(t *testing.T) {
 	nlOpsMock := netlinkopsMocks.NetlinkOps{}
 	netlinkops.SetNetlinkOps(&nlOpsMock)
@@ -724,3 +1024,773 @@ func TestSetRepresentorPeerMacAddress(t *testing.T) {
 	err := SetRepresentorPeerMacAddress("pf0vf24", mac)
 	assert.NoError(t, err)
 }
+
+func TestSetRepresentorPeerMacAddressPfSf(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+
+	teardown := setupRepresentorEnv(t, "", []*repContext{
+		{Name: "p0", PhysPortName: "p0", PhysSwitchID: "c2cfc60003a1420c"},
+		{Name: "pf0hpf", PhysPortName: "pf0", PhysSwitchID: "c2cfc60003a1420c"},
+		{Name: "pf0sf2", PhysPortName: "pf0sf2", PhysSwitchID: "c2cfc60003a1420c"},
+		{Name: "pf0sf3", PhysPortName: "pf0sf3", PhysSwitchID: "c2cfc60003a1420c"},
+		{Name: "pf0vf4", PhysPortName: "pf0vf4", PhysSwitchID: "c2cfc60003a1420c"},
+	})
+	defer teardown()
+	pfMacFile := filepath.Join(NetSysDir, "p0", "smart_nic", "pf", "mac")
+	assert.NoError(t, utilfs.Fs.MkdirAll(filepath.Dir(pfMacFile), os.FileMode(0755)))
+	assert.NoError(t, utilfs.Fs.WriteFile(pfMacFile, []byte(""), os.FileMode(0644)))
+
+	mac := net.HardwareAddr{0, 0, 0, 1, 2, 3}
+	fnAttrs := netlink.DevlinkPortFnSetAttrs{HwAddrValid: true}
+	fnAttrs.FnAttrs.HwAddr = mac
+	sfPort := &netlink.DevlinkPort{BusName: "pci", DeviceName: "0000:03:00.0", PortIndex: 32769,
+		NetdeviceName: "pf0sf2", PortFlavour: PORT_FLAVOUR_PCI_SF}
+	nlOpsMock.On("DevLinkGetPortByNetdevName", "pf0sf2").Return(sfPort, nil)
+	nlOpsMock.On("DevLinkGetPortByNetdevName", mock.AnythingOfType("string")).Return(nil, fmt.Errorf("no devlink support"))
+	nlOpsMock.On("DevlinkPortFnSet", "pci", "0000:03:00.0", uint32(32769), fnAttrs).Return(nil)
+
+	assert.NoError(t, SetRepresentorPeerMacAddress("pf0hpf", mac))
+	assert.Equal(t, mac.String(), readFakeFile(t, pfMacFile))
+	assert.NoError(t, SetRepresentorPeerMacAddress("pf0sf2", mac))
+	assert.Error(t, SetRepresentorPeerMacAddress("pf0sf3", mac))
+	assert.Error(t, SetRepresentorPeerMacAddress("p0", mac))
+	// neither devlink nor smart_nic sysfs, both failures are reported
+	err := SetRepresentorPeerMacAddress("pf0vf4", mac)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "smart_nic/vf4/mac")
+	assert.Contains(t, err.Error(), "no devlink support")
+	nlOpsMock.AssertExpectations(t)
+}
+
+func TestSetPortFnHwAddr(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+
+	mac := net.HardwareAddr{0, 0, 0, 0, 0x88, 0x88}
+	fnAttrs := netlink.DevlinkPortFnSetAttrs{HwAddrValid: true}
+	fnAttrs.FnAttrs.HwAddr = mac
+	dlport := netlink.DevlinkPort{BusName: "pci", DeviceName: "0000:03:00.0", PortIndex: 65537}
+	nlOpsMock.On("DevLinkGetPortByNetdevName", "pf0vf1").Return(&dlport, nil)
+	nlOpsMock.On("DevlinkPortFnSet", "pci", "0000:03:00.0", uint32(65537), fnAttrs).Return(nil)
+	nlOpsMock.On("DevlinkPortFnSet", "pci", "0000:03:00.0", uint32(32768), fnAttrs).Return(nil)
+
+	assert.NoError(t, SetPortFnHwAddr("pf0vf1", mac))
+	assert.NoError(t, SetPortFnHwAddr("pci/0000:03:00.0/32768", mac))
+	assert.Error(t, SetPortFnHwAddr("pci/0000:03:00.0/port1", mac))
+	nlOpsMock.AssertExpectations(t)
+}
+
+func TestRepresentorPeerTrust(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+
+	teardown := setupRepresentorEnv(t, "", []*repContext{
+		{Name: "p0", PhysPortName: "p0", PhysSwitchID: "c2cfc60003a1420c"},
+		{Name: "pf0vf1", PhysPortName: "pf0vf1", PhysSwitchID: "c2cfc60003a1420c"},
+		{Name: "pf0sf88", PhysPortName: "pf0sf88", PhysSwitchID: "c2cfc60003a1420c"},
+		// uplink of another NIC with the same physical port name
+		{Name: "enp4s0f0", PhysPortName: "p0", PhysSwitchID: "d3dfd70004b2531d"},
+	})
+	defer teardown()
+
+	uplink := &netlink.Device{LinkAttrs: netlink.LinkAttrs{
+		Name: "p0",
+		Vfs:  []netlink.VfInfo{{ID: 0}, {ID: 1, Trust: 1}},
+	}}
+	nlOpsMock.On("LinkByName", "p0").Return(uplink, nil)
+	nlOpsMock.On("LinkSetVfTrust", uplink, 1, false).Return(nil)
+
+	trusted, err := GetRepresentorPeerTrust("pf0vf1")
+	assert.NoError(t, err)
+	assert.True(t, trusted)
+	assert.NoError(t, SetRepresentorPeerTrust("pf0vf1", false))
+
+	err = SetRepresentorPeerTrust("pf0sf88", true)
+	assert.ErrorIs(t, err, ErrNotSupported)
+	_, err = GetRepresentorPeerTrust("pf0sf88")
+	assert.ErrorIs(t, err, ErrNotSupported)
+	nlOpsMock.AssertExpectations(t)
+}
+
+func TestRepresentorPeerTrustDPU(t *testing.T) {
+	teardown := setupRepresentorEnv(t, "", []*repContext{
+		{Name: "p0", PhysPortName: "p0", PhysSwitchID: "c2cfc60003a1420c"},
+		{Name: "pf0vf1", PhysPortName: "pf0vf1", PhysSwitchID: "c2cfc60003a1420c"},
+		{Name: "c1pf0vf2", PhysPortName: "c1pf0vf2", PhysSwitchID: "c2cfc60003a1420c"},
+	})
+	defer teardown()
+
+	// representors of an external controller eswitch stand for host VFs which are not VFs of the uplink
+	err := SetRepresentorPeerTrust("c1pf0vf2", true)
+	assert.ErrorIs(t, err, ErrNotSupported)
+	_, err = GetRepresentorPeerTrust("pf0vf1")
+	assert.ErrorIs(t, err, ErrNotSupported)
+}
+
+func TestPortFnRoce(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+
+	dlport := netlink.DevlinkPort{BusName: "pci", DeviceName: "0000:03:00.0", PortIndex: 65537}
+	nlOpsMock.On("DevLinkGetPortByNetdevName", "pf0vf1").Return(&dlport, nil)
+	nlOpsMock.On("DevlinkPortFnGetCaps", "pci", "0000:03:00.0", uint32(65537)).Return(
+		netlinkops.DevlinkPortFnCapRoce|netlinkops.DevlinkPortFnCapMigratable, nil)
+	nlOpsMock.On("DevlinkPortFnSetCaps", "pci", "0000:03:00.0", uint32(32768),
+		uint32(0), netlinkops.DevlinkPortFnCapRoce).Return(nil)
+
+	enabled, err := GetPortFnRoce("pf0vf1")
+	assert.NoError(t, err)
+	assert.True(t, enabled)
+	assert.NoError(t, SetPortFnRoce("pci/0000:03:00.0/32768", false))
+	nlOpsMock.AssertExpectations(t)
+}
+
+func TestGetVfRepresentorNonZeroPciDomain(t *testing.T) {
+	pfPciAddress := "00d5:01:00.1"
+	uplinkRep := &repContext{"p1", "p1", "c2cfc60003a1420c"}
+	vfReps := []*repContext{
+		{"eth0", "pf0vf1", "c2cfc60003a1420c"},
+		{"eth1", "pf1vf0", "c2cfc60003a1420c"},
+		{"eth2", "pf1vf1", "c2cfc60003a1420c"},
+	}
+	teardown := setupRepresentorEnv(t, "", append(vfReps, uplinkRep))
+	defer teardown()
+	pfPciPath := filepath.Join(PciSysDir, pfPciAddress)
+	assert.NoError(t, utilfs.Fs.MkdirAll(pfPciPath, os.FileMode(0755)))
+	assert.NoError(t, utilfs.Fs.Symlink(pfPciPath, filepath.Join(NetSysDir, uplinkRep.Name, pcidevPrefix)))
+	assert.NoError(t, utilfs.Fs.Symlink(NetSysDir, filepath.Join(NetSysDir, uplinkRep.Name, "subsystem")))
+
+	vfRep, err := GetVfRepresentor(uplinkRep.Name, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "eth2", vfRep)
+}
+
+func TestListRepresentors(t *testing.T) {
+	reps := []*repContext{
+		{"p0", "p0", "c2cfc60003a1420c"},
+		{"pf0hpf", "pf0", "c2cfc60003a1420c"},
+		{"eth0", "pf0vf0", "c2cfc60003a1420c"},
+		{"eth1", "c1pf0vf1", "c2cfc60003a1420c"},
+		{"eth2", "pf0sf3", "c2cfc60003a1420c"},
+		{"eth3", "pf0vf2", "aabbccddeeff0011"},
+		{"eth4", "", "c2cfc60003a1420c"},
+	}
+	teardown := setupRepresentorEnv(t, "", reps)
+	defer teardown()
+
+	all, err := ListRepresentors("p0", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []*Representor{
+		{Name: "eth0", RepresentorInfo: RepresentorInfo{Flavour: PORT_FLAVOUR_PCI_VF, ControllerNum: 0, PfID: 0, FuncIndex: 0}},
+		{Name: "eth1", RepresentorInfo: RepresentorInfo{Flavour: PORT_FLAVOUR_PCI_VF, ControllerNum: 1, PfID: 0, FuncIndex: 1}},
+		{Name: "eth2", RepresentorInfo: RepresentorInfo{Flavour: PORT_FLAVOUR_PCI_SF, ControllerNum: 0, PfID: 0, FuncIndex: 3}},
+		{Name: "p0", RepresentorInfo: RepresentorInfo{Flavour: PORT_FLAVOUR_PHYSICAL, ControllerNum: 0, PfID: 0, FuncIndex: -1}},
+		{Name: "pf0hpf", RepresentorInfo: RepresentorInfo{Flavour: PORT_FLAVOUR_PCI_PF, ControllerNum: 0, PfID: 0, FuncIndex: -1}},
+	}, all)
+
+	flavour := PortFlavour(PORT_FLAVOUR_PCI_VF)
+	controller := 0
+	vfs, err := ListRepresentors("p0", &RepresentorFilter{Flavour: &flavour, Controller: &controller})
+	assert.NoError(t, err)
+	assert.Len(t, vfs, 1)
+	assert.Equal(t, "eth0", vfs[0].Name)
+
+	_, err = ListRepresentors("eth4", nil)
+	assert.NoError(t, err)
+	_, err = ListRepresentors("missing", nil)
+	assert.Error(t, err)
+}
+
+func TestGetVfRepresentors(t *testing.T) {
+	pfPciAddress := "0000:03:00.1"
+	uplinkRep := &repContext{"p1", "p1", "c2cfc60003a1420c"}
+	vfReps := []*repContext{
+		{"eth0", "pf0vf1", "c2cfc60003a1420c"},
+		{"eth1", "pf1vf0", "c2cfc60003a1420c"},
+		{"eth2", "pf1vf1", "c2cfc60003a1420c"},
+		{"eth3", "pf1sf1", "c2cfc60003a1420c"},
+		{"eth4", "pf1vf2", "aabbccddeeff0011"},
+	}
+	teardown := setupRepresentorEnv(t, "", append(vfReps, uplinkRep))
+	defer teardown()
+	pfPciPath := filepath.Join(PciSysDir, pfPciAddress)
+	assert.NoError(t, utilfs.Fs.MkdirAll(pfPciPath, os.FileMode(0755)))
+	assert.NoError(t, utilfs.Fs.Symlink(pfPciPath, filepath.Join(NetSysDir, uplinkRep.Name, pcidevPrefix)))
+	assert.NoError(t, utilfs.Fs.Symlink(NetSysDir, filepath.Join(NetSysDir, uplinkRep.Name, "subsystem")))
+
+	reps, err := GetVfRepresentors(uplinkRep.Name)
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]string{0: "eth1", 1: "eth2"}, reps)
+
+	_, err = GetVfRepresentors("missing")
+	assert.Error(t, err)
+}
+
+func TestGetVfRepresentorFirstMatch(t *testing.T) {
+	pfPciAddress := "0000:03:00.0"
+	uplinkRep := &repContext{"p0", "p0", "c2cfc60003a1420c"}
+	vfReps := []*repContext{
+		{"eth0", "c1pf0vf1", "c2cfc60003a1420c"},
+		{"eth1", "c2pf0vf1", "c2cfc60003a1420c"},
+	}
+	teardown := setupRepresentorEnv(t, "", append(vfReps, uplinkRep))
+	defer teardown()
+	pfPciPath := filepath.Join(PciSysDir, pfPciAddress)
+	assert.NoError(t, utilfs.Fs.MkdirAll(pfPciPath, os.FileMode(0755)))
+	assert.NoError(t, utilfs.Fs.Symlink(pfPciPath, filepath.Join(NetSysDir, uplinkRep.Name, pcidevPrefix)))
+	assert.NoError(t, utilfs.Fs.Symlink(NetSysDir, filepath.Join(NetSysDir, uplinkRep.Name, "subsystem")))
+
+	rep, err := GetVfRepresentor(uplinkRep.Name, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "eth0", rep)
+}
+
+func TestGetSfRepresentors(t *testing.T) {
+	sfReps := []*repContext{
+		{Name: "eth0", PhysPortName: "pf0sf0"},
+		{Name: "eth1", PhysPortName: "c1pf0sf1"},
+		{Name: "eth2", PhysPortName: "pf0sf2"},
+		{Name: "eth3", PhysPortName: "c1pf0sf2"},
+		{Name: "eth4", PhysPortName: "pf0vf2"},
+	}
+	teardown := setupSfRepresentorEnv(t, sfReps)
+	defer teardown()
+
+	reps, err := GetSfRepresentors("p0")
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]*Representor{
+		0: {Name: "eth0", RepresentorInfo: RepresentorInfo{Flavour: PORT_FLAVOUR_PCI_SF, ControllerNum: 0, PfID: 0, FuncIndex: 0}},
+		1: {Name: "eth1", RepresentorInfo: RepresentorInfo{Flavour: PORT_FLAVOUR_PCI_SF, ControllerNum: 1, PfID: 0, FuncIndex: 1}},
+		2: {Name: "eth2", RepresentorInfo: RepresentorInfo{Flavour: PORT_FLAVOUR_PCI_SF, ControllerNum: 0, PfID: 0, FuncIndex: 2}},
+	}, reps)
+
+	_, err = GetSfRepresentors("missing")
+	assert.Error(t, err)
+}
+
+func TestGetRepresentorByMac(t *testing.T) {
+	vfReps := []*repContext{
+		{"eth0", "p0", "c2cfc60003a1420c"},
+		{"pf0hpf", "pf0", "c2cfc60003a1420c"},
+		{"rep_0", "pf0vf0", "c2cfc60003a1420c"},
+		{"rep_1", "pf0vf1", "c2cfc60003a1420c"},
+	}
+	teardown := setupRepresentorEnv(t, "", vfReps)
+	defer teardown()
+	defer netlinkops.ResetNetlinkOps()
+
+	setupDPUConfigFileForPort(t, "eth0", "pf", "MAC        : 0c:42:a1:de:cf:7c\n")
+	setupDPUConfigFileForPort(t, "eth0", "vf1", "MAC        : 0c:42:a1:de:cf:7d\n")
+
+	t.Run("devlink", func(t *testing.T) {
+		nlOpsMock := netlinkopsMocks.NetlinkOps{}
+		netlinkops.SetNetlinkOps(&nlOpsMock)
+		nlOpsMock.On("DevLinkGetAllPortList").Return([]*netlink.DevlinkPort{
+			{NetdeviceName: "eth0"},
+			{NetdeviceName: "rep_0", Fn: &netlink.DevlinkPortFn{HwAddr: net.HardwareAddr{0x0c, 0x42, 0xa1, 0, 0, 1}}},
+		}, nil)
+
+		rep, err := GetRepresentorByMac(net.HardwareAddr{0x0c, 0x42, 0xa1, 0, 0, 1})
+		assert.NoError(t, err)
+		assert.Equal(t, "rep_0", rep)
+	})
+
+	t.Run("sysfs", func(t *testing.T) {
+		nlOpsMock := netlinkopsMocks.NetlinkOps{}
+		netlinkops.SetNetlinkOps(&nlOpsMock)
+		nlOpsMock.On("DevLinkGetAllPortList").Return(nil, fmt.Errorf("not supported"))
+
+		rep, err := GetRepresentorByMac(net.HardwareAddr{0x0c, 0x42, 0xa1, 0xde, 0xcf, 0x7c})
+		assert.NoError(t, err)
+		assert.Equal(t, "pf0hpf", rep)
+		rep, err = GetRepresentorByMac(net.HardwareAddr{0x0c, 0x42, 0xa1, 0xde, 0xcf, 0x7d})
+		assert.NoError(t, err)
+		assert.Equal(t, "rep_1", rep)
+		_, err = GetRepresentorByMac(net.HardwareAddr{0x0c, 0x42, 0xa1, 0xde, 0xcf, 0x7e})
+		assert.Error(t, err)
+	})
+}
+
+func TestGetVfRepresentorByVfPciAddress(t *testing.T) {
+	pfPciAddress := "0000:03:00.0"
+	vfs := []string{"0000:03:00.2", "0000:03:00.3"}
+	uplinkRep := &repContext{"p0", "p0", "c2cfc60003a1420c"}
+	vfReps := []*repContext{
+		{"eth0", "pf0vf0", "c2cfc60003a1420c"},
+		{"eth1", "pf0vf1", "c2cfc60003a1420c"},
+	}
+	teardown := setupRepresentorEnv(t, "", append(vfReps, uplinkRep))
+	defer teardown()
+	setUpPfVfsPciEnv(t, pfPciAddress, vfs)
+	pfPciPath := filepath.Join(PciSysDir, pfPciAddress)
+	assert.NoError(t, utilfs.Fs.MkdirAll(filepath.Join(pfPciPath, "net", uplinkRep.Name), os.FileMode(0755)))
+	assert.NoError(t, utilfs.Fs.Symlink(pfPciPath, filepath.Join(NetSysDir, uplinkRep.Name, pcidevPrefix)))
+	assert.NoError(t, utilfs.Fs.Symlink(NetSysDir, filepath.Join(NetSysDir, uplinkRep.Name, "subsystem")))
+
+	vfRep, err := GetVfRepresentorByVfPciAddress(vfs[1])
+	assert.NoError(t, err)
+	assert.Equal(t, "eth1", vfRep)
+
+	_, err = GetVfRepresentorByVfPciAddress("0000:03:00.5")
+	assert.Error(t, err)
+}
+
+func TestGetVfPciFromRepresentor(t *testing.T) {
+	pfPciAddress := "0000:03:00.0"
+	vfs := []string{"0000:03:00.2", "0000:03:00.3"}
+	vfReps := []*repContext{
+		{"eth0", "pf0vf0", "c2cfc60003a1420c"},
+		{"eth1", "pf0vf1", "c2cfc60003a1420c"},
+		{"eth2", "pf0vf5", "c2cfc60003a1420c"},
+	}
+	teardown := setupRepresentorEnv(t, "", vfReps)
+	defer teardown()
+	defer netlinkops.ResetNetlinkOps()
+	setUpPfVfsPciEnv(t, pfPciAddress, vfs)
+	pfPciPath := filepath.Join(PciSysDir, pfPciAddress)
+	for _, rep := range vfReps {
+		assert.NoError(t, utilfs.Fs.Symlink(pfPciPath, filepath.Join(NetSysDir, rep.Name, pcidevPrefix)))
+	}
+
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	nlOpsMock.On("DevLinkGetPortByNetdevName", "eth0").Return(
+		&netlink.DevlinkPort{BusName: "pci", DeviceName: pfPciAddress, NetdeviceName: "eth0"}, nil)
+	nlOpsMock.On("DevLinkGetPortByNetdevName", mock.AnythingOfType("string")).Return(
+		nil, fmt.Errorf("failed to get devlink port"))
+
+	vfPci, err := GetVfPciFromRepresentor("eth0")
+	assert.NoError(t, err)
+	assert.Equal(t, vfs[0], vfPci)
+
+	vfPci, err = GetVfPciFromRepresentor("eth1")
+	assert.NoError(t, err)
+	assert.Equal(t, vfs[1], vfPci)
+
+	_, err = GetVfPciFromRepresentor("eth2")
+	assert.Error(t, err)
+	_, err = GetVfPciFromRepresentor("missing")
+	assert.Error(t, err)
+}
+
+func TestPortFlavourString(t *testing.T) {
+	assert.Equal(t, "physical", PortFlavour(PORT_FLAVOUR_PHYSICAL).String())
+	assert.Equal(t, "pcivf", PortFlavour(PORT_FLAVOUR_PCI_VF).String())
+	assert.Equal(t, "pcisf", PortFlavour(PORT_FLAVOUR_PCI_SF).String())
+	assert.Equal(t, "unknown", PortFlavour(PORT_FLAVOUR_UNKNOWN).String())
+	assert.Equal(t, "unknown(42)", PortFlavour(42).String())
+}
+
+func TestParsePortFlavour(t *testing.T) {
+	for _, flavour := range []PortFlavour{PORT_FLAVOUR_PHYSICAL, PORT_FLAVOUR_PCI_PF, PORT_FLAVOUR_PCI_VF,
+		PORT_FLAVOUR_PCI_SF, PORT_FLAVOUR_UNKNOWN} {
+		parsed, err := ParsePortFlavour(flavour.String())
+		assert.NoError(t, err)
+		assert.Equal(t, flavour, parsed)
+	}
+	_, err := ParsePortFlavour("foo")
+	assert.Error(t, err)
+
+	out, err := json.Marshal(map[string]PortFlavour{"flavour": PORT_FLAVOUR_PCI_SF})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"flavour":"pcisf"}`, string(out))
+	var in map[string]PortFlavour
+	assert.NoError(t, json.Unmarshal([]byte(`{"flavour":"pcivf"}`), &in))
+	assert.Equal(t, PortFlavour(PORT_FLAVOUR_PCI_VF), in["flavour"])
+	assert.Error(t, json.Unmarshal([]byte(`{"flavour":"foo"}`), &in))
+}
+
+func TestParsePortName(t *testing.T) {
+	tcases := []struct {
+		portName   string
+		pfIndex    int
+		vfIndex    int
+		shouldFail bool
+	}{
+		{portName: "3", pfIndex: 0, vfIndex: 3},
+		{portName: "vf3", pfIndex: -1, vfIndex: 3},
+		{portName: "pf1vf3", pfIndex: 1, vfIndex: 3},
+		{portName: "c1pf1vf3", pfIndex: 1, vfIndex: 3},
+		{portName: "pf1vfr3", pfIndex: 1, vfIndex: 3},
+		{portName: "pf1sf3", shouldFail: true},
+		{portName: "p0", shouldFail: true},
+	}
+	for _, tcase := range tcases {
+		pfIndex, vfIndex, err := parsePortName(tcase.portName)
+		if tcase.shouldFail {
+			assert.Error(t, err, tcase.portName)
+			continue
+		}
+		assert.NoError(t, err, tcase.portName)
+		assert.Equal(t, tcase.pfIndex, pfIndex, tcase.portName)
+		assert.Equal(t, tcase.vfIndex, vfIndex, tcase.portName)
+	}
+}
+
+func TestGetVfRepresentorLegacyPortNames(t *testing.T) {
+	uplinkRep := &repContext{"p0", "p0", "c2cfc60003a1420c"}
+	vfReps := []*repContext{
+		{"eth0", "vf0", "c2cfc60003a1420c"},
+		{"eth1", "pf0vfr1", "c2cfc60003a1420c"},
+	}
+	teardown := setupRepresentorEnv(t, "", append(vfReps, uplinkRep))
+	defer teardown()
+	pfPciPath := filepath.Join(PciSysDir, "0000:03:00.0")
+	assert.NoError(t, utilfs.Fs.MkdirAll(pfPciPath, os.FileMode(0755)))
+	assert.NoError(t, utilfs.Fs.Symlink(pfPciPath, filepath.Join(NetSysDir, uplinkRep.Name, pcidevPrefix)))
+	assert.NoError(t, utilfs.Fs.Symlink(NetSysDir, filepath.Join(NetSysDir, uplinkRep.Name, "subsystem")))
+
+	reps, err := GetVfRepresentors(uplinkRep.Name)
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]string{0: "eth0", 1: "eth1"}, reps)
+}
+
+func TestSetRepresentorPeerMacAddressIce(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+
+	pfPciAddress := "0000:af:00.0"
+	reps := []*repContext{
+		{Name: "ens1f0", PhysPortName: "p0", PhysSwitchID: "4a2b3c0003a1420c"},
+		{Name: "eth0", PhysPortName: "pf0vfr1", PhysSwitchID: "4a2b3c0003a1420c"},
+		// uplink of another NIC with the same physical port name
+		{Name: "p0", PhysPortName: "p0", PhysSwitchID: "c2cfc60003a1420c"},
+	}
+	teardown := setupRepresentorEnv(t, "", reps)
+	defer teardown()
+	setUpPciDriverEnv(t, "ice", pfPciAddress)
+	pfPciPath := filepath.Join(PciSysDir, pfPciAddress)
+	for _, rep := range reps[:2] {
+		assert.NoError(t, utilfs.Fs.Symlink(pfPciPath, filepath.Join(NetSysDir, rep.Name, pcidevPrefix)))
+	}
+
+	mac := net.HardwareAddr{0, 0, 0, 1, 2, 3}
+	uplink := &netlink.Device{LinkAttrs: netlink.LinkAttrs{Name: "ens1f0"}}
+	nlOpsMock.On("DevLinkGetPortByNetdevName", mock.AnythingOfType("string")).Return(nil, fmt.Errorf("no devlink support"))
+	nlOpsMock.On("LinkByName", "ens1f0").Return(uplink, nil)
+	nlOpsMock.On("LinkSetVfHardwareAddr", uplink, 1, mac).Return(nil)
+
+	assert.NoError(t, SetRepresentorPeerMacAddress("eth0", mac))
+	nlOpsMock.AssertExpectations(t)
+}
+
+func TestListControllers(t *testing.T) {
+	reps := []*repContext{
+		{"p0", "p0", "c2cfc60003a1420c"},
+		{"pf0hpf", "pf0", "c2cfc60003a1420c"},
+		{"eth0", "c2pf0vf0", "c2cfc60003a1420c"},
+		{"eth1", "c1pf0vf1", "c2cfc60003a1420c"},
+		{"eth2", "c2pf0sf3", "c2cfc60003a1420c"},
+		{"eth3", "c3pf0vf2", "aabbccddeeff0011"},
+	}
+	teardown := setupRepresentorEnv(t, "", reps)
+	defer teardown()
+
+	controllers, err := ListControllers("p0")
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, controllers)
+
+	_, err = ListControllers("missing")
+	assert.Error(t, err)
+}
+
+func TestGetVfLag(t *testing.T) {
+	pfPciAddress := "0000:03:00.0"
+	reps := []*repContext{
+		{"p0", "p0", "c2cfc60003a1420c"},
+		{"p1", "p1", "c2cfc60003a1420c"},
+		{"eth0", "pf0vf0", "c2cfc60003a1420c"},
+	}
+	teardown := setupRepresentorEnv(t, "", reps)
+	defer teardown()
+	assert.NoError(t, utilfs.Fs.MkdirAll(filepath.Join(PciSysDir, pfPciAddress, "net", "p0"), os.FileMode(0755)))
+
+	vfLag, err := GetVfLag(pfPciAddress)
+	assert.NoError(t, err)
+	assert.False(t, vfLag.Enabled())
+	assert.Equal(t, []string{"p0"}, vfLag.Uplinks)
+
+	bondPath := filepath.Join(NetSysDir, "bond0")
+	assert.NoError(t, utilfs.Fs.MkdirAll(filepath.Join(bondPath, "bonding"), os.FileMode(0755)))
+	assert.NoError(t, utilfs.Fs.WriteFile(filepath.Join(bondPath, "bonding", "slaves"), []byte("p1 p0\n"),
+		os.FileMode(0644)))
+	assert.NoError(t, utilfs.Fs.Symlink(bondPath, filepath.Join(NetSysDir, "p0", "master")))
+
+	vfLag, err = GetVfLag(pfPciAddress)
+	assert.NoError(t, err)
+	assert.True(t, vfLag.Enabled())
+	assert.Equal(t, "bond0", vfLag.Bond)
+	assert.Equal(t, []string{"p0", "p1"}, vfLag.Uplinks)
+
+	_, err = GetVfLag("0000:03:00.1")
+	assert.Error(t, err)
+}
+
+func TestSetRepresentorLinkAttrs(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+
+	teardown := setupRepresentorEnv(t, "", []*repContext{
+		{Name: "pf0vf1", PhysPortName: "pf0vf1", PhysSwitchID: "c2cfc60003a1420c"},
+		{Name: "eth0", PhysPortName: "", PhysSwitchID: ""},
+	})
+	defer teardown()
+
+	rep := &netlink.Device{LinkAttrs: netlink.LinkAttrs{Name: "pf0vf1"}}
+	nlOpsMock.On("LinkByName", "pf0vf1").Return(rep, nil)
+	nlOpsMock.On("LinkSetUp", rep).Return(nil)
+	nlOpsMock.On("LinkSetDown", rep).Return(nil)
+	nlOpsMock.On("LinkSetMTU", rep, 9000).Return(nil)
+
+	assert.NoError(t, SetRepresentorUp("pf0vf1"))
+	assert.NoError(t, SetRepresentorDown("pf0vf1"))
+	assert.NoError(t, SetRepresentorMtu("pf0vf1", 9000))
+	assert.Error(t, SetRepresentorUp("eth0"))
+	assert.Error(t, SetRepresentorMtu("eth0", 9000))
+	nlOpsMock.AssertExpectations(t)
+}
+
+func TestGetAndSetNetdevMtu(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+
+	nlOpsMock.On("LinkGetMTUByName", "eth0").Return(1500, nil)
+	nlOpsMock.On("LinkGetMTUByName", "eth1").Return(0, fmt.Errorf("link not found"))
+	nlOpsMock.On("LinkSetMTUByName", "eth0", 9000).Return(nil)
+
+	mtu, err := GetNetdevMtu("eth0")
+	assert.NoError(t, err)
+	assert.Equal(t, 1500, mtu)
+	_, err = GetNetdevMtu("eth1")
+	assert.Error(t, err)
+	assert.NoError(t, SetNetdevMtu("eth0", 9000))
+	nlOpsMock.AssertExpectations(t)
+}
+
+func TestSetVfAndRepresentorMtu(t *testing.T) {
+	pfPciAddress := "0000:03:00.0"
+	vfs := []string{"0000:03:00.2", "0000:03:00.3"}
+	uplinkRep := &repContext{"p0", "p0", "c2cfc60003a1420c"}
+	vfReps := []*repContext{
+		{"eth0", "pf0vf0", "c2cfc60003a1420c"},
+		{"eth1", "pf0vf1", "c2cfc60003a1420c"},
+	}
+	teardown := setupRepresentorEnv(t, "", append(vfReps, uplinkRep))
+	defer teardown()
+	setUpPfVfsPciEnv(t, pfPciAddress, vfs)
+	pfPciPath := filepath.Join(PciSysDir, pfPciAddress)
+	assert.NoError(t, utilfs.Fs.MkdirAll(filepath.Join(pfPciPath, "net", uplinkRep.Name), os.FileMode(0755)))
+	assert.NoError(t, utilfs.Fs.Symlink(pfPciPath, filepath.Join(NetSysDir, uplinkRep.Name, pcidevPrefix)))
+	assert.NoError(t, utilfs.Fs.Symlink(NetSysDir, filepath.Join(NetSysDir, uplinkRep.Name, "subsystem")))
+	assert.NoError(t, utilfs.Fs.MkdirAll(filepath.Join(PciSysDir, vfs[1], "net", "ens1f0v1"), os.FileMode(0755)))
+
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+
+	var order []string
+	rep := &netlink.Device{LinkAttrs: netlink.LinkAttrs{Name: "eth1"}}
+	nlOpsMock.On("LinkGetMTUByName", "ens1f0v1").Return(1500, nil)
+	nlOpsMock.On("LinkByName", "eth1").Return(rep, nil)
+	nlOpsMock.On("LinkSetMTU", rep, mock.Anything).Return(nil).Run(func(mock.Arguments) {
+		order = append(order, "eth1")
+	})
+	nlOpsMock.On("LinkSetMTUByName", "ens1f0v1", mock.Anything).Return(nil).Run(func(mock.Arguments) {
+		order = append(order, "ens1f0v1")
+	})
+
+	assert.NoError(t, SetVfAndRepresentorMtu(vfs[1], 9000))
+	assert.Equal(t, []string{"eth1", "ens1f0v1"}, order)
+	order = nil
+	assert.NoError(t, SetVfAndRepresentorMtu(vfs[1], 1400))
+	assert.Equal(t, []string{"ens1f0v1", "eth1"}, order)
+
+	// VF without a netdev, e.g bound to vfio-pci
+	assert.Error(t, SetVfAndRepresentorMtu(vfs[0], 9000))
+}
+
+func TestGetRepresentorStats(t *testing.T) {
+	teardown := setupRepresentorEnv(t, "", []*repContext{
+		{Name: "pf0vf1", PhysPortName: "pf0vf1", PhysSwitchID: "c2cfc60003a1420c"},
+		{Name: "eth0", PhysPortName: "", PhysSwitchID: ""},
+	})
+	defer teardown()
+
+	statsPath := filepath.Join(NetSysDir, "pf0vf1", netdevStatisticsDir)
+	assert.NoError(t, utilfs.Fs.MkdirAll(statsPath, os.FileMode(0755)))
+	counters := map[string]string{
+		"rx_bytes": "1000\n", "tx_bytes": "2000\n", "rx_packets": "10\n",
+		"tx_packets": "20\n", "rx_dropped": "1\n", "tx_dropped": "0\n",
+	}
+	for name, value := range counters {
+		assert.NoError(t, utilfs.Fs.WriteFile(filepath.Join(statsPath, name), []byte(value), os.FileMode(0644)))
+	}
+
+	stats, err := GetRepresentorStats("pf0vf1")
+	assert.NoError(t, err)
+	assert.Equal(t, &RepresentorStats{RxBytes: 1000, TxBytes: 2000, RxPackets: 10, TxPackets: 20, RxDropped: 1},
+		stats)
+
+	_, err = GetRepresentorStats("eth0")
+	assert.Error(t, err)
+
+	assert.NoError(t, utilfs.Fs.Remove(filepath.Join(statsPath, "tx_dropped")))
+	_, err = GetRepresentorStats("pf0vf1")
+	assert.Error(t, err)
+}
+
+func TestRepresentorPeerMaxTxRate(t *testing.T) {
+	teardown := setupRepresentorEnv(t, "", []*repContext{
+		{Name: "p0", PhysPortName: "p0", PhysSwitchID: "c2cfc60003a1420c"},
+		{Name: "pf0hpf", PhysPortName: "pf0", PhysSwitchID: "c2cfc60003a1420c"},
+		{Name: "pf0vf1", PhysPortName: "pf0vf1", PhysSwitchID: "c2cfc60003a1420c"},
+		{Name: "pf0sf1", PhysPortName: "pf0sf1", PhysSwitchID: "c2cfc60003a1420c"},
+		{Name: "pf0vf2", PhysPortName: "pf0vf2", PhysSwitchID: "c2cfc60003a1420c"},
+	})
+	defer teardown()
+	setupDPUConfigFileForPort(t, "p0", "pf", "MAC        : 0c:42:a1:de:cf:7c\nMaxTxRate  : 0\n")
+	setupDPUConfigFileForPort(t, "p0", "vf2", "MAC        : 0c:42:a1:de:cf:7e\n")
+	setupDPUConfigFileForPort(t, "p0", "vf1", "MAC        : 0c:42:a1:de:cf:7d\nMaxTxRate  : 1000\n")
+	rateFile := filepath.Join(NetSysDir, "p0", "smart_nic", "vf1", "max_tx_rate")
+	assert.NoError(t, utilfs.Fs.WriteFile(rateFile, []byte("1000"), os.FileMode(0644)))
+
+	rate, err := GetRepresentorPeerMaxTxRate("pf0hpf")
+	assert.NoError(t, err)
+	assert.Equal(t, uint(0), rate)
+	rate, err = GetRepresentorPeerMaxTxRate("pf0vf1")
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1000), rate)
+
+	assert.NoError(t, SetRepresentorPeerMaxTxRate("pf0vf1", 2500))
+	assert.Equal(t, "2500", readFakeFile(t, rateFile))
+
+	// no max_tx_rate attribute
+	assert.Error(t, SetRepresentorPeerMaxTxRate("pf0hpf", 100))
+	_, err = GetRepresentorPeerMaxTxRate("pf0sf1")
+	assert.Error(t, err)
+	// no MaxTxRate in config, unknown is not reported as unlimited
+	_, err = GetRepresentorPeerMaxTxRate("pf0vf2")
+	assert.Error(t, err)
+	assert.Error(t, SetRepresentorPeerMaxTxRate("pf0sf1", 100))
+}
+
+func TestRepresentorPeerState(t *testing.T) {
+	teardown := setupRepresentorEnv(t, "", []*repContext{
+		{Name: "p0", PhysPortName: "p0", PhysSwitchID: "c2cfc60003a1420c"},
+		{Name: "pf0hpf", PhysPortName: "pf0", PhysSwitchID: "c2cfc60003a1420c"},
+		{Name: "pf0vf1", PhysPortName: "pf0vf1", PhysSwitchID: "c2cfc60003a1420c"},
+	})
+	defer teardown()
+	setupDPUConfigFileForPort(t, "p0", "pf", "MAC        : 0c:42:a1:de:cf:7c\n")
+	setupDPUConfigFileForPort(t, "p0", "vf1", "MAC        : 0c:42:a1:de:cf:7d\nState      : Follow\n")
+	stateFile := filepath.Join(NetSysDir, "p0", "smart_nic", "vf1", "state")
+	assert.NoError(t, utilfs.Fs.WriteFile(stateFile, []byte("Follow"), os.FileMode(0644)))
+
+	state, err := GetRepresentorPeerState("pf0vf1")
+	assert.NoError(t, err)
+	assert.Equal(t, RepPeerStateFollow, state)
+	_, err = GetRepresentorPeerState("pf0hpf")
+	assert.Error(t, err)
+
+	assert.NoError(t, SetRepresentorPeerState("pf0vf1", RepPeerStateDown))
+	assert.Equal(t, "Down", readFakeFile(t, stateFile))
+	assert.Error(t, SetRepresentorPeerState("pf0vf1", "Auto"))
+}
+
+func TestParseRepConfig(t *testing.T) {
+	config, err := ParseRepConfig(`
+MAC        : 0c:42:a1:de:cf:7c
+MaxTxRate  : 100
+State      : Follow
+`)
+	assert.NoError(t, err)
+	maxTxRate := uint(100)
+	assert.Equal(t, &RepConfig{
+		MAC:       net.HardwareAddr{0x0c, 0x42, 0xa1, 0xde, 0xcf, 0x7c},
+		MaxTxRate: &maxTxRate,
+		State:     RepPeerStateFollow,
+	}, config)
+
+	config, err = ParseRepConfig("MAC        : 0c:42:a1:de:cf:7c\n")
+	assert.NoError(t, err)
+	assert.Nil(t, config.MaxTxRate)
+	assert.Equal(t, RepPeerState(""), config.State)
+
+	_, err = ParseRepConfig("MAC        : foo\n")
+	assert.Error(t, err)
+	_, err = ParseRepConfig("MaxTxRate  : -1\n")
+	assert.Error(t, err)
+}
+
+func TestGetRepresentorPeerConfig(t *testing.T) {
+	teardown := setupRepresentorEnv(t, "", []*repContext{
+		{Name: "p0", PhysPortName: "p0", PhysSwitchID: "c2cfc60003a1420c"},
+		{Name: "pf0vf1", PhysPortName: "pf0vf1", PhysSwitchID: "c2cfc60003a1420c"},
+		{Name: "pf0vf2", PhysPortName: "pf0vf2", PhysSwitchID: "c2cfc60003a1420c"},
+	})
+	defer teardown()
+	setupDPUConfigFileForPort(t, "p0", "vf1", "MAC        : 0c:42:a1:de:cf:7d\nMaxTxRate  : 10\nState      : Up\n")
+
+	config, err := GetRepresentorPeerConfig("pf0vf1")
+	assert.NoError(t, err)
+	assert.Equal(t, "0c:42:a1:de:cf:7d", config.MAC.String())
+	assert.Equal(t, uint(10), *config.MaxTxRate)
+	assert.Equal(t, RepPeerStateUp, config.State)
+
+	_, err = GetRepresentorPeerConfig("pf0vf2")
+	assert.Error(t, err)
+}
+
+func TestGetVfRepresentorsFromPfNetDevices(t *testing.T) {
+	pfPciAddress := "0000:03:00.1"
+	uplinkRep := &repContext{"p1", "p1", "c2cfc60003a1420c"}
+	vfReps := []*repContext{
+		{"eth0", "pf1vf0", "c2cfc60003a1420c"},
+		{"eth1", "pf1vf1", "c2cfc60003a1420c"},
+		{"eth2", "pf0vf2", "c2cfc60003a1420c"},
+	}
+	teardown := setupRepresentorEnv(t, "", append(vfReps, uplinkRep))
+	defer teardown()
+	pfPciPath := filepath.Join(PciSysDir, pfPciAddress)
+	for _, rep := range append(vfReps, uplinkRep) {
+		assert.NoError(t, utilfs.Fs.MkdirAll(filepath.Join(pfPciPath, "net", rep.Name), os.FileMode(0755)))
+	}
+	assert.NoError(t, utilfs.Fs.Symlink(pfPciPath, filepath.Join(NetSysDir, uplinkRep.Name, pcidevPrefix)))
+
+	// no subsystem link, representors are resolved from the PF net devices
+	reps, err := GetVfRepresentors(uplinkRep.Name)
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]string{0: "eth0", 1: "eth1"}, reps)
+}
+
+// noDirNamesFs hides the optional DirNamesReader interface of the wrapped Filesystem
+type noDirNamesFs struct {
+	utilfs.Filesystem
+}
+
+func TestGetVfRepresentorsWithoutDirNamesReader(t *testing.T) {
+	vfReps := []*repContext{
+		{"eth0", "pf0vf0", "c2cfc60003a1420c"},
+		{"eth1", "pf0vf1", "c2cfc60003a1420c"},
+	}
+	uplinkRep := &repContext{"p0", "p0", "c2cfc60003a1420c"}
+	teardown := setupRepresentorEnv(t, "", append(vfReps, uplinkRep))
+	defer teardown()
+	pfPciPath := filepath.Join(PciSysDir, "0000:03:00.0")
+	assert.NoError(t, utilfs.Fs.MkdirAll(pfPciPath, os.FileMode(0755)))
+	assert.NoError(t, utilfs.Fs.Symlink(pfPciPath, filepath.Join(NetSysDir, uplinkRep.Name, pcidevPrefix)))
+	assert.NoError(t, utilfs.Fs.Symlink(NetSysDir, filepath.Join(NetSysDir, uplinkRep.Name, "subsystem")))
+
+	utilfs.Fs = noDirNamesFs{utilfs.Fs}
+	reps, err := GetVfRepresentors(uplinkRep.Name)
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]string{0: "eth0", 1: "eth1"}, reps)
+}
@@ -0,0 +1,219 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sriovnet
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	utilfs "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/filesystem"
+)
+
+// SriovWatchHandler holds the callbacks invoked by a SriovWatcher, nil callbacks are skipped.
+// Callbacks are invoked sequentially from the goroutine running the watcher.
+type SriovWatchHandler struct {
+	// NumVfsChanged is called when sriov_numvfs of a PF changed
+	NumVfsChanged func(pfPciAddress string, oldNumVfs, newNumVfs int)
+	// VfAdded is called when a virtfn link of a PF appeared
+	VfAdded func(pfPciAddress string, vfIndex int, vfPciAddress string)
+	// VfRemoved is called when a virtfn link of a PF disappeared
+	VfRemoved func(pfPciAddress string, vfIndex int, vfPciAddress string)
+	// NetdevAdded is called when a netdev appeared in the net directory of a PF or of one of its VFs
+	NetdevAdded func(pciAddress, netdev string)
+	// NetdevRemoved is called when a netdev disappeared from the net directory of a PF or of one of its VFs
+	NetdevRemoved func(pciAddress, netdev string)
+}
+
+// pfSysfsState is the SR-IOV related sysfs state of a PF as observed by a SriovWatcher
+type pfSysfsState struct {
+	numVfs int
+	// vfs maps VF index to VF PCI address
+	vfs map[int]string
+	// netdevs maps the PCI address of the PF and of its VFs to their netdev names
+	netdevs map[string]map[string]bool
+}
+
+// SriovWatcher monitors sriov_numvfs, the virtfn links and the net directories of a set of PFs
+// and invokes the handler callbacks on changes, e.g to react to out-of-band changes made by admins
+// or other agents.
+type SriovWatcher struct {
+	pfPciAddresses []string
+	interval       time.Duration
+	handler        SriovWatchHandler
+	states         map[string]*pfSysfsState
+}
+
+// sriovWatchDefaultInterval is the sysfs polling interval of a SriovWatcher created with a non-positive interval
+const sriovWatchDefaultInterval = time.Second
+
+// NewSriovWatcher creates a SriovWatcher for the PFs with the given PCI addresses.
+//
+// The PF directories and the net directories of the PFs and their VFs are watched with inotify, which reports
+// the sysfs writes made from user space (e.g an admin writing sriov_numvfs). The kernel does not generate
+// inotify events for the sysfs entries it creates or removes itself (virtfn links, netdevs), so sysfs is also
+// polled every interval, a non-positive interval defaults to one second. The watcher only polls if inotify
+// is not available.
+func NewSriovWatcher(pfPciAddresses []string, interval time.Duration, handler SriovWatchHandler) *SriovWatcher {
+	if interval <= 0 {
+		interval = sriovWatchDefaultInterval
+	}
+	return &SriovWatcher{
+		pfPciAddresses: pfPciAddresses,
+		interval:       interval,
+		handler:        handler,
+		states:         make(map[string]*pfSysfsState),
+	}
+}
+
+// Run watches the PFs until done is closed. The state found when Run is called is the baseline,
+// callbacks are only invoked for subsequent changes.
+func (w *SriovWatcher) Run(done <-chan struct{}) {
+	// events and errors are left nil, i.e never ready, if inotify is not available
+	var events <-chan fsnotify.Event
+	var errs <-chan error
+	notifier, err := fsnotify.NewWatcher()
+	if err == nil {
+		defer notifier.Close()
+		events, errs = notifier.Events, notifier.Errors
+	}
+	w.poll()
+	w.watch(notifier)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		case <-events:
+			drainEvents(events)
+		case <-errs:
+			// an event was lost, e.g on inotify queue overflow, the poll below catches up
+		}
+		w.poll()
+		w.watch(notifier)
+	}
+}
+
+// drainEvents discards the pending events, changes are detected by polling once per burst of events
+func drainEvents(events <-chan fsnotify.Event) {
+	for {
+		select {
+		case <-events:
+		default:
+			return
+		}
+	}
+}
+
+// watch adds an inotify watch on the directories of the watched PFs, a nil notifier is a noop. Adding an
+// existing watch is a noop and inotify removes watches along with their directory, so watch is called after
+// every poll to cover the directories which appeared since the previous one.
+func (w *SriovWatcher) watch(notifier *fsnotify.Watcher) {
+	if notifier == nil {
+		return
+	}
+	for _, pf := range w.pfPciAddresses {
+		dirs := []string{filepath.Join(PciSysDir, pf)}
+		for pciAddress := range w.states[pf].netdevs {
+			dirs = append(dirs, filepath.Join(PciSysDir, pciAddress, "net"))
+		}
+		for _, dir := range dirs {
+			// directories which do not exist, e.g the net directory of a VF bound to vfio-pci, are skipped
+			if path, err := utilfs.RealPath(utilfs.Fs, dir); err == nil {
+				_ = notifier.Add(path)
+			}
+		}
+	}
+}
+
+// readNetdevNames returns the netdev names of the PCI device with the given address
+func readNetdevNames(pciAddress string) map[string]bool {
+	netdevs := make(map[string]bool)
+	names, err := utilfs.ReadDirNames(utilfs.Fs, filepath.Join(PciSysDir, pciAddress, "net"))
+	if err != nil {
+		return netdevs
+	}
+	for _, name := range names {
+		netdevs[name] = true
+	}
+	return netdevs
+}
+
+// readPfSysfsState reads the SR-IOV related sysfs state of the PF with the given PCI address
+func readPfSysfsState(pfPciAddress string) *pfSysfsState {
+	state := &pfSysfsState{netdevs: make(map[string]map[string]bool)}
+	state.numVfs, _ = readPciIntAttr(pfPciAddress, netDevCurrentVfCountFile)
+	vfs, err := getVfPciAddressMapFromPfPci(pfPciAddress)
+	if err != nil {
+		vfs = make(map[int]string)
+	}
+	state.vfs = vfs
+	state.netdevs[pfPciAddress] = readNetdevNames(pfPciAddress)
+	for _, vfPciAddress := range vfs {
+		state.netdevs[vfPciAddress] = readNetdevNames(vfPciAddress)
+	}
+	return state
+}
+
+// poll reads the state of the watched PFs and invokes the callbacks for the changes since the previous poll
+func (w *SriovWatcher) poll() {
+	for _, pf := range w.pfPciAddresses {
+		state := readPfSysfsState(pf)
+		prev, ok := w.states[pf]
+		w.states[pf] = state
+		if ok {
+			w.notify(pf, prev, state)
+		}
+	}
+}
+
+// notify invokes the callbacks for the changes between the prev and cur states of a PF
+func (w *SriovWatcher) notify(pf string, prev, cur *pfSysfsState) {
+	h := &w.handler
+	if prev.numVfs != cur.numVfs && h.NumVfsChanged != nil {
+		h.NumVfsChanged(pf, prev.numVfs, cur.numVfs)
+	}
+
+	for vfIndex, vfPciAddress := range prev.vfs {
+		if cur.vfs[vfIndex] != vfPciAddress && h.VfRemoved != nil {
+			h.VfRemoved(pf, vfIndex, vfPciAddress)
+		}
+	}
+	for vfIndex, vfPciAddress := range cur.vfs {
+		if prev.vfs[vfIndex] != vfPciAddress && h.VfAdded != nil {
+			h.VfAdded(pf, vfIndex, vfPciAddress)
+		}
+	}
+
+	for pciAddress, netdevs := range prev.netdevs {
+		for netdev := range netdevs {
+			if !cur.netdevs[pciAddress][netdev] && h.NetdevRemoved != nil {
+				h.NetdevRemoved(pciAddress, netdev)
+			}
+		}
+	}
+	for pciAddress, netdevs := range cur.netdevs {
+		for netdev := range netdevs {
+			if !prev.netdevs[pciAddress][netdev] && h.NetdevAdded != nil {
+				h.NetdevAdded(pciAddress, netdev)
+			}
+		}
+	}
+}
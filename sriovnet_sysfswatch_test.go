@@ -0,0 +1,172 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sriovnet
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	utilfs "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/filesystem"
+)
+
+func TestSriovWatcher(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+	pf := "0000:03:00.0"
+	setUpPfVfsPciEnv(t, pf, []string{"0000:03:00.2"})
+	assert.NoError(t, utilfs.Fs.WriteFile(filepath.Join(PciSysDir, pf, netDevCurrentVfCountFile), []byte("1\n"),
+		os.FileMode(0644)))
+	assert.NoError(t, utilfs.Fs.MkdirAll(filepath.Join(PciSysDir, pf, "net", "p0"), os.FileMode(0755)))
+	assert.NoError(t, utilfs.Fs.MkdirAll(filepath.Join(PciSysDir, "0000:03:00.2", "net", "eth0"), os.FileMode(0755)))
+
+	var events []string
+	watcher := NewSriovWatcher([]string{pf}, time.Second, SriovWatchHandler{
+		NumVfsChanged: func(pf string, oldNumVfs, newNumVfs int) {
+			events = append(events, fmt.Sprintf("numvfs %s %d->%d", pf, oldNumVfs, newNumVfs))
+		},
+		VfAdded: func(pf string, vfIndex int, vfPciAddress string) {
+			events = append(events, fmt.Sprintf("vf added %s %d %s", pf, vfIndex, vfPciAddress))
+		},
+		NetdevAdded: func(pciAddress, netdev string) {
+			events = append(events, fmt.Sprintf("netdev added %s %s", pciAddress, netdev))
+		},
+		NetdevRemoved: func(pciAddress, netdev string) {
+			events = append(events, fmt.Sprintf("netdev removed %s %s", pciAddress, netdev))
+		},
+	})
+
+	// the first poll is the baseline
+	watcher.poll()
+	assert.Empty(t, events)
+
+	// no change
+	watcher.poll()
+	assert.Empty(t, events)
+
+	assert.NoError(t, utilfs.Fs.WriteFile(filepath.Join(PciSysDir, pf, netDevCurrentVfCountFile), []byte("2\n"),
+		os.FileMode(0644)))
+	vfPath := filepath.Join(PciSysDir, "0000:03:00.3")
+	assert.NoError(t, utilfs.Fs.MkdirAll(filepath.Join(vfPath, "net", "eth1"), os.FileMode(0755)))
+	assert.NoError(t, utilfs.Fs.Symlink(vfPath, filepath.Join(PciSysDir, pf, "virtfn1")))
+	assert.NoError(t, utilfs.Fs.RemoveAll(filepath.Join(PciSysDir, pf, "net", "p0")))
+	assert.NoError(t, utilfs.Fs.MkdirAll(filepath.Join(PciSysDir, pf, "net", "pf0hpf"), os.FileMode(0755)))
+
+	watcher.poll()
+	sort.Strings(events)
+	assert.Equal(t, []string{
+		"netdev added 0000:03:00.0 pf0hpf",
+		"netdev added 0000:03:00.3 eth1",
+		"netdev removed 0000:03:00.0 p0",
+		"numvfs 0000:03:00.0 1->2",
+		"vf added 0000:03:00.0 1 0000:03:00.3",
+	}, events)
+}
+
+func TestSriovWatcherRun(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+	pf := "0000:03:00.0"
+	setUpPfVfsPciEnv(t, pf, nil)
+
+	changed := make(chan int, 1)
+	watcher := NewSriovWatcher([]string{pf}, 10*time.Millisecond, SriovWatchHandler{
+		NumVfsChanged: func(_ string, _, newNumVfs int) {
+			select {
+			case changed <- newNumVfs:
+			default:
+			}
+		},
+	})
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		watcher.Run(done)
+		close(stopped)
+	}()
+
+	// let the watcher establish its baseline before changing sriov_numvfs
+	time.Sleep(50 * time.Millisecond)
+	assert.NoError(t, utilfs.Fs.WriteFile(filepath.Join(PciSysDir, pf, netDevCurrentVfCountFile), []byte("4\n"),
+		os.FileMode(0644)))
+	select {
+	case numVfs := <-changed:
+		assert.Equal(t, 4, numVfs)
+	case <-time.After(5 * time.Second):
+		t.Error("sriov_numvfs change was not reported")
+	}
+	close(done)
+	<-stopped
+}
+
+func TestNewSriovWatcherDefaultInterval(t *testing.T) {
+	watcher := NewSriovWatcher([]string{"0000:03:00.0"}, 0, SriovWatchHandler{})
+	assert.Equal(t, sriovWatchDefaultInterval, watcher.interval)
+	watcher = NewSriovWatcher([]string{"0000:03:00.0"}, -time.Second, SriovWatchHandler{})
+	assert.Equal(t, sriovWatchDefaultInterval, watcher.interval)
+}
+
+func TestSriovWatcherRunInotify(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+	pf := "0000:03:00.0"
+	setUpPfVfsPciEnv(t, pf, nil)
+	assert.NoError(t, utilfs.Fs.MkdirAll(filepath.Join(PciSysDir, pf, "net"), os.FileMode(0755)))
+
+	events := make(chan string, 4)
+	// changes are only reported through inotify as the watcher does not poll in the test timeframe
+	watcher := NewSriovWatcher([]string{pf}, time.Hour, SriovWatchHandler{
+		NumVfsChanged: func(_ string, _, newNumVfs int) {
+			events <- fmt.Sprintf("numvfs %d", newNumVfs)
+		},
+		NetdevAdded: func(_, netdev string) {
+			events <- "netdev added " + netdev
+		},
+	})
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		watcher.Run(done)
+		close(stopped)
+	}()
+	defer func() {
+		close(done)
+		<-stopped
+	}()
+	waitEvent := func(expected string) {
+		t.Helper()
+		select {
+		case event := <-events:
+			assert.Equal(t, expected, event)
+		case <-time.After(5 * time.Second):
+			t.Errorf("%q was not reported", expected)
+		}
+	}
+
+	// let the watcher establish its baseline and watches before changing sysfs
+	time.Sleep(50 * time.Millisecond)
+	assert.NoError(t, utilfs.Fs.WriteFile(filepath.Join(PciSysDir, pf, netDevCurrentVfCountFile), []byte("4\n"),
+		os.FileMode(0644)))
+	waitEvent("numvfs 4")
+	assert.NoError(t, utilfs.Fs.MkdirAll(filepath.Join(PciSysDir, pf, "net", "p0"), os.FileMode(0755)))
+	waitEvent("netdev added p0")
+}
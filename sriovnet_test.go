@@ -17,14 +17,19 @@ limitations under the License.
 package sriovnet
 
 import (
+	"fmt"
+	"net"
 	"os"
 	"path/filepath"
 	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/vishvananda/netlink"
 
 	utilfs "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/filesystem"
+	"github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/netlinkops"
+	netlinkopsMocks "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/netlinkops/mocks"
 )
 
 const (
@@ -32,7 +37,7 @@ const (
 	pciSysDriversDir = "/sys/bus/pci/drivers"
 )
 
-func setupFakeFs(t *testing.T) func() {
+func setupFakeFs(t testing.TB) func() {
 	var err error
 	var teardown func()
 	utilfs.Fs, teardown, err = utilfs.NewFakeFs(fakeFsRoot)
@@ -123,6 +128,30 @@ func TestGetPfIndexByVfPciAddress(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestGetPfIndexByVfPciAddressNonZeroDomain(t *testing.T) {
+	pfPciAddr := "00d5:01:00.1"
+	vfPciAddr := "00d5:01:02.3"
+	teardown := SetupPfVfEnv(t, pfPciAddr, vfPciAddr)
+	defer teardown()
+	pfId, err := GetPfIndexByVfPciAddress(vfPciAddr)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, pfId)
+}
+
+func TestGetVfIndexByPciAddressNonZeroDomain(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+	vfs := []string{"00d5:01:00.2", "00d5:01:00.3", "00d5:01:00.4"}
+	setUpPfVfsPciEnv(t, "00d5:01:00.0", vfs)
+
+	vfIndex, err := GetVfIndexByPciAddress(vfs[1])
+	assert.NoError(t, err)
+	assert.Equal(t, 1, vfIndex)
+
+	_, err = GetVfIndexByPciAddress("00d5:01:00.0")
+	assert.Error(t, err)
+}
+
 func TestGetPfPciFromVfPci(t *testing.T) {
 	pfPciAddr := "0000:02:00.0"
 	vfPciAddr := "0000:02:00.6"
@@ -236,6 +265,21 @@ func TestGetPciFromNetDevice(t *testing.T) {
 	assert.Equal(t, devices[0].PciAddr, pci)
 }
 
+func TestGetPciFromNetDeviceNonZeroDomain(t *testing.T) {
+	devices := []*devContext{
+		{"p0", "00d5:01:00.0"},
+		{"p1", "10000:01:00.1"},
+	}
+	teardown := setupGetPciFromNetDeviceEnv(t, devices)
+	defer teardown()
+
+	for _, dev := range devices {
+		pci, err := GetPciFromNetDevice(dev.Name)
+		assert.NoError(t, err)
+		assert.Equal(t, dev.PciAddr, pci)
+	}
+}
+
 func TestGetPciFromNetDeviceNotPCI(t *testing.T) {
 	devices := []*devContext{
 		{"br0", ""},
@@ -248,6 +292,85 @@ func TestGetPciFromNetDeviceNotPCI(t *testing.T) {
 	assert.Contains(t, err.Error(), "is not a PCI device")
 }
 
+// setupRdmaDeviceEnv creates a (fake) RDMA device under the given sysfs device directory with the given netdevs
+func setupRdmaDeviceEnv(t *testing.T, rdmaDevice, deviceDir string, netDevs []string) {
+	rdmaPath := filepath.Join(deviceDir, "infiniband", rdmaDevice)
+	assert.NoError(t, utilfs.Fs.MkdirAll(rdmaPath, os.FileMode(0755)))
+	assert.NoError(t, utilfs.Fs.MkdirAll(InfinibandSysDir, os.FileMode(0755)))
+	assert.NoError(t, utilfs.Fs.Symlink(rdmaPath, filepath.Join(InfinibandSysDir, rdmaDevice)))
+	assert.NoError(t, utilfs.Fs.Symlink(deviceDir, filepath.Join(rdmaPath, "device")))
+	for _, netDev := range netDevs {
+		assert.NoError(t, utilfs.Fs.MkdirAll(filepath.Join(deviceDir, "net", netDev), os.FileMode(0755)))
+	}
+}
+
+func TestGetPciFromRdmaDevice(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+	setupRdmaDeviceEnv(t, "mlx5_0", "/sys/devices/pci0000:00/0000:03:00.0", []string{"p0"})
+	setupRdmaDeviceEnv(t, "mlx5_2", "/sys/devices/pci0000:00/0000:03:00.0/mlx5_core.sf.2", []string{"enp3s0f0s88"})
+	setupRdmaDeviceEnv(t, "rxe0", "/sys/devices/virtual/foo", nil)
+
+	pci, err := GetPciFromRdmaDevice("mlx5_0")
+	assert.NoError(t, err)
+	assert.Equal(t, "0000:03:00.0", pci)
+
+	pci, err = GetPciFromRdmaDevice("mlx5_2")
+	assert.NoError(t, err)
+	assert.Equal(t, "0000:03:00.0", pci)
+
+	_, err = GetPciFromRdmaDevice("rxe0")
+	assert.Error(t, err)
+	_, err = GetPciFromRdmaDevice("mlx5_1")
+	assert.Error(t, err)
+}
+
+func TestGetNetdevFromRdmaDevice(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+	setupRdmaDeviceEnv(t, "mlx5_0", "/sys/devices/pci0000:00/0000:03:00.0", []string{"p0"})
+	setupRdmaDeviceEnv(t, "mlx5_2", "/sys/devices/pci0000:00/0000:03:00.0/mlx5_core.sf.2", []string{"enp3s0f0s88"})
+
+	netDevs, err := GetNetdevFromRdmaDevice("mlx5_0")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"p0"}, netDevs)
+
+	netDevs, err = GetNetdevFromRdmaDevice("mlx5_2")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"enp3s0f0s88"}, netDevs)
+
+	_, err = GetNetdevFromRdmaDevice("mlx5_1")
+	assert.Error(t, err)
+}
+
+func TestGetNetdevLinkType(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+	teardown := setupFakeFs(t)
+	defer teardown()
+
+	for netdev, linkType := range map[string]string{"p0": "1", "ib0": "32", "gre0": "778"} {
+		assert.NoError(t, utilfs.Fs.MkdirAll(filepath.Join(NetSysDir, netdev), os.FileMode(0755)))
+		assert.NoError(t, utilfs.Fs.WriteFile(filepath.Join(NetSysDir, netdev, netdevTypeFile),
+			[]byte(linkType+"\n"), os.FileMode(0644)))
+	}
+	nlOpsMock.On("LinkByName", "gre0").Return(
+		&netlink.Gretun{LinkAttrs: netlink.LinkAttrs{Name: "gre0", EncapType: "ipgre"}}, nil)
+
+	linkType, err := GetNetdevLinkType("p0")
+	assert.NoError(t, err)
+	assert.Equal(t, LinkTypeEther, linkType)
+
+	linkType, err = GetNetdevLinkType("ib0")
+	assert.NoError(t, err)
+	assert.Equal(t, LinkTypeInfiniband, linkType)
+
+	linkType, err = GetNetdevLinkType("gre0")
+	assert.NoError(t, err)
+	assert.Equal(t, LinkType("ipgre"), linkType)
+}
+
 func TestGetPKeyByIndexFromPci(t *testing.T) {
 	teardown := setupFakeFs(t)
 	defer teardown()
@@ -306,3 +429,39 @@ func TestGetDefaultPKeyFromPci(t *testing.T) {
 		assert.Equal(t, v.pkey, pKey)
 	}
 }
+
+func TestGetNetdevMacAddress(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+
+	mac, _ := net.ParseMAC("02:00:00:00:00:01")
+	link := &netlink.Device{LinkAttrs: netlink.LinkAttrs{Name: "eth0", HardwareAddr: mac}}
+	nlOpsMock.On("LinkByName", "eth0").Return(link, nil)
+	nlOpsMock.On("LinkByName", "eth1").Return(nil, fmt.Errorf("link not found"))
+
+	addr, err := GetNetdevMacAddress("eth0")
+	assert.NoError(t, err)
+	assert.Equal(t, mac, addr)
+	_, err = GetNetdevMacAddress("eth1")
+	assert.Error(t, err)
+}
+
+func TestGetNetdevPermMacAddress(t *testing.T) {
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	defer netlinkops.ResetNetlinkOps()
+
+	mac, _ := net.ParseMAC("0c:42:a1:00:00:01")
+	nlOpsMock.On("LinkPermHardwareAddr", "eth0").Return(mac, nil)
+	nlOpsMock.On("LinkPermHardwareAddr", "eth0v1").Return(make(net.HardwareAddr, 6), nil)
+	nlOpsMock.On("LinkPermHardwareAddr", "eth1").Return(nil, fmt.Errorf("no such device"))
+
+	addr, err := GetNetdevPermMacAddress("eth0")
+	assert.NoError(t, err)
+	assert.Equal(t, mac, addr)
+	_, err = GetNetdevPermMacAddress("eth0v1")
+	assert.ErrorIs(t, err, ErrNotSupported)
+	_, err = GetNetdevPermMacAddress("eth1")
+	assert.Error(t, err)
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sriovnet
+
+import "time"
+
+// TraceHook is called when a traced operation starts, with the operation name (e.g 'GetVfRepresentor', or
+// 'sysfs.write' for an individual sysfs write step) and its attributes (e.g 'uplink' and 'vf'). The returned
+// function, if not nil, is called when the operation ends with its result, which allows to start and end a
+// tracing span or to record the operation latency.
+// Traced operations may be nested, e.g GetVfRepresentorByVfPciAddress calls GetUplinkRepresentor; nested
+// operations start and end within their parent on the same goroutine.
+type TraceHook func(op string, attrs map[string]string) (end func(err error))
+
+var traceHook TraceHook
+
+// SetTraceHook sets the hook called around representor lookups, SR-IOV enablement, VF configuration and
+// sysfs writes, e.g to create OpenTelemetry spans. A nil hook disables tracing. It is not safe to change the
+// hook while sriovnet calls are in flight, it is meant to be set once at startup.
+func SetTraceHook(hook TraceHook) {
+	traceHook = hook
+}
+
+// startTrace reports the start of op to the trace hook, attrs are given as name, value pairs.
+// The returned function reports the end of op, it is meant to be deferred with the named error result:
+//
+//	defer startTrace("EnableSriov", "pf", pfNetdevName)(&err)
+//
+// At LogLevelDebug the end of op is logged along with its duration.
+func startTrace(op string, attrs ...string) func(err *error) {
+	hook := traceHook
+	debug := logDebugEnabled()
+	if hook == nil && !debug {
+		return func(*error) {}
+	}
+	var end func(error)
+	if hook != nil {
+		attrMap := make(map[string]string, len(attrs)/2)
+		for i := 0; i+1 < len(attrs); i += 2 {
+			attrMap[attrs[i]] = attrs[i+1]
+		}
+		end = hook(op, attrMap)
+	}
+	start := time.Now()
+	return func(err *error) {
+		if end != nil {
+			end(*err)
+		}
+		if debug {
+			keysAndValues := make([]interface{}, 0, len(attrs)+6)
+			keysAndValues = append(keysAndValues, "op", op)
+			for _, attr := range attrs {
+				keysAndValues = append(keysAndValues, attr)
+			}
+			keysAndValues = append(keysAndValues, "duration", time.Since(start))
+			if *err != nil {
+				keysAndValues = append(keysAndValues, "error", *err)
+			}
+			logDebug("operation completed", keysAndValues...)
+		}
+	}
+}
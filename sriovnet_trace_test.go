@@ -0,0 +1,86 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sriovnet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type traceEvent struct {
+	op    string
+	attrs map[string]string
+	end   bool
+	err   error
+}
+
+func setUpTraceHook() (events *[]traceEvent, teardown func()) {
+	events = &[]traceEvent{}
+	SetTraceHook(func(op string, attrs map[string]string) func(error) {
+		*events = append(*events, traceEvent{op: op, attrs: attrs})
+		return func(err error) {
+			*events = append(*events, traceEvent{op: op, end: true, err: err})
+		}
+	})
+	return events, func() { SetTraceHook(nil) }
+}
+
+func TestTraceHookNestedLookup(t *testing.T) {
+	teardown := setUpSwitchdevInventoryEnv(t)
+	defer teardown()
+	events, teardownHook := setUpTraceHook()
+	defer teardownHook()
+
+	rep, err := GetVfRepresentorByVfPciAddress("0000:03:00.3")
+	assert.NoError(t, err)
+	assert.Equal(t, "rep1", rep)
+	assert.Equal(t, []traceEvent{
+		{op: "GetVfRepresentorByVfPciAddress", attrs: map[string]string{"pci": "0000:03:00.3"}},
+		{op: "GetUplinkRepresentor", attrs: map[string]string{"pci": "0000:03:00.3"}},
+		{op: "GetUplinkRepresentor", end: true},
+		{op: "GetVfRepresentor", attrs: map[string]string{"uplink": "p0", "vf": "1"}},
+		{op: "GetVfRepresentors", attrs: map[string]string{"uplink": "p0"}},
+		{op: "GetVfRepresentors", end: true},
+		{op: "GetVfRepresentor", end: true},
+		{op: "GetVfRepresentorByVfPciAddress", end: true},
+	}, *events)
+
+	*events = nil
+	_, err = GetVfRepresentorByVfPciAddress("0000:03:00.5")
+	assert.Error(t, err)
+	assert.Len(t, *events, 4)
+	assert.Equal(t, traceEvent{op: "GetVfRepresentorByVfPciAddress", end: true, err: err}, (*events)[3])
+}
+
+func TestTraceHookSysfsWrite(t *testing.T) {
+	teardown := setupFakeFs(t)
+	defer teardown()
+	setUpPciAttrs(t, "0000:03:00.2", map[string]string{pciDevDriverOverride: "(null)\n"})
+	events, teardownHook := setUpTraceHook()
+	defer teardownHook()
+
+	assert.NoError(t, SetPciDriverOverride("0000:03:00.2", vfioPciDriver))
+	assert.Equal(t, []traceEvent{
+		{op: "sysfs.write", attrs: map[string]string{"path": "/sys/bus/pci/devices/0000:03:00.2/driver_override"}},
+		{op: "sysfs.write", end: true},
+	}, *events)
+
+	SetTraceHook(nil)
+	assert.NoError(t, SetPciDriverOverride("0000:03:00.2", vfioPciDriver))
+	assert.Len(t, *events, 2)
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sriovnet
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	utilfs "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/filesystem"
+)
+
+const (
+	netdevFlagsFile = "flags"
+	// iffUp is the IFF_UP net device flag
+	iffUp = 0x1
+)
+
+// SwitchdevIssue is the kind of problem reported by ValidateSwitchdevSetup
+type SwitchdevIssue string
+
+const (
+	// SwitchdevIssueMissingRepresentor is reported for a VF of the uplink PF without a representor
+	SwitchdevIssueMissingRepresentor SwitchdevIssue = "MissingRepresentor"
+	// SwitchdevIssueSwitchIDMismatch is reported for a representor of the uplink PF with a different phys_switch_id
+	SwitchdevIssueSwitchIDMismatch SwitchdevIssue = "SwitchIDMismatch"
+	// SwitchdevIssueRepresentorDown is reported for a representor which is administratively down
+	SwitchdevIssueRepresentorDown SwitchdevIssue = "RepresentorDown"
+)
+
+// SwitchdevFinding is a problem found by ValidateSwitchdevSetup
+type SwitchdevFinding struct {
+	Issue SwitchdevIssue
+	// Netdev is the representor netdev the finding refers to, empty for missing representors
+	Netdev string
+	// VfIndex is the VF index the finding refers to, -1 if unknown
+	VfIndex int
+	Message string
+}
+
+// isNetdevAdminUp returns true if the IFF_UP flag of the given netdev is set
+func isNetdevAdminUp(netdev string) (bool, error) {
+	data, err := utilfs.Fs.ReadFile(filepath.Join(NetSysDir, netdev, netdevFlagsFile))
+	if err != nil {
+		return false, err
+	}
+	flags, err := strconv.ParseUint(strings.TrimSpace(string(data)), 0, 32)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse flags of %s: %v", netdev, err)
+	}
+	return flags&iffUp != 0, nil
+}
+
+// ValidateSwitchdevSetup verifies the switchdev setup of the given uplink: every VF of the uplink PF has
+// a representor, the representors of the PF share the uplink phys_switch_id and are administratively up.
+// Returns the findings sorted by VF index and issue, an empty list means the setup is consistent.
+func ValidateSwitchdevSetup(uplink string) ([]SwitchdevFinding, error) {
+	physSwitchID, err := utilfs.Fs.ReadFile(filepath.Join(NetSysDir, uplink, netdevPhysSwitchID))
+	if err != nil || len(physSwitchID) == 0 {
+		return nil, fmt.Errorf("cant get uplink %s switch id", uplink)
+	}
+	pfPciAddress, err := getPCIFromDeviceName(uplink)
+	if err != nil {
+		return nil, err
+	}
+	vfs, err := getVfPciAddressMapFromPfPci(pfPciAddress)
+	if err != nil {
+		return nil, err
+	}
+	reps, err := GetVfRepresentors(uplink)
+	if err != nil {
+		return nil, err
+	}
+
+	findings := make([]SwitchdevFinding, 0)
+	for vfIndex, vfPciAddress := range vfs {
+		rep, ok := reps[vfIndex]
+		if !ok {
+			findings = append(findings, SwitchdevFinding{
+				Issue:   SwitchdevIssueMissingRepresentor,
+				VfIndex: vfIndex,
+				Message: fmt.Sprintf("VF %d (%s) of %s has no representor", vfIndex, vfPciAddress, uplink),
+			})
+			continue
+		}
+		up, err := isNetdevAdminUp(rep)
+		if err != nil || !up {
+			findings = append(findings, SwitchdevFinding{
+				Issue:   SwitchdevIssueRepresentorDown,
+				Netdev:  rep,
+				VfIndex: vfIndex,
+				Message: fmt.Sprintf("representor %s of VF %d of %s is not administratively up", rep, vfIndex, uplink),
+			})
+		}
+	}
+
+	// Representors are parented to the uplink PF device, look for ones on a different switch
+	pfNetPath := filepath.Join(NetSysDir, uplink, pcidevPrefix, "net")
+	netdevs, err := utilfs.Fs.ReadDir(pfNetPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read net devices of %s: %v", pfPciAddress, err)
+	}
+	for _, netdev := range netdevs {
+		portName, err := GetPhysPortName(netdev.Name())
+		if err != nil {
+			continue
+		}
+		_, vfIndex, err := parsePortName(portName)
+		if err != nil {
+			continue
+		}
+		swID, err := utilfs.Fs.ReadFile(filepath.Join(NetSysDir, netdev.Name(), netdevPhysSwitchID))
+		if err == nil && bytes.Equal(swID, physSwitchID) {
+			continue
+		}
+		findings = append(findings, SwitchdevFinding{
+			Issue:   SwitchdevIssueSwitchIDMismatch,
+			Netdev:  netdev.Name(),
+			VfIndex: vfIndex,
+			Message: fmt.Sprintf("representor %s phys_switch_id does not match uplink %s", netdev.Name(), uplink),
+		})
+	}
+
+	sort.Slice(findings, func(i, j int) bool {
+		if findings[i].VfIndex != findings[j].VfIndex {
+			return findings[i].VfIndex < findings[j].VfIndex
+		}
+		return findings[i].Issue < findings[j].Issue
+	})
+	return findings, nil
+}
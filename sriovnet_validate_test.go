@@ -0,0 +1,63 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sriovnet
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	utilfs "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/filesystem"
+)
+
+func TestValidateSwitchdevSetup(t *testing.T) {
+	pfPciAddress := "0000:03:00.0"
+	vfs := []string{"0000:03:00.2", "0000:03:00.3", "0000:03:00.4"}
+	uplinkRep := &repContext{"p0", "p0", "c2cfc60003a1420c"}
+	vfReps := []*repContext{
+		{"eth0", "pf0vf0", "c2cfc60003a1420c"},
+		{"eth1", "pf0vf1", "c2cfc60003a1420c"},
+		{"eth2", "pf0vf2", "aabbccddeeff0011"},
+	}
+	teardown := setupRepresentorEnv(t, "", append(vfReps, uplinkRep))
+	defer teardown()
+	setUpPfVfsPciEnv(t, pfPciAddress, vfs)
+	pfPciPath := filepath.Join(PciSysDir, pfPciAddress)
+	assert.NoError(t, utilfs.Fs.Symlink(pfPciPath, filepath.Join(NetSysDir, uplinkRep.Name, pcidevPrefix)))
+	assert.NoError(t, utilfs.Fs.Symlink(NetSysDir, filepath.Join(NetSysDir, uplinkRep.Name, "subsystem")))
+	flags := map[string]string{"p0": "0x1003\n", "eth0": "0x1003\n", "eth1": "0x1002\n", "eth2": "0x1003\n"}
+	for netdev, flag := range flags {
+		assert.NoError(t, utilfs.Fs.MkdirAll(filepath.Join(pfPciPath, "net", netdev), os.FileMode(0755)))
+		assert.NoError(t, utilfs.Fs.WriteFile(filepath.Join(NetSysDir, netdev, netdevFlagsFile), []byte(flag),
+			os.FileMode(0644)))
+	}
+
+	findings, err := ValidateSwitchdevSetup("p0")
+	assert.NoError(t, err)
+	assert.Len(t, findings, 3)
+	assert.Equal(t, SwitchdevIssueRepresentorDown, findings[0].Issue)
+	assert.Equal(t, "eth1", findings[0].Netdev)
+	assert.Equal(t, SwitchdevIssueMissingRepresentor, findings[1].Issue)
+	assert.Equal(t, 2, findings[1].VfIndex)
+	assert.Equal(t, SwitchdevIssueSwitchIDMismatch, findings[2].Issue)
+	assert.Equal(t, "eth2", findings[2].Netdev)
+
+	_, err = ValidateSwitchdevSetup("missing")
+	assert.Error(t, err)
+}
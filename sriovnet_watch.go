@@ -0,0 +1,291 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sriovnet
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+
+	"github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/netlinkops"
+)
+
+// RepresentorEventType is the kind of representor change reported by WatchRepresentors
+type RepresentorEventType int
+
+const (
+	// RepresentorAdded is reported for representors that appeared (or existed when the watch started)
+	RepresentorAdded RepresentorEventType = iota
+	// RepresentorRemoved is reported for representors that disappeared
+	RepresentorRemoved
+	// RepresentorRenamed is reported for representors whose netdev name changed
+	RepresentorRenamed
+)
+
+var representorEventTypeNames = map[RepresentorEventType]string{
+	RepresentorAdded:   "added",
+	RepresentorRemoved: "removed",
+	RepresentorRenamed: "renamed",
+}
+
+func (t RepresentorEventType) String() string {
+	if name, ok := representorEventTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown(%d)", int(t))
+}
+
+// RepresentorEvent describes a change of a VF or SF representor on the eswitch of an uplink
+type RepresentorEvent struct {
+	Type RepresentorEventType
+	// Representor is the representor after the change, or before it for RepresentorRemoved events
+	Representor
+	// OldName is the previous netdev name of a RepresentorRenamed event
+	OldName string
+}
+
+// DevlinkPortEventType is the kind of devlink port change reported by WatchDevlinkPorts
+type DevlinkPortEventType int
+
+const (
+	// DevlinkPortAdded is reported for newly registered devlink ports (e.g VF port registered, SF port added)
+	DevlinkPortAdded DevlinkPortEventType = iota
+	// DevlinkPortRemoved is reported for unregistered devlink ports
+	DevlinkPortRemoved
+	// DevlinkPortStateChanged is reported when the port function state or operational state changed
+	// (e.g SF activated and attached to its driver)
+	DevlinkPortStateChanged
+	// DevlinkPortUpdated is reported for the other notifications of a known port, which leave its function
+	// states unchanged (e.g its netdev was attached, detached or renamed)
+	DevlinkPortUpdated
+)
+
+var devlinkPortEventTypeNames = map[DevlinkPortEventType]string{
+	DevlinkPortAdded:        "added",
+	DevlinkPortRemoved:      "removed",
+	DevlinkPortStateChanged: "state-changed",
+	DevlinkPortUpdated:      "updated",
+}
+
+func (t DevlinkPortEventType) String() string {
+	if name, ok := devlinkPortEventTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown(%d)", int(t))
+}
+
+// DevlinkPortEvent describes a change of a devlink port
+type DevlinkPortEvent struct {
+	Type DevlinkPortEventType
+	Port *netlink.DevlinkPort
+}
+
+// devlinkPortKey identifies a devlink port
+type devlinkPortKey struct {
+	bus    string
+	device string
+	index  uint32
+}
+
+// devlinkPortFnStates holds the function state and operational state of a devlink port
+type devlinkPortFnStates struct {
+	state   uint8
+	opState uint8
+}
+
+func newDevlinkPortFnStates(port *netlink.DevlinkPort) devlinkPortFnStates {
+	if port.Fn == nil {
+		return devlinkPortFnStates{}
+	}
+	return devlinkPortFnStates{state: port.Fn.State, opState: port.Fn.OpState}
+}
+
+// devlinkPortWatcher tracks the function states of devlink ports
+type devlinkPortWatcher struct {
+	ports map[devlinkPortKey]devlinkPortFnStates
+}
+
+// handle returns the devlink port event resulting from a devlink port notification
+func (w *devlinkPortWatcher) handle(update netlinkops.DevlinkPortUpdate) *DevlinkPortEvent {
+	key := devlinkPortKey{bus: update.Port.BusName, device: update.Port.DeviceName, index: update.Port.PortIndex}
+	states, isKnown := w.ports[key]
+
+	if update.Cmd == nl.DEVLINK_CMD_PORT_DEL {
+		delete(w.ports, key)
+		return &DevlinkPortEvent{Type: DevlinkPortRemoved, Port: update.Port}
+	}
+
+	newStates := newDevlinkPortFnStates(update.Port)
+	w.ports[key] = newStates
+	if !isKnown {
+		return &DevlinkPortEvent{Type: DevlinkPortAdded, Port: update.Port}
+	}
+	if newStates != states {
+		return &DevlinkPortEvent{Type: DevlinkPortStateChanged, Port: update.Port}
+	}
+	return &DevlinkPortEvent{Type: DevlinkPortUpdated, Port: update.Port}
+}
+
+// WatchDevlinkPorts subscribes to devlink notifications and reports devlink ports that are added,
+// removed, whose function state changed or which are otherwise updated. Ports existing when the watch starts
+// are not reported until they change. This allows waiting for SF activation without polling (e.g with
+// WaitForAuxDev).
+// The notifications are subscribed to before the existing ports are listed so no change is missed, the
+// listed ports take precedence over the notifications received meanwhile, which are reported as changes of
+// the listed ports.
+// done is required, the returned channel is closed once done is closed or the devlink subscription
+// terminates.
+func WatchDevlinkPorts(done <-chan struct{}) (<-chan DevlinkPortEvent, error) {
+	if done == nil {
+		return nil, fmt.Errorf("done channel is required to watch devlink ports")
+	}
+	nlOps := netlinkops.GetNetlinkOps()
+
+	// the subscription is terminated once done is closed, or right away if the ports can't be listed
+	subDone := make(chan struct{})
+	listFailed := make(chan struct{})
+	go func() {
+		defer close(subDone)
+		select {
+		case <-done:
+		case <-listFailed:
+		}
+	}()
+	updates := make(chan netlinkops.DevlinkPortUpdate)
+	if err := nlOps.DevlinkPortSubscribe(updates, subDone); err != nil {
+		close(listFailed)
+		return nil, fmt.Errorf("failed to subscribe to devlink notifications: %v", err)
+	}
+
+	ports, err := nlOps.DevLinkGetAllPortList()
+	if err != nil {
+		close(listFailed)
+		go func() {
+			for range updates {
+			}
+		}()
+		return nil, fmt.Errorf("failed to list devlink ports: %v", err)
+	}
+	watcher := &devlinkPortWatcher{ports: make(map[devlinkPortKey]devlinkPortFnStates)}
+	for _, port := range ports {
+		key := devlinkPortKey{bus: port.BusName, device: port.DeviceName, index: port.PortIndex}
+		watcher.ports[key] = newDevlinkPortFnStates(port)
+	}
+
+	events := make(chan DevlinkPortEvent)
+	go func() {
+		defer close(events)
+		// drain pending updates so the subscription is not blocked
+		defer func() {
+			for range updates {
+			}
+		}()
+		for update := range updates {
+			select {
+			case events <- *watcher.handle(update):
+			case <-done:
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+// representorWatcher tracks the representors of an eswitch by their netdev index
+type representorWatcher struct {
+	switchID []byte
+	reps     map[int]*Representor
+}
+
+// handle returns the representor event resulting from a link update, or nil if the update
+// is not relevant to the watched eswitch
+func (w *representorWatcher) handle(update netlink.LinkUpdate) *RepresentorEvent {
+	attrs := update.Link.Attrs()
+	known, isKnown := w.reps[attrs.Index]
+
+	if update.Header.Type == unix.RTM_DELLINK {
+		if !isKnown {
+			return nil
+		}
+		delete(w.reps, attrs.Index)
+		return &RepresentorEvent{Type: RepresentorRemoved, Representor: *known}
+	}
+
+	if isKnown && known.Name == attrs.Name {
+		return nil
+	}
+	// the sysfs attributes are read directly as the representor cache may not reflect the change yet
+	meta := readNetdevSwitchMeta(attrs.Name)
+	if !bytes.Equal(meta.switchID, w.switchID) || meta.portName == "" {
+		return nil
+	}
+	info, err := parseRepresentorPortName(meta.portName)
+	if err != nil || (info.Flavour != PORT_FLAVOUR_PCI_VF && info.Flavour != PORT_FLAVOUR_PCI_SF) {
+		return nil
+	}
+
+	rep := &Representor{Name: attrs.Name, RepresentorInfo: *info}
+	w.reps[attrs.Index] = rep
+	if isKnown {
+		return &RepresentorEvent{Type: RepresentorRenamed, Representor: *rep, OldName: known.Name}
+	}
+	return &RepresentorEvent{Type: RepresentorAdded, Representor: *rep}
+}
+
+// WatchRepresentors subscribes to RTNETLINK link notifications and reports the VF and SF representors
+// of the given uplink eswitch that appear, disappear or are renamed. Representors existing when the
+// watch starts are reported first as RepresentorAdded events.
+// The returned channel is closed once done is closed or the netlink subscription terminates.
+func WatchRepresentors(uplink string, done <-chan struct{}) (<-chan RepresentorEvent, error) {
+	switchID := readNetdevSwitchMeta(uplink).switchID
+	if len(switchID) == 0 {
+		return nil, fmt.Errorf("cant get uplink %s switch id", uplink)
+	}
+
+	updates := make(chan netlink.LinkUpdate)
+	err := netlinkops.GetNetlinkOps().LinkSubscribeWithOptions(updates, done,
+		netlink.LinkSubscribeOptions{ListExisting: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to link notifications: %v", err)
+	}
+
+	events := make(chan RepresentorEvent)
+	watcher := &representorWatcher{switchID: switchID, reps: make(map[int]*Representor)}
+	go func() {
+		defer close(events)
+		// netlink keeps sending updates until it notices done is closed, drain them so it does not block
+		defer func() {
+			for range updates {
+			}
+		}()
+		for update := range updates {
+			event := watcher.handle(update)
+			if event == nil {
+				continue
+			}
+			select {
+			case events <- *event:
+			case <-done:
+				return
+			}
+		}
+	}()
+	return events, nil
+}
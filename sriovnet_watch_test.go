@@ -0,0 +1,205 @@
+/*
+Copyright 2023 NVIDIA CORPORATION & AFFILIATES
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sriovnet
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+
+	"github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/netlinkops"
+	netlinkopsMocks "github.com/k8snetworkplumbingwg/sriovnet/pkg/utils/netlinkops/mocks"
+)
+
+func newLinkUpdate(msgType uint16, index int, name string) netlink.LinkUpdate {
+	return netlink.LinkUpdate{
+		Header: unix.NlMsghdr{Type: msgType},
+		Link:   &netlink.Device{LinkAttrs: netlink.LinkAttrs{Index: index, Name: name}},
+	}
+}
+
+func TestWatchRepresentors(t *testing.T) {
+	reps := []*repContext{
+		{"p0", "p0", "c2cfc60003a1420c"},
+		{"eth0", "pf0vf0", "c2cfc60003a1420c"},
+		{"rep0", "pf0vf0", "c2cfc60003a1420c"},
+		{"eth1", "pf0sf3", "c2cfc60003a1420c"},
+		{"eth2", "pf0vf1", "a2cfc60003a1420c"},
+	}
+	teardown := setupRepresentorEnv(t, "", reps)
+	defer teardown()
+	defer netlinkops.ResetNetlinkOps()
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+
+	updates := []netlink.LinkUpdate{
+		newLinkUpdate(unix.RTM_NEWLINK, 1, "p0"),
+		newLinkUpdate(unix.RTM_NEWLINK, 2, "eth0"),
+		newLinkUpdate(unix.RTM_NEWLINK, 3, "eth1"),
+		newLinkUpdate(unix.RTM_NEWLINK, 4, "eth2"),
+		newLinkUpdate(unix.RTM_NEWLINK, 2, "rep0"),
+		newLinkUpdate(unix.RTM_NEWLINK, 2, "rep0"),
+		newLinkUpdate(unix.RTM_DELLINK, 3, "eth1"),
+		newLinkUpdate(unix.RTM_DELLINK, 4, "eth2"),
+	}
+	nlOpsMock.On("LinkSubscribeWithOptions", mock.Anything, mock.Anything,
+		netlink.LinkSubscribeOptions{ListExisting: true}).Run(func(args mock.Arguments) {
+		ch := args.Get(0).(chan<- netlink.LinkUpdate)
+		go func() {
+			defer close(ch)
+			for _, update := range updates {
+				ch <- update
+			}
+		}()
+	}).Return(nil)
+
+	done := make(chan struct{})
+	defer close(done)
+	events, err := WatchRepresentors("p0", done)
+	assert.NoError(t, err)
+
+	received := make([]RepresentorEvent, 0)
+	for event := range events {
+		received = append(received, event)
+	}
+	vf0 := RepresentorInfo{Flavour: PORT_FLAVOUR_PCI_VF, PfID: 0, FuncIndex: 0}
+	sf3 := RepresentorInfo{Flavour: PORT_FLAVOUR_PCI_SF, PfID: 0, FuncIndex: 3}
+	assert.Equal(t, []RepresentorEvent{
+		{Type: RepresentorAdded, Representor: Representor{Name: "eth0", RepresentorInfo: vf0}},
+		{Type: RepresentorAdded, Representor: Representor{Name: "eth1", RepresentorInfo: sf3}},
+		{Type: RepresentorRenamed, Representor: Representor{Name: "rep0", RepresentorInfo: vf0}, OldName: "eth0"},
+		{Type: RepresentorRemoved, Representor: Representor{Name: "eth1", RepresentorInfo: sf3}},
+	}, received)
+}
+
+func TestWatchRepresentorsFailures(t *testing.T) {
+	teardown := setupRepresentorEnv(t, "", []*repContext{{"p0", "p0", "c2cfc60003a1420c"}})
+	defer teardown()
+	defer netlinkops.ResetNetlinkOps()
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	nlOpsMock.On("LinkSubscribeWithOptions", mock.Anything, mock.Anything, mock.Anything).Return(
+		fmt.Errorf("failed to subscribe"))
+
+	_, err := WatchRepresentors("notswitchdev", nil)
+	assert.Error(t, err)
+	_, err = WatchRepresentors("p0", nil)
+	assert.Error(t, err)
+}
+
+func newDevlinkPortUpdate(cmd uint8, index uint32, state, opState uint8) netlinkops.DevlinkPortUpdate {
+	return netlinkops.DevlinkPortUpdate{
+		Cmd: cmd,
+		Port: &netlink.DevlinkPort{
+			BusName:     "pci",
+			DeviceName:  "0000:03:00.0",
+			PortIndex:   index,
+			PortFlavour: nl.DEVLINK_PORT_FLAVOUR_PCI_SF,
+			Fn:          &netlink.DevlinkPortFn{State: state, OpState: opState},
+		},
+	}
+}
+
+func TestWatchDevlinkPorts(t *testing.T) {
+	defer netlinkops.ResetNetlinkOps()
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+
+	existing := newDevlinkPortUpdate(nl.DEVLINK_CMD_PORT_NEW, 1, nl.DEVLINK_PORT_FN_STATE_INACTIVE,
+		nl.DEVLINK_PORT_FN_OPSTATE_DETACHED)
+	updates := []netlinkops.DevlinkPortUpdate{
+		// existing port is reported again without a state change
+		existing,
+		newDevlinkPortUpdate(nl.DEVLINK_CMD_PORT_NEW, 1, nl.DEVLINK_PORT_FN_STATE_ACTIVE,
+			nl.DEVLINK_PORT_FN_OPSTATE_DETACHED),
+		newDevlinkPortUpdate(nl.DEVLINK_CMD_PORT_NEW, 2, nl.DEVLINK_PORT_FN_STATE_INACTIVE,
+			nl.DEVLINK_PORT_FN_OPSTATE_DETACHED),
+		newDevlinkPortUpdate(nl.DEVLINK_CMD_PORT_NEW, 2, nl.DEVLINK_PORT_FN_STATE_ACTIVE,
+			nl.DEVLINK_PORT_FN_OPSTATE_ATTACHED),
+		newDevlinkPortUpdate(nl.DEVLINK_CMD_PORT_DEL, 1, nl.DEVLINK_PORT_FN_STATE_INACTIVE,
+			nl.DEVLINK_PORT_FN_OPSTATE_DETACHED),
+	}
+	subscribed := false
+	nlOpsMock.On("DevLinkGetAllPortList").Run(func(args mock.Arguments) {
+		// the notifications must be subscribed to before the ports are listed
+		assert.True(t, subscribed)
+	}).Return([]*netlink.DevlinkPort{existing.Port}, nil)
+	nlOpsMock.On("DevlinkPortSubscribe", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		subscribed = true
+		ch := args.Get(0).(chan<- netlinkops.DevlinkPortUpdate)
+		go func() {
+			defer close(ch)
+			for _, update := range updates {
+				ch <- update
+			}
+		}()
+	}).Return(nil)
+
+	done := make(chan struct{})
+	defer close(done)
+	events, err := WatchDevlinkPorts(done)
+	assert.NoError(t, err)
+
+	received := make([]DevlinkPortEvent, 0)
+	for event := range events {
+		received = append(received, event)
+	}
+	assert.Equal(t, []DevlinkPortEvent{
+		{Type: DevlinkPortUpdated, Port: updates[0].Port},
+		{Type: DevlinkPortStateChanged, Port: updates[1].Port},
+		{Type: DevlinkPortAdded, Port: updates[2].Port},
+		{Type: DevlinkPortStateChanged, Port: updates[3].Port},
+		{Type: DevlinkPortRemoved, Port: updates[4].Port},
+	}, received)
+}
+
+func TestWatchDevlinkPortsFailures(t *testing.T) {
+	defer netlinkops.ResetNetlinkOps()
+	nlOpsMock := netlinkopsMocks.NetlinkOps{}
+	netlinkops.SetNetlinkOps(&nlOpsMock)
+	done := make(chan struct{})
+	defer close(done)
+
+	_, err := WatchDevlinkPorts(nil)
+	assert.Error(t, err)
+
+	nlOpsMock.On("DevlinkPortSubscribe", mock.Anything, mock.Anything).Return(
+		fmt.Errorf("failed to subscribe")).Once()
+	_, err = WatchDevlinkPorts(done)
+	assert.Error(t, err)
+
+	// the subscription is terminated if the ports can't be listed
+	subDone := make(chan (<-chan struct{}), 1)
+	nlOpsMock.On("DevlinkPortSubscribe", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		subDone <- args.Get(1).(<-chan struct{})
+		close(args.Get(0).(chan<- netlinkops.DevlinkPortUpdate))
+	}).Return(nil).Once()
+	nlOpsMock.On("DevLinkGetAllPortList").Return(nil, fmt.Errorf("failed to list ports")).Once()
+	_, err = WatchDevlinkPorts(done)
+	assert.Error(t, err)
+	select {
+	case <-<-subDone:
+	case <-time.After(time.Second):
+		t.Error("devlink subscription not terminated")
+	}
+}